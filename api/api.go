@@ -14,7 +14,11 @@ import (
 	"github.com/ipfs/go-log/v2"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	"github.com/anywherelan/awl/awldns"
+	"github.com/anywherelan/awl/awlevent"
 	"github.com/anywherelan/awl/config"
 	"github.com/anywherelan/awl/p2p"
 	"github.com/anywherelan/awl/ringbuffer"
@@ -24,43 +28,63 @@ import (
 type DNSService interface {
 	AwlDNSAddress() string
 	IsAwlDNSSetAsSystem() bool
+	DNSStats() awldns.Stats
 }
 
 type Handler struct {
-	conf       *config.Config
-	logger     *log.ZapEventLogger
-	p2p        *p2p.P2p
-	authStatus *service.AuthStatus
-	tunnel     *service.Tunnel
-	socks5     *service.SOCKS5
-	dns        DNSService
-	logBuffer  *ringbuffer.RingBuffer
-
-	echo      *echo.Echo
-	echoAdmin *echo.Echo
+	conf         *config.Config
+	logger       *log.ZapEventLogger
+	p2p          *p2p.P2p
+	authStatus   *service.AuthStatus
+	tunnel       *service.Tunnel
+	socks5       *service.SOCKS5
+	hooks        *service.Hooks
+	remoteAdmin  *service.RemoteAdmin
+	fileTransfer *service.FileTransfer
+	wakeOnLAN    *service.WakeOnLAN
+	dns          DNSService
+	logBuffer    *ringbuffer.RingBuffer
+	eventbus     awlevent.Bus
+
+	echo           *echo.Echo
+	echoAdmin      *echo.Echo
+	metricsHandler http.Handler
 
 	ctx       context.Context
 	ctxCancel context.CancelFunc
 }
 
 func NewHandler(conf *config.Config, p2p *p2p.P2p, authStatus *service.AuthStatus, tunnel *service.Tunnel, socks5 *service.SOCKS5,
-	logBuffer *ringbuffer.RingBuffer, dns DNSService) *Handler {
+	hooks *service.Hooks, remoteAdmin *service.RemoteAdmin, fileTransfer *service.FileTransfer,
+	wakeOnLAN *service.WakeOnLAN, logBuffer *ringbuffer.RingBuffer, dns DNSService, eventbus awlevent.Bus) *Handler {
 	ctx, ctxCancel := context.WithCancel(context.Background())
 	return &Handler{
-		conf:       conf,
-		p2p:        p2p,
-		authStatus: authStatus,
-		tunnel:     tunnel,
-		socks5:     socks5,
-		dns:        dns,
-		logBuffer:  logBuffer,
-		logger:     log.Logger("awl/api"),
-		ctx:        ctx,
-		ctxCancel:  ctxCancel,
+		conf:         conf,
+		p2p:          p2p,
+		authStatus:   authStatus,
+		tunnel:       tunnel,
+		socks5:       socks5,
+		hooks:        hooks,
+		remoteAdmin:  remoteAdmin,
+		fileTransfer: fileTransfer,
+		wakeOnLAN:    wakeOnLAN,
+		dns:          dns,
+		logBuffer:    logBuffer,
+		eventbus:     eventbus,
+		logger:       log.Logger("awl/api"),
+		ctx:          ctx,
+		ctxCancel:    ctxCancel,
 	}
 }
 
 func (h *Handler) SetupAPI() error {
+	registry := prometheus.NewRegistry()
+	err := registry.Register(newPrometheusCollector(h))
+	if err != nil {
+		return fmt.Errorf("register prometheus collector: %v", err)
+	}
+	h.metricsHandler = promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+
 	e1, err := h.setupRouter(h.conf.HttpListenAddress)
 	if err != nil {
 		return err
@@ -104,9 +128,52 @@ func (h *Handler) setupRouter(address string) (*echo.Echo, error) {
 	e.POST(SendFriendRequestPath, h.SendFriendRequest)
 	e.POST(AcceptPeerInvitationPath, h.AcceptFriend)
 	e.POST(UpdatePeerSettingsPath, h.UpdatePeerSettings)
+	e.POST(UpdateExitNodeRulesPath, h.UpdateExitNodeRules)
 	e.POST(RemovePeerSettingsPath, h.RemovePeer)
 	e.GET(GetAuthRequestsPath, h.GetAuthRequests)
+	e.POST(DismissAuthRequestPath, h.DismissAuthRequest)
 	e.GET(GetBlockedPeersPath, h.GetBlockedPeers)
+	e.POST(GetPeerHistoryPath, h.GetPeerHistory)
+	e.POST(JoinByInvitePath, h.JoinByInvite)
+	e.POST(IntroducePeerPath, h.IntroducePeer)
+	e.POST(UpdateRemoteAdminPath, h.UpdateRemoteAdmin)
+	e.POST(UpdateFilePolicyPath, h.UpdateFileReceivePolicy)
+	e.POST(WakeOnLANPath, h.WakeOnLAN)
+	e.POST(UpdateWakePermissionPath, h.UpdateWakeOnLANPermission)
+	e.POST(UpdateWakeTargetsPath, h.UpdateWakeOnLANTargets)
+	e.POST(UpdateDNSRecordsPath, h.UpdateDNSRecords)
+	e.POST(UpdateDNSPermissionPath, h.UpdateDNSQueriesPermission)
+
+	// DNS
+	e.GET(GetDNSForwardingRulesPath, h.GetDNSForwardingRules)
+	e.POST(SetDNSForwardingRulePath, h.SetDNSForwardingRule)
+	e.POST(RemoveDNSForwardingRulePath, h.RemoveDNSForwardingRule)
+
+	// Groups
+	e.GET(GetGroupsPath, h.GetGroups)
+	e.POST(UpsertGroupPath, h.UpsertGroup)
+	e.POST(RemoveGroupPath, h.RemoveGroup)
+	e.POST(UpdateGroupPeersPath, h.UpdateGroupPeers)
+
+	// Files
+	e.POST(SendFilePath, h.SendFile)
+	e.GET(ListFileTransfersPath, h.ListFileTransfers)
+	e.POST(AcceptFilePath, h.AcceptFile)
+	e.POST(DeclineFilePath, h.DeclineFile)
+
+	// Network
+	e.GET(GetNetworkInfoPath, h.GetNetworkInfo)
+	e.POST(CreateNetworkPath, h.CreateNetwork)
+	e.POST(SignCertificatePath, h.SignCertificate)
+	e.POST(JoinNetworkPath, h.JoinNetwork)
+	e.POST(RevokeMembershipPath, h.RevokeMembership)
+	e.POST(UpdateNetworkAdminsPath, h.UpdateNetworkAdmins)
+	e.POST(LeaveNetworkPath, h.LeaveNetwork)
+
+	// Invites
+	e.POST(CreateInvitePath, h.CreateInvite)
+	e.GET(ListInvitesPath, h.ListInvites)
+	e.POST(RevokeInvitePath, h.RevokeInvite)
 
 	// Settings
 	e.GET(GetMyPeerInfoPath, h.GetMyPeerInfo)
@@ -114,10 +181,32 @@ func (h *Handler) setupRouter(address string) (*echo.Echo, error) {
 	e.GET(ListAvailableProxiesPath, h.ListAvailableProxies)
 	e.POST(UpdateProxySettingsPath, h.UpdateProxySettings)
 	e.GET(ExportServerConfigPath, h.ExportServerConfiguration)
+	e.POST(RotateIdentityKeyPath, h.RotateIdentityKey)
+	e.GET(StatusSharingPath, h.GetStatusSharing)
+	e.POST(StatusSharingPath, h.UpdateStatusSharing)
+	e.GET(GetRemoteAdminAuditPath, h.GetRemoteAdminAudit)
+
+	// Remote admin
+	e.Any(RemoteAdminProxyPath+"*", h.ProxyRemoteAdmin)
+
+	// Bootstrap peers
+	e.GET(GetBootstrapPeersPath, h.GetBootstrapPeers)
+	e.POST(AddBootstrapPeerPath, h.AddBootstrapPeer)
+	e.POST(RemoveBootstrapPeerPath, h.RemoveBootstrapPeer)
+	e.POST(UpdateBootstrapPeersSettingsPath, h.UpdateBootstrapPeersSettings)
 
 	// Debug
 	e.GET(GetP2pDebugInfoPath, h.GetP2pDebugInfo)
 	e.GET(GetDebugLogPath, h.GetLog)
+	e.GET(GetNATDiagnosticsPath, h.GetNATDiagnostics)
+	e.GET(GetHookDeliveriesPath, h.GetHookDeliveries)
+	e.GET(GetDNSDebugInfoPath, h.GetDNSDebugInfo)
+
+	// Events
+	e.GET(EventsPath, h.StreamEvents)
+
+	// Prometheus
+	e.GET(MetricsPath, echo.WrapHandler(h.metricsHandler))
 
 	if h.conf.DevMode() {
 		e.Any(V0Prefix+"debug/pprof/", echo.WrapHandler(http.HandlerFunc(http_pprof.Index)))
@@ -157,6 +246,8 @@ func (h *Handler) SetupFrontend(fsys fs.FS) {
 }
 
 func (h *Handler) Shutdown(ctx context.Context) error {
+	// stops event streams, server waits for them otherwise
+	h.ctxCancel()
 	if h.echoAdmin != nil {
 		err := h.echoAdmin.Server.Shutdown(ctx)
 		if err != nil {
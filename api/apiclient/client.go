@@ -1,11 +1,16 @@
 package apiclient
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/google/go-querystring/query"
@@ -30,6 +35,23 @@ func New(address string) *Client {
 	}
 }
 
+// SetRemotePeer makes client send all requests to api of peer through our node, peer should allow us remote admin.
+func (c *Client) SetRemotePeer(peerID string) {
+	c.cli.Transport = &remoteTransport{base: c.cli.Transport, peerID: peerID}
+}
+
+type remoteTransport struct {
+	base   http.RoundTripper
+	peerID string
+}
+
+func (t *remoteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Path = api.RemoteAdminProxyPath + strings.TrimPrefix(req.URL.Path, api.V0Prefix)
+	req.Header.Set(api.RemotePeerHeader, t.peerID)
+	return t.base.RoundTrip(req)
+}
+
 func (c *Client) KnownPeers() ([]entity.KnownPeersResponse, error) {
 	knownPeers := make([]entity.KnownPeersResponse, 0)
 	err := c.sendGetRequest(api.GetKnownPeersPath, &knownPeers)
@@ -39,6 +61,15 @@ func (c *Client) KnownPeers() ([]entity.KnownPeersResponse, error) {
 	return knownPeers, nil
 }
 
+func (c *Client) KnownPeersInGroup(group string) ([]entity.KnownPeersResponse, error) {
+	knownPeers := make([]entity.KnownPeersResponse, 0)
+	err := c.sendGetRequestWithParams(api.GetKnownPeersPath, entity.KnownPeersRequest{Group: group}, &knownPeers)
+	if err != nil {
+		return nil, err
+	}
+	return knownPeers, nil
+}
+
 func (c *Client) KnownPeerConfig(peerID string) (*config.KnownPeer, error) {
 	knownPeer := new(config.KnownPeer)
 	request := entity.PeerIDRequest{PeerID: peerID}
@@ -49,6 +80,16 @@ func (c *Client) KnownPeerConfig(peerID string) (*config.KnownPeer, error) {
 	return knownPeer, nil
 }
 
+func (c *Client) PeerHistory(peerID string) (*entity.PeerHistoryResponse, error) {
+	history := new(entity.PeerHistoryResponse)
+	request := entity.PeerIDRequest{PeerID: peerID}
+	err := c.sendPostRequest(api.GetPeerHistoryPath, request, history)
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
 func (c *Client) PeerInfo() (*entity.PeerInfo, error) {
 	peerInfo := new(entity.PeerInfo)
 	err := c.sendGetRequest(api.GetMyPeerInfoPath, peerInfo)
@@ -74,10 +115,11 @@ func (c *Client) UpdateProxySettings(usingPeerID string) error {
 	return c.sendPostRequest(api.UpdateProxySettingsPath, request, nil)
 }
 
-func (c *Client) SendFriendRequest(peerID, alias string) error {
+func (c *Client) SendFriendRequest(peerID, alias, message string) error {
 	request := entity.FriendRequest{
-		PeerID: peerID,
-		Alias:  alias,
+		PeerID:  peerID,
+		Alias:   alias,
+		Message: message,
 	}
 	return c.sendPostRequest(api.SendFriendRequestPath, request, nil)
 }
@@ -91,6 +133,48 @@ func (c *Client) ReplyFriendRequest(peerID, alias string, decline bool) error {
 	return c.sendPostRequest(api.AcceptPeerInvitationPath, request, nil)
 }
 
+func (c *Client) JoinByInvite(token, alias string) error {
+	request := entity.JoinByInviteRequest{
+		Token: token,
+		Alias: alias,
+	}
+	return c.sendPostRequest(api.JoinByInvitePath, request, nil)
+}
+
+func (c *Client) CreateInvite(expiresIn time.Duration, maxUses int, allowUsingAsExitNode bool, groups []string) (*entity.CreateInviteResponse, error) {
+	invite := new(entity.CreateInviteResponse)
+	request := entity.CreateInviteRequest{
+		ExpiresInSec:         int64(expiresIn.Seconds()),
+		MaxUses:              maxUses,
+		AllowUsingAsExitNode: allowUsingAsExitNode,
+		Groups:               groups,
+	}
+	err := c.sendPostRequest(api.CreateInvitePath, request, invite)
+	if err != nil {
+		return nil, err
+	}
+	return invite, nil
+}
+
+func (c *Client) Invites() ([]entity.InviteInfo, error) {
+	invites := make([]entity.InviteInfo, 0)
+	err := c.sendGetRequest(api.ListInvitesPath, &invites)
+	if err != nil {
+		return nil, err
+	}
+	return invites, nil
+}
+
+func (c *Client) RevokeInvite(id string) error {
+	request := entity.InviteIDRequest{ID: id}
+	return c.sendPostRequest(api.RevokeInvitePath, request, nil)
+}
+
+func (c *Client) IntroducePeer(peerID, introducedPeerID string) error {
+	request := entity.IntroducePeerRequest{PeerID: peerID, IntroducedPeerID: introducedPeerID}
+	return c.sendPostRequest(api.IntroducePeerPath, request, nil)
+}
+
 func (c *Client) AuthRequests() ([]entity.AuthRequest, error) {
 	authRequests := make([]entity.AuthRequest, 0)
 	err := c.sendGetRequest(api.GetAuthRequestsPath, &authRequests)
@@ -100,6 +184,16 @@ func (c *Client) AuthRequests() ([]entity.AuthRequest, error) {
 	return authRequests, nil
 }
 
+func (c *Client) UpdateExitNodeRules(peerID string, rules config.ExitNodeRules) error {
+	request := entity.ExitNodeRulesRequest{PeerID: peerID, ExitNodeRules: rules}
+	return c.sendPostRequest(api.UpdateExitNodeRulesPath, request, nil)
+}
+
+func (c *Client) DismissAuthRequest(peerID string) error {
+	request := entity.PeerIDRequest{PeerID: peerID}
+	return c.sendPostRequest(api.DismissAuthRequestPath, request, nil)
+}
+
 func (c *Client) UpdatePeerSettings(request entity.UpdatePeerSettingsRequest) error {
 	return c.sendPostRequest(api.UpdatePeerSettingsPath, request, nil)
 }
@@ -125,6 +219,254 @@ func (c *Client) P2pDebugInfo() (*entity.P2pDebugInfo, error) {
 	return debugInfo, nil
 }
 
+func (c *Client) Groups() ([]entity.PeerGroupResponse, error) {
+	groups := make([]entity.PeerGroupResponse, 0)
+	err := c.sendGetRequest(api.GetGroupsPath, &groups)
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (c *Client) UpsertGroup(request entity.UpsertGroupRequest) error {
+	return c.sendPostRequest(api.UpsertGroupPath, request, nil)
+}
+
+func (c *Client) RemoveGroup(name string) error {
+	request := entity.GroupNameRequest{Name: name}
+	return c.sendPostRequest(api.RemoveGroupPath, request, nil)
+}
+
+func (c *Client) UpdateGroupPeers(name string, addPeerIDs, removePeerIDs []string) error {
+	request := entity.UpdateGroupPeersRequest{
+		Name:          name,
+		AddPeerIDs:    addPeerIDs,
+		RemovePeerIDs: removePeerIDs,
+	}
+	return c.sendPostRequest(api.UpdateGroupPeersPath, request, nil)
+}
+
+func (c *Client) RotateIdentityKey(gracePeriod time.Duration) (*entity.RotateIdentityKeyResponse, error) {
+	resp := new(entity.RotateIdentityKeyResponse)
+	request := entity.RotateIdentityKeyRequest{GracePeriodSec: int64(gracePeriod.Seconds())}
+	err := c.sendPostRequest(api.RotateIdentityKeyPath, request, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Client) UpdateRemoteAdmin(peerID string, allow bool) error {
+	request := entity.RemoteAdminRequest{PeerID: peerID, Allow: allow}
+	return c.sendPostRequest(api.UpdateRemoteAdminPath, request, nil)
+}
+
+func (c *Client) RemoteAdminAudit() ([]config.RemoteAdminAuditEntry, error) {
+	entries := make([]config.RemoteAdminAuditEntry, 0)
+	err := c.sendGetRequest(api.GetRemoteAdminAuditPath, &entries)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (c *Client) UpdateFileReceivePolicy(peerID string, policy config.FileReceivePolicy) error {
+	request := entity.FileReceivePolicyRequest{PeerID: peerID, Policy: policy}
+	return c.sendPostRequest(api.UpdateFilePolicyPath, request, nil)
+}
+
+func (c *Client) UpdateDNSRecords(peerID string, records []config.DNSRecord) error {
+	request := entity.DNSRecordsRequest{PeerID: peerID, Records: records}
+	return c.sendPostRequest(api.UpdateDNSRecordsPath, request, nil)
+}
+
+// WakeOnLAN asks peer to wake device with mac or saved target in its LAN.
+func (c *Client) WakeOnLAN(peerID, mac, target string) (*entity.WakeOnLANResponse, error) {
+	request := entity.WakeOnLANRequest{PeerID: peerID, MAC: mac, Target: target}
+	response := new(entity.WakeOnLANResponse)
+	err := c.sendPostRequest(api.WakeOnLANPath, request, response)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (c *Client) UpdateWakeOnLANPermission(peerID string, allow bool) error {
+	request := entity.WakeOnLANPermissionRequest{PeerID: peerID, Allow: allow}
+	return c.sendPostRequest(api.UpdateWakePermissionPath, request, nil)
+}
+
+func (c *Client) UpdateWakeOnLANTargets(peerID string, targets []config.WakeOnLANTarget) error {
+	request := entity.WakeOnLANTargetsRequest{PeerID: peerID, Targets: targets}
+	return c.sendPostRequest(api.UpdateWakeTargetsPath, request, nil)
+}
+
+func (c *Client) UpdateDNSQueriesPermission(peerID string, allow bool) error {
+	request := entity.DNSQueriesPermissionRequest{PeerID: peerID, Allow: allow}
+	return c.sendPostRequest(api.UpdateDNSPermissionPath, request, nil)
+}
+
+func (c *Client) DNSForwardingRules() ([]entity.DNSForwardingRule, error) {
+	rules := make([]entity.DNSForwardingRule, 0)
+	err := c.sendGetRequest(api.GetDNSForwardingRulesPath, &rules)
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// SetDNSForwardingRule forwards queries for zone to peer or upstream, only one of them should be set.
+func (c *Client) SetDNSForwardingRule(zone, peerID, upstream string) error {
+	request := entity.DNSForwardingRuleRequest{Zone: zone, PeerID: peerID, Upstream: upstream}
+	return c.sendPostRequest(api.SetDNSForwardingRulePath, request, nil)
+}
+
+func (c *Client) RemoveDNSForwardingRule(zone string) error {
+	request := entity.RemoveDNSForwardingRuleRequest{Zone: zone}
+	return c.sendPostRequest(api.RemoveDNSForwardingRulePath, request, nil)
+}
+
+// SendFile starts sending file at path of device running awl, use FileTransfers to follow progress.
+func (c *Client) SendFile(peerID, path string) (*entity.FileTransfer, error) {
+	request := entity.SendFileRequest{PeerID: peerID, Path: path}
+	transfer := new(entity.FileTransfer)
+	err := c.sendPostRequest(api.SendFilePath, request, transfer)
+	if err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+func (c *Client) FileTransfers() ([]entity.FileTransfer, error) {
+	transfers := make([]entity.FileTransfer, 0)
+	err := c.sendGetRequest(api.ListFileTransfersPath, &transfers)
+	if err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+func (c *Client) AcceptFile(id string) error {
+	request := entity.FileTransferRequest{ID: id}
+	return c.sendPostRequest(api.AcceptFilePath, request, nil)
+}
+
+func (c *Client) DeclineFile(id string) error {
+	request := entity.FileTransferRequest{ID: id}
+	return c.sendPostRequest(api.DeclineFilePath, request, nil)
+}
+
+func (c *Client) StatusSharing() (*config.StatusSharingConfig, error) {
+	sharing := new(config.StatusSharingConfig)
+	err := c.sendGetRequest(api.StatusSharingPath, sharing)
+	if err != nil {
+		return nil, err
+	}
+	return sharing, nil
+}
+
+func (c *Client) UpdateStatusSharing(sharing config.StatusSharingConfig) error {
+	return c.sendPostRequest(api.StatusSharingPath, sharing, nil)
+}
+
+func (c *Client) NetworkInfo() (*entity.NetworkInfoResponse, error) {
+	info := new(entity.NetworkInfoResponse)
+	err := c.sendGetRequest(api.GetNetworkInfoPath, info)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (c *Client) CreateNetwork(name string) error {
+	request := entity.CreateNetworkRequest{Name: name}
+	return c.sendPostRequest(api.CreateNetworkPath, request, nil)
+}
+
+func (c *Client) SignCertificate(request entity.SignCertificateRequest) (*entity.SignCertificateResponse, error) {
+	resp := new(entity.SignCertificateResponse)
+	err := c.sendPostRequest(api.SignCertificatePath, request, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Client) JoinNetwork(certificate string) error {
+	request := entity.JoinNetworkRequest{Certificate: certificate}
+	return c.sendPostRequest(api.JoinNetworkPath, request, nil)
+}
+
+func (c *Client) RevokeMembership(peerID string) error {
+	request := entity.PeerIDRequest{PeerID: peerID}
+	return c.sendPostRequest(api.RevokeMembershipPath, request, nil)
+}
+
+func (c *Client) UpdateNetworkAdmins(add, remove []string) error {
+	request := entity.UpdateNetworkAdminsRequest{Add: add, Remove: remove}
+	return c.sendPostRequest(api.UpdateNetworkAdminsPath, request, nil)
+}
+
+func (c *Client) LeaveNetwork() error {
+	return c.sendPostRequest(api.LeaveNetworkPath, nil, nil)
+}
+
+func (c *Client) BootstrapPeers() (*entity.BootstrapPeersResponse, error) {
+	resp := new(entity.BootstrapPeersResponse)
+	err := c.sendGetRequest(api.GetBootstrapPeersPath, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Client) AddBootstrapPeer(address string) (*entity.BootstrapPeer, error) {
+	bootstrapPeer := new(entity.BootstrapPeer)
+	request := entity.BootstrapPeerRequest{Address: address}
+	err := c.sendPostRequest(api.AddBootstrapPeerPath, request, bootstrapPeer)
+	if err != nil {
+		return nil, err
+	}
+	return bootstrapPeer, nil
+}
+
+func (c *Client) RemoveBootstrapPeer(addrOrPeerID string) error {
+	request := entity.RemoveBootstrapPeerRequest{Address: addrOrPeerID}
+	return c.sendPostRequest(api.RemoveBootstrapPeerPath, request, nil)
+}
+
+func (c *Client) UpdateBootstrapPeersSettings(disableDefaultPeers bool) error {
+	request := entity.UpdateBootstrapSettingsRequest{DisableDefaultPeers: disableDefaultPeers}
+	return c.sendPostRequest(api.UpdateBootstrapPeersSettingsPath, request, nil)
+}
+
+func (c *Client) NATDiagnostics() (*entity.NATDiagnosticsResponse, error) {
+	diag := new(entity.NATDiagnosticsResponse)
+	err := c.sendGetRequest(api.GetNATDiagnosticsPath, diag)
+	if err != nil {
+		return nil, err
+	}
+	return diag, nil
+}
+
+func (c *Client) HookDeliveries() ([]entity.HookDelivery, error) {
+	deliveries := make([]entity.HookDelivery, 0)
+	err := c.sendGetRequest(api.GetHookDeliveriesPath, &deliveries)
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (c *Client) DNSDebugInfo() (*entity.DNSDebugInfo, error) {
+	debugInfo := new(entity.DNSDebugInfo)
+	err := c.sendGetRequest(api.GetDNSDebugInfoPath, debugInfo)
+	if err != nil {
+		return nil, err
+	}
+	return debugInfo, nil
+}
+
 // ApplicationLog
 // send numberOfLogs = 0 to print all logs
 func (c *Client) ApplicationLog(numberOfLogs int, startFromHead bool) (string, error) {
@@ -148,6 +490,39 @@ func (c *Client) ApplicationLog(numberOfLogs int, startFromHead bool) (string, e
 	return string(b), err
 }
 
+// SubscribeEvents calls handler for each event of given types (all types if empty) until ctx is done or stream is closed.
+func (c *Client) SubscribeEvents(ctx context.Context, types []string, handler func(entity.Event)) error {
+	reqURL, err := c.getUrl(api.EventsPath, entity.EventsRequest{Types: types})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	// stream is long-lived, so client timeout is not applied
+	cli := &http.Client{Transport: c.cli.Transport}
+	resp, err := cli.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return c.readResponseBody(resp, nil)
+	}
+
+	err = readEventStream(bufio.NewScanner(resp.Body), handler)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
 func (c *Client) getUrl(methodPath string, getParamsStruct interface{}) (string, error) {
 	reqURL := url.URL{
 		Scheme: "http",
@@ -164,7 +539,11 @@ func (c *Client) getUrl(methodPath string, getParamsStruct interface{}) (string,
 }
 
 func (c *Client) sendGetRequest(path string, responseRef interface{}) error {
-	reqURL, err := c.getUrl(path, nil)
+	return c.sendGetRequestWithParams(path, nil, responseRef)
+}
+
+func (c *Client) sendGetRequestWithParams(path string, getParamsStruct interface{}, responseRef interface{}) error {
+	reqURL, err := c.getUrl(path, getParamsStruct)
 	if err != nil {
 		return err
 	}
@@ -215,3 +594,36 @@ func (c *Client) readResponseBody(resp *http.Response, responseRef interface{})
 
 	return nil
 }
+
+// readEventStream parses Server-Sent Events, only data fields are used because event name is duplicated in data.
+func readEventStream(scanner *bufio.Scanner, handler func(entity.Event)) error {
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if data.Len() == 0 {
+				continue
+			}
+			event := entity.Event{}
+			err := json.Unmarshal([]byte(data.String()), &event)
+			if err != nil {
+				return fmt.Errorf("invalid event: %v", err)
+			}
+			data.Reset()
+			handler(event)
+			continue
+		}
+		if value, ok := strings.CutPrefix(line, "data:"); ok {
+			if data.Len() != 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(value, " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return errors.New("event stream closed")
+}
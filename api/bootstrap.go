@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+
+	"github.com/anywherelan/awl/config"
+	"github.com/anywherelan/awl/entity"
+	"github.com/anywherelan/awl/p2p"
+)
+
+const connectBootstrapPeerTimeout = 5 * time.Second
+
+// @Tags Bootstrap
+// @Summary Get bootstrap peers with their health
+// @Accept json
+// @Produce json
+// @Success 200 {object} entity.BootstrapPeersResponse
+// @Router /bootstrap/list [GET]
+func (h *Handler) GetBootstrapPeers(c echo.Context) (err error) {
+	debugInfo := h.p2p.BootstrapPeersStatsDetailed()
+	bootstrapPeers := h.p2p.BootstrapPeers()
+
+	h.conf.RLock()
+	response := entity.BootstrapPeersResponse{
+		DefaultPeersDisabled: h.conf.P2pNode.DisableDefaultBootstrapPeers,
+		Peers:                make([]entity.BootstrapPeer, 0, len(bootstrapPeers)),
+	}
+	h.conf.RUnlock()
+
+	for _, addrInfo := range bootstrapPeers {
+		response.Peers = append(response.Peers, h.makeBootstrapPeer(addrInfo, debugInfo[addrInfo.ID.String()]))
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// @Tags Bootstrap
+// @Summary Add custom bootstrap peer and connect to it
+// @Accept json
+// @Produce json
+// @Param body body entity.BootstrapPeerRequest true "Params"
+// @Success 200 {object} entity.BootstrapPeer
+// @Failure 400 {object} api.Error
+// @Router /bootstrap/add [POST]
+func (h *Handler) AddBootstrapPeer(c echo.Context) (err error) {
+	req := entity.BootstrapPeerRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	addr, err := multiaddr.NewMultiaddr(req.Address)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage("invalid multiaddr: "+err.Error()))
+	}
+	addrInfo, err := peer.AddrInfoFromP2pAddr(addr)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage("multiaddr should contain peer id: "+err.Error()))
+	}
+
+	if !h.conf.AddBootstrapPeer(addr) {
+		return c.JSON(http.StatusBadRequest, ErrorMessage("bootstrap peer already added"))
+	}
+	h.p2p.SetBootstrapPeers(h.conf.GetBootstrapPeers())
+
+	ctx, cancel := context.WithTimeout(h.ctx, connectBootstrapPeerTimeout)
+	defer cancel()
+	info := h.p2p.ConnectBootstrapPeer(ctx, *addrInfo)
+
+	return c.JSON(http.StatusOK, h.makeBootstrapPeer(*addrInfo, info))
+}
+
+// @Tags Bootstrap
+// @Summary Remove custom bootstrap peer
+// @Accept json
+// @Produce json
+// @Param body body entity.RemoveBootstrapPeerRequest true "Params"
+// @Success 200 "OK"
+// @Failure 400 {object} api.Error
+// @Failure 404 {object} api.Error
+// @Router /bootstrap/remove [POST]
+func (h *Handler) RemoveBootstrapPeer(c echo.Context) (err error) {
+	req := entity.RemoveBootstrapPeerRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+
+	if !h.conf.RemoveBootstrapPeer(req.Address) {
+		return c.JSON(http.StatusNotFound, ErrorMessage("custom bootstrap peer not found"))
+	}
+	h.p2p.SetBootstrapPeers(h.conf.GetBootstrapPeers())
+
+	return c.NoContent(http.StatusOK)
+}
+
+// @Tags Bootstrap
+// @Summary Update bootstrap peers settings
+// @Accept json
+// @Produce json
+// @Param body body entity.UpdateBootstrapSettingsRequest true "Params"
+// @Success 200 "OK"
+// @Failure 400 {object} api.Error
+// @Router /bootstrap/update_settings [POST]
+func (h *Handler) UpdateBootstrapPeersSettings(c echo.Context) (err error) {
+	req := entity.UpdateBootstrapSettingsRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+
+	h.conf.SetDefaultBootstrapPeersDisabled(req.DisableDefaultPeers)
+	added := h.p2p.SetBootstrapPeers(h.conf.GetBootstrapPeers())
+	for _, addrInfo := range added {
+		go func() {
+			ctx, cancel := context.WithTimeout(h.ctx, connectBootstrapPeerTimeout)
+			defer cancel()
+			h.p2p.ConnectBootstrapPeer(ctx, addrInfo)
+		}()
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *Handler) makeBootstrapPeer(addrInfo peer.AddrInfo, info p2p.BootstrapPeerDebugInfo) entity.BootstrapPeer {
+	addrs := make([]string, 0, len(addrInfo.Addrs))
+	for _, addr := range addrInfo.Addrs {
+		addrs = append(addrs, addr.String())
+	}
+
+	return entity.BootstrapPeer{
+		PeerID:      addrInfo.ID.String(),
+		Addresses:   addrs,
+		IsDefault:   config.IsDefaultBootstrapPeer(addrInfo.ID),
+		Connected:   h.p2p.IsConnected(addrInfo.ID),
+		Error:       info.Error,
+		Connections: info.Connections,
+	}
+}
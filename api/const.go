@@ -3,17 +3,57 @@ package api
 const (
 	V0Prefix = "/api/v0/"
 
+	// MetricsPath exposes metrics in Prometheus format
+	MetricsPath = "/metrics"
+
 	// Peers
 	GetKnownPeersPath        = V0Prefix + "peers/get_known"
 	GetKnownPeerSettingsPath = V0Prefix + "peers/get_known_peer_settings"
 	UpdatePeerSettingsPath   = V0Prefix + "peers/update_settings"
 	RemovePeerSettingsPath   = V0Prefix + "peers/remove"
+	GetPeerHistoryPath       = V0Prefix + "peers/history"
 
 	GetBlockedPeersPath = V0Prefix + "peers/get_blocked"
 
 	SendFriendRequestPath    = V0Prefix + "peers/invite_peer"
 	AcceptPeerInvitationPath = V0Prefix + "peers/accept_peer"
 	GetAuthRequestsPath      = V0Prefix + "peers/auth_requests"
+	JoinByInvitePath         = V0Prefix + "peers/join_invite"
+	IntroducePeerPath        = V0Prefix + "peers/introduce"
+	DismissAuthRequestPath   = V0Prefix + "peers/dismiss_request"
+	UpdateExitNodeRulesPath  = V0Prefix + "peers/exit_node_rules"
+	UpdateRemoteAdminPath    = V0Prefix + "peers/remote_admin"
+	UpdateFilePolicyPath     = V0Prefix + "peers/file_policy"
+	WakeOnLANPath            = V0Prefix + "peers/wake"
+	UpdateWakePermissionPath = V0Prefix + "peers/wake_permission"
+	UpdateWakeTargetsPath    = V0Prefix + "peers/wake_targets"
+	UpdateDNSRecordsPath     = V0Prefix + "peers/dns_records"
+	UpdateDNSPermissionPath  = V0Prefix + "peers/dns_permission"
+
+	// DNS
+	GetDNSForwardingRulesPath   = V0Prefix + "dns/forwarding/list"
+	SetDNSForwardingRulePath    = V0Prefix + "dns/forwarding/set"
+	RemoveDNSForwardingRulePath = V0Prefix + "dns/forwarding/remove"
+
+	// Groups
+	GetGroupsPath        = V0Prefix + "groups/list"
+	UpsertGroupPath      = V0Prefix + "groups/upsert"
+	RemoveGroupPath      = V0Prefix + "groups/remove"
+	UpdateGroupPeersPath = V0Prefix + "groups/update_peers"
+
+	// Network
+	GetNetworkInfoPath      = V0Prefix + "network/info"
+	CreateNetworkPath       = V0Prefix + "network/create"
+	SignCertificatePath     = V0Prefix + "network/sign"
+	JoinNetworkPath         = V0Prefix + "network/join"
+	RevokeMembershipPath    = V0Prefix + "network/revoke"
+	UpdateNetworkAdminsPath = V0Prefix + "network/admins"
+	LeaveNetworkPath        = V0Prefix + "network/leave"
+
+	// Invites
+	CreateInvitePath = V0Prefix + "invites/create"
+	ListInvitesPath  = V0Prefix + "invites/list"
+	RevokeInvitePath = V0Prefix + "invites/revoke"
 
 	// Settings
 	GetMyPeerInfoPath        = V0Prefix + "settings/peer_info"
@@ -21,8 +61,33 @@ const (
 	ListAvailableProxiesPath = V0Prefix + "settings/list_proxies"
 	UpdateProxySettingsPath  = V0Prefix + "settings/set_proxy"
 	ExportServerConfigPath   = V0Prefix + "settings/export_server_config"
+	RotateIdentityKeyPath    = V0Prefix + "settings/rotate_key"
+	StatusSharingPath        = V0Prefix + "settings/status_sharing"
+	GetRemoteAdminAuditPath  = V0Prefix + "settings/remote_admin_audit"
+
+	// Files
+	SendFilePath          = V0Prefix + "files/send"
+	ListFileTransfersPath = V0Prefix + "files/list"
+	AcceptFilePath        = V0Prefix + "files/accept"
+	DeclineFilePath       = V0Prefix + "files/decline"
+
+	// Bootstrap peers
+	GetBootstrapPeersPath            = V0Prefix + "bootstrap/list"
+	AddBootstrapPeerPath             = V0Prefix + "bootstrap/add"
+	RemoveBootstrapPeerPath          = V0Prefix + "bootstrap/remove"
+	UpdateBootstrapPeersSettingsPath = V0Prefix + "bootstrap/update_settings"
+
+	// Events
+	EventsPath = V0Prefix + "events"
+
+	// RemoteAdminProxyPath forwards "RemoteAdminProxyPath + path" to "V0Prefix + path" of peer from RemotePeerHeader
+	RemoteAdminProxyPath = V0Prefix + "remote/"
+	RemotePeerHeader     = "X-Awl-Remote-Peer"
 
 	// Debug
-	GetP2pDebugInfoPath = V0Prefix + "debug/p2p_info"
-	GetDebugLogPath     = V0Prefix + "debug/log"
+	GetP2pDebugInfoPath   = V0Prefix + "debug/p2p_info"
+	GetDebugLogPath       = V0Prefix + "debug/log"
+	GetNATDiagnosticsPath = V0Prefix + "debug/nat"
+	GetHookDeliveriesPath = V0Prefix + "debug/hooks"
+	GetDNSDebugInfoPath   = V0Prefix + "debug/dns"
 )
@@ -56,6 +56,52 @@ func (h *Handler) GetP2pDebugInfo(c echo.Context) (err error) {
 	return c.JSONPretty(http.StatusOK, debugInfo, "    ")
 }
 
+// @Tags Debug
+// @Summary Get recent deliveries of events to hooks, the newest last
+// @Produce json
+// @Success 200 {array} entity.HookDelivery
+// @Router /debug/hooks [GET]
+func (h *Handler) GetHookDeliveries(c echo.Context) (err error) {
+	return c.JSON(http.StatusOK, h.hooks.Deliveries())
+}
+
+// @Tags Debug
+// @Summary Get NAT type and connectivity diagnostics
+// @Produce json
+// @Success 200 {object} entity.NATDiagnosticsResponse
+// @Router /debug/nat [GET]
+func (h *Handler) GetNATDiagnostics(c echo.Context) (err error) {
+	diag := h.p2p.NATDiagnostics()
+	response := entity.NATDiagnosticsResponse{
+		NATDiagnostics: diag,
+		HolePunches:    make([]entity.HolePunchInfo, 0, len(diag.HolePunches)),
+	}
+	for _, result := range diag.HolePunches {
+		knownPeer, _ := h.conf.GetPeer(result.PeerID)
+		response.HolePunches = append(response.HolePunches, entity.HolePunchInfo{
+			HolePunchResult: result,
+			DisplayName:     knownPeer.DisplayName(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// @Tags Debug
+// @Summary Get awl DNS resolver stats: cache and health of upstream resolvers
+// @Produce json
+// @Success 200 {object} entity.DNSDebugInfo
+// @Router /debug/dns [GET]
+func (h *Handler) GetDNSDebugInfo(c echo.Context) (err error) {
+	debugInfo := entity.DNSDebugInfo{
+		Stats:               h.dns.DNSStats(),
+		AwlDNSAddress:       h.dns.AwlDNSAddress(),
+		IsAwlDNSSetAsSystem: h.dns.IsAwlDNSSetAsSystem(),
+	}
+
+	return c.JSON(http.StatusOK, debugInfo)
+}
+
 // @Tags Debug
 // @Summary Get logs
 // @Param logs query int false "Define number of rows of logs to output. On default and 0 prints all."
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/anywherelan/awl/config"
+	"github.com/anywherelan/awl/entity"
+)
+
+// @Tags DNS
+// @Summary Get DNS forwarding rules for private zones
+// @Produce json
+// @Success 200 {array} entity.DNSForwardingRule
+// @Router /dns/forwarding/list [GET]
+func (h *Handler) GetDNSForwardingRules(c echo.Context) (err error) {
+	rules := h.conf.DNSForwardingRules()
+	result := make([]entity.DNSForwardingRule, 0, len(rules))
+	for _, rule := range rules {
+		item := entity.DNSForwardingRule{DNSForwardingRule: rule}
+		if rule.PeerID != "" {
+			knownPeer, _ := h.conf.GetPeer(rule.PeerID)
+			item.PeerName = knownPeer.DisplayName()
+		}
+		result = append(result, item)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// @Tags DNS
+// @Summary Add DNS forwarding rule or replace rule for the same zone. Queries are sent to peer over p2p or to upstream
+// @Accept json
+// @Produce json
+// @Param body body entity.DNSForwardingRuleRequest true "Params"
+// @Success 200 "OK"
+// @Failure 400 {object} api.Error
+// @Failure 404 {object} api.Error
+// @Router /dns/forwarding/set [POST]
+func (h *Handler) SetDNSForwardingRule(c echo.Context) (err error) {
+	req := entity.DNSForwardingRuleRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+
+	err = h.conf.SetDNSForwardingRule(config.DNSForwardingRule{Zone: req.Zone, PeerID: req.PeerID, Upstream: req.Upstream})
+	if errors.Is(err, config.ErrPeerNotFound) {
+		return c.JSON(http.StatusNotFound, ErrorMessage(err.Error()))
+	} else if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// @Tags DNS
+// @Summary Remove DNS forwarding rule
+// @Accept json
+// @Produce json
+// @Param body body entity.RemoveDNSForwardingRuleRequest true "Params"
+// @Success 200 "OK"
+// @Failure 400 {object} api.Error
+// @Failure 404 {object} api.Error
+// @Router /dns/forwarding/remove [POST]
+func (h *Handler) RemoveDNSForwardingRule(c echo.Context) (err error) {
+	req := entity.RemoveDNSForwardingRuleRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+
+	err = h.conf.RemoveDNSForwardingRule(req.Zone)
+	if errors.Is(err, config.ErrDNSForwardingRuleNotFound) {
+		return c.JSON(http.StatusNotFound, ErrorMessage(err.Error()))
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorMessage(err.Error()))
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// @Tags Peers
+// @Summary Allow or forbid peer to resolve names with DNS upstreams of this device, peer uses it for its DNS forwarding rules
+// @Accept json
+// @Produce json
+// @Param body body entity.DNSQueriesPermissionRequest true "Params"
+// @Success 200 "OK"
+// @Failure 400 {object} api.Error
+// @Failure 404 {object} api.Error
+// @Router /peers/dns_permission [POST]
+func (h *Handler) UpdateDNSQueriesPermission(c echo.Context) (err error) {
+	req := entity.DNSQueriesPermissionRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+
+	err = h.conf.SetDNSQueriesAllowed(req.PeerID, req.Allow)
+	if errors.Is(err, config.ErrPeerNotFound) {
+		return c.JSON(http.StatusNotFound, ErrorMessage(err.Error()))
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorMessage(err.Error()))
+	}
+
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/anywherelan/awl/entity"
+	"github.com/anywherelan/awl/service"
+)
+
+const (
+	eventsHeartbeatInterval = 30 * time.Second
+	// eventsWriteTimeout closes stream of client which doesn't read events
+	eventsWriteTimeout = 10 * time.Second
+)
+
+// @Tags Events
+// @Summary Stream events as Server-Sent Events, event name is entity.Event Type
+// @Produce text/event-stream
+// @Param types query []string false "Filter events by type, comma separated" collectionFormat(csv)
+// @Success 200 {object} entity.Event
+// @Failure 400 {object} api.Error
+// @Router /events [GET]
+func (h *Handler) StreamEvents(c echo.Context) (err error) {
+	req := entity.EventsRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	types, err := parseEventTypes(req.Types)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+
+	events, closeEvents, err := service.SubscribeEvents(h.conf, h.eventbus, h.p2p.Host().EventBus())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorMessage(err.Error()))
+	}
+	defer closeEvents()
+
+	resp := c.Response()
+	resp.Header().Set(echo.HeaderContentType, "text/event-stream")
+	resp.Header().Set(echo.HeaderCacheControl, "no-cache")
+	resp.Header().Set(echo.HeaderConnection, "keep-alive")
+	resp.WriteHeader(http.StatusOK)
+	resp.Flush()
+
+	controller := http.NewResponseController(resp)
+	write := func(format string, args ...any) error {
+		_ = controller.SetWriteDeadline(time.Now().Add(eventsWriteTimeout))
+		_, err := fmt.Fprintf(resp, format, args...)
+		if err != nil {
+			return err
+		}
+		return controller.Flush()
+	}
+
+	heartbeat := time.NewTicker(eventsHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		var (
+			apiEvent entity.Event
+			open     bool
+		)
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case <-h.ctx.Done():
+			return nil
+		case <-heartbeat.C:
+			if write(": ping\n\n") != nil {
+				return nil
+			}
+			continue
+		case apiEvent, open = <-events:
+			if !open {
+				return nil
+			}
+		}
+
+		if len(types) != 0 && !slices.Contains(types, apiEvent.Type) {
+			continue
+		}
+		data, err := json.Marshal(apiEvent)
+		if err != nil {
+			h.logger.Errorf("marshal event %s: %v", apiEvent.Type, err)
+			continue
+		}
+		if write("event: %s\ndata: %s\n\n", apiEvent.Type, data) != nil {
+			return nil
+		}
+	}
+}
+
+func parseEventTypes(values []string) ([]string, error) {
+	types := make([]string, 0, len(values))
+	for _, value := range values {
+		for _, eventType := range strings.Split(value, ",") {
+			eventType = strings.TrimSpace(eventType)
+			if eventType == "" {
+				continue
+			}
+			if !slices.Contains(entity.EventTypes, eventType) {
+				return nil, fmt.Errorf("unknown event type %q", eventType)
+			}
+			types = append(types, eventType)
+		}
+	}
+	return types, nil
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/libp2p/go-libp2p/core/peer"
+
+	"github.com/anywherelan/awl/config"
+	"github.com/anywherelan/awl/entity"
+	"github.com/anywherelan/awl/service"
+)
+
+// @Tags Files
+// @Summary Send file from this device to peer, transfer runs in background
+// @Accept json
+// @Produce json
+// @Param body body entity.SendFileRequest true "Params"
+// @Success 200 {object} entity.FileTransfer
+// @Failure 400 {object} api.Error
+// @Failure 404 {object} api.Error
+// @Router /files/send [POST]
+func (h *Handler) SendFile(c echo.Context) (err error) {
+	req := entity.SendFileRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	peerID, err := peer.Decode(req.PeerID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+
+	transfer, err := h.fileTransfer.SendFile(peerID, req.Path)
+	if errors.Is(err, config.ErrPeerNotFound) {
+		return c.JSON(http.StatusNotFound, ErrorMessage(err.Error()))
+	} else if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, transfer)
+}
+
+// @Tags Files
+// @Summary Get recent file transfers, the newest last
+// @Produce json
+// @Success 200 {array} entity.FileTransfer
+// @Router /files/list [GET]
+func (h *Handler) ListFileTransfers(c echo.Context) (err error) {
+	return c.JSON(http.StatusOK, h.fileTransfer.Transfers())
+}
+
+// @Tags Files
+// @Summary Accept file offered by peer, it is saved into download dir
+// @Accept json
+// @Produce json
+// @Param body body entity.FileTransferRequest true "Params"
+// @Success 200 "OK"
+// @Failure 400 {object} api.Error
+// @Failure 404 {object} api.Error
+// @Router /files/accept [POST]
+func (h *Handler) AcceptFile(c echo.Context) (err error) {
+	return h.decideFile(c, h.fileTransfer.Accept)
+}
+
+// @Tags Files
+// @Summary Decline file offered by peer
+// @Accept json
+// @Produce json
+// @Param body body entity.FileTransferRequest true "Params"
+// @Success 200 "OK"
+// @Failure 400 {object} api.Error
+// @Failure 404 {object} api.Error
+// @Router /files/decline [POST]
+func (h *Handler) DeclineFile(c echo.Context) (err error) {
+	return h.decideFile(c, h.fileTransfer.Decline)
+}
+
+func (h *Handler) decideFile(c echo.Context, decide func(id string) error) (err error) {
+	req := entity.FileTransferRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+
+	err = decide(req.ID)
+	if errors.Is(err, service.ErrFileTransferNotFound) {
+		return c.JSON(http.StatusNotFound, ErrorMessage(err.Error()))
+	} else if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// @Tags Peers
+// @Summary Set what to do with files sent by peer: ask, auto accept or deny
+// @Accept json
+// @Produce json
+// @Param body body entity.FileReceivePolicyRequest true "Params"
+// @Success 200 "OK"
+// @Failure 400 {object} api.Error
+// @Failure 404 {object} api.Error
+// @Router /peers/file_policy [POST]
+func (h *Handler) UpdateFileReceivePolicy(c echo.Context) (err error) {
+	req := entity.FileReceivePolicyRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+
+	err = h.conf.SetFileReceivePolicy(req.PeerID, req.Policy)
+	if errors.Is(err, config.ErrPeerNotFound) {
+		return c.JSON(http.StatusNotFound, ErrorMessage(err.Error()))
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorMessage(err.Error()))
+	}
+
+	return c.NoContent(http.StatusOK)
+}
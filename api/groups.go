@@ -0,0 +1,148 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/anywherelan/awl/config"
+	"github.com/anywherelan/awl/entity"
+)
+
+// @Tags Groups
+// @Summary Get peer groups
+// @Accept json
+// @Produce json
+// @Success 200 {array} entity.PeerGroupResponse
+// @Router /groups/list [GET]
+func (h *Handler) GetGroups(c echo.Context) (err error) {
+	groups := h.conf.ListGroups()
+	result := make([]entity.PeerGroupResponse, 0, len(groups))
+	for _, group := range groups {
+		result = append(result, entity.PeerGroupResponse{
+			PeerGroup: group,
+			PeerIDs:   h.conf.GroupPeers(group.Name),
+		})
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// @Tags Groups
+// @Summary Create group or update its policy, fields which are not set keep their current values
+// @Accept json
+// @Produce json
+// @Param body body entity.UpsertGroupRequest true "Params"
+// @Success 200 "OK"
+// @Failure 400 {object} api.Error
+// @Router /groups/upsert [POST]
+func (h *Handler) UpsertGroup(c echo.Context) (err error) {
+	req := entity.UpsertGroupRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	} else if !config.IsValidGroupName(req.Name) {
+		return c.JSON(http.StatusBadRequest, ErrorMessage("invalid group name: use up to 32 lowercase letters, digits, '-' and '_'"))
+	}
+
+	group, _ := h.conf.GetGroup(req.Name)
+	group.Name = req.Name
+	if req.Description != nil {
+		group.Description = *req.Description
+	}
+	if req.AllowUsingAsExitNode != nil {
+		group.AllowUsingAsExitNode = *req.AllowUsingAsExitNode
+	}
+	if req.HideFromDNS != nil {
+		group.HideFromDNS = *req.HideFromDNS
+	}
+	if req.TrustIntroductions != nil {
+		group.TrustIntroductions = *req.TrustIntroductions
+	}
+	if req.BlockInboundAccess != nil {
+		group.BlockInboundAccess = *req.BlockInboundAccess
+	}
+	h.conf.UpsertGroup(group)
+	h.exchangeStatusInfoWithGroup(req.Name)
+
+	return c.NoContent(http.StatusOK)
+}
+
+// @Tags Groups
+// @Summary Remove group, peers are excluded from it
+// @Accept json
+// @Produce json
+// @Param body body entity.GroupNameRequest true "Params"
+// @Success 200 "OK"
+// @Failure 400 {object} api.Error
+// @Failure 404 {object} api.Error
+// @Router /groups/remove [POST]
+func (h *Handler) RemoveGroup(c echo.Context) (err error) {
+	req := entity.GroupNameRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+
+	peerIDs := h.conf.GroupPeers(req.Name)
+	if !h.conf.RemoveGroup(req.Name) {
+		return c.JSON(http.StatusNotFound, ErrorMessage(config.ErrGroupNotFound.Error()))
+	}
+	h.exchangeStatusInfo(peerIDs)
+
+	return c.NoContent(http.StatusOK)
+}
+
+// @Tags Groups
+// @Summary Add peers to group or remove them from it
+// @Accept json
+// @Produce json
+// @Param body body entity.UpdateGroupPeersRequest true "Params"
+// @Success 200 "OK"
+// @Failure 400 {object} api.Error
+// @Failure 404 {object} api.Error
+// @Router /groups/update_peers [POST]
+func (h *Handler) UpdateGroupPeers(c echo.Context) (err error) {
+	req := entity.UpdateGroupPeersRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+
+	err = h.conf.UpdateGroupPeers(req.Name, req.AddPeerIDs, req.RemovePeerIDs)
+	if errors.Is(err, config.ErrGroupNotFound) {
+		return c.JSON(http.StatusNotFound, ErrorMessage(err.Error()))
+	} else if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	h.exchangeStatusInfo(append(req.AddPeerIDs, req.RemovePeerIDs...))
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *Handler) exchangeStatusInfoWithGroup(name string) {
+	h.exchangeStatusInfo(h.conf.GroupPeers(name))
+}
+
+// exchangeStatusInfo notifies peers about changed permissions.
+func (h *Handler) exchangeStatusInfo(peerIDs []string) {
+	for _, peerID := range peerIDs {
+		knownPeer, exists := h.conf.GetPeer(peerID)
+		if !exists {
+			continue
+		}
+		go func() {
+			_ = h.authStatus.ExchangeNewStatusInfo(h.ctx, knownPeer.PeerId(), knownPeer)
+		}()
+	}
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	manet "github.com/multiformats/go-multiaddr/net"
+
+	"github.com/anywherelan/awl/config"
+	"github.com/anywherelan/awl/entity"
+	"github.com/anywherelan/awl/protocol"
+)
+
+const (
+	defaultInviteLifetime = 24 * time.Hour
+	// maxInviteAddrs limits token size, so it fits into QR code
+	maxInviteAddrs         = 8
+	connectInvitingTimeout = 10 * time.Second
+)
+
+// @Tags Invites
+// @Summary Create signed invite, peers which use it are accepted automatically
+// @Accept json
+// @Produce json
+// @Param body body entity.CreateInviteRequest true "Params"
+// @Success 200 {object} entity.CreateInviteResponse
+// @Failure 400 {object} api.Error
+// @Failure 500 {object} api.Error
+// @Router /invites/create [POST]
+func (h *Handler) CreateInvite(c echo.Context) (err error) {
+	req := entity.CreateInviteRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	for _, group := range req.Groups {
+		if _, exists := h.conf.GetGroup(group); !exists {
+			return c.JSON(http.StatusBadRequest, ErrorMessage(fmt.Sprintf("group %s not found", group)))
+		}
+	}
+	lifetime := defaultInviteLifetime
+	if req.ExpiresInSec != 0 {
+		lifetime = time.Duration(req.ExpiresInSec) * time.Second
+	}
+
+	idBytes := make([]byte, 8)
+	_, _ = rand.Read(idBytes)
+	now := time.Now()
+	invite := config.Invite{
+		ID:                   hex.EncodeToString(idBytes),
+		CreatedAt:            now,
+		ExpiresAt:            now.Add(lifetime),
+		MaxUses:              req.MaxUses,
+		UsedBy:               make([]string, 0),
+		AllowUsingAsExitNode: req.AllowUsingAsExitNode,
+		Groups:               req.Groups,
+	}
+
+	addrs := make([]string, 0)
+	for _, addr := range h.p2p.AnnouncedAs() {
+		if manet.IsIPLoopback(addr) {
+			continue
+		}
+		addrs = append(addrs, addr.String())
+		if len(addrs) == maxInviteAddrs {
+			break
+		}
+	}
+	h.conf.RLock()
+	myName := h.conf.P2pNode.Name
+	h.conf.RUnlock()
+
+	token, err := protocol.EncodeInviteToken(protocol.InviteToken{
+		ID:        invite.ID,
+		PeerID:    h.p2p.PeerID().String(),
+		Name:      myName,
+		Addrs:     addrs,
+		ExpiresAt: invite.ExpiresAt,
+		MaxUses:   invite.MaxUses,
+		Permissions: protocol.InvitePermissions{
+			AllowUsingAsExitNode: invite.AllowUsingAsExitNode,
+		},
+	}, h.p2p.Host().Peerstore().PrivKey(h.p2p.PeerID()))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorMessage(err.Error()))
+	}
+	h.conf.AddInvite(invite)
+
+	return c.JSON(http.StatusOK, entity.CreateInviteResponse{
+		InviteInfo: makeInviteInfo(invite, now),
+		Token:      token,
+		URL:        protocol.InviteURLPrefix + token,
+	})
+}
+
+// @Tags Invites
+// @Summary Get issued invites
+// @Accept json
+// @Produce json
+// @Success 200 {array} entity.InviteInfo
+// @Router /invites/list [GET]
+func (h *Handler) ListInvites(c echo.Context) (err error) {
+	invites := h.conf.ListInvites()
+	now := time.Now()
+	result := make([]entity.InviteInfo, 0, len(invites))
+	for _, invite := range invites {
+		result = append(result, makeInviteInfo(invite, now))
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// @Tags Invites
+// @Summary Revoke invite
+// @Accept json
+// @Produce json
+// @Param body body entity.InviteIDRequest true "Params"
+// @Success 200 "OK"
+// @Failure 400 {object} api.Error
+// @Failure 404 {object} api.Error
+// @Router /invites/revoke [POST]
+func (h *Handler) RevokeInvite(c echo.Context) (err error) {
+	req := entity.InviteIDRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+
+	if !h.conf.RevokeInvite(req.ID) {
+		return c.JSON(http.StatusNotFound, ErrorMessage("invite not found"))
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// @Tags Peers
+// @Summary Add peer using its invite, peer accepts us automatically
+// @Accept json
+// @Produce json
+// @Param body body entity.JoinByInviteRequest true "Params"
+// @Success 200 "OK"
+// @Failure 400 {object} api.Error
+// @Router /peers/join_invite [POST]
+func (h *Handler) JoinByInvite(c echo.Context) (err error) {
+	req := entity.JoinByInviteRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	req.Token = strings.TrimPrefix(strings.TrimSpace(req.Token), protocol.InviteURLPrefix)
+	invite, err := protocol.DecodeInviteToken(req.Token, time.Now())
+	if errors.Is(err, protocol.ErrInviteExpired) {
+		return c.JSON(http.StatusBadRequest, ErrorMessage("Invite has expired"))
+	} else if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage("Invalid invite: "+err.Error()))
+	}
+
+	if invite.PeerID == h.p2p.PeerID().String() {
+		return c.JSON(http.StatusBadRequest, ErrorMessage("You can't add yourself"))
+	}
+	_, exist := h.conf.GetPeer(invite.PeerID)
+	if exist {
+		return c.JSON(http.StatusBadRequest, ErrorMessage("Peer has already been added"))
+	}
+
+	alias := strings.TrimSpace(req.Alias)
+	if alias == "" {
+		alias = h.conf.GenUniqPeerAlias(invite.Name, "")
+	} else if !h.conf.IsUniqPeerAlias("", alias) {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(ErrorPeerAliasIsNotUniq))
+	}
+
+	addrInfo := invite.AddrInfo()
+	if len(addrInfo.Addrs) > 0 {
+		ctx, cancel := context.WithTimeout(h.ctx, connectInvitingTimeout)
+		err = h.p2p.Host().Connect(ctx, addrInfo)
+		cancel()
+		if err != nil {
+			h.logger.Warnf("connect to inviting peer %s by invite addresses: %v", invite.PeerID, err)
+		}
+	}
+	h.authStatus.AddPeerWithInvite(h.ctx, invite, req.Token, alias)
+
+	return c.NoContent(http.StatusOK)
+}
+
+func makeInviteInfo(invite config.Invite, now time.Time) entity.InviteInfo {
+	return entity.InviteInfo{
+		ID:                   invite.ID,
+		CreatedAt:            invite.CreatedAt,
+		ExpiresAt:            invite.ExpiresAt,
+		Expired:              invite.Expired(now),
+		MaxUses:              invite.MaxUses,
+		Uses:                 len(invite.UsedBy),
+		AllowUsingAsExitNode: invite.AllowUsingAsExitNode,
+		Groups:               invite.Groups,
+	}
+}
@@ -0,0 +1,325 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/libp2p/go-libp2p/core/peer"
+
+	"github.com/anywherelan/awl/config"
+	"github.com/anywherelan/awl/entity"
+	"github.com/anywherelan/awl/protocol"
+)
+
+const defaultCertificateLifetime = 365 * 24 * time.Hour
+
+// @Tags Network
+// @Summary Get network membership info
+// @Accept json
+// @Produce json
+// @Success 200 {object} entity.NetworkInfoResponse
+// @Router /network/info [GET]
+func (h *Handler) GetNetworkInfo(c echo.Context) (err error) {
+	network := h.conf.GetNetwork()
+	resp := entity.NetworkInfoResponse{
+		Name:           network.Name,
+		AdminKeys:      network.AdminKeys,
+		IsAdmin:        network.IsAdmin(h.p2p.PeerID().String()),
+		RevokedPeerIDs: network.RevokedPeerIDs,
+		MemberPeerIDs:  h.conf.NetworkMembers(),
+	}
+	if network.Certificate != "" {
+		cert, err := protocol.DecodeCertificate(network.Certificate)
+		if err == nil {
+			resp.Groups = cert.Groups
+			resp.ExpiresAt = cert.ExpiresAt
+		}
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// @Tags Network
+// @Summary Create network, we become its admin
+// @Accept json
+// @Produce json
+// @Param body body entity.CreateNetworkRequest true "Params"
+// @Success 200 "OK"
+// @Failure 400 {object} api.Error
+// @Failure 500 {object} api.Error
+// @Router /network/create [POST]
+func (h *Handler) CreateNetwork(c echo.Context) (err error) {
+	req := entity.CreateNetworkRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if h.conf.GetNetwork().Enabled() {
+		return c.JSON(http.StatusBadRequest, ErrorMessage("You are already a member of network, leave it first"))
+	}
+
+	myPeerID := h.p2p.PeerID().String()
+	network := config.NetworkConfig{
+		Name:      strings.TrimSpace(req.Name),
+		AdminKeys: []string{myPeerID},
+	}
+	h.conf.RLock()
+	myName := h.conf.P2pNode.Name
+	h.conf.RUnlock()
+	now := time.Now()
+	network.Certificate, err = h.signCertificate(network, protocol.MembershipCertificate{
+		PeerID:    myPeerID,
+		Name:      myName,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(defaultCertificateLifetime),
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorMessage(err.Error()))
+	}
+	h.conf.SetNetwork(network)
+
+	return c.NoContent(http.StatusOK)
+}
+
+// @Tags Network
+// @Summary Sign membership certificate for peer, only admin can sign
+// @Accept json
+// @Produce json
+// @Param body body entity.SignCertificateRequest true "Params"
+// @Success 200 {object} entity.SignCertificateResponse
+// @Failure 400 {object} api.Error
+// @Failure 403 {object} api.Error
+// @Failure 500 {object} api.Error
+// @Router /network/sign [POST]
+func (h *Handler) SignCertificate(c echo.Context) (err error) {
+	req := entity.SignCertificateRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if _, err = peer.Decode(req.PeerID); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage("Invalid hex-encoded multihash representing of a peer ID"))
+	}
+	for _, group := range req.Groups {
+		if !config.IsValidGroupName(group) {
+			return c.JSON(http.StatusBadRequest, ErrorMessage("invalid group name "+group))
+		}
+	}
+	network := h.conf.GetNetwork()
+	myPeerID := h.p2p.PeerID().String()
+	if !network.IsAdmin(myPeerID) {
+		return c.JSON(http.StatusForbidden, ErrorMessage("Only network admin can sign certificates"))
+	}
+	if network.IsRevoked(req.PeerID) {
+		return c.JSON(http.StatusBadRequest, ErrorMessage("Peer membership has been revoked"))
+	}
+	lifetime := defaultCertificateLifetime
+	if req.ExpiresInSec != 0 {
+		lifetime = time.Duration(req.ExpiresInSec) * time.Second
+	}
+
+	now := time.Now()
+	cert := protocol.MembershipCertificate{
+		PeerID:    req.PeerID,
+		Name:      req.Name,
+		Groups:    req.Groups,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(lifetime),
+	}
+	raw, err := h.signCertificate(network, cert)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorMessage(err.Error()))
+	}
+	if req.PeerID == myPeerID {
+		network.Certificate = raw
+		h.conf.SetNetwork(network)
+	}
+
+	return c.JSON(http.StatusOK, entity.SignCertificateResponse{
+		Certificate: raw,
+		ExpiresAt:   cert.ExpiresAt,
+	})
+}
+
+// @Tags Network
+// @Summary Join network with membership certificate signed by its admin
+// @Accept json
+// @Produce json
+// @Param body body entity.JoinNetworkRequest true "Params"
+// @Success 200 "OK"
+// @Failure 400 {object} api.Error
+// @Router /network/join [POST]
+func (h *Handler) JoinNetwork(c echo.Context) (err error) {
+	req := entity.JoinNetworkRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	req.Certificate = strings.TrimSpace(req.Certificate)
+	cert, err := protocol.DecodeCertificate(req.Certificate)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	network := h.conf.GetNetwork()
+	if network.Enabled() && network.Name != cert.Network {
+		return c.JSON(http.StatusBadRequest, ErrorMessage("You are already a member of another network, leave it first"))
+	}
+	err = cert.Verify(cert.Network, cert.Admins, h.p2p.PeerID().String(), network.RevokedPeerIDs, time.Now())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+
+	network.Name = cert.Network
+	// admins from certificate are older than the latest admin list which we already have
+	if !network.AdminListIssuedAt.After(cert.IssuedAt) {
+		network.AdminKeys = cert.Admins
+	}
+	network.Certificate = req.Certificate
+	h.conf.SetNetwork(network)
+	h.authStatus.JoinNetwork(h.ctx, cert)
+
+	return c.NoContent(http.StatusOK)
+}
+
+// @Tags Network
+// @Summary Revoke peer membership, revocation list is distributed to all members
+// @Accept json
+// @Produce json
+// @Param body body entity.PeerIDRequest true "Params"
+// @Success 200 "OK"
+// @Failure 400 {object} api.Error
+// @Failure 403 {object} api.Error
+// @Failure 500 {object} api.Error
+// @Router /network/revoke [POST]
+func (h *Handler) RevokeMembership(c echo.Context) (err error) {
+	req := entity.PeerIDRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	network := h.conf.GetNetwork()
+	myPeerID := h.p2p.PeerID().String()
+	if !network.IsAdmin(myPeerID) {
+		return c.JSON(http.StatusForbidden, ErrorMessage("Only network admin can revoke membership"))
+	}
+	if req.PeerID == myPeerID {
+		return c.JSON(http.StatusBadRequest, ErrorMessage("You can't revoke yourself"))
+	}
+
+	revoked := network.RevokedPeerIDs
+	if !slices.Contains(revoked, req.PeerID) {
+		revoked = append(revoked, req.PeerID)
+	}
+	raw, err := protocol.EncodeRevocationList(protocol.RevocationList{
+		Network:        network.Name,
+		IssuedAt:       time.Now(),
+		Issuer:         myPeerID,
+		RevokedPeerIDs: revoked,
+	}, h.p2p.Host().Peerstore().PrivKey(h.p2p.PeerID()))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorMessage(err.Error()))
+	}
+	err = h.authStatus.ApplyRevocationList(raw)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorMessage(err.Error()))
+	}
+	go h.authStatus.ExchangeStatusInfoWithAllKnownPeers(h.ctx)
+
+	return c.NoContent(http.StatusOK)
+}
+
+// @Tags Network
+// @Summary Add or remove network admins, signed admin list is distributed to all members. Only admin can update it
+// @Accept json
+// @Produce json
+// @Param body body entity.UpdateNetworkAdminsRequest true "Params"
+// @Success 200 "OK"
+// @Failure 400 {object} api.Error
+// @Failure 403 {object} api.Error
+// @Failure 500 {object} api.Error
+// @Router /network/admins [POST]
+func (h *Handler) UpdateNetworkAdmins(c echo.Context) (err error) {
+	req := entity.UpdateNetworkAdminsRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	for _, peerID := range req.Add {
+		if _, err = peer.Decode(peerID); err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorMessage("Invalid hex-encoded multihash representing of a peer ID"))
+		}
+	}
+	network := h.conf.GetNetwork()
+	myPeerID := h.p2p.PeerID().String()
+	if !network.IsAdmin(myPeerID) {
+		return c.JSON(http.StatusForbidden, ErrorMessage("Only network admin can update admins"))
+	}
+
+	admins := network.AdminKeys
+	for _, peerID := range req.Add {
+		if !slices.Contains(admins, peerID) {
+			admins = append(admins, peerID)
+		}
+	}
+	admins = slices.DeleteFunc(admins, func(peerID string) bool {
+		return slices.Contains(req.Remove, peerID)
+	})
+	if len(admins) == 0 {
+		return c.JSON(http.StatusBadRequest, ErrorMessage("Network should have at least one admin"))
+	}
+
+	raw, err := protocol.EncodeAdminList(protocol.AdminList{
+		Network:  network.Name,
+		IssuedAt: time.Now(),
+		Issuer:   myPeerID,
+		Admins:   admins,
+	}, h.p2p.Host().Peerstore().PrivKey(h.p2p.PeerID()))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorMessage(err.Error()))
+	}
+	err = h.authStatus.ApplyAdminList(raw)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorMessage(err.Error()))
+	}
+	go h.authStatus.ExchangeStatusInfoWithAllKnownPeers(h.ctx)
+
+	return c.NoContent(http.StatusOK)
+}
+
+// @Tags Network
+// @Summary Leave network, known members stay known peers
+// @Accept json
+// @Produce json
+// @Success 200 "OK"
+// @Router /network/leave [POST]
+func (h *Handler) LeaveNetwork(c echo.Context) (err error) {
+	h.conf.SetNetwork(config.NetworkConfig{})
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *Handler) signCertificate(network config.NetworkConfig, cert protocol.MembershipCertificate) (string, error) {
+	cert.Network = network.Name
+	cert.Issuer = h.p2p.PeerID().String()
+	cert.Admins = network.AdminKeys
+
+	return protocol.EncodeCertificate(cert, h.p2p.Host().Peerstore().PrivKey(h.p2p.PeerID()))
+}
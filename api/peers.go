@@ -1,13 +1,18 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"math"
 	"net/http"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/anywherelan/awl/awldns"
 	"github.com/anywherelan/awl/config"
 	"github.com/anywherelan/awl/entity"
+	"github.com/anywherelan/awl/protocol"
 	"github.com/labstack/echo/v4"
 	"github.com/libp2p/go-libp2p/core/peer"
 )
@@ -18,13 +23,24 @@ const ErrorPeerAliasIsNotUniq = "peer name is not unique"
 // @Summary Get known peers info
 // @Accept json
 // @Produce json
+// @Param group query string false "Filter peers by group"
 // @Success 200 {array} entity.KnownPeersResponse
+// @Failure 400 {object} api.Error
 // @Router /peers/get_known [GET]
 func (h *Handler) GetKnownPeers(c echo.Context) (err error) {
+	req := entity.KnownPeersRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+
 	h.conf.RLock()
 	result := make([]entity.KnownPeersResponse, 0, len(h.conf.KnownPeers))
 	peers := make([]string, 0, len(h.conf.KnownPeers))
-	for peerID := range h.conf.KnownPeers {
+	for peerID, knownPeer := range h.conf.KnownPeers {
+		if req.Group != "" && !knownPeer.InGroup(req.Group) {
+			continue
+		}
 		peers = append(peers, peerID)
 	}
 	h.conf.RUnlock()
@@ -35,6 +51,7 @@ func (h *Handler) GetKnownPeers(c echo.Context) (err error) {
 
 		id := knownPeer.PeerId()
 		netStats := h.p2p.NetworkStatsForPeer(id)
+		connected := h.p2p.IsConnected(id)
 		kpr := entity.KnownPeersResponse{
 			PeerID:                 peerID,
 			Name:                   knownPeer.DisplayName(),
@@ -43,15 +60,26 @@ func (h *Handler) GetKnownPeers(c echo.Context) (err error) {
 			Version:                config.VersionFromUserAgent(h.p2p.PeerUserAgent(id)),
 			IpAddr:                 knownPeer.IPAddr,
 			DomainName:             knownPeer.DomainName,
-			Connected:              h.p2p.IsConnected(id),
+			Connected:              connected,
 			Confirmed:              knownPeer.Confirmed,
 			Declined:               knownPeer.Declined,
 			WeAllowUsingAsExitNode: knownPeer.WeAllowUsingAsExitNode,
 			AllowedUsingAsExitNode: knownPeer.AllowedUsingAsExitNode,
+			TrustIntroductions:     knownPeer.TrustIntroductions,
+			AllowRemoteAdmin:       knownPeer.AllowRemoteAdmin,
+			FileReceivePolicy:      knownPeer.GetFileReceivePolicy(),
+			AllowWakeOnLAN:         knownPeer.AllowWakeOnLAN,
+			WakeOnLANTargets:       knownPeer.WakeOnLANTargets,
+			DNSRecords:             knownPeer.DNSRecords,
+			AllowDNSQueries:        knownPeer.AllowDNSQueries,
 			LastSeen:               knownPeer.LastSeen,
+			ProtocolVersion:        knownPeer.ProtocolVersion,
+			Capabilities:           knownPeer.Capabilities,
+			Groups:                 knownPeer.Groups,
 			Connections:            h.p2p.PeerConnectionsInfo(id),
 			NetworkStats:           netStats,
 			NetworkStatsInIECUnits: getStatsInIECUnits(netStats),
+			SharedStatus:           makePeerSharedStatus(knownPeer.SharedStatus, connected),
 		}
 		result = append(result, kpr)
 	}
@@ -124,6 +152,7 @@ func (h *Handler) UpdatePeerSettings(c echo.Context) (err error) {
 	knownPeer.Alias = req.Alias
 	knownPeer.DomainName = req.DomainName
 	knownPeer.WeAllowUsingAsExitNode = req.AllowUsingAsExitNode
+	knownPeer.TrustIntroductions = req.TrustIntroductions
 
 	h.conf.UpsertPeer(knownPeer)
 
@@ -134,6 +163,64 @@ func (h *Handler) UpdatePeerSettings(c echo.Context) (err error) {
 	return c.NoContent(http.StatusOK)
 }
 
+// @Tags Peers
+// @Summary Set destinations which peer can reach using this device as exit node
+// @Accept json
+// @Produce json
+// @Param body body entity.ExitNodeRulesRequest true "Params"
+// @Success 200 "OK"
+// @Failure 400 {object} api.Error
+// @Failure 404 {object} api.Error
+// @Router /peers/exit_node_rules [POST]
+func (h *Handler) UpdateExitNodeRules(c echo.Context) (err error) {
+	req := entity.ExitNodeRulesRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+
+	err = h.socks5.SetExitNodeRules(req.PeerID, req.ExitNodeRules)
+	if errors.Is(err, config.ErrPeerNotFound) {
+		return c.JSON(http.StatusNotFound, ErrorMessage(err.Error()))
+	} else if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// @Tags Peers
+// @Summary Replace user defined TXT and SRV records of peer in awl DNS
+// @Accept json
+// @Produce json
+// @Param body body entity.DNSRecordsRequest true "Params"
+// @Success 200 "OK"
+// @Failure 400 {object} api.Error
+// @Failure 404 {object} api.Error
+// @Router /peers/dns_records [POST]
+func (h *Handler) UpdateDNSRecords(c echo.Context) (err error) {
+	req := entity.DNSRecordsRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+
+	err = h.conf.SetDNSRecords(req.PeerID, req.Records)
+	if errors.Is(err, config.ErrPeerNotFound) {
+		return c.JSON(http.StatusNotFound, ErrorMessage(err.Error()))
+	} else if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
 // @Tags Peers
 // @Summary Invite new peer
 // @Accept json
@@ -173,7 +260,7 @@ func (h *Handler) SendFriendRequest(c echo.Context) (err error) {
 		return c.JSON(http.StatusBadRequest, ErrorMessage(ErrorPeerAliasIsNotUniq))
 	}
 
-	h.authStatus.AddPeer(h.ctx, peerId, "", req.Alias, false)
+	h.authStatus.SendFriendRequest(h.ctx, peerId, req.Alias, strings.TrimSpace(req.Message))
 
 	return c.NoContent(http.StatusOK)
 }
@@ -212,8 +299,7 @@ func (h *Handler) AcceptFriend(c echo.Context) (err error) {
 		return c.JSON(http.StatusBadRequest, ErrorMessage("Peer has been already added"))
 	}
 
-	authRequestsMap := h.authStatus.GetIngoingAuthRequests()
-	auth, exist := authRequestsMap[req.PeerID]
+	auth, exist := h.conf.GetIngoingAuthRequest(req.PeerID)
 	if !exist {
 		return c.JSON(http.StatusBadRequest, ErrorMessage("Peer did not send you friend request"))
 	}
@@ -233,6 +319,43 @@ func (h *Handler) AcceptFriend(c echo.Context) (err error) {
 	return c.NoContent(http.StatusOK)
 }
 
+// @Tags Peers
+// @Summary Introduce known peer to another known peer, it sees introduced peer in its auth requests
+// @Accept json
+// @Produce json
+// @Param body body entity.IntroducePeerRequest true "Params"
+// @Success 200 "OK"
+// @Failure 400 {object} api.Error
+// @Failure 500 {object} api.Error
+// @Router /peers/introduce [POST]
+func (h *Handler) IntroducePeer(c echo.Context) (err error) {
+	req := entity.IntroducePeerRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	recipientID, err := peer.Decode(req.PeerID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage("Invalid hex-encoded multihash representing of a peer ID"))
+	}
+	introducedID, err := peer.Decode(req.IntroducedPeerID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage("Invalid hex-encoded multihash representing of a peer ID"))
+	}
+
+	ctx, cancel := context.WithTimeout(h.ctx, 10*time.Second)
+	defer cancel()
+	err = h.authStatus.IntroducePeer(ctx, h.p2p.Host().Peerstore().PrivKey(h.p2p.PeerID()), recipientID, introducedID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
 // @Tags Peers
 // @Summary Get ingoing auth requests
 // @Accept json
@@ -240,17 +363,64 @@ func (h *Handler) AcceptFriend(c echo.Context) (err error) {
 // @Success 200 {array} entity.AuthRequest
 // @Router /peers/auth_requests [GET]
 func (h *Handler) GetAuthRequests(c echo.Context) (err error) {
-	authRequestsMap := h.authStatus.GetIngoingAuthRequests()
-	authRequests := make([]entity.AuthRequest, 0, len(authRequestsMap))
-	for peerID, req := range authRequestsMap {
-		authRequests = append(authRequests, entity.AuthRequest{
-			AuthPeer: req,
-			PeerID:   peerID,
-		})
+	requests := h.authStatus.GetIngoingAuthRequests()
+	authRequests := make([]entity.AuthRequest, 0, len(requests))
+	for _, req := range requests {
+		authRequest := entity.AuthRequest{
+			AuthPeer: protocol.AuthPeer{
+				Name:          req.Name,
+				Introduction:  req.Introduction,
+				Message:       req.Message,
+				OS:            req.OS,
+				Version:       req.Version,
+				ProposedAlias: req.ProposedAlias,
+			},
+			PeerID:     req.PeerID,
+			ReceivedAt: req.ReceivedAt,
+			ExpiresAt:  req.ExpiresAt,
+		}
+		if req.Introduction != "" {
+			introduction, introducer, err := h.authStatus.DecodeIntroduction(req.Introduction)
+			if err == nil {
+				authRequest.IntroducedBy = introduction.Introducer
+				authRequest.IntroducedByName = introducer.DisplayName()
+			}
+		}
+		authRequests = append(authRequests, authRequest)
 	}
 	return c.JSON(http.StatusOK, authRequests)
 }
 
+// @Tags Peers
+// @Summary Dismiss ingoing auth request without blocking peer, it could send a new request later
+// @Accept json
+// @Produce json
+// @Param body body entity.PeerIDRequest true "Params"
+// @Success 200 "OK"
+// @Failure 400 {object} api.Error
+// @Failure 404 {object} api.Error
+// @Router /peers/dismiss_request [POST]
+func (h *Handler) DismissAuthRequest(c echo.Context) (err error) {
+	req := entity.PeerIDRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	peerID, err := peer.Decode(req.PeerID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage("Invalid hex-encoded multihash representing of a peer ID"))
+	}
+
+	if !h.authStatus.DismissAuthRequest(peerID) {
+		return c.JSON(http.StatusNotFound, ErrorMessage("Peer did not send you friend request"))
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
 // @Tags Peers
 // @Summary Remove known peer
 // @Accept json
@@ -279,6 +449,7 @@ func (h *Handler) RemovePeer(c echo.Context) (err error) {
 	if !exists {
 		return c.JSON(http.StatusNotFound, ErrorMessage("peer not found"))
 	}
+	h.authStatus.PeerHistory().Remove(req.PeerID)
 
 	h.p2p.UnprotectPeer(peerId)
 	h.authStatus.BlockPeer(peerId, knownPeer.DisplayName())
@@ -286,6 +457,45 @@ func (h *Handler) RemovePeer(c echo.Context) (err error) {
 	return c.NoContent(http.StatusOK)
 }
 
+// @Tags Peers
+// @Summary Get known peer connection history
+// @Accept json
+// @Produce json
+// @Param body body entity.PeerIDRequest true "Params"
+// @Success 200 {object} entity.PeerHistoryResponse
+// @Failure 400 {object} api.Error
+// @Failure 404 {object} api.Error
+// @Router /peers/history [POST]
+func (h *Handler) GetPeerHistory(c echo.Context) (err error) {
+	req := entity.PeerIDRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	knownPeer, exists := h.conf.GetPeer(req.PeerID)
+	if !exists {
+		return c.JSON(http.StatusNotFound, ErrorMessage("peer not found"))
+	}
+
+	events := h.authStatus.PeerHistory().Get(req.PeerID)
+	response := entity.PeerHistoryResponse{
+		PeerID:      req.PeerID,
+		DisplayName: knownPeer.DisplayName(),
+		Connected:   h.p2p.IsConnected(knownPeer.PeerId()),
+		Events:      events,
+	}
+	if len(events) > 0 {
+		response.TrackedSince = events[0].ConnectedAt
+		uptime := config.UptimeRatio(events, response.TrackedSince, time.Now())
+		response.UptimePercent = math.Round(uptime*10000) / 100
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
 // @Tags Peers
 // @Summary Get blocked peers info
 // @Accept json
@@ -308,3 +518,21 @@ func (h *Handler) GetBlockedPeers(c echo.Context) (err error) {
 
 	return c.JSON(http.StatusOK, result)
 }
+
+func makePeerSharedStatus(status config.PeerSharedStatus, connected bool) entity.PeerSharedStatus {
+	result := entity.PeerSharedStatus{
+		Hostname:     status.Hostname,
+		OS:           status.OS,
+		Arch:         status.Arch,
+		Version:      status.Version,
+		HealthShared: status.HealthShared,
+		TunUp:        status.TunUp,
+		DNSUp:        status.DNSUp,
+		Note:         status.Note,
+		UpdatedAt:    status.UpdatedAt,
+	}
+	if connected && !status.StartedAt.IsZero() {
+		result.Uptime = time.Since(status.StartedAt).Round(time.Second)
+	}
+	return result
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "awl"
+
+// prometheusCollector exposes awl metrics with stable names and labels.
+// Metrics are read from services on each scrape, so they are always consistent with API responses.
+type prometheusCollector struct {
+	h *Handler
+
+	peerInfo            *prometheus.Desc
+	peerConnected       *prometheus.Desc
+	peerBytes           *prometheus.Desc
+	protocolBytes       *prometheus.Desc
+	totalBytes          *prometheus.Desc
+	connectedPeers      *prometheus.Desc
+	openConnections     *prometheus.Desc
+	openStreams         *prometheus.Desc
+	bootstrapPeers      *prometheus.Desc
+	bootstrapConnected  *prometheus.Desc
+	routingTableSize    *prometheus.Desc
+	tunnelPackets       *prometheus.Desc
+	tunnelBytes         *prometheus.Desc
+	tunnelDropped       *prometheus.Desc
+	socks5Active        *prometheus.Desc
+	socks5Total         *prometheus.Desc
+	dnsQueries          *prometheus.Desc
+	dnsUpstreamErrors   *prometheus.Desc
+	uptimeSeconds       *prometheus.Desc
+	reachabilityPublic  *prometheus.Desc
+	knownPeersTotal     *prometheus.Desc
+	knownPeersConnected *prometheus.Desc
+}
+
+func newPrometheusCollector(h *Handler) *prometheusCollector {
+	desc := func(subsystem, name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, subsystem, name), help, labels, nil)
+	}
+
+	return &prometheusCollector{
+		h: h,
+
+		peerInfo:            desc("peer", "info", "Known peer info, value is always 1.", "peer_id", "peer_name"),
+		peerConnected:       desc("peer", "connected", "Whether known peer is connected.", "peer_id"),
+		peerBytes:           desc("peer", "bytes_total", "Bytes transferred with known peer over p2p.", "peer_id", "direction"),
+		protocolBytes:       desc("p2p", "protocol_bytes_total", "Bytes transferred over p2p by protocol.", "protocol", "direction"),
+		totalBytes:          desc("p2p", "bytes_total", "Total bytes transferred over p2p.", "direction"),
+		connectedPeers:      desc("p2p", "connected_peers", "Number of connected peers, including non-awl peers."),
+		openConnections:     desc("p2p", "open_connections", "Number of open p2p connections."),
+		openStreams:         desc("p2p", "open_streams", "Number of open p2p streams."),
+		bootstrapPeers:      desc("p2p", "bootstrap_peers", "Number of bootstrap peers."),
+		bootstrapConnected:  desc("p2p", "bootstrap_peers_connected", "Number of connected bootstrap peers."),
+		routingTableSize:    desc("dht", "routing_table_size", "Number of peers in DHT routing table."),
+		tunnelPackets:       desc("tunnel", "packets_total", "Packets tunneled with known peer.", "peer_id", "direction"),
+		tunnelBytes:         desc("tunnel", "bytes_total", "Bytes of packets tunneled with known peer.", "peer_id", "direction"),
+		tunnelDropped:       desc("tunnel", "dropped_packets_total", "Packets dropped by tunnel.", "peer_id", "direction"),
+		socks5Active:        desc("socks5", "active_sessions", "Number of active SOCKS5 sessions.", "role"),
+		socks5Total:         desc("socks5", "sessions_total", "Total SOCKS5 sessions.", "role"),
+		dnsQueries:          desc("dns", "queries_total", "DNS queries handled by awl DNS resolver.", "type"),
+		dnsUpstreamErrors:   desc("dns", "upstream_errors_total", "Failed DNS queries to upstream server."),
+		uptimeSeconds:       desc("", "uptime_seconds", "Uptime of awl node."),
+		reachabilityPublic:  desc("p2p", "reachability_public", "Whether node is publicly reachable according to AutoNAT."),
+		knownPeersTotal:     desc("", "known_peers", "Number of known peers."),
+		knownPeersConnected: desc("", "known_peers_connected", "Number of connected known peers."),
+	}
+}
+
+func (pc *prometheusCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		pc.peerInfo, pc.peerConnected, pc.peerBytes, pc.protocolBytes, pc.totalBytes,
+		pc.connectedPeers, pc.openConnections, pc.openStreams, pc.bootstrapPeers, pc.bootstrapConnected,
+		pc.routingTableSize, pc.tunnelPackets, pc.tunnelBytes, pc.tunnelDropped, pc.socks5Active,
+		pc.socks5Total, pc.dnsQueries, pc.dnsUpstreamErrors, pc.uptimeSeconds, pc.reachabilityPublic,
+		pc.knownPeersTotal, pc.knownPeersConnected,
+	} {
+		ch <- desc
+	}
+}
+
+func (pc *prometheusCollector) Collect(ch chan<- prometheus.Metric) {
+	h := pc.h
+	gauge := func(desc *prometheus.Desc, value float64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labels...)
+	}
+	counter := func(desc *prometheus.Desc, value float64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value, labels...)
+	}
+	boolToFloat := func(val bool) float64 {
+		if val {
+			return 1
+		}
+		return 0
+	}
+
+	gauge(pc.uptimeSeconds, h.p2p.Uptime().Seconds())
+	gauge(pc.reachabilityPublic, boolToFloat(h.p2p.Reachability() == network.ReachabilityPublic))
+
+	knownPeers := h.conf.KnownPeersIds()
+	connectedKnownPeers := 0
+	for _, peerID := range knownPeers {
+		knownPeer, exists := h.conf.GetPeer(peerID.String())
+		if !exists {
+			continue
+		}
+		connected := h.p2p.IsConnected(peerID)
+		if connected {
+			connectedKnownPeers++
+		}
+		id := peerID.String()
+		gauge(pc.peerInfo, 1, id, knownPeer.DisplayName())
+		gauge(pc.peerConnected, boolToFloat(connected), id)
+		stats := h.p2p.NetworkStatsForPeer(peerID)
+		counter(pc.peerBytes, float64(stats.TotalIn), id, "in")
+		counter(pc.peerBytes, float64(stats.TotalOut), id, "out")
+	}
+	gauge(pc.knownPeersTotal, float64(len(knownPeers)))
+	gauge(pc.knownPeersConnected, float64(connectedKnownPeers))
+
+	for proto, stats := range h.p2p.NetworkStatsByProtocol() {
+		counter(pc.protocolBytes, float64(stats.TotalIn), string(proto), "in")
+		counter(pc.protocolBytes, float64(stats.TotalOut), string(proto), "out")
+	}
+	totalStats := h.p2p.NetworkStats()
+	counter(pc.totalBytes, float64(totalStats.TotalIn), "in")
+	counter(pc.totalBytes, float64(totalStats.TotalOut), "out")
+
+	gauge(pc.connectedPeers, float64(h.p2p.ConnectedPeersCount()))
+	gauge(pc.openConnections, float64(h.p2p.OpenConnectionsCount()))
+	gauge(pc.openStreams, float64(h.p2p.OpenStreamsCount()))
+	totalBootstraps, connectedBootstraps := h.p2p.BootstrapPeersStats()
+	gauge(pc.bootstrapPeers, float64(totalBootstraps))
+	gauge(pc.bootstrapConnected, float64(connectedBootstraps))
+	gauge(pc.routingTableSize, float64(h.p2p.RoutingTableSize()))
+
+	for peerID, stats := range h.tunnel.PeersStats() {
+		id := peerID.String()
+		counter(pc.tunnelPackets, float64(stats.PacketsIn), id, "in")
+		counter(pc.tunnelPackets, float64(stats.PacketsOut), id, "out")
+		counter(pc.tunnelBytes, float64(stats.BytesIn), id, "in")
+		counter(pc.tunnelBytes, float64(stats.BytesOut), id, "out")
+		counter(pc.tunnelDropped, float64(stats.DroppedIn), id, "in")
+		counter(pc.tunnelDropped, float64(stats.DroppedOut), id, "out")
+	}
+
+	socks5Stats := h.socks5.Stats()
+	gauge(pc.socks5Active, float64(socks5Stats.ActiveClientSessions), "client")
+	gauge(pc.socks5Active, float64(socks5Stats.ActiveServerSessions), "server")
+	counter(pc.socks5Total, float64(socks5Stats.TotalClientSessions), "client")
+	counter(pc.socks5Total, float64(socks5Stats.TotalServerSessions), "server")
+
+	dnsStats := h.dns.DNSStats()
+	counter(pc.dnsQueries, float64(dnsStats.LocalQueries), "local")
+	counter(pc.dnsQueries, float64(dnsStats.ReverseQueries), "reverse")
+	counter(pc.dnsQueries, float64(dnsStats.UpstreamQueries), "upstream")
+	counter(pc.dnsQueries, float64(dnsStats.CacheHits), "cache")
+	counter(pc.dnsUpstreamErrors, float64(dnsStats.UpstreamErrors))
+}
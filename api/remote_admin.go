@@ -0,0 +1,132 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/libp2p/go-libp2p/core/peer"
+
+	"github.com/anywherelan/awl/config"
+	"github.com/anywherelan/awl/entity"
+)
+
+// remoteAdminEndpoints are available to peers with config.KnownPeer AllowRemoteAdmin.
+// Endpoints which expose private key, change remote admin permissions or manage network membership are not included.
+var remoteAdminEndpoints = map[string][]string{
+	http.MethodGet: {
+		GetKnownPeersPath,
+		GetAuthRequestsPath,
+		GetBlockedPeersPath,
+		GetGroupsPath,
+		GetMyPeerInfoPath,
+		ListAvailableProxiesPath,
+		StatusSharingPath,
+		GetBootstrapPeersPath,
+		GetP2pDebugInfoPath,
+		GetDebugLogPath,
+		GetNATDiagnosticsPath,
+	},
+	http.MethodPost: {
+		GetKnownPeerSettingsPath,
+		SendFriendRequestPath,
+		AcceptPeerInvitationPath,
+		UpdatePeerSettingsPath,
+		UpdateExitNodeRulesPath,
+		RemovePeerSettingsPath,
+		DismissAuthRequestPath,
+		GetPeerHistoryPath,
+		UpdateMyInfoPath,
+		UpdateProxySettingsPath,
+		StatusSharingPath,
+	},
+}
+
+// RemoteAdminHandler serves requests of remote peers, only remoteAdminEndpoints are allowed.
+func (h *Handler) RemoteAdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !slices.Contains(remoteAdminEndpoints[r.Method], r.URL.Path) {
+			w.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = io.WriteString(w, `{"error":"endpoint is not available for remote admin"}`)
+			return
+		}
+		h.echo.ServeHTTP(w, r)
+	})
+}
+
+// @Tags Peers
+// @Summary Allow or forbid peer to manage this device remotely
+// @Accept json
+// @Produce json
+// @Param body body entity.RemoteAdminRequest true "Params"
+// @Success 200 "OK"
+// @Failure 400 {object} api.Error
+// @Failure 404 {object} api.Error
+// @Router /peers/remote_admin [POST]
+func (h *Handler) UpdateRemoteAdmin(c echo.Context) (err error) {
+	req := entity.RemoteAdminRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+
+	err = h.conf.SetRemoteAdminAllowed(req.PeerID, req.Allow)
+	if errors.Is(err, config.ErrPeerNotFound) {
+		return c.JSON(http.StatusNotFound, ErrorMessage(err.Error()))
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorMessage(err.Error()))
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// @Tags Settings
+// @Summary Get requests made by peers through remote admin, the newest last
+// @Produce json
+// @Success 200 {array} config.RemoteAdminAuditEntry
+// @Router /settings/remote_admin_audit [GET]
+func (h *Handler) GetRemoteAdminAudit(c echo.Context) (err error) {
+	return c.JSON(http.StatusOK, h.remoteAdmin.Audit())
+}
+
+// @Tags Remote
+// @Summary Forward request to api of peer which allowed us remote admin, path after /remote/ is path of peer api
+// @Param X-Awl-Remote-Peer header string true "Peer ID"
+// @Failure 400 {object} api.Error
+// @Failure 502 {object} api.Error
+// @Router /remote/{path} [GET]
+// @Router /remote/{path} [POST]
+func (h *Handler) ProxyRemoteAdmin(c echo.Context) (err error) {
+	peerID, err := peer.Decode(c.Request().Header.Get(RemotePeerHeader))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage("invalid "+RemotePeerHeader+" header: "+err.Error()))
+	}
+
+	origReq := c.Request()
+	req, err := http.NewRequestWithContext(origReq.Context(), origReq.Method, V0Prefix+strings.TrimPrefix(origReq.URL.Path, RemoteAdminProxyPath), origReq.Body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	req.URL.RawQuery = origReq.URL.RawQuery
+	req.Host = peerID.String()
+	if contentType := origReq.Header.Get(echo.HeaderContentType); contentType != "" {
+		req.Header.Set(echo.HeaderContentType, contentType)
+	}
+
+	resp, err := h.remoteAdmin.SendRequest(origReq.Context(), peerID, req)
+	if errors.Is(err, config.ErrPeerNotFound) {
+		return c.JSON(http.StatusNotFound, ErrorMessage(err.Error()))
+	} else if err != nil {
+		return c.JSON(http.StatusBadGateway, ErrorMessage(err.Error()))
+	}
+	defer resp.Body.Close()
+
+	return c.Stream(resp.StatusCode, resp.Header.Get(echo.HeaderContentType), resp.Body)
+}
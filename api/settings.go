@@ -2,6 +2,7 @@ package api
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
@@ -9,6 +10,8 @@ import (
 	"github.com/anywherelan/awl/entity"
 )
 
+const defaultKeyRotationGracePeriod = 7 * 24 * time.Hour
+
 // @Tags Settings
 // @Summary Get my peer info
 // @Accept json
@@ -75,6 +78,76 @@ func (h *Handler) UpdateMySettings(c echo.Context) (err error) {
 	return c.NoContent(http.StatusOK)
 }
 
+// @Tags Settings
+// @Summary Get which details about this device are shared with known peers
+// @Produce json
+// @Success 200 {object} config.StatusSharingConfig
+// @Router /settings/status_sharing [GET]
+func (h *Handler) GetStatusSharing(c echo.Context) (err error) {
+	return c.JSON(http.StatusOK, h.conf.GetStatusSharing())
+}
+
+// @Tags Settings
+// @Summary Update which details about this device are shared with known peers
+// @Accept json
+// @Produce json
+// @Param body body config.StatusSharingConfig true "Params"
+// @Success 200 "OK"
+// @Failure 400 {object} api.Error
+// @Router /settings/status_sharing [POST]
+func (h *Handler) UpdateStatusSharing(c echo.Context) (err error) {
+	req := config.StatusSharingConfig{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+
+	h.conf.SetStatusSharing(req)
+	go func() {
+		h.authStatus.ExchangeStatusInfoWithAllKnownPeers(h.ctx)
+	}()
+
+	return c.NoContent(http.StatusOK)
+}
+
+// @Tags Settings
+// @Summary Rotate identity key, new key is used after restart and friends follow it keeping their settings
+// @Accept json
+// @Produce json
+// @Param body body entity.RotateIdentityKeyRequest true "Params"
+// @Success 200 {object} entity.RotateIdentityKeyResponse
+// @Failure 400 {object} api.Error
+// @Failure 500 {object} api.Error
+// @Router /settings/rotate_key [POST]
+func (h *Handler) RotateIdentityKey(c echo.Context) (err error) {
+	req := entity.RotateIdentityKeyRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	gracePeriod := defaultKeyRotationGracePeriod
+	if req.GracePeriodSec != 0 {
+		gracePeriod = time.Duration(req.GracePeriodSec) * time.Second
+	}
+
+	handover, err := h.authStatus.RotateIdentityKey(h.p2p.Host().Peerstore().PrivKey(h.p2p.PeerID()), gracePeriod)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorMessage(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, entity.RotateIdentityKeyResponse{
+		OldPeerID:     handover.OldPeerID,
+		NewPeerID:     handover.NewPeerID,
+		BlockOldAfter: handover.BlockOldAfter,
+	})
+}
+
 // @Tags Settings
 // @Summary Export server configuration
 // @Accept json
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/libp2p/go-libp2p/core/peer"
+
+	"github.com/anywherelan/awl/config"
+	"github.com/anywherelan/awl/entity"
+)
+
+// @Tags Peers
+// @Summary Ask peer to broadcast Wake-on-LAN magic packet in its LAN, peer should allow it
+// @Accept json
+// @Produce json
+// @Param body body entity.WakeOnLANRequest true "Params"
+// @Success 200 {object} entity.WakeOnLANResponse
+// @Failure 400 {object} api.Error
+// @Failure 404 {object} api.Error
+// @Failure 502 {object} api.Error
+// @Router /peers/wake [POST]
+func (h *Handler) WakeOnLAN(c echo.Context) (err error) {
+	req := entity.WakeOnLANRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	peerID, err := peer.Decode(req.PeerID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	knownPeer, exists := h.conf.GetPeer(req.PeerID)
+	if !exists {
+		return c.JSON(http.StatusNotFound, ErrorMessage(config.ErrPeerNotFound.Error()))
+	}
+
+	macStr := req.MAC
+	if req.Target != "" {
+		target, found := knownPeer.WakeOnLANTarget(req.Target)
+		if !found {
+			return c.JSON(http.StatusNotFound, ErrorMessage("wake on lan target not found"))
+		}
+		macStr = target.MAC
+	}
+	mac, err := config.ParseWakeOnLANMAC(macStr)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+
+	interfaces, err := h.wakeOnLAN.Wake(c.Request().Context(), peerID, mac)
+	if err != nil {
+		return c.JSON(http.StatusBadGateway, ErrorMessage(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, entity.WakeOnLANResponse{MAC: mac.String(), Interfaces: interfaces})
+}
+
+// @Tags Peers
+// @Summary Allow or forbid peer to wake devices in LAN of this device
+// @Accept json
+// @Produce json
+// @Param body body entity.WakeOnLANPermissionRequest true "Params"
+// @Success 200 "OK"
+// @Failure 400 {object} api.Error
+// @Failure 404 {object} api.Error
+// @Router /peers/wake_permission [POST]
+func (h *Handler) UpdateWakeOnLANPermission(c echo.Context) (err error) {
+	req := entity.WakeOnLANPermissionRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+
+	err = h.conf.SetWakeOnLANAllowed(req.PeerID, req.Allow)
+	if errors.Is(err, config.ErrPeerNotFound) {
+		return c.JSON(http.StatusNotFound, ErrorMessage(err.Error()))
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorMessage(err.Error()))
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// @Tags Peers
+// @Summary Replace saved Wake-on-LAN targets of peer, they are devices in LAN of peer
+// @Accept json
+// @Produce json
+// @Param body body entity.WakeOnLANTargetsRequest true "Params"
+// @Success 200 "OK"
+// @Failure 400 {object} api.Error
+// @Failure 404 {object} api.Error
+// @Router /peers/wake_targets [POST]
+func (h *Handler) UpdateWakeOnLANTargets(c echo.Context) (err error) {
+	req := entity.WakeOnLANTargetsRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+	if err = c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+
+	err = h.conf.SetWakeOnLANTargets(req.PeerID, req.Targets)
+	if errors.Is(err, config.ErrPeerNotFound) {
+		return c.JSON(http.StatusNotFound, ErrorMessage(err.Error()))
+	} else if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorMessage(err.Error()))
+	}
+
+	return c.NoContent(http.StatusOK)
+}
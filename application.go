@@ -2,6 +2,7 @@ package awl
 
 import (
 	"context"
+	"crypto/tls"
 	"embed"
 	"fmt"
 	"io/fs"
@@ -9,7 +10,9 @@ import (
 	"net/netip"
 	"os"
 	"runtime"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/anywherelan/ts-dns/control/controlknobs"
@@ -19,10 +22,14 @@ import (
 	dssync "github.com/ipfs/go-datastore/sync"
 	"github.com/ipfs/go-log/v2"
 	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/libp2p/go-libp2p/p2p/host/autorelay"
 	"github.com/libp2p/go-libp2p/p2p/host/eventbus"
 	"github.com/libp2p/go-libp2p/p2p/host/peerstore/pstoremem"
 	rcmgr "github.com/libp2p/go-libp2p/p2p/host/resource-manager"
+	"github.com/libp2p/go-libp2p/p2p/protocol/holepunch"
+	dnsmsg "github.com/miekg/dns"
+	"github.com/multiformats/go-multiaddr"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"golang.zx2c4.com/wireguard/tun"
@@ -72,15 +79,20 @@ type Application struct {
 	Conf      *config.Config
 	Eventbus  awlevent.Bus
 
-	ctx        context.Context
-	ctxCancel  context.CancelFunc
-	vpnDevice  *vpn.Device
-	P2p        *p2p.P2p
-	Api        *api.Handler
-	AuthStatus *service.AuthStatus
-	Tunnel     *service.Tunnel
-	SOCKS5     *service.SOCKS5
-	Dns        *DNSService
+	ctx           context.Context
+	ctxCancel     context.CancelFunc
+	vpnDevice     *vpn.Device
+	P2p           *p2p.P2p
+	Api           *api.Handler
+	AuthStatus    *service.AuthStatus
+	Tunnel        *service.Tunnel
+	SOCKS5        *service.SOCKS5
+	Hooks         *service.Hooks
+	RemoteAdmin   *service.RemoteAdmin
+	FileTransfer  *service.FileTransfer
+	WakeOnLAN     *service.WakeOnLAN
+	DNSForwarding *service.DNSForwarding
+	Dns           *DNSService
 }
 
 func New() *Application {
@@ -90,7 +102,11 @@ func New() *Application {
 func (a *Application) Init(ctx context.Context, tunDevice tun.Device) error {
 	a.ctx, a.ctxCancel = context.WithCancel(ctx)
 	a.P2p = p2p.NewP2p(a.ctx)
-	p2pHost, err := a.P2p.InitHost(a.makeP2pHostConfig())
+	hostConfig, err := a.makeP2pHostConfig()
+	if err != nil {
+		return err
+	}
+	p2pHost, err := a.P2p.InitHost(hostConfig)
 	if err != nil {
 		return err
 	}
@@ -114,34 +130,62 @@ func (a *Application) Init(ctx context.Context, tunDevice tun.Device) error {
 		return err
 	}
 
-	a.Dns = NewDNSService(a.Conf, a.Eventbus, a.ctx, a.logger)
+	a.DNSForwarding = service.NewDNSForwarding(a.P2p, a.Conf)
+	a.Dns = NewDNSService(a.Conf, a.DNSForwarding, a.Eventbus, a.ctx, a.logger)
 	a.AuthStatus = service.NewAuthStatus(a.P2p, a.Conf, a.Eventbus)
+	a.AuthStatus.SetHealthCheck(func() (tunUp, dnsUp bool) {
+		return vpnDevice.IsUp(), a.Dns.AwlDNSAddress() != "" && a.Dns.IsAwlDNSSetAsSystem()
+	})
 	a.Tunnel = service.NewTunnel(a.P2p, vpnDevice, a.Conf)
-	a.SOCKS5, err = service.NewSOCKS5(a.P2p, a.Conf)
+	a.SOCKS5, err = service.NewSOCKS5(a.P2p, a.Conf, a.Eventbus)
 	if err != nil {
 		return fmt.Errorf("failed to init socks5: %v", err)
 	}
+	a.Hooks = service.NewHooks(a.Conf)
+	a.RemoteAdmin = service.NewRemoteAdmin(a.P2p, a.Conf)
+	a.FileTransfer = service.NewFileTransfer(a.P2p, a.Conf, a.Eventbus)
+	a.WakeOnLAN = service.NewWakeOnLAN(a.P2p, a.Conf)
 
 	p2pHost.SetStreamHandler(protocol.GetStatusMethod, a.AuthStatus.StatusStreamHandler)
 	p2pHost.SetStreamHandler(protocol.AuthMethod, a.AuthStatus.AuthStreamHandler)
+	p2pHost.SetStreamHandler(protocol.IntroduceMethod, a.AuthStatus.IntroductionStreamHandler)
+	p2pHost.SetStreamHandler(protocol.LegacyGetStatusMethod, a.AuthStatus.StatusStreamHandler)
+	p2pHost.SetStreamHandler(protocol.LegacyAuthMethod, a.AuthStatus.AuthStreamHandler)
 	p2pHost.SetStreamHandler(protocol.TunnelPacketMethod, a.Tunnel.StreamHandler)
+	p2pHost.SetStreamHandler(protocol.TunnelVarintPacketMethod, a.Tunnel.VarintStreamHandler)
 	p2pHost.SetStreamHandler(protocol.Socks5PacketMethod, a.SOCKS5.ProxyStreamHandler)
+	p2pHost.SetStreamHandler(protocol.FileTransferMethod, a.FileTransfer.StreamHandler)
+	p2pHost.SetStreamHandler(protocol.WakeOnLANMethod, a.WakeOnLAN.StreamHandler)
+	p2pHost.SetStreamHandler(protocol.DNSQueryMethod, a.DNSForwarding.StreamHandler)
 
 	awlevent.WrapSubscriptionToCallback(a.ctx, func(_ interface{}) {
 		a.Tunnel.RefreshPeersList()
 	}, a.Eventbus, new(awlevent.KnownPeerChanged))
 
-	handler := api.NewHandler(a.Conf, a.P2p, a.AuthStatus, a.Tunnel, a.SOCKS5, a.LogBuffer, a.Dns)
+	handler := api.NewHandler(a.Conf, a.P2p, a.AuthStatus, a.Tunnel, a.SOCKS5, a.Hooks, a.RemoteAdmin, a.FileTransfer, a.WakeOnLAN, a.LogBuffer, a.Dns, a.Eventbus)
 	a.Api = handler
 	err = handler.SetupAPI()
 	if err != nil {
 		return fmt.Errorf("failed to setup api: %v", err)
 	}
+	a.RemoteAdmin.SetHandler(handler.RemoteAdminHandler())
+	p2pHost.SetStreamHandler(protocol.RemoteAdminMethod, a.RemoteAdmin.StreamHandler)
+
+	hookEvents, closeHookEvents, err := service.SubscribeEvents(a.Conf, a.Eventbus, p2pHost.EventBus())
+	if err != nil {
+		return fmt.Errorf("failed to subscribe hooks to events: %v", err)
+	}
 
 	go a.P2p.MaintainBackgroundConnections(a.ctx, a.Conf.P2pNode.ReconnectionIntervalSec*time.Second, a.Conf.KnownPeersIds)
 	go a.AuthStatus.BackgroundRetryAuthRequests(a.ctx)
 	go a.AuthStatus.BackgroundExchangeStatusInfo(a.ctx)
+	go a.AuthStatus.BackgroundSavePeerHistory(a.ctx)
 	go a.SOCKS5.ServeConns(a.ctx)
+	go a.Hooks.Run(a.ctx, hookEvents)
+	go func() {
+		<-a.ctx.Done()
+		closeHookEvents()
+	}()
 
 	if useAwldns {
 		interfaceName, err := a.vpnDevice.InterfaceName()
@@ -213,6 +257,17 @@ func (a *Application) Ctx() context.Context {
 	return a.ctx
 }
 
+// NotifyUpdateAvailable emits awlevent.UpdateAvailable, it is streamed to api event subscribers.
+func (a *Application) NotifyUpdateAvailable(version string) {
+	emitter, err := a.Eventbus.Emitter(new(awlevent.UpdateAvailable))
+	if err != nil {
+		a.logger.Errorf("create update event emitter: %v", err)
+		return
+	}
+	defer emitter.Close()
+	_ = emitter.Emit(awlevent.UpdateAvailable{Version: version})
+}
+
 func (a *Application) Close() {
 	a.Conf.Save()
 	if a.ctxCancel != nil {
@@ -233,6 +288,9 @@ func (a *Application) Close() {
 	if a.SOCKS5 != nil {
 		a.SOCKS5.Close()
 	}
+	if a.AuthStatus != nil {
+		a.AuthStatus.Close()
+	}
 
 	if a.P2p != nil {
 		err := a.P2p.Close()
@@ -252,7 +310,12 @@ func (a *Application) Close() {
 	a.Conf.Save()
 }
 
-func (a *Application) makeP2pHostConfig() p2p.HostConfig {
+func (a *Application) makeP2pHostConfig() (p2p.HostConfig, error) {
+	transports, err := a.makeP2pTransports()
+	if err != nil {
+		return p2p.HostConfig{}, err
+	}
+
 	// TODO: use persistent datastore. Check out badger2. Old badger datastore constantly use disk io
 	peerstore, err := pstoremem.NewPeerstore()
 	if err != nil {
@@ -265,23 +328,42 @@ func (a *Application) makeP2pHostConfig() p2p.HostConfig {
 		panic(err)
 	}
 
+	bootstrapPeers := a.Conf.GetBootstrapPeers()
+	libp2pOpts := []libp2p.Option{
+		libp2p.EnableRelay(),
+		libp2p.EnableAutoRelay(
+			autorelay.WithPeerSource(a.P2p.RelayPeerSource(a.Conf.KnownPeersIds)),
+			autorelay.WithMinCandidates(p2p.DesiredRelays),
+			autorelay.WithNumRelays(p2p.DesiredRelays),
+			autorelay.WithBootDelay(p2p.RelayBootDelay),
+		),
+		libp2p.EnableAutoNATv2(),
+		libp2p.ResourceManager(mgr),
+		libp2p.EnableHolePunching(holepunch.WithTracer(a.P2p.HolePunchTracer())),
+		libp2p.NATManager(a.P2p.NewNATManager),
+	}
+	a.Conf.RLock()
+	relayConf := a.Conf.P2pNode.RelayService
+	a.Conf.RUnlock()
+	if relayConf.Enabled {
+		libp2pOpts = append(libp2pOpts, p2p.RelayServiceOption(p2p.RelayServiceConfig{
+			MaxReservations: relayConf.MaxReservations,
+			MaxCircuits:     relayConf.MaxCircuits,
+			LimitDuration:   max(relayConf.LimitDurationSec, 0) * time.Second,
+			LimitData:       max(relayConf.LimitDataBytes, 0),
+		}, func(peerID peer.ID) bool {
+			_, known := a.Conf.GetPeer(peerID.String())
+			return known
+		}))
+	}
+
 	return p2p.HostConfig{
 		PrivKeyBytes:   a.Conf.PrivKey(),
 		ListenAddrs:    a.Conf.GetListenAddresses(),
+		Transports:     transports,
 		UserAgent:      config.UserAgent,
-		BootstrapPeers: a.Conf.GetBootstrapPeers(),
-		Libp2pOpts: []libp2p.Option{
-			libp2p.EnableRelay(),
-			libp2p.EnableAutoRelayWithStaticRelays(
-				a.Conf.GetBootstrapPeers(),
-				autorelay.WithNumRelays(p2p.DesiredRelays),
-				autorelay.WithBootDelay(p2p.RelayBootDelay),
-			),
-			libp2p.EnableAutoNATv2(),
-			libp2p.ResourceManager(mgr),
-			libp2p.EnableHolePunching(),
-			libp2p.NATPortMap(),
-		},
+		BootstrapPeers: bootstrapPeers,
+		Libp2pOpts:     libp2pOpts,
 		ConnManager: struct {
 			LowWater    int
 			HighWater   int
@@ -293,29 +375,91 @@ func (a *Application) makeP2pHostConfig() p2p.HostConfig {
 		},
 		Peerstore:    peerstore,
 		DHTDatastore: dssync.MutexWrap(ds.NewMapDatastore()),
+	}, nil
+}
+
+func (a *Application) makeP2pTransports() ([]p2p.TransportConfig, error) {
+	a.Conf.RLock()
+	conf := a.Conf.P2pNode.Transports
+	a.Conf.RUnlock()
+
+	makeTransport := func(name string, transportConf config.TransportConfig) p2p.TransportConfig {
+		transport := p2p.TransportConfig{
+			Name:         name,
+			Port:         transportConf.Port,
+			DialPriority: transportConf.DialPriority,
+		}
+		for _, val := range transportConf.ListenAddresses {
+			addr, err := multiaddr.NewMultiaddr(val)
+			if err != nil {
+				a.logger.Errorf("parse %s listen address '%s': %v", name, val, err)
+				continue
+			}
+			transport.ListenAddrs = append(transport.ListenAddrs, addr)
+		}
+		return transport
+	}
+
+	var transports []p2p.TransportConfig
+	if conf.TCP.Enabled {
+		transports = append(transports, makeTransport(p2p.TransportTCP, conf.TCP))
 	}
+	if conf.QUIC.Enabled {
+		transports = append(transports, makeTransport(p2p.TransportQUIC, conf.QUIC))
+	}
+	if conf.WebSocket.Enabled {
+		transport := makeTransport(p2p.TransportWebSocket, conf.WebSocket.TransportConfig)
+		if conf.WebSocket.TLSCertFile != "" || conf.WebSocket.TLSKeyFile != "" {
+			cert, err := tls.LoadX509KeyPair(conf.WebSocket.TLSCertFile, conf.WebSocket.TLSKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("load websocket tls certificate: %v", err)
+			}
+			transport.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+		}
+		transports = append(transports, transport)
+	}
+	if conf.WebTransport.Enabled {
+		transports = append(transports, makeTransport(p2p.TransportWebTransport, conf.WebTransport))
+	}
+
+	return transports, nil
 }
 
 type DNSService struct {
-	conf     *config.Config
-	eventbus awlevent.Bus
-	ctx      context.Context
-	logger   *log.ZapEventLogger
+	conf          *config.Config
+	dnsForwarding *service.DNSForwarding
+	eventbus      awlevent.Bus
+	ctx           context.Context
+	logger        *log.ZapEventLogger
 
 	dnsOsConfigurator   dns.OSConfigurator
 	dnsResolver         *awldns.Resolver
-	upstreamDNS         string
 	isAwlDNSSetAsSystem bool
+
+	forwardingLock sync.Mutex
+	// osConfig is applied to dnsOsConfigurator, its MatchDomains are updated with forwarding rules in split dns mode
+	osConfig dns.OSConfig
+	// forwardingRules are applied to dnsResolver, they are compared with config to skip unchanged rules
+	forwardingRules []appliedForwardingRule
+}
+
+type appliedForwardingRule struct {
+	config.DNSForwardingRule
+	peerName string
 }
 
-func NewDNSService(conf *config.Config, eventbus awlevent.Bus, ctx context.Context, logger *log.ZapEventLogger) *DNSService {
-	return &DNSService{conf: conf, eventbus: eventbus, ctx: ctx, logger: logger}
+func NewDNSService(conf *config.Config, dnsForwarding *service.DNSForwarding, eventbus awlevent.Bus, ctx context.Context, logger *log.ZapEventLogger) *DNSService {
+	return &DNSService{conf: conf, dnsForwarding: dnsForwarding, eventbus: eventbus, ctx: ctx, logger: logger}
 }
 
 func (a *DNSService) initDNS(interfaceName string) {
 	var err error
-	a.dnsResolver = awldns.NewResolver(awldns.DNSAddress)
-	a.upstreamDNS = awldns.DefaultUpstreamDNSAddress
+	a.dnsResolver = awldns.NewResolver(awldns.DNSAddress, a.conf.DNSCacheSize())
+	configuredUpstreams := a.conf.DNSUpstreams()
+	if len(configuredUpstreams) != 0 {
+		a.setDNSUpstreams(configuredUpstreams)
+	}
+	a.dnsForwarding.SetResolver(a.dnsResolver.ExchangeUpstream)
 	a.refreshDNSConfig()
 
 	awlevent.WrapSubscriptionToCallback(a.ctx, func(_ interface{}) {
@@ -340,6 +484,14 @@ func (a *DNSService) initDNS(interfaceName string) {
 		Nameservers:  []netip.Addr{netip.MustParseAddr(awldns.DNSIp)},
 		MatchDomains: []dnsname.FQDN{fqdn},
 	}
+	defer func() {
+		if !a.isAwlDNSSetAsSystem {
+			return
+		}
+		a.forwardingLock.Lock()
+		a.osConfig = newOSConfig
+		a.forwardingLock.Unlock()
+	}()
 
 	if !a.dnsOsConfigurator.SupportsSplitDNS() {
 		newOSConfig.MatchDomains = nil
@@ -350,12 +502,16 @@ func (a *DNSService) initDNS(interfaceName string) {
 		}
 
 		a.logger.Infof("os does not support split dns. base config: %v", baseOSConfig)
-		if len(baseOSConfig.Nameservers) == 0 {
+		if len(configuredUpstreams) != 0 {
+			a.logger.Infof("use configured dns upstreams instead of os nameservers: %v", configuredUpstreams)
+		} else if len(baseOSConfig.Nameservers) == 0 {
 			a.logger.Errorf("got zero nameservers from os configurator, use %s as default", awldns.DefaultUpstreamDNSAddress)
-			a.upstreamDNS = awldns.DefaultUpstreamDNSAddress
 		} else {
-			// TODO: use all nameservers in awldns resolver proxy
-			a.upstreamDNS = net.JoinHostPort(baseOSConfig.Nameservers[0].String(), awldns.DefaultDNSPort)
+			upstreams := make([]string, 0, len(baseOSConfig.Nameservers))
+			for _, nameserver := range baseOSConfig.Nameservers {
+				upstreams = append(upstreams, net.JoinHostPort(nameserver.String(), awldns.DefaultDNSPort))
+			}
+			a.setDNSUpstreams(upstreams)
 		}
 	}
 
@@ -374,8 +530,91 @@ func (a *DNSService) refreshDNSConfig() {
 		return
 	}
 	dnsNamesMapping := a.conf.DNSNamesMapping()
-	dnsNamesMapping[config.AdminHttpServerDomainName] = config.AdminHttpServerIP
-	a.dnsResolver.ReceiveConfiguration(a.upstreamDNS, dnsNamesMapping)
+	dnsNamesMapping[config.AdminHttpServerDomainName] = []string{config.AdminHttpServerIP}
+	a.dnsResolver.ReceiveConfiguration(dnsNamesMapping, a.conf.DNSRecords())
+	a.refreshDNSForwarding()
+}
+
+// refreshDNSForwarding applies changed forwarding rules to resolver, and adds their zones to OS match domains.
+func (a *DNSService) refreshDNSForwarding() {
+	a.forwardingLock.Lock()
+	defer a.forwardingLock.Unlock()
+
+	configRules := a.conf.DNSForwardingRules()
+	appliedRules := make([]appliedForwardingRule, 0, len(configRules))
+	for _, rule := range configRules {
+		applied := appliedForwardingRule{DNSForwardingRule: rule}
+		if rule.PeerID != "" {
+			knownPeer, exists := a.conf.GetPeer(rule.PeerID)
+			if !exists {
+				a.logger.Warnf("skip dns forwarding rule for zone %s: peer %s not found", rule.Zone, rule.PeerID)
+				continue
+			}
+			applied.peerName = knownPeer.DisplayName()
+		}
+		appliedRules = append(appliedRules, applied)
+	}
+	if !slices.Equal(appliedRules, a.forwardingRules) {
+		a.applyForwardingRules(appliedRules)
+	}
+
+	// osConfig is set only after successful SetDNS. Without split dns all queries are sent to awl dns,
+	// so there is nothing to update
+	if len(a.osConfig.MatchDomains) == 0 {
+		return
+	}
+	matchDomains := []dnsname.FQDN{a.osConfig.MatchDomains[0]}
+	for _, rule := range appliedRules {
+		fqdn, err := dnsname.ToFQDN(rule.Zone)
+		if err != nil {
+			a.logger.Warnf("skip dns forwarding zone %s for os config: %v", rule.Zone, err)
+			continue
+		}
+		matchDomains = append(matchDomains, fqdn)
+	}
+	if slices.Equal(matchDomains, a.osConfig.MatchDomains) {
+		return
+	}
+	newOSConfig := a.osConfig
+	newOSConfig.MatchDomains = matchDomains
+	err := a.dnsOsConfigurator.SetDNS(newOSConfig)
+	if err != nil {
+		a.logger.Errorf("set dns forwarding zones to os configurator: %v", err)
+		return
+	}
+	a.osConfig = newOSConfig
+	a.logger.Infof("set dns match domains to os: %v", matchDomains)
+}
+
+func (a *DNSService) applyForwardingRules(appliedRules []appliedForwardingRule) {
+	resolverRules := make([]awldns.ForwardingRule, 0, len(appliedRules))
+	for _, rule := range appliedRules {
+		resolverRule := awldns.ForwardingRule{Zone: rule.Zone, Upstream: rule.Upstream}
+		if rule.PeerID != "" {
+			peerID, err := peer.Decode(rule.PeerID)
+			if err != nil {
+				a.logger.Warnf("skip dns forwarding rule for zone %s: invalid peer id: %v", rule.Zone, err)
+				continue
+			}
+			resolverRule.Name = rule.peerName
+			resolverRule.Exchange = func(ctx context.Context, req *dnsmsg.Msg) (*dnsmsg.Msg, error) {
+				return a.dnsForwarding.Query(ctx, peerID, req)
+			}
+		}
+		resolverRules = append(resolverRules, resolverRule)
+	}
+	err := a.dnsResolver.SetForwardingRules(resolverRules)
+	if err != nil {
+		a.logger.Errorf("set dns forwarding rules: %v", err)
+	}
+	a.forwardingRules = appliedRules
+}
+
+func (a *DNSService) setDNSUpstreams(upstreams []string) {
+	err := a.dnsResolver.SetUpstreams(upstreams)
+	if err != nil {
+		a.logger.Errorf("set dns upstreams: %v", err)
+	}
 }
 
 func (a *DNSService) Close() {
@@ -397,6 +636,13 @@ func (a *DNSService) AwlDNSAddress() string {
 	return ""
 }
 
+func (a *DNSService) DNSStats() awldns.Stats {
+	if a.dnsResolver != nil {
+		return a.dnsResolver.Stats()
+	}
+	return awldns.Stats{}
+}
+
 func (a *DNSService) IsAwlDNSSetAsSystem() bool {
 	return a.isAwlDNSSetAsSystem
 }
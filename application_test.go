@@ -4,14 +4,18 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
@@ -28,6 +32,7 @@ import (
 	"github.com/libp2p/go-libp2p/p2p/host/eventbus"
 	"github.com/libp2p/go-libp2p/p2p/host/peerstore/pstoremem"
 	rcmgr "github.com/libp2p/go-libp2p/p2p/host/resource-manager"
+	"github.com/miekg/dns"
 	"github.com/multiformats/go-multiaddr"
 	"github.com/quic-go/quic-go/integrationtests/tools/israce"
 	"github.com/stretchr/testify/require"
@@ -41,6 +46,8 @@ import (
 	"github.com/anywherelan/awl/config"
 	"github.com/anywherelan/awl/entity"
 	"github.com/anywherelan/awl/p2p"
+	"github.com/anywherelan/awl/protocol"
+	"github.com/anywherelan/awl/service"
 	"github.com/anywherelan/awl/vpn"
 )
 
@@ -56,6 +63,285 @@ func TestMakeFriends(t *testing.T) {
 	peer2 := ts.newTestPeer(false)
 
 	ts.makeFriends(peer2, peer1)
+
+	history, err := peer1.api.PeerHistory(peer2.PeerID())
+	ts.NoError(err)
+	ts.True(history.Connected)
+	ts.NotEmpty(history.Events)
+	ts.True(history.Events[len(history.Events)-1].DisconnectedAt.IsZero())
+
+	knownPeers, err := peer1.api.KnownPeers()
+	ts.NoError(err)
+	ts.Len(knownPeers, 1)
+	ts.Equal(protocol.SupportedVersions[0], knownPeers[0].ProtocolVersion)
+	ts.ElementsMatch(protocol.SupportedCapabilities, knownPeers[0].Capabilities)
+}
+
+func TestStatusSharing(t *testing.T) {
+	ts := NewTestSuite(t)
+
+	peer1 := ts.newTestPeer(false)
+	peer2 := ts.newTestPeer(false)
+
+	err := peer2.api.UpdateStatusSharing(config.StatusSharingConfig{OS: true, Uptime: true, Note: "on vacation"})
+	ts.NoError(err)
+	ts.makeFriends(peer2, peer1)
+
+	knownPeers, err := peer1.api.KnownPeers()
+	ts.NoError(err)
+	ts.Len(knownPeers, 1)
+	status := knownPeers[0].SharedStatus
+	ts.Equal(runtime.GOOS, status.OS)
+	ts.Equal(runtime.GOARCH, status.Arch)
+	ts.Equal("on vacation", status.Note)
+	ts.Empty(status.Hostname)
+	ts.Empty(status.Version)
+	ts.False(status.HealthShared)
+
+	// peer1 doesn't share anything
+	knownPeers, err = peer2.api.KnownPeers()
+	ts.NoError(err)
+	ts.Len(knownPeers, 1)
+	ts.Equal(entity.PeerSharedStatus{UpdatedAt: knownPeers[0].SharedStatus.UpdatedAt}, knownPeers[0].SharedStatus)
+
+	err = peer2.api.UpdateStatusSharing(config.StatusSharingConfig{Hostname: true, Health: true})
+	ts.NoError(err)
+	ts.Eventually(func() bool {
+		knownPeers, err = peer1.api.KnownPeers()
+		ts.NoError(err)
+		return knownPeers[0].SharedStatus.Hostname != ""
+	}, 15*time.Second, 50*time.Millisecond)
+	status = knownPeers[0].SharedStatus
+	ts.Empty(status.Note)
+	ts.Empty(status.OS)
+	ts.True(status.HealthShared)
+	ts.True(status.TunUp)
+
+	err = peer2.api.UpdateStatusSharing(config.StatusSharingConfig{Note: strings.Repeat("a", protocol.MaxStatusNoteLength+1)})
+	ts.Error(err)
+}
+
+func TestRemoteAdmin(t *testing.T) {
+	ts := NewTestSuite(t)
+
+	peer1 := ts.newTestPeer(false)
+	peer2 := ts.newTestPeer(false)
+	ts.makeFriends(peer2, peer1)
+
+	remote := apiclient.New(peer1.app.Api.Address())
+	remote.SetRemotePeer(peer2.PeerID())
+	_, err := remote.PeerInfo()
+	ts.ErrorContains(err, "remote admin is not allowed")
+
+	err = peer2.api.UpdateRemoteAdmin(peer1.PeerID(), true)
+	ts.NoError(err)
+	peerInfo, err := remote.PeerInfo()
+	ts.NoError(err)
+	ts.Equal(peer2.PeerID(), peerInfo.PeerID)
+	err = remote.UpdateMySettings("managed_remotely")
+	ts.NoError(err)
+	peerInfo, err = remote.PeerInfo()
+	ts.NoError(err)
+	ts.Equal("managed_remotely", peerInfo.Name)
+	knownPeers, err := remote.KnownPeers()
+	ts.NoError(err)
+	ts.Len(knownPeers, 1)
+	ts.Equal(peer1.PeerID(), knownPeers[0].PeerID)
+	ts.True(knownPeers[0].AllowRemoteAdmin)
+
+	// sensitive endpoints are not available
+	_, err = remote.RotateIdentityKey(time.Hour)
+	ts.ErrorContains(err, "not available for remote admin")
+	err = remote.UpdateRemoteAdmin(peer1.PeerID(), false)
+	ts.ErrorContains(err, "not available for remote admin")
+
+	audit, err := peer2.api.RemoteAdminAudit()
+	ts.NoError(err)
+	ts.Len(audit, 7)
+	ts.Equal(http.StatusForbidden, audit[0].Status)
+	ts.Equal(api.GetMyPeerInfoPath, audit[0].Path)
+	ts.Equal(peer1.PeerID(), audit[1].PeerID)
+	ts.Equal(http.StatusOK, audit[1].Status)
+	ts.Equal(api.UpdateMyInfoPath, audit[2].Path)
+	ts.Equal(http.StatusForbidden, audit[6].Status)
+
+	// audit survives restart
+	peer2 = ts.restartTestPeer(peer2)
+	restoredAudit, err := peer2.api.RemoteAdminAudit()
+	ts.NoError(err)
+	ts.Equal(audit, restoredAudit)
+}
+
+func TestFileTransfer(t *testing.T) {
+	ts := NewTestSuite(t)
+
+	peer1 := ts.newTestPeer(false)
+	peer2 := ts.newTestPeer(false)
+	ts.makeFriends(peer2, peer1)
+
+	content := make([]byte, 300<<10)
+	_, err := rand.Read(content)
+	ts.NoError(err)
+	filePath := filepath.Join(t.TempDir(), "photo.jpg")
+	ts.NoError(os.WriteFile(filePath, content, 0600))
+	sum := sha256.Sum256(content)
+
+	waitTransfer := func(peer testPeer, id, status string) entity.FileTransfer {
+		var result entity.FileTransfer
+		ts.Eventually(func() bool {
+			transfers, err := peer.api.FileTransfers()
+			ts.NoError(err)
+			for _, transfer := range transfers {
+				if transfer.ID == id || (id == "" && transfer.Direction == entity.FileTransferIncoming && transfer.Status == status) {
+					result = transfer
+					return transfer.Status == status
+				}
+			}
+			return false
+		}, 15*time.Second, 50*time.Millisecond)
+		return result
+	}
+
+	// peer2 asks by default
+	transfer, err := peer1.api.SendFile(peer2.PeerID(), filePath)
+	ts.NoError(err)
+	ts.Equal("photo.jpg", transfer.Name)
+	ts.EqualValues(len(content), transfer.Size)
+	offer := waitTransfer(peer2, "", entity.FileTransferPending)
+	ts.Equal(peer1.PeerID(), offer.PeerID)
+	ts.Equal(hex.EncodeToString(sum[:]), offer.SHA256)
+	ts.NoError(peer2.api.DeclineFile(offer.ID))
+	declined := waitTransfer(peer1, transfer.ID, entity.FileTransferDeclined)
+	ts.Equal("declined by user", declined.Error)
+	ts.Error(peer2.api.AcceptFile(offer.ID))
+
+	transfer, err = peer1.api.SendFile(peer2.PeerID(), filePath)
+	ts.NoError(err)
+	offer = waitTransfer(peer2, "", entity.FileTransferPending)
+	ts.NoError(peer2.api.AcceptFile(offer.ID))
+	waitTransfer(peer1, transfer.ID, entity.FileTransferCompleted)
+	received := waitTransfer(peer2, offer.ID, entity.FileTransferCompleted)
+	downloadDir := peer2.app.Conf.DownloadDir()
+	ts.Equal(filepath.Join(downloadDir, "photo.jpg"), received.Path)
+	data, err := os.ReadFile(received.Path)
+	ts.NoError(err)
+	ts.Equal(content, data)
+
+	// interrupted transfer continues from partially received file
+	ts.NoError(peer2.api.UpdateFileReceivePolicy(peer1.PeerID(), config.FileReceivePolicyAuto))
+	partialPath := filepath.Join(downloadDir, "."+hex.EncodeToString(sum[:])[:16]+".awlpart")
+	ts.NoError(os.WriteFile(partialPath, content[:100<<10], 0600))
+	transfer, err = peer1.api.SendFile(peer2.PeerID(), filePath)
+	ts.NoError(err)
+	sent := waitTransfer(peer1, transfer.ID, entity.FileTransferCompleted)
+	ts.EqualValues(100<<10, sent.ResumedFrom)
+	ts.EqualValues(len(content), sent.Transferred)
+	ts.NoFileExists(partialPath)
+	data, err = os.ReadFile(filepath.Join(downloadDir, "photo (1).jpg"))
+	ts.NoError(err)
+	ts.Equal(content, data)
+
+	ts.NoError(peer2.api.UpdateFileReceivePolicy(peer1.PeerID(), config.FileReceivePolicyDeny))
+	transfer, err = peer1.api.SendFile(peer2.PeerID(), filePath)
+	ts.NoError(err)
+	declined = waitTransfer(peer1, transfer.ID, entity.FileTransferDeclined)
+	ts.Equal("peer doesn't accept files", declined.Error)
+	knownPeers, err := peer2.api.KnownPeers()
+	ts.NoError(err)
+	ts.Equal(config.FileReceivePolicyDeny, knownPeers[0].FileReceivePolicy)
+}
+
+func TestWakeOnLAN(t *testing.T) {
+	ts := NewTestSuite(t)
+
+	peer1 := ts.newTestPeer(false)
+	peer2 := ts.newTestPeer(false)
+	ts.makeFriends(peer2, peer1)
+
+	_, err := peer1.api.WakeOnLAN(peer2.PeerID(), "02:00:00:00:00:01", "")
+	ts.ErrorContains(err, "wake on lan is not allowed")
+
+	err = peer1.api.UpdateWakeOnLANTargets(peer2.PeerID(), []config.WakeOnLANTarget{{Name: "server", MAC: "02-00-00-00-00-01"}})
+	ts.NoError(err)
+	err = peer1.api.UpdateWakeOnLANTargets(peer2.PeerID(), []config.WakeOnLANTarget{{Name: "server", MAC: "invalid"}})
+	ts.Error(err)
+	knownPeers, err := peer1.api.KnownPeers()
+	ts.NoError(err)
+	ts.Equal([]config.WakeOnLANTarget{{Name: "server", MAC: "02:00:00:00:00:01"}}, knownPeers[0].WakeOnLANTargets)
+
+	ts.NoError(peer2.api.UpdateWakeOnLANPermission(peer1.PeerID(), true))
+	_, err = peer1.api.WakeOnLAN(peer2.PeerID(), "", "unknown")
+	ts.ErrorContains(err, "target not found")
+	response, err := peer1.api.WakeOnLAN(peer2.PeerID(), "", "server")
+	if err != nil && strings.Contains(err.Error(), service.ErrNoWakeOnLANInterfaces.Error()) {
+		t.Skip("no LAN interfaces to send magic packet")
+	}
+	ts.NoError(err)
+	ts.Equal("02:00:00:00:00:01", response.MAC)
+	ts.NotEmpty(response.Interfaces)
+}
+
+func TestDNSForwarding(t *testing.T) {
+	ts := NewTestSuite(t)
+
+	peer1 := ts.newTestPeer(false)
+	peer2 := ts.newTestPeer(false)
+	ts.makeFriends(peer2, peer1)
+	peer2.app.DNSForwarding.SetResolver(func(_ context.Context, req *dns.Msg) (*dns.Msg, error) {
+		m := new(dns.Msg)
+		m.SetReply(req)
+		m.Answer = append(m.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   net.ParseIP("10.1.2.3"),
+		})
+		return m, nil
+	})
+
+	ts.Error(peer1.api.SetDNSForwardingRule("corp.internal", "", ""))
+	ts.Error(peer1.api.SetDNSForwardingRule("peer.awl", peer2.PeerID(), ""))
+	ts.NoError(peer1.api.SetDNSForwardingRule("corp.internal", peer2.PeerID(), ""))
+	rules, err := peer1.api.DNSForwardingRules()
+	ts.NoError(err)
+	ts.Len(rules, 1)
+	ts.Equal("corp.internal", rules[0].Zone)
+	ts.Equal(peer2.PeerID(), rules[0].PeerID)
+	ts.NotEmpty(rules[0].PeerName)
+
+	req := new(dns.Msg)
+	req.SetQuestion("git.corp.internal.", dns.TypeA)
+	_, err = peer1.app.DNSForwarding.Query(context.Background(), peer2.app.P2p.PeerID(), req)
+	ts.ErrorContains(err, "not allowed")
+
+	ts.NoError(peer2.api.UpdateDNSQueriesPermission(peer1.PeerID(), true))
+	resp, err := peer1.app.DNSForwarding.Query(context.Background(), peer2.app.P2p.PeerID(), req)
+	ts.NoError(err)
+	ts.Equal(req.Id, resp.Id)
+	ts.Len(resp.Answer, 1)
+	ts.Equal("10.1.2.3", resp.Answer[0].(*dns.A).A.String())
+
+	ts.NoError(peer1.api.RemoveDNSForwardingRule("corp.internal"))
+	rules, err = peer1.api.DNSForwardingRules()
+	ts.NoError(err)
+	ts.Empty(rules)
+}
+
+func TestMakeFriendsWithLegacyPeer(t *testing.T) {
+	ts := NewTestSuite(t)
+
+	peer1 := ts.newTestPeer(false)
+	peer2 := ts.newTestPeer(false)
+	// peer2 supports only 0.3.0 auth and status protocols with JSON encoding
+	peer2.app.P2p.Host().RemoveStreamHandler(protocol.AuthMethod)
+	peer2.app.P2p.Host().RemoveStreamHandler(protocol.GetStatusMethod)
+
+	ts.makeFriends(peer2, peer1)
+
+	knownPeer, exists := peer1.app.Conf.GetPeer(peer2.PeerID())
+	ts.True(exists)
+	ts.True(knownPeer.Confirmed)
+	knownPeer, exists = peer2.app.Conf.GetPeer(peer1.PeerID())
+	ts.True(exists)
+	ts.True(knownPeer.Confirmed)
 }
 
 func TestRemovePeer(t *testing.T) {
@@ -87,7 +373,7 @@ func TestRemovePeer(t *testing.T) {
 	ts.Len(peer2.app.AuthStatus.GetIngoingAuthRequests(), 0)
 
 	// Add peer2 from peer1 - should succeed
-	err = peer1.api.SendFriendRequest(peer2.PeerID(), "peer_2")
+	err = peer1.api.SendFriendRequest(peer2.PeerID(), "peer_2", "")
 	ts.NoError(err)
 	time.Sleep(500 * time.Millisecond)
 
@@ -116,7 +402,7 @@ func TestDeclinePeerFriendRequest(t *testing.T) {
 	peer2 := ts.newTestPeer(false)
 	ts.ensurePeersAvailableInDHT(peer1, peer2)
 
-	err := peer1.api.SendFriendRequest(peer2.PeerID(), "peer_2")
+	err := peer1.api.SendFriendRequest(peer2.PeerID(), "peer_2", "")
 	ts.NoError(err)
 
 	var authRequests []entity.AuthRequest
@@ -139,6 +425,168 @@ func TestDeclinePeerFriendRequest(t *testing.T) {
 	ts.True(blockedPeerExists)
 }
 
+func TestDismissPeerFriendRequest(t *testing.T) {
+	ts := NewTestSuite(t)
+
+	peer1 := ts.newTestPeer(false)
+	peer2 := ts.newTestPeer(false)
+	ts.ensurePeersAvailableInDHT(peer1, peer2)
+
+	err := peer1.api.SendFriendRequest(peer2.PeerID(), "peer_2", "hi, it's\x1b me")
+	ts.NoError(err)
+
+	var authRequests []entity.AuthRequest
+	ts.Eventually(func() bool {
+		authRequests, err = peer2.api.AuthRequests()
+		ts.NoError(err)
+		return len(authRequests) == 1
+	}, 15*time.Second, 50*time.Millisecond)
+	ts.Equal("hi, it's me", authRequests[0].Message)
+	ts.Equal("peer_2", authRequests[0].ProposedAlias)
+	ts.Equal(config.Version, authRequests[0].Version)
+	ts.Equal(runtime.GOOS, authRequests[0].OS)
+	receivedAt := authRequests[0].ReceivedAt
+	ts.False(receivedAt.IsZero())
+	ts.True(authRequests[0].ExpiresAt.After(receivedAt))
+
+	// pending request survives restart
+	peer2 = ts.restartTestPeer(peer2)
+	ts.ensurePeersAvailableInDHT(peer1, peer2)
+	authRequests, err = peer2.api.AuthRequests()
+	ts.NoError(err)
+	ts.Len(authRequests, 1)
+	ts.Equal(peer1.PeerID(), authRequests[0].PeerID)
+	ts.True(receivedAt.Equal(authRequests[0].ReceivedAt))
+
+	err = peer2.api.DismissAuthRequest(peer1.PeerID())
+	ts.NoError(err)
+	err = peer2.api.DismissAuthRequest(peer1.PeerID())
+	ts.Error(err)
+
+	_, blockedPeerExists := peer2.app.Conf.GetBlockedPeer(peer1.PeerID())
+	ts.False(blockedPeerExists)
+	knownPeer, exists := peer1.app.Conf.GetPeer(peer2.PeerID())
+	ts.True(exists)
+	ts.False(knownPeer.Confirmed)
+	ts.False(knownPeer.Declined)
+}
+
+func TestEventsStream(t *testing.T) {
+	ts := NewTestSuite(t)
+
+	peer1 := ts.newTestPeer(false)
+	peer2 := ts.newTestPeer(false)
+	ts.ensurePeersAvailableInDHT(peer1, peer2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	allEvents := make(chan entity.Event, 100)
+	authEvents := make(chan entity.Event, 100)
+	go func() {
+		_ = peer2.api.SubscribeEvents(ctx, nil, func(event entity.Event) { allEvents <- event })
+	}()
+	go func() {
+		_ = peer2.api.SubscribeEvents(ctx, []string{entity.EventAuthRequest}, func(event entity.Event) { authEvents <- event })
+	}()
+	err := peer2.api.SubscribeEvents(ctx, []string{"unknown"}, func(entity.Event) {})
+	ts.ErrorContains(err, "unknown event type")
+	waitEvent := func(events chan entity.Event, eventType string) entity.Event {
+		timeout := time.After(15 * time.Second)
+		for {
+			select {
+			case event := <-events:
+				if event.Type == eventType {
+					return event
+				}
+			case <-timeout:
+				ts.FailNow("event was not received", eventType)
+			}
+		}
+	}
+	// subscriptions are established asynchronously
+	time.Sleep(200 * time.Millisecond)
+
+	err = peer1.api.SendFriendRequest(peer2.PeerID(), "peer_2", "hello")
+	ts.NoError(err)
+	event := waitEvent(authEvents, entity.EventAuthRequest)
+	ts.Equal(peer1.PeerID(), event.PeerID)
+	ts.Equal("hello", event.AuthRequest.Message)
+	waitEvent(allEvents, entity.EventAuthRequest)
+
+	err = peer2.api.ReplyFriendRequest(peer1.PeerID(), "peer_1", false)
+	ts.NoError(err)
+	waitEvent(allEvents, entity.EventPeerChanged)
+
+	for len(authEvents) > 0 {
+		ts.Equal(entity.EventAuthRequest, (<-authEvents).Type)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	ts := NewTestSuite(t)
+
+	peer1 := ts.newTestPeer(false)
+	peer2 := ts.newTestPeer(false)
+	ts.ensurePeersAvailableInDHT(peer1, peer2)
+
+	const secret = "hook_secret"
+	var requestsCount atomic.Int32
+	received := make(chan entity.Event, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil || r.Header.Get(service.HookSignatureHeader) != service.SignHookBody(secret, body) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		// first delivery fails to check retries
+		if requestsCount.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var event entity.Event
+		_ = json.Unmarshal(body, &event)
+		received <- event
+	}))
+	defer server.Close()
+
+	peer2.app.Conf.Lock()
+	peer2.app.Conf.Hooks = []config.Hook{
+		{Name: "webhook", Events: []string{entity.EventAuthRequest}, URL: server.URL, Secret: secret},
+		{Name: "invalid", Events: []string{entity.EventAuthRequest}, MaxRetries: 1},
+	}
+	peer2.app.Conf.Unlock()
+
+	err := peer1.api.SendFriendRequest(peer2.PeerID(), "peer_2", "")
+	ts.NoError(err)
+	select {
+	case event := <-received:
+		ts.Equal(entity.EventAuthRequest, event.Type)
+		ts.Equal(peer1.PeerID(), event.PeerID)
+	case <-time.After(15 * time.Second):
+		ts.FailNow("webhook was not called")
+	}
+
+	ts.Eventually(func() bool {
+		deliveries, err := peer2.api.HookDeliveries()
+		ts.NoError(err)
+		return len(deliveries) >= 2
+	}, 15*time.Second, 50*time.Millisecond)
+	deliveries, err := peer2.api.HookDeliveries()
+	ts.NoError(err)
+	for _, delivery := range deliveries {
+		ts.Equal(entity.EventAuthRequest, delivery.EventType)
+		switch delivery.Hook {
+		case "webhook":
+			ts.True(delivery.Success)
+			ts.Equal(2, delivery.Attempts)
+		case "invalid":
+			ts.False(delivery.Success)
+			ts.Equal(2, delivery.Attempts)
+			ts.Contains(delivery.Error, "neither url nor command")
+		}
+	}
+}
+
 func TestAutoAcceptFriendRequest(t *testing.T) {
 	ts := NewTestSuite(t)
 
@@ -150,7 +598,7 @@ func TestAutoAcceptFriendRequest(t *testing.T) {
 	peer2.app.Conf.P2pNode.AutoAcceptAuthRequests = true
 	peer2.app.Conf.Unlock()
 
-	err := peer1.api.SendFriendRequest(peer2.PeerID(), "peer_2")
+	err := peer1.api.SendFriendRequest(peer2.PeerID(), "peer_2", "")
 	ts.NoError(err)
 
 	ts.Eventually(func() bool {
@@ -171,6 +619,279 @@ func TestAutoAcceptFriendRequest(t *testing.T) {
 	ts.False(knownPeer.Declined)
 }
 
+func TestJoinByInvite(t *testing.T) {
+	ts := NewTestSuite(t)
+
+	peer1 := ts.newTestPeer(false)
+	peer2 := ts.newTestPeer(false)
+	peer3 := ts.newTestPeer(false)
+	ts.ensurePeersAvailableInDHT(peer1, peer2)
+	ts.ensurePeersAvailableInDHT(peer3, peer2)
+
+	invite, err := peer2.api.CreateInvite(time.Hour, 1, true, nil)
+	ts.NoError(err)
+	ts.True(strings.HasPrefix(invite.URL, protocol.InviteURLPrefix))
+
+	err = peer1.api.JoinByInvite(invite.URL, "")
+	ts.NoError(err)
+
+	ts.Eventually(func() bool {
+		knownPeer, exists := peer1.app.Conf.GetPeer(peer2.PeerID())
+		return exists && knownPeer.Confirmed && knownPeer.AllowedUsingAsExitNode
+	}, 15*time.Second, 50*time.Millisecond)
+	knownPeer, exists := peer2.app.Conf.GetPeer(peer1.PeerID())
+	ts.True(exists)
+	ts.True(knownPeer.Confirmed)
+	ts.True(knownPeer.WeAllowUsingAsExitNode)
+	ts.Len(peer2.app.AuthStatus.GetIngoingAuthRequests(), 0)
+
+	invites, err := peer2.api.Invites()
+	ts.NoError(err)
+	ts.Len(invites, 1)
+	ts.Equal(1, invites[0].Uses)
+
+	// invite is used up, so it becomes an ordinary friend request
+	err = peer3.api.JoinByInvite(invite.Token, "peer_2")
+	ts.NoError(err)
+	ts.Eventually(func() bool {
+		return len(peer2.app.AuthStatus.GetIngoingAuthRequests()) == 1
+	}, 15*time.Second, 50*time.Millisecond)
+	_, exists = peer2.app.Conf.GetPeer(peer3.PeerID())
+	ts.False(exists)
+
+	err = peer2.api.RevokeInvite(invite.ID)
+	ts.NoError(err)
+	invites, err = peer2.api.Invites()
+	ts.NoError(err)
+	ts.Len(invites, 0)
+
+	err = peer1.api.JoinByInvite("invalid", "")
+	ts.Error(err)
+}
+
+func TestPeerGroups(t *testing.T) {
+	ts := NewTestSuite(t)
+
+	peer1 := ts.newTestPeer(false)
+	peer2 := ts.newTestPeer(false)
+	ts.makeFriends(peer2, peer1)
+
+	err := peer1.api.UpsertGroup(entity.UpsertGroupRequest{Name: "Invalid name"})
+	ts.Error(err)
+	enabled := true
+	err = peer1.api.UpsertGroup(entity.UpsertGroupRequest{Name: "ops", AllowUsingAsExitNode: &enabled})
+	ts.NoError(err)
+	err = peer1.api.UpdateGroupPeers("ops", []string{peer2.PeerID()}, nil)
+	ts.NoError(err)
+
+	groups, err := peer1.api.Groups()
+	ts.NoError(err)
+	ts.Len(groups, 1)
+	ts.Equal([]string{peer2.PeerID()}, groups[0].PeerIDs)
+
+	knownPeers, err := peer1.api.KnownPeersInGroup("ops")
+	ts.NoError(err)
+	ts.Len(knownPeers, 1)
+	ts.Equal([]string{"ops"}, knownPeers[0].Groups)
+	knownPeers, err = peer1.api.KnownPeersInGroup("other")
+	ts.NoError(err)
+	ts.Len(knownPeers, 0)
+
+	// exit node is allowed by group policy
+	ts.Eventually(func() bool {
+		knownPeer, _ := peer2.app.Conf.GetPeer(peer1.PeerID())
+		return knownPeer.AllowedUsingAsExitNode
+	}, 15*time.Second, 50*time.Millisecond)
+
+	// blocked inbound access takes precedence, omitted fields are not changed
+	err = peer1.api.UpsertGroup(entity.UpsertGroupRequest{Name: "ops", BlockInboundAccess: &enabled})
+	ts.NoError(err)
+	groups, err = peer1.api.Groups()
+	ts.NoError(err)
+	ts.True(groups[0].AllowUsingAsExitNode)
+	ts.True(groups[0].BlockInboundAccess)
+	ts.Eventually(func() bool {
+		knownPeer, _ := peer2.app.Conf.GetPeer(peer1.PeerID())
+		return !knownPeer.AllowedUsingAsExitNode
+	}, 15*time.Second, 50*time.Millisecond)
+
+	err = peer1.api.RemoveGroup("ops")
+	ts.NoError(err)
+	groups, err = peer1.api.Groups()
+	ts.NoError(err)
+	ts.Empty(groups)
+}
+
+func TestNetworkMembership(t *testing.T) {
+	ts := NewTestSuite(t)
+
+	admin := ts.newTestPeer(false)
+	peer1 := ts.newTestPeer(false)
+	peer2 := ts.newTestPeer(false)
+	ts.ensurePeersAvailableInDHT(admin, peer1)
+	ts.ensurePeersAvailableInDHT(admin, peer2)
+	ts.ensurePeersAvailableInDHT(peer1, peer2)
+
+	err := admin.api.CreateNetwork("team")
+	ts.NoError(err)
+	err = admin.api.CreateNetwork("other")
+	ts.Error(err)
+	_, err = peer1.api.SignCertificate(entity.SignCertificateRequest{PeerID: peer2.PeerID()})
+	ts.Error(err)
+
+	join := func(member testPeer, groups []string) {
+		cert, err := admin.api.SignCertificate(entity.SignCertificateRequest{PeerID: member.PeerID(), Name: "member", Groups: groups})
+		ts.NoError(err)
+		err = member.api.JoinNetwork(cert.Certificate)
+		ts.NoError(err)
+		ts.Eventually(func() bool {
+			knownPeer, exists := member.app.Conf.GetPeer(admin.PeerID())
+			return exists && knownPeer.Confirmed
+		}, 15*time.Second, 50*time.Millisecond)
+	}
+	join(peer1, []string{"ops"})
+	knownPeer, exists := admin.app.Conf.GetPeer(peer1.PeerID())
+	ts.True(exists)
+	ts.Equal([]string{"ops"}, knownPeer.Groups)
+	ts.Len(admin.app.AuthStatus.GetIngoingAuthRequests(), 0)
+
+	join(peer2, nil)
+	// peer1 learns about peer2 from admin, they accept each other automatically
+	admin.app.AuthStatus.ExchangeStatusInfoWithAllKnownPeers(context.Background())
+	ts.Eventually(func() bool {
+		knownPeer, exists := peer1.app.Conf.GetPeer(peer2.PeerID())
+		return exists && knownPeer.Confirmed
+	}, 15*time.Second, 50*time.Millisecond)
+	ts.Eventually(func() bool {
+		knownPeer, exists := peer2.app.Conf.GetPeer(peer1.PeerID())
+		return exists && knownPeer.Confirmed && knownPeer.InGroup("ops")
+	}, 15*time.Second, 50*time.Millisecond)
+	ts.Len(peer1.app.AuthStatus.GetIngoingAuthRequests(), 0)
+	ts.Len(peer2.app.AuthStatus.GetIngoingAuthRequests(), 0)
+
+	info, err := peer2.api.NetworkInfo()
+	ts.NoError(err)
+	ts.Equal("team", info.Name)
+	ts.False(info.IsAdmin)
+	ts.ElementsMatch([]string{admin.PeerID(), peer1.PeerID()}, info.MemberPeerIDs)
+
+	err = peer1.api.UpdateNetworkAdmins([]string{peer2.PeerID()}, nil)
+	ts.Error(err)
+	err = admin.api.UpdateNetworkAdmins(nil, []string{admin.PeerID()})
+	ts.Error(err)
+	err = admin.api.UpdateNetworkAdmins([]string{peer1.PeerID()}, nil)
+	ts.NoError(err)
+	ts.Eventually(func() bool {
+		info, err := peer2.api.NetworkInfo()
+		return err == nil && len(info.AdminKeys) == 2 && info.AdminKeys[1] == peer1.PeerID()
+	}, 15*time.Second, 50*time.Millisecond)
+
+	err = admin.api.RevokeMembership(peer2.PeerID())
+	ts.NoError(err)
+	_, exists = admin.app.Conf.GetPeer(peer2.PeerID())
+	ts.False(exists)
+	ts.Eventually(func() bool {
+		_, exists := peer1.app.Conf.GetPeer(peer2.PeerID())
+		return !exists
+	}, 15*time.Second, 50*time.Millisecond)
+	ts.Eventually(func() bool {
+		knownPeer, _ := peer2.app.Conf.GetPeer(admin.PeerID())
+		return knownPeer.Declined
+	}, 15*time.Second, 50*time.Millisecond)
+}
+
+func TestRotateIdentityKey(t *testing.T) {
+	ts := NewTestSuite(t)
+
+	peer1 := ts.newTestPeer(false)
+	peer2 := ts.newTestPeer(false)
+	ts.makeFriends(peer2, peer1)
+	err := peer1.api.UpdatePeerSettings(entity.UpdatePeerSettingsRequest{
+		PeerID:               peer2.PeerID(),
+		Alias:                "laptop",
+		DomainName:           "laptop",
+		AllowUsingAsExitNode: true,
+	})
+	ts.NoError(err)
+	before, _ := peer1.app.Conf.GetPeer(peer2.PeerID())
+
+	oldPeerID := peer2.PeerID()
+	resp, err := peer2.api.RotateIdentityKey(time.Hour)
+	ts.NoError(err)
+	ts.Equal(oldPeerID, resp.OldPeerID)
+	// new key is used only after restart
+	ts.Equal(oldPeerID, peer2.PeerID())
+
+	peer2 = ts.restartTestPeer(peer2)
+	ts.Equal(resp.NewPeerID, peer2.PeerID())
+	ts.ensurePeersAvailableInDHT(peer1, peer2)
+
+	ts.Eventually(func() bool {
+		_, exists := peer1.app.Conf.GetPeer(resp.NewPeerID)
+		return exists
+	}, 15*time.Second, 50*time.Millisecond)
+	after, _ := peer1.app.Conf.GetPeer(resp.NewPeerID)
+	ts.Equal(before.Alias, after.Alias)
+	ts.Equal(before.IPAddr, after.IPAddr)
+	ts.Equal(before.DomainName, after.DomainName)
+	ts.True(after.WeAllowUsingAsExitNode)
+	_, exists := peer1.app.Conf.GetPeer(oldPeerID)
+	ts.False(exists)
+	_, blocked := peer1.app.Conf.GetBlockedPeer(oldPeerID)
+	ts.False(blocked)
+
+	ts.Equal([]string{oldPeerID}, peer1.app.Conf.BlockRotatedPeers(time.Now().Add(2*time.Hour)))
+	_, blocked = peer1.app.Conf.GetBlockedPeer(oldPeerID)
+	ts.True(blocked)
+}
+
+func TestPeerIntroductions(t *testing.T) {
+	ts := NewTestSuite(t)
+
+	peerA := ts.newTestPeer(false)
+	peerB := ts.newTestPeer(false)
+	peerC := ts.newTestPeer(false)
+	ts.makeFriends(peerB, peerA)
+	ts.makeFriends(peerC, peerB)
+	ts.ensurePeersAvailableInDHT(peerA, peerC)
+
+	err := peerB.api.IntroducePeer(peerA.PeerID(), peerB.PeerID())
+	ts.Error(err)
+	err = peerB.api.IntroducePeer(peerA.PeerID(), peerC.PeerID())
+	ts.NoError(err)
+
+	var authRequests []entity.AuthRequest
+	ts.Eventually(func() bool {
+		authRequests, err = peerA.api.AuthRequests()
+		ts.NoError(err)
+		return len(authRequests) == 1
+	}, 15*time.Second, 50*time.Millisecond)
+	ts.Equal(peerC.PeerID(), authRequests[0].PeerID)
+	ts.Equal(peerB.PeerID(), authRequests[0].IntroducedBy)
+
+	// peerC trusts introductions of peerB, so it accepts peerA automatically
+	knownPeer, _ := peerC.app.Conf.GetPeer(peerB.PeerID())
+	err = peerC.api.UpdatePeerSettings(entity.UpdatePeerSettingsRequest{
+		PeerID:             peerB.PeerID(),
+		Alias:              knownPeer.Alias,
+		DomainName:         knownPeer.DomainName,
+		TrustIntroductions: true,
+	})
+	ts.NoError(err)
+
+	err = peerA.api.ReplyFriendRequest(peerC.PeerID(), "peer_c", false)
+	ts.NoError(err)
+	ts.Eventually(func() bool {
+		knownPeer, exists := peerA.app.Conf.GetPeer(peerC.PeerID())
+		return exists && knownPeer.Confirmed
+	}, 15*time.Second, 50*time.Millisecond)
+	knownPeer, exists := peerC.app.Conf.GetPeer(peerA.PeerID())
+	ts.True(exists)
+	ts.True(knownPeer.Confirmed)
+	ts.Len(peerC.app.AuthStatus.GetIngoingAuthRequests(), 0)
+	ts.Len(peerA.app.AuthStatus.GetIngoingAuthRequests(), 0)
+}
+
 func TestUniquePeerAlias(t *testing.T) {
 	ts := NewTestSuite(t)
 
@@ -180,15 +901,49 @@ func TestUniquePeerAlias(t *testing.T) {
 	ts.ensurePeersAvailableInDHT(peer1, peer2)
 	ts.ensurePeersAvailableInDHT(peer2, peer3)
 
-	err := peer1.api.SendFriendRequest(peer2.PeerID(), "peer")
+	err := peer1.api.SendFriendRequest(peer2.PeerID(), "peer", "")
 	ts.NoError(err)
 
 	time.Sleep(200 * time.Millisecond)
 
-	err = peer1.api.SendFriendRequest(peer3.PeerID(), "peer")
+	err = peer1.api.SendFriendRequest(peer3.PeerID(), "peer", "")
 	ts.EqualError(err, api.ErrorPeerAliasIsNotUniq)
 }
 
+func TestBootstrapPeersManagement(t *testing.T) {
+	ts := NewTestSuite(t)
+
+	peer1 := ts.newTestPeer(false)
+	peer2 := ts.newTestPeer(false)
+
+	bootstrapPeers, err := peer1.api.BootstrapPeers()
+	ts.NoError(err)
+	ts.Len(bootstrapPeers.Peers, len(ts.bootstrapAddrs))
+
+	peer2Host := peer2.app.P2p.Host()
+	peer2Addr := peer2Host.Addrs()[0].Encapsulate(multiaddr.StringCast("/p2p/" + peer2.PeerID()))
+	added, err := peer1.api.AddBootstrapPeer(peer2Addr.String())
+	ts.NoError(err)
+	ts.True(added.Connected)
+	ts.False(added.IsDefault)
+
+	_, err = peer1.api.AddBootstrapPeer(peer2Addr.String())
+	ts.EqualError(err, "bootstrap peer already added")
+
+	bootstrapPeers, err = peer1.api.BootstrapPeers()
+	ts.NoError(err)
+	ts.Len(bootstrapPeers.Peers, len(ts.bootstrapAddrs)+1)
+
+	err = peer1.api.RemoveBootstrapPeer(peer2.PeerID())
+	ts.NoError(err)
+	bootstrapPeers, err = peer1.api.BootstrapPeers()
+	ts.NoError(err)
+	ts.Len(bootstrapPeers.Peers, len(ts.bootstrapAddrs))
+
+	err = peer1.api.RemoveBootstrapPeer(peer2.PeerID())
+	ts.EqualError(err, "custom bootstrap peer not found")
+}
+
 func TestUpdateUseAsExitNodeConfig(t *testing.T) {
 	ts := NewTestSuite(t)
 
@@ -285,6 +1040,19 @@ func TestUpdateUseAsExitNodeConfig(t *testing.T) {
 	testSOCKS5Proxy(ts, peer2.app.Conf.SOCKS5.ListenAddress, "")
 	peer1.app.SOCKS5.SetProxyingLocalhostEnabled(false)
 
+	// exit node rules
+	err = peer1.api.UpdateExitNodeRules(peer2.PeerID(), config.ExitNodeRules{AllowedPorts: []string{"80-"}})
+	ts.Error(err)
+	err = peer1.api.UpdateExitNodeRules("unknown", config.ExitNodeRules{})
+	ts.ErrorContains(err, "peer not found")
+	rules := config.ExitNodeRules{AllowedCIDRs: []string{"192.168.1.10"}, DeniedDomains: []string{"*.example.com"}, AllowedPorts: []string{"443"}}
+	err = peer1.api.UpdateExitNodeRules(peer2.PeerID(), rules)
+	ts.NoError(err)
+	peer2From1, err := peer1.api.KnownPeerConfig(peer2.PeerID())
+	ts.NoError(err)
+	ts.Equal(rules, peer2From1.ExitNodeRules)
+	testSOCKS5Proxy(ts, peer2.app.Conf.SOCKS5.ListenAddress, fmt.Sprintf("%s %s", "unknown error", "connection not allowed by ruleset"))
+
 	// Testing API
 	err = peer1.api.UpdateProxySettings(peer2.PeerID())
 	ts.ErrorContains(err, "peer doesn't allow using as exit node")
@@ -365,6 +1133,11 @@ func TestTunnelPackets(t *testing.T) {
 	peer2 := ts.newTestPeer(false)
 
 	ts.makeFriends(peer2, peer1)
+	// both peers support varint framing, so tunnel uses it instead of legacy uint64 length prefix
+	ts.Eventually(func() bool {
+		knownPeer, _ := peer1.app.Conf.GetPeer(peer2.PeerID())
+		return knownPeer.HasCapability(protocol.CapabilityTunnelVarintFraming)
+	}, 15*time.Second, 50*time.Millisecond)
 
 	current := goleak.IgnoreCurrent()
 	goleak.VerifyNone(t, current)
@@ -401,6 +1174,18 @@ func TestTunnelPackets(t *testing.T) {
 	received2 := peer2.tun.InboundCount()
 	ts.EqualValues(packetsCount, received1)
 	ts.EqualValues(packetsCount, received2)
+
+	stats := peer1.app.Tunnel.PeersStats()[peer2.app.P2p.PeerID()]
+	ts.EqualValues(packetsCount, stats.PacketsIn)
+	ts.EqualValues(packetsCount, stats.PacketsOut)
+
+	resp, err := http.Get("http://" + peer1.app.Api.Address() + api.MetricsPath)
+	ts.NoError(err)
+	defer resp.Body.Close()
+	metrics, err := io.ReadAll(resp.Body)
+	ts.NoError(err)
+	ts.Contains(string(metrics), fmt.Sprintf(`awl_tunnel_packets_total{direction="in",peer_id="%s"} %d`, peer2.PeerID(), packetsCount))
+	ts.Contains(string(metrics), fmt.Sprintf(`awl_peer_info{peer_id="%s",peer_name="peer_1"} 1`, peer2.PeerID()))
 }
 
 func BenchmarkTunnelPackets(b *testing.B) {
@@ -489,13 +1274,14 @@ func NewTestSuite(t testing.TB) *TestSuite {
 }
 
 type testPeer struct {
-	app *Application
-	api *apiclient.Client
-	tun *TestTUN
+	app       *Application
+	api       *apiclient.Client
+	tun       *TestTUN
+	closeOnce *sync.Once
 }
 
 func (tp testPeer) Close() {
-	tp.app.Close()
+	tp.closeOnce.Do(tp.app.Close)
 }
 
 func (tp testPeer) PeerID() string {
@@ -512,6 +1298,18 @@ func (ts *TestSuite) newTestPeer(disableLogging bool) testPeer {
 	}
 	tempConf.Save()
 
+	return ts.startTestPeer(disableLogging)
+}
+
+// restartTestPeer closes peer and starts it again with the same data directory.
+func (ts *TestSuite) restartTestPeer(tp testPeer) testPeer {
+	tp.Close()
+	ts.t.Setenv(config.AppDataDirEnvKey, tp.app.Conf.DataDir())
+
+	return ts.startTestPeer(false)
+}
+
+func (ts *TestSuite) startTestPeer(disableLogging bool) testPeer {
 	app := New()
 	app.SetupLoggerAndConfig()
 	if disableLogging {
@@ -538,9 +1336,10 @@ func (ts *TestSuite) newTestPeer(disableLogging bool) testPeer {
 	ts.NoError(err)
 
 	tp := testPeer{
-		app: app,
-		api: apiclient.New(app.Api.Address()),
-		tun: testTUN,
+		app:       app,
+		api:       apiclient.New(app.Api.Address()),
+		tun:       testTUN,
+		closeOnce: new(sync.Once),
 	}
 
 	ts.t.Cleanup(func() {
@@ -613,7 +1412,7 @@ func (ts *TestSuite) ensurePeersAvailableInDHT(peer1, peer2 testPeer) {
 
 func (ts *TestSuite) makeFriends(peer1, peer2 testPeer) {
 	ts.ensurePeersAvailableInDHT(peer1, peer2)
-	err := peer1.api.SendFriendRequest(peer2.PeerID(), "peer_2")
+	err := peer1.api.SendFriendRequest(peer2.PeerID(), "peer_2", "")
 	ts.NoError(err)
 
 	var authRequests []entity.AuthRequest
@@ -1,6 +1,8 @@
 package awldns
 
 import (
+	"context"
+	"errors"
 	"net"
 	"strings"
 	"sync/atomic"
@@ -15,6 +17,14 @@ const (
 	defaultTTL        = 60 * time.Second
 	defaultTTLSeconds = uint32(defaultTTL / time.Second)
 	ptrV4Suffix       = ".in-addr.arpa."
+	// upstreamQueryTimeout limits failover between upstreams, clients usually wait about 5 seconds
+	upstreamQueryTimeout = 4 * time.Second
+
+	// nsName is name server of LocalDomain zone, it resolves to DNSIp
+	nsName            = "ns." + LocalDomain + "."
+	soaRefreshSeconds = 3600
+	soaRetrySeconds   = 600
+	soaExpireSeconds  = 86400
 )
 
 const (
@@ -28,10 +38,13 @@ const (
 type Resolver struct {
 	udpServer *dns.Server
 	tcpServer *dns.Server
-	udpClient *dns.Client
-	tcpClient *dns.Client
-	cfg       atomic.Pointer[config]
-	logger    *log.ZapEventLogger
+	upstreams atomic.Pointer[upstreamGroup]
+	// forwarders are set by SetForwardingRules
+	forwarders atomic.Pointer[[]forwarder]
+	cache      *cache
+	cfg        atomic.Pointer[config]
+	logger     *log.ZapEventLogger
+	stats      stats
 
 	udpServerWorking bool
 	tcpServerWorking bool
@@ -39,24 +52,64 @@ type Resolver struct {
 	dnsAddress string
 }
 
+// Stats contains counters of handled queries since start.
+type Stats struct {
+	LocalQueries   uint64
+	ReverseQueries uint64
+	// UpstreamQueries are queries which were not answered from cache
+	UpstreamQueries uint64
+	UpstreamErrors  uint64
+	CacheHits       uint64
+	CacheMisses     uint64
+	CacheEvictions  uint64
+	CacheEntries    int
+	// CacheSize is the maximum number of entries, 0 means that cache is disabled
+	CacheSize       int
+	Upstreams       []UpstreamStats
+	ForwardingRules []ForwardingStats
+}
+
+type stats struct {
+	localQueries    atomic.Uint64
+	reverseQueries  atomic.Uint64
+	upstreamQueries atomic.Uint64
+	upstreamErrors  atomic.Uint64
+}
+
 type config struct {
-	upstreamDNS    string
-	directMapping  map[string]string
+	directMapping  map[string][]net.IP
 	reverseMapping map[string]string
+	// records are user defined records by canonical owner name
+	records map[string][]dns.RR
+	// names contains all names of LocalDomain zone including empty non-terminals, e.g. _tcp.peer.awl. for _http._tcp.peer.awl.
+	names  map[string]struct{}
+	serial uint32
 }
 
-func NewResolver(dnsAddress string) *Resolver {
+// Record is user defined TXT or SRV record, Name and Target are relative to LocalDomain.
+type Record struct {
+	Name string
+	// Type is dns.TypeTXT or dns.TypeSRV
+	Type uint16
+	Text string
+	// Priority, Weight, Port and Target are used by SRV record
+	Priority uint16
+	Weight   uint16
+	Port     uint16
+	// Target ending with dot is fully qualified name outside of LocalDomain
+	Target string
+}
+
+// NewResolver starts DNS server on dnsAddress with DefaultUpstreamDNSAddress as upstream, see SetUpstreams.
+// Responses of upstreams are cached, cacheSize is the maximum number of cached responses, 0 disables cache.
+func NewResolver(dnsAddress string, cacheSize int) *Resolver {
 	r := &Resolver{
-		logger: log.Logger("awl/dns"),
-		udpClient: &dns.Client{
-			Net: "udp",
-		},
-		tcpClient: &dns.Client{
-			Net: "tcp",
-		},
+		logger:     log.Logger("awl/dns"),
+		cache:      newCache(cacheSize),
 		dnsAddress: dnsAddress,
 	}
 	r.cfg.Store(&config{})
+	_ = r.SetUpstreams([]string{DefaultUpstreamDNSAddress})
 
 	mux := dns.NewServeMux()
 	mux.HandleFunc(LocalDomain, r.dnsLocalDomainHandler)
@@ -99,30 +152,119 @@ func NewResolver(dnsAddress string) *Resolver {
 	return r
 }
 
-func (r *Resolver) ReceiveConfiguration(upstreamDNS string, namesMapping map[string]string) {
+// SetUpstreams replaces upstream resolvers and clears cache. Upstreams are used in the given order with failover,
+// see ValidateUpstream for supported formats. Invalid upstreams are skipped and returned as error.
+func (r *Resolver) SetUpstreams(addresses []string) error {
+	group, err := newUpstreamGroup(addresses)
+	if len(group.upstreams) == 0 {
+		r.logger.Warnf("no valid upstreams in %v, use %s", addresses, DefaultUpstreamDNSAddress)
+		group, _ = newUpstreamGroup([]string{DefaultUpstreamDNSAddress})
+	}
+	r.upstreams.Store(group)
+	r.cache.clear()
+	return err
+}
+
+// ReceiveConfiguration updates records of LocalDomain zone, namesMapping contains IPv4 and IPv6 addresses of names.
+func (r *Resolver) ReceiveConfiguration(namesMapping map[string][]string, records []Record) {
 	reverseMapping := make(map[string]string, len(namesMapping))
-	directMapping := make(map[string]string, len(namesMapping))
-	for key, ip := range namesMapping {
+	directMapping := make(map[string][]net.IP, len(namesMapping))
+	names := make(map[string]struct{}, len(namesMapping)+len(records))
+	for key, ips := range namesMapping {
 		canonicalName := dns.CanonicalName(key + "." + LocalDomain)
-		directMapping[canonicalName] = ip
-		existedName, exists := reverseMapping[ip]
-		// we always have at least two names for one ip: peerName and peerID
-		// for consistency we will take the shortest one (usually peerName, which is more human-readable)
-		if !exists {
-			reverseMapping[ip] = canonicalName
-		} else if exists && len(canonicalName) < len(existedName) {
-			reverseMapping[ip] = canonicalName
+		addName(names, canonicalName)
+		for _, ipStr := range ips {
+			ip := net.ParseIP(ipStr)
+			if ip == nil {
+				r.logger.Warnf("invalid ip %q of name %s", ipStr, key)
+				continue
+			}
+			directMapping[canonicalName] = append(directMapping[canonicalName], ip)
+			existedName, exists := reverseMapping[ip.String()]
+			// we always have at least two names for one ip: peerName and peerID
+			// for consistency we will take the shortest one (usually peerName, which is more human-readable)
+			if !exists || len(canonicalName) < len(existedName) {
+				reverseMapping[ip.String()] = canonicalName
+			}
+		}
+	}
+
+	recordsByName := make(map[string][]dns.RR, len(records))
+	for _, record := range records {
+		rr, err := record.toRR()
+		if err != nil {
+			r.logger.Warnf("invalid %s record %s: %v", dns.TypeToString[record.Type], record.Name, err)
+			continue
 		}
+		name := rr.Header().Name
+		addName(names, name)
+		recordsByName[name] = append(recordsByName[name], rr)
 	}
 
 	cfg := config{
-		upstreamDNS:    upstreamDNS,
 		directMapping:  directMapping,
 		reverseMapping: reverseMapping,
+		records:        recordsByName,
+		names:          names,
+		serial:         uint32(time.Now().Unix()),
 	}
 	r.cfg.Store(&cfg)
 }
 
+func (record Record) toRR() (dns.RR, error) {
+	name := dns.CanonicalName(record.Name + "." + LocalDomain)
+	if _, ok := dns.IsDomainName(name); !ok {
+		return nil, errors.New("invalid name")
+	}
+	hdr := dns.RR_Header{
+		Name:   name,
+		Rrtype: record.Type,
+		Class:  dns.ClassINET,
+		Ttl:    defaultTTLSeconds,
+	}
+
+	switch record.Type {
+	case dns.TypeTXT:
+		return &dns.TXT{Hdr: hdr, Txt: splitTXT(record.Text)}, nil
+	case dns.TypeSRV:
+		target := record.Target
+		if !dns.IsFqdn(target) {
+			target = target + "." + LocalDomain
+		}
+		target = dns.CanonicalName(target)
+		if _, ok := dns.IsDomainName(target); !ok {
+			return nil, errors.New("invalid target")
+		}
+		return &dns.SRV{Hdr: hdr, Priority: record.Priority, Weight: record.Weight, Port: record.Port, Target: target}, nil
+	default:
+		return nil, errors.New("unsupported type")
+	}
+}
+
+// splitTXT splits text into character strings of maximum allowed length.
+func splitTXT(text string) []string {
+	const maxLength = 255
+	parts := make([]string, 0, len(text)/maxLength+1)
+	for len(text) > maxLength {
+		parts = append(parts, text[:maxLength])
+		text = text[maxLength:]
+	}
+	return append(parts, text)
+}
+
+// addName adds name and its parents inside LocalDomain to names.
+func addName(names map[string]struct{}, name string) {
+	zone := dns.Fqdn(LocalDomain)
+	for name != zone && dns.IsSubDomain(zone, name) {
+		names[name] = struct{}{}
+		next, end := dns.NextLabel(name, 0)
+		if end {
+			return
+		}
+		name = name[next:]
+	}
+}
+
 func (r *Resolver) DNSAddress() string {
 	if !r.tcpServerWorking || !r.udpServerWorking {
 		return ""
@@ -131,6 +273,22 @@ func (r *Resolver) DNSAddress() string {
 	return r.dnsAddress
 }
 
+func (r *Resolver) Stats() Stats {
+	return Stats{
+		LocalQueries:    r.stats.localQueries.Load(),
+		ReverseQueries:  r.stats.reverseQueries.Load(),
+		UpstreamQueries: r.stats.upstreamQueries.Load(),
+		UpstreamErrors:  r.stats.upstreamErrors.Load(),
+		CacheHits:       r.cache.hits.Load(),
+		CacheMisses:     r.cache.misses.Load(),
+		CacheEvictions:  r.cache.evictions.Load(),
+		CacheEntries:    r.cache.len(),
+		CacheSize:       r.cache.size,
+		Upstreams:       r.upstreams.Load().stats(),
+		ForwardingRules: r.forwardingStats(),
+	}
+}
+
 func (r *Resolver) Close() {
 	err := r.udpServer.Shutdown()
 	if err != nil {
@@ -146,36 +304,22 @@ func (r *Resolver) dnsLocalDomainHandler(resp dns.ResponseWriter, req *dns.Msg)
 	if len(req.Question) == 0 {
 		return
 	}
+	r.stats.localQueries.Add(1)
 	cfg := r.loadConfig()
 
 	m := new(dns.Msg)
 	m.SetReply(req)
 
 	for _, question := range req.Question {
-		hostname := question.Name
-		qtype := question.Qtype
-		hostnameLower := strings.ToLower(hostname)
-		mappedIP, found := cfg.directMapping[hostnameLower]
-
-		switch qtype {
-		case dns.TypeA, dns.TypeAAAA, dns.TypeANY:
-			aRec := &dns.A{
-				Hdr: dns.RR_Header{
-					// we should return original name from the request as some clients expect that
-					Name:   hostname,
-					Rrtype: dns.TypeA,
-					Class:  dns.ClassINET,
-					Ttl:    defaultTTLSeconds,
-				},
-			}
-			if found {
-				// TODO: support ipv6
-				aRec.A = net.ParseIP(mappedIP).To4()
-			} else {
-				m.SetRcode(req, dns.RcodeNameError)
-			}
-			m.Answer = append(m.Answer, aRec)
+		answers, exists := cfg.answer(question)
+		if !exists {
+			m.Rcode = dns.RcodeNameError
 		}
+		m.Answer = append(m.Answer, answers...)
+	}
+	if len(m.Answer) == 0 {
+		// NXDOMAIN and NODATA responses contain SOA for negative caching
+		m.Ns = append(m.Ns, cfg.soa(dns.Fqdn(LocalDomain)))
 	}
 
 	processOwnResponse(req, resp, m)
@@ -183,6 +327,68 @@ func (r *Resolver) dnsLocalDomainHandler(resp dns.ResponseWriter, req *dns.Msg)
 	_ = resp.WriteMsg(m)
 }
 
+// answer returns records of question type, false means that name doesn't exist.
+func (cfg config) answer(question dns.Question) ([]dns.RR, bool) {
+	zone := dns.Fqdn(LocalDomain)
+	name := strings.ToLower(question.Name)
+	_, exists := cfg.names[name]
+	ips := cfg.directMapping[name]
+	if name == nsName && len(ips) == 0 {
+		ips = []net.IP{net.ParseIP(DNSIp)}
+		exists = true
+	}
+	if !exists && name != zone {
+		return nil, false
+	}
+
+	// we should return original name from the request as some clients expect that
+	header := func(rrtype uint16) dns.RR_Header {
+		return dns.RR_Header{Name: question.Name, Rrtype: rrtype, Class: dns.ClassINET, Ttl: defaultTTLSeconds}
+	}
+	match := func(rrtype uint16) bool {
+		return question.Qtype == rrtype || question.Qtype == dns.TypeANY
+	}
+
+	answers := make([]dns.RR, 0)
+	for _, ip := range ips {
+		if ipv4 := ip.To4(); ipv4 != nil && match(dns.TypeA) {
+			answers = append(answers, &dns.A{Hdr: header(dns.TypeA), A: ipv4})
+		} else if ipv4 == nil && match(dns.TypeAAAA) {
+			answers = append(answers, &dns.AAAA{Hdr: header(dns.TypeAAAA), AAAA: ip})
+		}
+	}
+	for _, rr := range cfg.records[name] {
+		if match(rr.Header().Rrtype) {
+			rr = dns.Copy(rr)
+			rr.Header().Name = question.Name
+			answers = append(answers, rr)
+		}
+	}
+	if name == zone {
+		if match(dns.TypeSOA) {
+			answers = append(answers, cfg.soa(question.Name))
+		}
+		if match(dns.TypeNS) {
+			answers = append(answers, &dns.NS{Hdr: header(dns.TypeNS), Ns: nsName})
+		}
+	}
+
+	return answers, true
+}
+
+func (cfg config) soa(name string) *dns.SOA {
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: name, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: defaultTTLSeconds},
+		Ns:      nsName,
+		Mbox:    "hostmaster." + dns.Fqdn(LocalDomain),
+		Serial:  cfg.serial,
+		Refresh: soaRefreshSeconds,
+		Retry:   soaRetrySeconds,
+		Expire:  soaExpireSeconds,
+		Minttl:  defaultTTLSeconds,
+	}
+}
+
 func (r *Resolver) ptrv4Handler(resp dns.ResponseWriter, req *dns.Msg) {
 	if len(req.Question) == 0 || req.Question[0].Qtype != dns.TypePTR {
 		r.dnsProxyHandler(resp, req)
@@ -202,6 +408,7 @@ func (r *Resolver) ptrv4Handler(resp dns.ResponseWriter, req *dns.Msg) {
 		r.dnsProxyHandler(resp, req)
 		return
 	}
+	r.stats.reverseQueries.Add(1)
 
 	m := new(dns.Msg)
 	m.SetReply(req)
@@ -223,22 +430,31 @@ func (r *Resolver) ptrv4Handler(resp dns.ResponseWriter, req *dns.Msg) {
 }
 
 func (r *Resolver) dnsProxyHandler(resp dns.ResponseWriter, req *dns.Msg) {
-	cfg := r.loadConfig()
-
-	dnsClient := r.udpClient
-	if _, ok := resp.RemoteAddr().(*net.TCPAddr); ok {
-		dnsClient = r.tcpClient
+	if cached := r.cache.get(req); cached != nil {
+		cached.Truncate(maxResponseSize(req, resp))
+		_ = resp.WriteMsg(cached)
+		return
 	}
+	r.stats.upstreamQueries.Add(1)
 
-	upstreamResp, _, err := dnsClient.Exchange(req, cfg.upstreamDNS)
+	_, isTCP := resp.RemoteAddr().(*net.TCPAddr)
+	ctx, cancel := context.WithTimeout(context.Background(), upstreamQueryTimeout)
+	defer cancel()
+	upstreamResp, err := r.upstreamGroupFor(req).exchange(ctx, req, isTCP)
 	if err != nil {
+		r.stats.upstreamErrors.Add(1)
 		r.logger.Warnf("send request to upstream dns: %v", err)
 		m := new(dns.Msg)
 		m.SetRcode(req, dns.RcodeServerFailure)
 		_ = resp.WriteMsg(m)
 		return
 	}
+	r.cache.set(req, upstreamResp)
 
+	// encrypted upstreams could return response bigger than client accepts over udp
+	if !isTCP && !upstreamResp.Truncated {
+		upstreamResp.Truncate(maxResponseSize(req, resp))
+	}
 	_ = resp.WriteMsg(upstreamResp)
 }
 
@@ -251,6 +467,13 @@ func (r *Resolver) loadConfig() config {
 }
 
 func processOwnResponse(req *dns.Msg, respWriter dns.ResponseWriter, resp *dns.Msg) {
+	resp.Truncate(maxResponseSize(req, respWriter))
+
+	resp.Authoritative = true
+	resp.RecursionAvailable = true
+}
+
+func maxResponseSize(req *dns.Msg, respWriter dns.ResponseWriter) int {
 	maxSize := dns.MinMsgSize
 	if respWriter.LocalAddr().Network() == "tcp" {
 		maxSize = dns.MaxMsgSize
@@ -262,10 +485,7 @@ func processOwnResponse(req *dns.Msg, respWriter dns.ResponseWriter, resp *dns.M
 			}
 		}
 	}
-	resp.Truncate(maxSize)
-
-	resp.Authoritative = true
-	resp.RecursionAvailable = true
+	return maxSize
 }
 
 func TrimDomainName(domain string) string {
@@ -18,7 +18,7 @@ func TestDNS(t *testing.T) {
 	port := FindFreePort()
 	addr := fmt.Sprintf("127.0.0.1:%d", port)
 
-	resolver := NewResolver(addr)
+	resolver := NewResolver(addr, 0)
 	defer resolver.Close()
 	// TODO: remove sleep. We need it because NewResolver starts servers in goroutines
 	time.Sleep(50 * time.Millisecond)
@@ -30,11 +30,11 @@ func TestDNS(t *testing.T) {
 	name2Capitalized := "LAPTOP.office"
 	addr2 := "10.66.0.2"
 
-	namesMapping := map[string]string{
-		name1: addr1,
-		name2: addr2,
+	namesMapping := map[string][]string{
+		name1: {addr1},
+		name2: {addr2},
 	}
-	resolver.ReceiveConfiguration("", namesMapping)
+	resolver.ReceiveConfiguration(namesMapping, nil)
 
 	client := NewResolverClient(addr)
 
@@ -68,6 +68,85 @@ func TestDNS(t *testing.T) {
 	}
 }
 
+func TestDNSRecords(t *testing.T) {
+	a := require.New(t)
+	port := FindFreePort()
+	addr := fmt.Sprintf("127.0.0.1:%d", port)
+
+	resolver := NewResolver(addr, 0)
+	defer resolver.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	resolver.ReceiveConfiguration(map[string][]string{
+		"laptop": {"10.66.0.2", "fd66::2"},
+		"phone":  {"10.66.0.3"},
+	}, []Record{
+		{Name: "laptop", Type: dns.TypeTXT, Text: "hello"},
+		{Name: "_http._tcp.laptop", Type: dns.TypeSRV, Port: 8080, Priority: 1, Weight: 5, Target: "laptop"},
+		{Name: "_sip._udp.phone", Type: dns.TypeSRV, Port: 5060, Target: "sip.example.com."},
+	})
+
+	client := &dns.Client{Net: "udp"}
+	query := func(name string, qtype uint16) *dns.Msg {
+		req := new(dns.Msg)
+		req.SetQuestion(name, qtype)
+		resp, _, err := client.Exchange(req, addr)
+		a.NoError(err)
+		return resp
+	}
+	assertNoData := func(resp *dns.Msg) {
+		a.Equal(dns.RcodeSuccess, resp.Rcode)
+		a.Empty(resp.Answer)
+		a.Len(resp.Ns, 1)
+		a.IsType(&dns.SOA{}, resp.Ns[0])
+	}
+
+	resp := query("LAPTOP.awl.", dns.TypeA)
+	a.Len(resp.Answer, 1)
+	a.Equal("LAPTOP.awl.", resp.Answer[0].Header().Name)
+	a.Equal("10.66.0.2", resp.Answer[0].(*dns.A).A.String())
+
+	resp = query("laptop.awl.", dns.TypeAAAA)
+	a.Len(resp.Answer, 1)
+	a.Equal("fd66::2", resp.Answer[0].(*dns.AAAA).AAAA.String())
+	assertNoData(query("phone.awl.", dns.TypeAAAA))
+	assertNoData(query("phone.awl.", dns.TypeMX))
+	assertNoData(query("_tcp.laptop.awl.", dns.TypeA))
+
+	resp = query("laptop.awl.", dns.TypeANY)
+	a.Len(resp.Answer, 3)
+
+	resp = query("laptop.awl.", dns.TypeTXT)
+	a.Len(resp.Answer, 1)
+	a.Equal([]string{"hello"}, resp.Answer[0].(*dns.TXT).Txt)
+
+	resp = query("_http._tcp.laptop.awl.", dns.TypeSRV)
+	a.Len(resp.Answer, 1)
+	srv := resp.Answer[0].(*dns.SRV)
+	a.Equal(uint16(8080), srv.Port)
+	a.Equal(uint16(1), srv.Priority)
+	a.Equal(uint16(5), srv.Weight)
+	a.Equal("laptop.awl.", srv.Target)
+	resp = query("_sip._udp.phone.awl.", dns.TypeSRV)
+	a.Len(resp.Answer, 1)
+	a.Equal("sip.example.com.", resp.Answer[0].(*dns.SRV).Target)
+
+	resp = query("awl.", dns.TypeSOA)
+	a.Len(resp.Answer, 1)
+	a.Equal(nsName, resp.Answer[0].(*dns.SOA).Ns)
+	resp = query("awl.", dns.TypeNS)
+	a.Len(resp.Answer, 1)
+	a.Equal(nsName, resp.Answer[0].(*dns.NS).Ns)
+	resp = query(nsName, dns.TypeA)
+	a.Len(resp.Answer, 1)
+	a.Equal(DNSIp, resp.Answer[0].(*dns.A).A.String())
+
+	resp = query("unknown.awl.", dns.TypeA)
+	a.Equal(dns.RcodeNameError, resp.Rcode)
+	a.Empty(resp.Answer)
+	a.Len(resp.Ns, 1)
+}
+
 func NewResolverClient(address string) *net.Resolver {
 	dialer := &net.Dialer{Timeout: time.Second}
 	return &net.Resolver{
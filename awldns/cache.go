@@ -0,0 +1,209 @@
+package awldns
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	DefaultCacheSize = 4096
+	// maxCacheTTL limits ttl of cached responses, so we don't keep stale records for too long
+	maxCacheTTL = time.Hour
+)
+
+type cacheKey struct {
+	name     string
+	qtype    uint16
+	qclass   uint16
+	dnssecOK bool
+}
+
+type cacheEntry struct {
+	msg      *dns.Msg
+	storedAt time.Time
+	expires  time.Time
+}
+
+// cache stores upstream responses respecting their TTLs, negative responses are cached with ttl from SOA (RFC 2308).
+type cache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[cacheKey]cacheEntry
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+// newCache returns cache with maximum size of entries, zero or negative size disables it.
+func newCache(size int) *cache {
+	return &cache{size: max(size, 0), entries: make(map[cacheKey]cacheEntry)}
+}
+
+func newCacheKey(req *dns.Msg) (cacheKey, bool) {
+	if len(req.Question) != 1 {
+		return cacheKey{}, false
+	}
+	question := req.Question[0]
+	key := cacheKey{
+		name:   strings.ToLower(question.Name),
+		qtype:  question.Qtype,
+		qclass: question.Qclass,
+	}
+	if opt := req.IsEdns0(); opt != nil {
+		key.dnssecOK = opt.Do()
+	}
+	return key, true
+}
+
+// get returns copy of cached response for req with decreased TTLs.
+func (c *cache) get(req *dns.Msg) *dns.Msg {
+	if c.size == 0 {
+		return nil
+	}
+	key, ok := newCacheKey(req)
+	if !ok {
+		return nil
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	entry, found := c.entries[key]
+	if found && !now.Before(entry.expires) {
+		delete(c.entries, key)
+		found = false
+	}
+	c.mu.Unlock()
+	if !found {
+		c.misses.Add(1)
+		return nil
+	}
+	c.hits.Add(1)
+
+	elapsed := uint32(now.Sub(entry.storedAt) / time.Second)
+	msg := entry.msg.Copy()
+	msg.Id = req.Id
+	// we should return original name from the request as some clients expect that
+	msg.Question = req.Question
+	for _, records := range [][]dns.RR{msg.Answer, msg.Ns, msg.Extra} {
+		for _, rr := range records {
+			hdr := rr.Header()
+			if hdr.Rrtype == dns.TypeOPT {
+				continue
+			}
+			if strings.EqualFold(hdr.Name, req.Question[0].Name) {
+				hdr.Name = req.Question[0].Name
+			}
+			hdr.Ttl -= min(hdr.Ttl, elapsed)
+		}
+	}
+	return msg
+}
+
+// set stores copy of successful or negative response for req.
+func (c *cache) set(req, resp *dns.Msg) {
+	if c.size == 0 || resp.Truncated || (resp.Rcode != dns.RcodeSuccess && resp.Rcode != dns.RcodeNameError) {
+		return
+	}
+	key, ok := newCacheKey(req)
+	if !ok {
+		return
+	}
+	ttl, ok := responseTTL(resp)
+	if !ok || ttl == 0 {
+		return
+	}
+
+	now := time.Now()
+	entry := cacheEntry{
+		msg:      resp.Copy(),
+		storedAt: now,
+		expires:  now.Add(min(time.Duration(ttl)*time.Second, maxCacheTTL)),
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= c.size {
+		c.evict(now)
+	}
+	c.entries[key] = entry
+}
+
+// evict removes expired entries, or a random one if there are no expired entries. It should be called with lock.
+func (c *cache) evict(now time.Time) {
+	evicted := 0
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, key)
+			evicted++
+		}
+	}
+	if evicted == 0 {
+		for key := range c.entries {
+			delete(c.entries, key)
+			evicted++
+			break
+		}
+	}
+	c.evictions.Add(uint64(evicted))
+}
+
+func (c *cache) clear() {
+	c.mu.Lock()
+	clear(c.entries)
+	c.mu.Unlock()
+}
+
+func (c *cache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// responseTTL returns the minimum ttl of records, for negative responses it's limited by SOA minimum ttl.
+func responseTTL(resp *dns.Msg) (uint32, bool) {
+	var (
+		ttl   uint32
+		found bool
+	)
+	update := func(value uint32) {
+		if !found || value < ttl {
+			ttl = value
+		}
+		found = true
+	}
+
+	for _, rr := range resp.Answer {
+		update(rr.Header().Ttl)
+	}
+	for _, rr := range resp.Ns {
+		update(rr.Header().Ttl)
+		if soa, isSOA := rr.(*dns.SOA); isSOA && len(resp.Answer) == 0 {
+			update(soa.Minttl)
+		}
+	}
+	for _, rr := range resp.Extra {
+		if rr.Header().Rrtype != dns.TypeOPT {
+			update(rr.Header().Ttl)
+		}
+	}
+
+	if len(resp.Answer) == 0 && !hasSOA(resp.Ns) {
+		// negative response without SOA should not be cached
+		return 0, false
+	}
+	return ttl, found
+}
+
+func hasSOA(records []dns.RR) bool {
+	for _, rr := range records {
+		if rr.Header().Rrtype == dns.TypeSOA {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package awldns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// UpstreamProtocolPeer is protocol of forwarding rules with ForwardingRule Exchange, e.g. queries to peer over p2p.
+const UpstreamProtocolPeer = "peer"
+
+// ExchangeFunc sends query and returns response.
+type ExchangeFunc func(ctx context.Context, req *dns.Msg) (*dns.Msg, error)
+
+// ForwardingRule sends queries for Zone and its subdomains to Upstream, or with Exchange if it's set.
+type ForwardingRule struct {
+	Zone     string
+	Upstream string
+	// Name is shown in stats instead of Upstream for rules with Exchange
+	Name     string
+	Exchange ExchangeFunc
+}
+
+// ForwardingStats contains health and counters of forwarding rule.
+type ForwardingStats struct {
+	Zone string
+	UpstreamStats
+}
+
+type forwarder struct {
+	// zone is canonical fully qualified name
+	zone  string
+	group *upstreamGroup
+}
+
+// ValidateForwardingZone checks that zone is a domain name which doesn't overlap with LocalDomain.
+func ValidateForwardingZone(zone string) error {
+	zone = dns.CanonicalName(strings.TrimSpace(zone))
+	if _, ok := dns.IsDomainName(zone); !ok || zone == "." {
+		return fmt.Errorf("invalid zone %q", zone)
+	}
+	localZone := dns.Fqdn(LocalDomain)
+	if dns.IsSubDomain(localZone, zone) || dns.IsSubDomain(zone, localZone) {
+		return fmt.Errorf("zone %q overlaps with %s zone", zone, LocalDomain)
+	}
+	return nil
+}
+
+// SetForwardingRules replaces forwarding rules and clears cache. Invalid rules are skipped and returned as error.
+func (r *Resolver) SetForwardingRules(rules []ForwardingRule) error {
+	forwarders := make([]forwarder, 0, len(rules))
+	var errs []error
+	for _, rule := range rules {
+		err := ValidateForwardingZone(rule.Zone)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		zone := dns.CanonicalName(strings.TrimSpace(rule.Zone))
+
+		var group *upstreamGroup
+		if rule.Exchange != nil {
+			exchange := rule.Exchange
+			group = &upstreamGroup{upstreams: []*upstream{{
+				address:  rule.Name,
+				protocol: UpstreamProtocolPeer,
+				exchange: func(ctx context.Context, req *dns.Msg, _ bool) (*dns.Msg, error) {
+					return exchange(ctx, req)
+				},
+			}}}
+		} else {
+			group, err = newUpstreamGroup([]string{rule.Upstream})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("zone %s: %v", zone, err))
+				continue
+			}
+		}
+		forwarders = append(forwarders, forwarder{zone: zone, group: group})
+	}
+
+	r.forwarders.Store(&forwarders)
+	r.cache.clear()
+	return errors.Join(errs...)
+}
+
+// ExchangeUpstream resolves query with upstreams, without LocalDomain and forwarding rules.
+// It's used for queries forwarded to us by peers, truncated udp responses are retried over tcp.
+func (r *Resolver) ExchangeUpstream(ctx context.Context, req *dns.Msg) (*dns.Msg, error) {
+	group := r.upstreams.Load()
+	resp, err := group.exchange(ctx, req, false)
+	if err == nil && resp.Truncated {
+		return group.exchange(ctx, req, true)
+	}
+	return resp, err
+}
+
+// upstreamGroupFor returns group of the most specific forwarding rule which matches question, or default upstreams.
+func (r *Resolver) upstreamGroupFor(req *dns.Msg) *upstreamGroup {
+	group := r.upstreams.Load()
+	forwarders := r.forwarders.Load()
+	if forwarders == nil || len(req.Question) != 1 {
+		return group
+	}
+
+	name := dns.CanonicalName(req.Question[0].Name)
+	matchedLabels := 0
+	for _, fwd := range *forwarders {
+		if labels := dns.CountLabel(fwd.zone); labels > matchedLabels && dns.IsSubDomain(fwd.zone, name) {
+			group = fwd.group
+			matchedLabels = labels
+		}
+	}
+	return group
+}
+
+func (r *Resolver) forwardingStats() []ForwardingStats {
+	forwarders := r.forwarders.Load()
+	if forwarders == nil {
+		return nil
+	}
+	result := make([]ForwardingStats, 0, len(*forwarders))
+	for _, fwd := range *forwarders {
+		for _, stats := range fwd.group.stats() {
+			result = append(result, ForwardingStats{Zone: fwd.zone, UpstreamStats: stats})
+		}
+	}
+	return result
+}
@@ -0,0 +1,95 @@
+package awldns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForwardingRules(t *testing.T) {
+	a := require.New(t)
+	answer := func(ip string) func(req *dns.Msg) *dns.Msg {
+		return func(req *dns.Msg) *dns.Msg {
+			m := new(dns.Msg)
+			m.SetReply(req)
+			m.Answer = append(m.Answer, &dns.A{
+				Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+				A:   net.ParseIP(ip),
+			})
+			return m
+		}
+	}
+	defaultAddr, defaultQueries := startTestUpstream(t, answer("1.1.1.1"))
+	corpAddr, corpQueries := startTestUpstream(t, answer("10.0.0.1"))
+	peerQueries := new(atomic.Int64)
+	exchangeWithPeer := func(_ context.Context, req *dns.Msg) (*dns.Msg, error) {
+		peerQueries.Add(1)
+		return answer("10.0.1.1")(req), nil
+	}
+
+	addr := fmt.Sprintf("127.0.0.1:%d", FindFreePort())
+	resolver := NewResolver(addr, 0)
+	defer resolver.Close()
+	time.Sleep(50 * time.Millisecond)
+	a.NoError(resolver.SetUpstreams([]string{defaultAddr}))
+	err := resolver.SetForwardingRules([]ForwardingRule{
+		{Zone: "corp.internal", Upstream: corpAddr},
+		{Zone: "dev.corp.internal", Name: "office", Exchange: exchangeWithPeer},
+		{Zone: "peer.awl", Upstream: corpAddr},
+	})
+	a.Error(err)
+
+	client := &dns.Client{Net: "udp"}
+	query := func(name string) string {
+		req := new(dns.Msg)
+		req.SetQuestion(name, dns.TypeA)
+		resp, _, err := client.Exchange(req, addr)
+		a.NoError(err)
+		a.Len(resp.Answer, 1)
+		return resp.Answer[0].(*dns.A).A.String()
+	}
+
+	a.Equal("1.1.1.1", query("example.com."))
+	a.Equal("10.0.0.1", query("corp.internal."))
+	a.Equal("10.0.0.1", query("Git.Corp.Internal."))
+	a.Equal("10.0.1.1", query("app.dev.corp.internal."))
+	a.Equal("1.1.1.1", query("notcorp.internal."))
+	a.Equal(int64(2), defaultQueries.Load())
+	a.Equal(int64(2), corpQueries.Load())
+	a.Equal(int64(1), peerQueries.Load())
+
+	stats := resolver.Stats()
+	a.Len(stats.ForwardingRules, 2)
+	a.Equal("dev.corp.internal.", stats.ForwardingRules[1].Zone)
+	a.Equal("office", stats.ForwardingRules[1].Address)
+	a.Equal(UpstreamProtocolPeer, stats.ForwardingRules[1].Protocol)
+	a.Equal(uint64(1), stats.ForwardingRules[1].Queries)
+
+	// queries from peers ignore forwarding rules
+	req := new(dns.Msg)
+	req.SetQuestion("corp.internal.", dns.TypeA)
+	resp, err := resolver.ExchangeUpstream(context.Background(), req)
+	a.NoError(err)
+	a.Equal("1.1.1.1", resp.Answer[0].(*dns.A).A.String())
+
+	a.NoError(resolver.SetForwardingRules(nil))
+	a.Equal("1.1.1.1", query("corp.internal."))
+}
+
+func TestValidateForwardingZone(t *testing.T) {
+	a := require.New(t)
+	a.NoError(ValidateForwardingZone("corp.internal"))
+	a.NoError(ValidateForwardingZone("Corp.Internal."))
+	a.NoError(ValidateForwardingZone("10.in-addr.arpa"))
+	a.Error(ValidateForwardingZone(""))
+	a.Error(ValidateForwardingZone("."))
+	a.Error(ValidateForwardingZone("awl"))
+	a.Error(ValidateForwardingZone("peer.awl"))
+	a.Error(ValidateForwardingZone("bad..zone"))
+}
@@ -0,0 +1,379 @@
+package awldns
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	UpstreamProtocolDNS   = "dns"
+	UpstreamProtocolTLS   = "tls"
+	UpstreamProtocolHTTPS = "https"
+
+	defaultDNSOverTLSPort = "853"
+	dnsMessageMimeType    = "application/dns-message"
+
+	upstreamTimeout = 2 * time.Second
+	// upstream is skipped after failure for upstreamBackoff, it doubles after each consecutive failure up to upstreamMaxBackoff
+	upstreamBackoff    = 5 * time.Second
+	upstreamMaxBackoff = 5 * time.Minute
+	maxDoHResponseSize = dns.MaxMsgSize
+)
+
+// UpstreamStats contains health and counters of upstream resolver.
+type UpstreamStats struct {
+	Address  string
+	Protocol string
+	Healthy  bool
+	Queries  uint64
+	Errors   uint64
+	// ConsecutiveFailures is reset after successful query
+	ConsecutiveFailures int
+	UnhealthyUntil      time.Time
+	LastRTT             time.Duration
+	LastError           string
+}
+
+// upstreamAddress is parsed address of upstream resolver:
+//   - "1.1.1.1" or "1.1.1.1:53" for plain DNS over udp/tcp
+//   - "tls://1.1.1.1" or "tls://dns.example.com:853" for DNS-over-TLS
+//   - "https://cloudflare-dns.com/dns-query" for DNS-over-HTTPS
+type upstreamAddress struct {
+	protocol string
+	// hostPort is used for dns and tls protocols
+	hostPort string
+	// url is used for https protocol
+	url *url.URL
+}
+
+// ValidateUpstream checks that address of upstream resolver is supported.
+func ValidateUpstream(address string) error {
+	_, err := parseUpstreamAddress(address)
+	return err
+}
+
+func parseUpstreamAddress(address string) (upstreamAddress, error) {
+	address = strings.TrimSpace(address)
+	scheme, rest, found := strings.Cut(address, "://")
+	if !found {
+		scheme, rest = UpstreamProtocolDNS, address
+	}
+
+	switch scheme {
+	case UpstreamProtocolDNS, UpstreamProtocolTLS:
+		defaultPort := DefaultDNSPort
+		if scheme == UpstreamProtocolTLS {
+			defaultPort = defaultDNSOverTLSPort
+		}
+		hostPort, err := withDefaultPort(rest, defaultPort)
+		if err != nil {
+			return upstreamAddress{}, fmt.Errorf("invalid upstream %q: %v", address, err)
+		}
+		host, _, _ := net.SplitHostPort(hostPort)
+		if scheme == UpstreamProtocolDNS && net.ParseIP(host) == nil {
+			return upstreamAddress{}, fmt.Errorf("invalid upstream %q: plain dns upstream should be ip address", address)
+		}
+		return upstreamAddress{protocol: scheme, hostPort: hostPort}, nil
+	case UpstreamProtocolHTTPS:
+		u, err := url.Parse(address)
+		if err != nil {
+			return upstreamAddress{}, fmt.Errorf("invalid upstream %q: %v", address, err)
+		}
+		if u.Host == "" {
+			return upstreamAddress{}, fmt.Errorf("invalid upstream %q: empty host", address)
+		}
+		return upstreamAddress{protocol: scheme, url: u}, nil
+	default:
+		return upstreamAddress{}, fmt.Errorf("invalid upstream %q: unsupported protocol %q", address, scheme)
+	}
+}
+
+func withDefaultPort(address, defaultPort string) (string, error) {
+	if address == "" {
+		return "", errors.New("empty address")
+	}
+	if _, _, err := net.SplitHostPort(address); err == nil {
+		return address, nil
+	}
+	host := strings.TrimSuffix(strings.TrimPrefix(address, "["), "]")
+	if strings.ContainsAny(host, "[]/") {
+		return "", errors.New("invalid host")
+	}
+	return net.JoinHostPort(host, defaultPort), nil
+}
+
+type upstream struct {
+	address  string
+	protocol string
+	exchange func(ctx context.Context, req *dns.Msg, tcp bool) (*dns.Msg, error)
+
+	queries atomic.Uint64
+	errors  atomic.Uint64
+
+	mu                  sync.Mutex
+	consecutiveFailures int
+	unhealthyUntil      time.Time
+	lastRTT             time.Duration
+	lastError           string
+}
+
+// newUpstream creates upstream resolver, hostnames of tls and https upstreams are resolved with bootstrapResolver.
+func newUpstream(address string, bootstrapResolver *net.Resolver) (*upstream, error) {
+	parsed, err := parseUpstreamAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	u := &upstream{address: address, protocol: parsed.protocol}
+	dialer := &net.Dialer{Timeout: upstreamTimeout, Resolver: bootstrapResolver}
+
+	switch parsed.protocol {
+	case UpstreamProtocolDNS:
+		udpClient := &dns.Client{Net: "udp", Timeout: upstreamTimeout}
+		tcpClient := &dns.Client{Net: "tcp", Timeout: upstreamTimeout}
+		u.exchange = func(ctx context.Context, req *dns.Msg, tcp bool) (*dns.Msg, error) {
+			client := udpClient
+			if tcp {
+				client = tcpClient
+			}
+			resp, _, err := client.ExchangeContext(ctx, req, parsed.hostPort)
+			return resp, err
+		}
+	case UpstreamProtocolTLS:
+		host, _, _ := net.SplitHostPort(parsed.hostPort)
+		client := &dns.Client{
+			Net:       "tcp-tls",
+			Timeout:   upstreamTimeout,
+			Dialer:    dialer,
+			TLSConfig: &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12},
+		}
+		u.exchange = func(ctx context.Context, req *dns.Msg, _ bool) (*dns.Msg, error) {
+			resp, _, err := client.ExchangeContext(ctx, req, parsed.hostPort)
+			return resp, err
+		}
+	case UpstreamProtocolHTTPS:
+		client := &http.Client{
+			Timeout: upstreamTimeout,
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSClientConfig:     &tls.Config{MinVersion: tls.VersionTLS12},
+				TLSHandshakeTimeout: upstreamTimeout,
+				ForceAttemptHTTP2:   true,
+				MaxIdleConnsPerHost: 4,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		}
+		endpoint := parsed.url.String()
+		u.exchange = func(ctx context.Context, req *dns.Msg, _ bool) (*dns.Msg, error) {
+			return exchangeHTTPS(ctx, client, endpoint, req)
+		}
+	}
+
+	return u, nil
+}
+
+// exchangeHTTPS sends request with POST method as described in RFC 8484.
+func exchangeHTTPS(ctx context.Context, client *http.Client, endpoint string, req *dns.Msg) (*dns.Msg, error) {
+	// id should be 0 for better caching by http servers
+	msg := req.Copy()
+	msg.Id = 0
+	packed, err := msg.Pack()
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(packed))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", dnsMessageMimeType)
+	httpReq.Header.Set("Accept", dnsMessageMimeType)
+
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected http status %s", httpResp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(httpResp.Body, maxDoHResponseSize))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(dns.Msg)
+	err = resp.Unpack(body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Id = req.Id
+	return resp, nil
+}
+
+func (u *upstream) isHealthy(now time.Time) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return !now.Before(u.unhealthyUntil)
+}
+
+func (u *upstream) retryAt() time.Time {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.unhealthyUntil
+}
+
+func (u *upstream) markSuccess(rtt time.Duration) {
+	u.mu.Lock()
+	u.consecutiveFailures = 0
+	u.unhealthyUntil = time.Time{}
+	u.lastRTT = rtt
+	u.mu.Unlock()
+}
+
+func (u *upstream) markFailure(err error) {
+	u.errors.Add(1)
+	u.mu.Lock()
+	u.consecutiveFailures++
+	backoff := upstreamMaxBackoff
+	if shift := u.consecutiveFailures - 1; shift < 16 {
+		backoff = min(upstreamBackoff<<shift, upstreamMaxBackoff)
+	}
+	u.unhealthyUntil = time.Now().Add(backoff)
+	u.lastError = err.Error()
+	u.mu.Unlock()
+}
+
+func (u *upstream) stats(now time.Time) UpstreamStats {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return UpstreamStats{
+		Address:             u.address,
+		Protocol:            u.protocol,
+		Healthy:             !now.Before(u.unhealthyUntil),
+		Queries:             u.queries.Load(),
+		Errors:              u.errors.Load(),
+		ConsecutiveFailures: u.consecutiveFailures,
+		UnhealthyUntil:      u.unhealthyUntil,
+		LastRTT:             u.lastRTT,
+		LastError:           u.lastError,
+	}
+}
+
+// upstreamGroup sends queries to upstreams in configured order, unhealthy upstreams are tried only after healthy ones.
+type upstreamGroup struct {
+	upstreams []*upstream
+}
+
+// newUpstreamGroup creates group from valid addresses, it returns error for each invalid one.
+func newUpstreamGroup(addresses []string) (*upstreamGroup, error) {
+	bootstrapResolver := newBootstrapResolver(addresses)
+	group := &upstreamGroup{}
+	var errs []error
+	for _, address := range addresses {
+		u, err := newUpstream(address, bootstrapResolver)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		group.upstreams = append(group.upstreams, u)
+	}
+	return group, errors.Join(errs...)
+}
+
+// newBootstrapResolver resolves hostnames of encrypted upstreams with the first plain dns upstream.
+// We can't use system resolver because awl DNS could be the system one.
+func newBootstrapResolver(addresses []string) *net.Resolver {
+	bootstrapAddress := DefaultUpstreamDNSAddress
+	for _, address := range addresses {
+		parsed, err := parseUpstreamAddress(address)
+		if err == nil && parsed.protocol == UpstreamProtocolDNS {
+			bootstrapAddress = parsed.hostPort
+			break
+		}
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			d := net.Dialer{Timeout: upstreamTimeout}
+			return d.DialContext(ctx, network, bootstrapAddress)
+		},
+	}
+}
+
+// exchange returns the first successful response. Responses with SERVFAIL and REFUSED codes
+// are returned only if all upstreams failed.
+func (g *upstreamGroup) exchange(ctx context.Context, req *dns.Msg, tcp bool) (*dns.Msg, error) {
+	if len(g.upstreams) == 0 {
+		return nil, errors.New("no upstream dns servers")
+	}
+
+	now := time.Now()
+	ordered := make([]*upstream, 0, len(g.upstreams))
+	var unhealthy []*upstream
+	for _, u := range g.upstreams {
+		if u.isHealthy(now) {
+			ordered = append(ordered, u)
+		} else {
+			unhealthy = append(unhealthy, u)
+		}
+	}
+	// the sooner upstream is going to recover, the more chances it works now
+	slices.SortStableFunc(unhealthy, func(a, b *upstream) int {
+		return a.retryAt().Compare(b.retryAt())
+	})
+	ordered = append(ordered, unhealthy...)
+
+	var (
+		lastErr      error
+		fallbackResp *dns.Msg
+	)
+	for _, u := range ordered {
+		u.queries.Add(1)
+		start := time.Now()
+		resp, err := u.exchange(ctx, req, tcp)
+		switch {
+		case err != nil:
+			lastErr = fmt.Errorf("%s: %v", u.address, err)
+			u.markFailure(err)
+		case resp.Rcode == dns.RcodeServerFailure || resp.Rcode == dns.RcodeRefused:
+			lastErr = fmt.Errorf("%s: response code %s", u.address, dns.RcodeToString[resp.Rcode])
+			u.markFailure(errors.New(dns.RcodeToString[resp.Rcode]))
+			if fallbackResp == nil {
+				fallbackResp = resp
+			}
+		default:
+			u.markSuccess(time.Since(start))
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if fallbackResp != nil {
+		return fallbackResp, nil
+	}
+	return nil, lastErr
+}
+
+func (g *upstreamGroup) stats() []UpstreamStats {
+	now := time.Now()
+	result := make([]UpstreamStats, 0, len(g.upstreams))
+	for _, u := range g.upstreams {
+		result = append(result, u.stats(now))
+	}
+	return result
+}
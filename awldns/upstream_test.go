@@ -0,0 +1,211 @@
+package awldns
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUpstreamAddress(t *testing.T) {
+	tests := []struct {
+		address  string
+		protocol string
+		hostPort string
+		wantErr  bool
+	}{
+		{address: "1.1.1.1", protocol: UpstreamProtocolDNS, hostPort: "1.1.1.1:53"},
+		{address: "1.1.1.1:5353", protocol: UpstreamProtocolDNS, hostPort: "1.1.1.1:5353"},
+		{address: "2606:4700:4700::1111", protocol: UpstreamProtocolDNS, hostPort: "[2606:4700:4700::1111]:53"},
+		{address: "[2606:4700:4700::1111]:53", protocol: UpstreamProtocolDNS, hostPort: "[2606:4700:4700::1111]:53"},
+		{address: "dns://8.8.8.8", protocol: UpstreamProtocolDNS, hostPort: "8.8.8.8:53"},
+		{address: "tls://1.1.1.1", protocol: UpstreamProtocolTLS, hostPort: "1.1.1.1:853"},
+		{address: "tls://dns.example.com:8853", protocol: UpstreamProtocolTLS, hostPort: "dns.example.com:8853"},
+		{address: "https://cloudflare-dns.com/dns-query", protocol: UpstreamProtocolHTTPS},
+		{address: "dns.example.com", wantErr: true},
+		{address: "", wantErr: true},
+		{address: "https:///dns-query", wantErr: true},
+		{address: "quic://1.1.1.1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			parsed, err := parseUpstreamAddress(tt.address)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.protocol, parsed.protocol)
+			require.Equal(t, tt.hostPort, parsed.hostPort)
+		})
+	}
+}
+
+func TestUpstreamFailoverAndCache(t *testing.T) {
+	a := require.New(t)
+	failingAddr, failingQueries := startTestUpstream(t, func(req *dns.Msg) *dns.Msg {
+		m := new(dns.Msg)
+		m.SetRcode(req, dns.RcodeServerFailure)
+		return m
+	})
+	workingAddr, workingQueries := startTestUpstream(t, func(req *dns.Msg) *dns.Msg {
+		m := new(dns.Msg)
+		m.SetReply(req)
+		if req.Question[0].Name == "example.com." {
+			m.Answer = append(m.Answer, &dns.A{
+				Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300},
+				A:   net.ParseIP("93.184.216.34"),
+			})
+		} else {
+			m.Rcode = dns.RcodeNameError
+			m.Ns = append(m.Ns, &dns.SOA{
+				Hdr:    dns.RR_Header{Name: "com.", Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 900},
+				Ns:     "ns.com.",
+				Mbox:   "hostmaster.com.",
+				Minttl: 30,
+			})
+		}
+		return m
+	})
+
+	addr := fmt.Sprintf("127.0.0.1:%d", FindFreePort())
+	resolver := NewResolver(addr, DefaultCacheSize)
+	defer resolver.Close()
+	time.Sleep(50 * time.Millisecond)
+	a.Error(resolver.SetUpstreams([]string{failingAddr, "invalid", workingAddr}))
+
+	client := &dns.Client{Net: "udp"}
+	query := func(name string) *dns.Msg {
+		req := new(dns.Msg)
+		req.SetQuestion(name, dns.TypeA)
+		resp, _, err := client.Exchange(req, addr)
+		a.NoError(err)
+		return resp
+	}
+
+	resp := query("example.com.")
+	a.Equal(dns.RcodeSuccess, resp.Rcode)
+	a.Len(resp.Answer, 1)
+	a.Equal(int64(1), failingQueries.Load())
+	a.Equal(int64(1), workingQueries.Load())
+
+	// answered from cache
+	resp = query("EXAMPLE.com.")
+	a.Len(resp.Answer, 1)
+	a.Equal("EXAMPLE.com.", resp.Answer[0].Header().Name)
+	a.LessOrEqual(resp.Answer[0].Header().Ttl, uint32(300))
+	a.Equal(int64(1), workingQueries.Load())
+
+	// failing upstream is skipped while it's unhealthy
+	resp = query("unknown.com.")
+	a.Equal(dns.RcodeNameError, resp.Rcode)
+	a.Equal(int64(1), failingQueries.Load())
+	a.Equal(int64(2), workingQueries.Load())
+	resp = query("unknown.com.")
+	a.Equal(dns.RcodeNameError, resp.Rcode)
+	a.Equal(int64(2), workingQueries.Load())
+
+	stats := resolver.Stats()
+	a.Equal(uint64(2), stats.CacheHits)
+	a.Equal(uint64(2), stats.CacheMisses)
+	a.Equal(2, stats.CacheEntries)
+	a.Equal(uint64(2), stats.UpstreamQueries)
+	a.Len(stats.Upstreams, 2)
+	a.False(stats.Upstreams[0].Healthy)
+	a.Equal(uint64(1), stats.Upstreams[0].Errors)
+	a.True(stats.Upstreams[1].Healthy)
+	a.Equal(uint64(2), stats.Upstreams[1].Queries)
+
+	// cache is cleared with new upstreams
+	a.NoError(resolver.SetUpstreams([]string{workingAddr}))
+	query("example.com.")
+	a.Equal(int64(3), workingQueries.Load())
+}
+
+func TestExchangeHTTPS(t *testing.T) {
+	a := require.New(t)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.Equal(http.MethodPost, r.Method)
+		a.Equal(dnsMessageMimeType, r.Header.Get("Content-Type"))
+		body, err := io.ReadAll(r.Body)
+		a.NoError(err)
+		req := new(dns.Msg)
+		a.NoError(req.Unpack(body))
+		a.Equal(uint16(0), req.Id)
+
+		m := new(dns.Msg)
+		m.SetReply(req)
+		m.Answer = append(m.Answer, &dns.TXT{
+			Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 60},
+			Txt: []string{"doh"},
+		})
+		packed, err := m.Pack()
+		a.NoError(err)
+		w.Header().Set("Content-Type", dnsMessageMimeType)
+		_, _ = w.Write(packed)
+	}))
+	defer server.Close()
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.com.", dns.TypeTXT)
+	resp, err := exchangeHTTPS(context.Background(), server.Client(), server.URL+"/dns-query", req)
+	a.NoError(err)
+	a.Equal(req.Id, resp.Id)
+	a.Len(resp.Answer, 1)
+	a.Equal([]string{"doh"}, resp.Answer[0].(*dns.TXT).Txt)
+}
+
+func TestResponseTTL(t *testing.T) {
+	a := require.New(t)
+	rr := func(s string) dns.RR {
+		record, err := dns.NewRR(s)
+		a.NoError(err)
+		return record
+	}
+
+	resp := new(dns.Msg)
+	resp.Answer = []dns.RR{rr("example.com. 300 IN A 1.2.3.4"), rr("example.com. 60 IN A 1.2.3.5")}
+	ttl, ok := responseTTL(resp)
+	a.True(ok)
+	a.Equal(uint32(60), ttl)
+
+	resp = new(dns.Msg)
+	resp.Ns = []dns.RR{rr("com. 900 IN SOA ns.com. hostmaster.com. 1 3600 600 86400 30")}
+	ttl, ok = responseTTL(resp)
+	a.True(ok)
+	a.Equal(uint32(30), ttl)
+
+	_, ok = responseTTL(new(dns.Msg))
+	a.False(ok)
+}
+
+func startTestUpstream(t *testing.T, handler func(req *dns.Msg) *dns.Msg) (string, *atomic.Int64) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	queries := new(atomic.Int64)
+	started := make(chan struct{})
+	server := &dns.Server{
+		PacketConn: conn,
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+			queries.Add(1)
+			_ = w.WriteMsg(handler(req))
+		}),
+		NotifyStartedFunc: func() { close(started) },
+	}
+	go func() {
+		_ = server.ActivateAndServe()
+	}()
+	<-started
+	t.Cleanup(func() {
+		_ = server.Shutdown()
+	})
+	return conn.LocalAddr().String(), queries
+}
@@ -18,6 +18,25 @@ type ReceivedAuthRequest struct {
 	PeerID string
 }
 
+// ProxyChanged is emitted when peer used as exit node for our socks5 proxy changes, empty UsingPeerID disables proxy.
+type ProxyChanged struct {
+	UsingPeerID string
+}
+
+// FileTransferChanged is emitted when peer offers file and when transfer finishes.
+type FileTransferChanged struct {
+	ID        string
+	PeerID    string
+	Direction string
+	Name      string
+	Size      int64
+	Status    string
+}
+
+type UpdateAvailable struct {
+	Version string
+}
+
 func WrapSubscriptionToCallback(ctx context.Context, callback func(interface{}), bus Bus,
 	eventType interface{}, opts ...event.SubscriptionOpt) {
 	sub, err := bus.Subscribe(eventType, opts...)
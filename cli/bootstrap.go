@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+
+	"github.com/anywherelan/awl/api/apiclient"
+)
+
+func printBootstrapPeers(api *apiclient.Client) error {
+	resp, err := api.BootstrapPeers()
+	if err != nil {
+		return err
+	}
+	if resp.DefaultPeersDisabled {
+		fmt.Println("default bootstrap peers are disabled")
+	}
+	if len(resp.Peers) == 0 {
+		fmt.Println("no bootstrap peers")
+		return nil
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetBorders(tablewriter.Border{Left: false, Top: false, Right: false, Bottom: false})
+	table.SetRowLine(true)
+	table.SetHeader([]string{"peer id", "default", "status", "addresses", "connections"})
+	for _, bootstrapPeer := range resp.Peers {
+		status := "disconnected"
+		if bootstrapPeer.Connected {
+			status = "connected"
+		} else if bootstrapPeer.Error != "" {
+			status = "error: " + bootstrapPeer.Error
+		}
+		table.Append([]string{
+			bootstrapPeer.PeerID,
+			fmt.Sprint(bootstrapPeer.IsDefault),
+			status,
+			strings.Join(bootstrapPeer.Addresses, "\n"),
+			strings.Join(bootstrapPeer.Connections, "\n"),
+		})
+	}
+	table.Render()
+
+	return nil
+}
+
+func addBootstrapPeer(api *apiclient.Client, address string) error {
+	bootstrapPeer, err := api.AddBootstrapPeer(address)
+	if err != nil {
+		return err
+	}
+
+	if bootstrapPeer.Connected {
+		fmt.Println("bootstrap peer added and connected successfully")
+	} else {
+		fmt.Printf("bootstrap peer added, but connection failed: %s\n", bootstrapPeer.Error)
+	}
+
+	return nil
+}
+
+func removeBootstrapPeer(api *apiclient.Client, addrOrPeerID string) error {
+	err := api.RemoveBootstrapPeer(addrOrPeerID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("bootstrap peer removed successfully")
+
+	return nil
+}
+
+func setDefaultBootstrapPeersDisabled(api *apiclient.Client, disabled bool) error {
+	err := api.UpdateBootstrapPeersSettings(disabled)
+	if err != nil {
+		return err
+	}
+
+	if disabled {
+		fmt.Println("default bootstrap peers disabled")
+	} else {
+		fmt.Println("default bootstrap peers enabled")
+	}
+
+	return nil
+}
@@ -9,14 +9,17 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/GrigoryKrasnochub/updaterini"
 	"github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/libp2p/go-libp2p/p2p/host/eventbus"
 	"github.com/urfave/cli/v2"
 
 	"github.com/anywherelan/awl/api/apiclient"
 	"github.com/anywherelan/awl/config"
+	"github.com/anywherelan/awl/entity"
 	"github.com/anywherelan/awl/update"
 )
 
@@ -80,6 +83,11 @@ func (a *Application) init() {
 				Usage:    fmt.Sprintf("awl api address, example: %s", defaultApiAddr),
 				Required: false,
 			},
+			&cli.StringFlag{
+				Name:     "remote",
+				Usage:    "id or name of known peer to manage instead of this device, peer should allow it with 'peers remote_admin'",
+				Required: false,
+			},
 		},
 		Commands: []*cli.Command{
 			{
@@ -91,208 +99,1198 @@ func (a *Application) init() {
 						Usage:  "Print your server status, network stats",
 						Before: a.initApiConnection,
 						Action: func(c *cli.Context) error {
-							return printStatus(a.api)
+							return printStatus(a.api)
+						},
+					},
+					{
+						Name:   "id",
+						Usage:  "Print your peer id",
+						Before: a.initApiConnection,
+						Action: func(c *cli.Context) error {
+							return printPeerId(a.api)
+						},
+					},
+					{
+						Name:  "rename",
+						Usage: "Rename your peer",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "name",
+								Usage:    "peer name",
+								Required: true,
+							},
+						},
+						Before: a.initApiConnection,
+						Action: func(c *cli.Context) error {
+							return renameMe(a.api, c.String("name"))
+						},
+					},
+					{
+						Name:    "rotate_key",
+						Aliases: []string{"rotate-key"},
+						Usage:   "Generates new identity key, friends follow it after restart keeping their settings",
+						Flags: []cli.Flag{
+							&cli.DurationFlag{
+								Name:  "grace",
+								Usage: "grace period after which friends block the old peer id",
+								Value: 7 * 24 * time.Hour,
+							},
+						},
+						Before: a.initApiConnection,
+						Action: func(c *cli.Context) error {
+							return rotateKey(a.api, c.Duration("grace"))
+						},
+					},
+					{
+						Name:  "share",
+						Usage: "Choose which details are shared with known peers, prints current settings if no flags are set",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{Name: "hostname", Usage: "share hostname"},
+							&cli.BoolFlag{Name: "os", Usage: "share OS and architecture"},
+							&cli.BoolFlag{Name: "version", Usage: "share awl version"},
+							&cli.BoolFlag{Name: "uptime", Usage: "share awl uptime"},
+							&cli.BoolFlag{Name: "health", Usage: "share whether TUN interface and DNS are working"},
+							&cli.StringFlag{Name: "note", Usage: "note shown to peers, e.g. \"on vacation\". Empty value removes it"},
+						},
+						Before: a.initApiConnection,
+						Action: func(c *cli.Context) error {
+							return updateStatusSharing(a.api, func(sharing *config.StatusSharingConfig) bool {
+								changed := false
+								for name, value := range map[string]*bool{
+									"hostname": &sharing.Hostname,
+									"os":       &sharing.OS,
+									"version":  &sharing.Version,
+									"uptime":   &sharing.Uptime,
+									"health":   &sharing.Health,
+								} {
+									if c.IsSet(name) {
+										*value = c.Bool(name)
+										changed = true
+									}
+								}
+								if c.IsSet("note") {
+									sharing.Note = c.String("note")
+									changed = true
+								}
+								return changed
+							})
+						},
+					},
+					{
+						Name:   "remote_audit",
+						Usage:  "Prints requests made by peers to manage this device remotely",
+						Before: a.initApiConnection,
+						Action: func(c *cli.Context) error {
+							return printRemoteAdminAudit(a.api)
+						},
+					},
+					{
+						Name:   "list_proxies",
+						Usage:  "Prints list of available SOCKS5 proxies",
+						Before: a.initApiConnection,
+						Action: func(c *cli.Context) error {
+							return listProxies(a.api)
+						},
+					},
+					{
+						Name:  "set_proxy",
+						Usage: "Sets SOCKS5 proxy for your peer, empty pid/name means disable proxy",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "pid",
+								Usage:    "peer id",
+								Required: false,
+							},
+							&cli.StringFlag{
+								Name:     "name",
+								Usage:    "peer name",
+								Required: false,
+							},
+						},
+						Before: func(c *cli.Context) error {
+							return a.initApiAndPeerId(c, false)
+						},
+						Action: func(c *cli.Context) error {
+							return setProxy(a.api, c.String("pid"))
+						},
+					},
+				},
+			},
+			{
+				Name:  "peers",
+				Usage: "Group of commands to work with peers. Use to check friend requests and work with known peers",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "status",
+						Usage: "Print peers status",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "format",
+								Aliases:  []string{"f"},
+								Required: false,
+								Value:    "npslucevd",
+								Usage: "control table columns list and order.Each char add column, write column chars together without gap. Use these chars to add specific columns:\n   " +
+									"n - peers number\n   p - peers name, domain and ip address\n   i - peers id\n   s - peers status\n   l - peers last seen datetime\n   v - peers awl version" +
+									"\n   u - network usage by peer (in/out)\n   c - list of peers connections (IP address + protocol)\n   e - exit node status" +
+									"\n   d - details shared by peer (hostname, OS, uptime, health, note)\n  ",
+							},
+							&cli.StringFlag{
+								Name:  "group",
+								Usage: "print only peers from group",
+							},
+						},
+						Before: a.initApiConnection,
+						Action: func(c *cli.Context) error {
+							return printPeersStatus(a.api, c.String("format"), c.String("group"))
+						},
+					},
+					{
+						Name:   "requests",
+						Usage:  "Print all incoming friend requests",
+						Before: a.initApiConnection,
+						Action: func(c *cli.Context) error {
+							return printFriendRequests(a.api)
+						},
+					},
+					{
+						Name:  "dismiss",
+						Usage: "Dismiss incoming friend request without blocking peer",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "pid",
+								Usage:    "peer id",
+								Required: true,
+							},
+						},
+						Before: a.initApiConnection,
+						Action: func(c *cli.Context) error {
+							return dismissFriendRequest(a.api, c.String("pid"))
+						},
+					},
+					{
+						Name:  "history",
+						Usage: "Print peer connection history and uptime",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "pid",
+								Usage:    "peer id",
+								Required: false,
+							},
+							&cli.StringFlag{
+								Name:     "name",
+								Usage:    "peer name",
+								Required: false,
+							},
+						},
+						Before: a.initApiAndPeerIdRequired,
+						Action: func(c *cli.Context) error {
+							return printPeerHistory(a.api, c.String("pid"))
+						},
+					},
+					{
+						Name:  "add",
+						Usage: "Invite peer or accept existing invitation from this peer",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "pid",
+								Usage:    "peer id",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:     "name",
+								Usage:    "peer name",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:     "message",
+								Usage:    "message shown to peer along with friend request",
+								Required: false,
+							},
+						},
+						Before: a.initApiConnection,
+						Action: func(c *cli.Context) error {
+							return addPeer(a.api, c.String("pid"), c.String("name"), c.String("message"))
+						},
+					},
+					{
+						Name:  "join",
+						Usage: "Add peer using its invite link, peer accepts you automatically",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "invite",
+								Usage:    "invite link or token",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:     "name",
+								Usage:    "peer name, name from invite is used by default",
+								Required: false,
+							},
+						},
+						Before: a.initApiConnection,
+						Action: func(c *cli.Context) error {
+							return joinByInvite(a.api, c.String("invite"), c.String("name"))
+						},
+					},
+					{
+						Name:  "remove",
+						Usage: "Remove peer from the friends list",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "pid",
+								Usage:    "peer id",
+								Required: false,
+							},
+							&cli.StringFlag{
+								Name:     "name",
+								Usage:    "peer name",
+								Required: false,
+							},
+						},
+						Before: a.initApiAndPeerIdRequired,
+						Action: func(c *cli.Context) error {
+							return removePeer(a.api, c.String("pid"))
+						},
+					},
+					{
+						Name:  "rename",
+						Usage: "Change known peer name",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "pid",
+								Usage:    "peer id",
+								Required: false,
+							},
+							&cli.StringFlag{
+								Name:     "name",
+								Usage:    "peer name",
+								Required: false,
+							},
+							&cli.StringFlag{
+								Name:     "new_name",
+								Usage:    "peer new name",
+								Required: true,
+							},
+						},
+						Before: a.initApiAndPeerIdRequired,
+						Action: func(c *cli.Context) error {
+							return changePeerAlias(a.api, c.String("pid"), c.String("new_name"))
+						},
+					},
+					{
+						Name:  "update_domain",
+						Usage: "Change known peer domain name",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "pid",
+								Usage:    "peer id",
+								Required: false,
+							},
+							&cli.StringFlag{
+								Name:     "name",
+								Usage:    "peer name",
+								Required: false,
+							},
+							&cli.StringFlag{
+								Name:     "domain",
+								Usage:    "peer domain name",
+								Required: true,
+							},
+						},
+						Before: a.initApiAndPeerIdRequired,
+						Action: func(c *cli.Context) error {
+							return changePeerDomain(a.api, c.String("pid"), c.String("domain"))
+						},
+					},
+					{
+						Name:  "allow_exit_node",
+						Usage: "Allow known peer to use this device as exit node (as socks5 proxy)",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "pid",
+								Usage:    "peer id",
+								Required: false,
+							},
+							&cli.StringFlag{
+								Name:     "name",
+								Usage:    "peer name",
+								Required: false,
+							},
+							&cli.BoolFlag{
+								Name:     "allow",
+								Usage:    "allow",
+								Required: false,
+							},
+							peersGroupFlag(),
+						},
+						Before: a.initApiAndPeerIdOrGroupRequired,
+						Action: a.peerOrGroupAction(func(c *cli.Context, peerID string) error {
+							return setAllowUsingAsExitNode(a.api, peerID, c.Bool("allow"))
+						}),
+					},
+					{
+						Name:  "exit_rules",
+						Usage: "Replace destinations which peer can reach using this device as exit node, private networks are denied unless allowed explicitly",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "pid",
+								Usage:    "peer id",
+								Required: false,
+							},
+							&cli.StringFlag{
+								Name:     "name",
+								Usage:    "peer name",
+								Required: false,
+							},
+							&cli.StringSliceFlag{
+								Name:  "allow_cidr",
+								Usage: "allowed network or ip address, e.g. 192.168.1.10",
+							},
+							&cli.StringSliceFlag{
+								Name:  "deny_cidr",
+								Usage: "denied network or ip address",
+							},
+							&cli.StringSliceFlag{
+								Name:  "allow_domain",
+								Usage: "allowed domain, e.g. example.com or *.example.com",
+							},
+							&cli.StringSliceFlag{
+								Name:  "deny_domain",
+								Usage: "denied domain",
+							},
+							&cli.StringSliceFlag{
+								Name:  "allow_port",
+								Usage: "allowed port or range, e.g. 443 or 8000-9000",
+							},
+							&cli.StringSliceFlag{
+								Name:  "deny_port",
+								Usage: "denied port or range",
+							},
+							peersGroupFlag(),
+						},
+						Before: a.initApiAndPeerIdOrGroupRequired,
+						Action: a.peerOrGroupAction(func(c *cli.Context, peerID string) error {
+							return setExitNodeRules(a.api, peerID, config.ExitNodeRules{
+								AllowedCIDRs:   c.StringSlice("allow_cidr"),
+								DeniedCIDRs:    c.StringSlice("deny_cidr"),
+								AllowedDomains: c.StringSlice("allow_domain"),
+								DeniedDomains:  c.StringSlice("deny_domain"),
+								AllowedPorts:   c.StringSlice("allow_port"),
+								DeniedPorts:    c.StringSlice("deny_port"),
+							})
+						}),
+					},
+					{
+						Name:  "trust_introductions",
+						Usage: "Accept peers introduced by known peer automatically",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "pid",
+								Usage:    "peer id",
+								Required: false,
+							},
+							&cli.StringFlag{
+								Name:     "name",
+								Usage:    "peer name",
+								Required: false,
+							},
+							&cli.BoolFlag{
+								Name:     "trust",
+								Usage:    "trust",
+								Required: false,
+							},
+							peersGroupFlag(),
+						},
+						Before: a.initApiAndPeerIdOrGroupRequired,
+						Action: a.peerOrGroupAction(func(c *cli.Context, peerID string) error {
+							return setTrustIntroductions(a.api, peerID, c.Bool("trust"))
+						}),
+					},
+					{
+						Name:  "remote_admin",
+						Usage: "Allow known peer to manage this device remotely with '" + binaryName + " " + CliCommandName + " --remote'",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "pid",
+								Usage:    "peer id",
+								Required: false,
+							},
+							&cli.StringFlag{
+								Name:     "name",
+								Usage:    "peer name",
+								Required: false,
+							},
+							&cli.BoolFlag{
+								Name:     "allow",
+								Usage:    "allow",
+								Required: false,
+							},
+							peersGroupFlag(),
+						},
+						Before: a.initApiAndPeerIdOrGroupRequired,
+						Action: a.peerOrGroupAction(func(c *cli.Context, peerID string) error {
+							return setRemoteAdmin(a.api, peerID, c.Bool("allow"))
+						}),
+					},
+					{
+						Name:  "dns_records",
+						Usage: "Prints user defined TXT and SRV records of peer in awl DNS",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "pid",
+								Usage:    "peer id",
+								Required: false,
+							},
+							&cli.StringFlag{
+								Name:     "name",
+								Usage:    "peer name",
+								Required: false,
+							},
+						},
+						Before: a.initApiAndPeerIdRequired,
+						Action: func(c *cli.Context) error {
+							return printDNSRecords(a.api, c.String("pid"))
+						},
+					},
+					{
+						Name:  "add_dns_record",
+						Usage: "Adds TXT or SRV record under domain of peer in awl DNS",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "pid",
+								Usage:    "peer id",
+								Required: false,
+							},
+							&cli.StringFlag{
+								Name:     "name",
+								Usage:    "peer name",
+								Required: false,
+							},
+							&cli.StringFlag{
+								Name:     "type",
+								Usage:    "TXT or SRV",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:  "record",
+								Usage: "record name prepended to domain of peer, e.g. _http._tcp for SRV. Empty means domain of peer",
+							},
+							&cli.StringFlag{
+								Name:  "text",
+								Usage: "text of TXT record",
+							},
+							&cli.UintFlag{
+								Name:  "port",
+								Usage: "port of SRV record",
+							},
+							&cli.UintFlag{
+								Name:  "priority",
+								Usage: "priority of SRV record",
+							},
+							&cli.UintFlag{
+								Name:  "weight",
+								Usage: "weight of SRV record",
+							},
+							&cli.StringFlag{
+								Name:  "target",
+								Usage: "target of SRV record: awl domain without suffix or fully qualified name with trailing dot. Empty means domain of peer",
+							},
+						},
+						Before: a.initApiAndPeerIdRequired,
+						Action: func(c *cli.Context) error {
+							return addDNSRecord(a.api, c.String("pid"), config.DNSRecord{
+								Type:     c.String("type"),
+								Name:     c.String("record"),
+								Text:     c.String("text"),
+								Priority: uint16(c.Uint("priority")),
+								Weight:   uint16(c.Uint("weight")),
+								Port:     uint16(c.Uint("port")),
+								Target:   c.String("target"),
+							})
+						},
+					},
+					{
+						Name:  "remove_dns_record",
+						Usage: "Removes TXT or SRV records with given name from domain of peer in awl DNS",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "pid",
+								Usage:    "peer id",
+								Required: false,
+							},
+							&cli.StringFlag{
+								Name:     "name",
+								Usage:    "peer name",
+								Required: false,
+							},
+							&cli.StringFlag{
+								Name:     "type",
+								Usage:    "TXT or SRV",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:  "record",
+								Usage: "record name, empty means domain of peer",
+							},
+						},
+						Before: a.initApiAndPeerIdRequired,
+						Action: func(c *cli.Context) error {
+							return removeDNSRecords(a.api, c.String("pid"), c.String("type"), c.String("record"))
+						},
+					},
+					{
+						Name:  "wake",
+						Usage: "Asks peer to send Wake-on-LAN magic packet to its LAN, peer should allow it with 'peers allow_wake'",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "via",
+								Usage:    "id or name of peer in the same LAN as device",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:  "mac",
+								Usage: "MAC address of device, e.g. aa:bb:cc:dd:ee:ff",
+							},
+							&cli.StringFlag{
+								Name:  "target",
+								Usage: "name of target saved with 'peers wake_targets'",
+							},
+						},
+						Before: a.initApiConnection,
+						Action: func(c *cli.Context) error {
+							return wakeOnLAN(a.api, c.String("via"), c.String("mac"), c.String("target"))
+						},
+					},
+					{
+						Name:  "allow_wake",
+						Usage: "Allow known peer to wake devices in LAN of this device",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "pid",
+								Usage:    "peer id",
+								Required: false,
+							},
+							&cli.StringFlag{
+								Name:     "name",
+								Usage:    "peer name",
+								Required: false,
+							},
+							&cli.BoolFlag{
+								Name:     "allow",
+								Usage:    "allow",
+								Required: false,
+							},
+							peersGroupFlag(),
+						},
+						Before: a.initApiAndPeerIdOrGroupRequired,
+						Action: a.peerOrGroupAction(func(c *cli.Context, peerID string) error {
+							return setWakeOnLANPermission(a.api, peerID, c.Bool("allow"))
+						}),
+					},
+					{
+						Name:  "allow_dns",
+						Usage: "Allow known peer to resolve names with DNS upstreams of this device, e.g. for private zones of your LAN",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "pid",
+								Usage:    "peer id",
+								Required: false,
+							},
+							&cli.StringFlag{
+								Name:     "name",
+								Usage:    "peer name",
+								Required: false,
+							},
+							&cli.BoolFlag{
+								Name:     "allow",
+								Usage:    "allow",
+								Required: false,
+							},
+							peersGroupFlag(),
+						},
+						Before: a.initApiAndPeerIdOrGroupRequired,
+						Action: a.peerOrGroupAction(func(c *cli.Context, peerID string) error {
+							return setDNSQueriesPermission(a.api, peerID, c.Bool("allow"))
+						}),
+					},
+					{
+						Name:  "wake_targets",
+						Usage: "Prints and updates saved Wake-on-LAN targets in LAN of peer",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "pid",
+								Usage:    "peer id",
+								Required: false,
+							},
+							&cli.StringFlag{
+								Name:     "name",
+								Usage:    "peer name",
+								Required: false,
+							},
+							&cli.StringSliceFlag{
+								Name:  "add",
+								Usage: "add or update target in format name=mac, could be specified multiple times",
+							},
+							&cli.StringSliceFlag{
+								Name:  "remove",
+								Usage: "remove target by name, could be specified multiple times",
+							},
+						},
+						Before: a.initApiAndPeerIdRequired,
+						Action: func(c *cli.Context) error {
+							return updateWakeOnLANTargets(a.api, c.String("pid"), c.StringSlice("add"), c.StringSlice("remove"))
+						},
+					},
+					{
+						Name:  "file_policy",
+						Usage: "Set what to do with files sent by peer: ask, auto (accept into download dir) or deny",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "pid",
+								Usage:    "peer id",
+								Required: false,
+							},
+							&cli.StringFlag{
+								Name:     "name",
+								Usage:    "peer name",
+								Required: false,
+							},
+							&cli.StringFlag{
+								Name:     "policy",
+								Usage:    "ask, auto or deny",
+								Required: true,
+							},
+							peersGroupFlag(),
+						},
+						Before: a.initApiAndPeerIdOrGroupRequired,
+						Action: a.peerOrGroupAction(func(c *cli.Context, peerID string) error {
+							return setFileReceivePolicy(a.api, peerID, c.String("policy"))
+						}),
+					},
+					{
+						Name:  "introduce",
+						Usage: "Introduce known peer to another known peer, it sees introduced peer in its friend requests",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "pid",
+								Usage:    "id of peer which receives introduction",
+								Required: false,
+							},
+							&cli.StringFlag{
+								Name:     "name",
+								Usage:    "name of peer which receives introduction",
+								Required: false,
+							},
+							&cli.StringFlag{
+								Name:     "introduce_pid",
+								Usage:    "id of introduced peer",
+								Required: false,
+							},
+							&cli.StringFlag{
+								Name:     "introduce_name",
+								Usage:    "name of introduced peer",
+								Required: false,
+							},
+						},
+						Before: a.initApiAndPeerIdRequired,
+						Action: func(c *cli.Context) error {
+							return introducePeer(a.api, c.String("pid"), c.String("introduce_pid"), c.String("introduce_name"))
+						},
+					},
+				},
+			},
+			{
+				Name:  "dns",
+				Usage: "Group of commands to forward DNS queries for private zones to peers or resolvers in awl network",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "forwarding",
+						Usage:  "Prints DNS forwarding rules",
+						Before: a.initApiConnection,
+						Action: func(c *cli.Context) error {
+							return printDNSForwardingRules(a.api)
+						},
+					},
+					{
+						Name:  "forward",
+						Usage: "Forwards queries for zone and its subdomains to peer or upstream resolver, replaces existing rule for zone",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "zone",
+								Usage:    "zone like corp.internal",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:  "peer",
+								Usage: "id or name of peer which resolves queries, it should allow it with 'peers allow_dns'",
+							},
+							&cli.StringFlag{
+								Name:  "upstream",
+								Usage: "address of resolver, e.g. 10.66.0.3, tls://10.66.0.3 or https://dns.example.com/dns-query",
+							},
+						},
+						Before: a.initApiConnection,
+						Action: func(c *cli.Context) error {
+							return setDNSForwardingRule(a.api, c.String("zone"), c.String("peer"), c.String("upstream"))
+						},
+					},
+					{
+						Name:  "remove_forwarding",
+						Usage: "Removes DNS forwarding rule for zone",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "zone",
+								Usage:    "zone like corp.internal",
+								Required: true,
+							},
+						},
+						Before: a.initApiConnection,
+						Action: func(c *cli.Context) error {
+							return removeDNSForwardingRule(a.api, c.String("zone"))
+						},
+					},
+				},
+			},
+			{
+				Name:  "groups",
+				Usage: "Group of commands to manage peer groups. Group policy applies to all peers in group",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "list",
+						Usage:  "Prints groups with their policy and peers",
+						Before: a.initApiConnection,
+						Action: func(c *cli.Context) error {
+							return printGroups(a.api)
 						},
 					},
 					{
-						Name:   "id",
-						Usage:  "Print your peer id",
+						Name:  "set",
+						Usage: "Creates group or updates its policy",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "group",
+								Usage:    "group name, e.g. ops",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:  "description",
+								Usage: "group description",
+							},
+							&cli.BoolFlag{
+								Name:  "allow_exit_node",
+								Usage: "allow peers from group to use this device as exit node",
+							},
+							&cli.BoolFlag{
+								Name:  "hide_dns",
+								Usage: "exclude peers from group from awl DNS",
+							},
+							&cli.BoolFlag{
+								Name:  "trust_introductions",
+								Usage: "accept peers introduced by peers from group automatically",
+							},
+							&cli.BoolFlag{
+								Name:  "block_inbound",
+								Usage: "drop traffic from peers from group and reject their requests to this device services",
+							},
+						},
 						Before: a.initApiConnection,
 						Action: func(c *cli.Context) error {
-							return printPeerId(a.api)
+							// flags which are not set keep their current values
+							request := entity.UpsertGroupRequest{Name: c.String("group")}
+							for name, value := range map[string]**bool{
+								"allow_exit_node":     &request.AllowUsingAsExitNode,
+								"hide_dns":            &request.HideFromDNS,
+								"trust_introductions": &request.TrustIntroductions,
+								"block_inbound":       &request.BlockInboundAccess,
+							} {
+								if c.IsSet(name) {
+									enabled := c.Bool(name)
+									*value = &enabled
+								}
+							}
+							if c.IsSet("description") {
+								description := c.String("description")
+								request.Description = &description
+							}
+							return upsertGroup(a.api, request)
 						},
 					},
 					{
-						Name:  "rename",
-						Usage: "Rename your peer",
+						Name:  "remove",
+						Usage: "Removes group, peers are kept",
 						Flags: []cli.Flag{
 							&cli.StringFlag{
-								Name:     "name",
-								Usage:    "peer name",
+								Name:     "group",
+								Usage:    "group name",
 								Required: true,
 							},
 						},
 						Before: a.initApiConnection,
 						Action: func(c *cli.Context) error {
-							return renameMe(a.api, c.String("name"))
+							return removeGroup(a.api, c.String("group"))
 						},
 					},
 					{
-						Name:   "list_proxies",
-						Usage:  "Prints list of available SOCKS5 proxies",
+						Name:  "add_peers",
+						Usage: "Adds peers to group",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "group",
+								Usage:    "group name",
+								Required: true,
+							},
+							&cli.StringSliceFlag{
+								Name:     "peer",
+								Usage:    "peer id or name, could be specified multiple times",
+								Required: true,
+							},
+						},
 						Before: a.initApiConnection,
 						Action: func(c *cli.Context) error {
-							return listProxies(a.api)
+							return updateGroupPeers(a.api, c.String("group"), c.StringSlice("peer"), false)
 						},
 					},
 					{
-						Name:  "set_proxy",
-						Usage: "Sets SOCKS5 proxy for your peer, empty pid/name means disable proxy",
+						Name:  "remove_peers",
+						Usage: "Removes peers from group",
 						Flags: []cli.Flag{
 							&cli.StringFlag{
-								Name:     "pid",
-								Usage:    "peer id",
-								Required: false,
+								Name:     "group",
+								Usage:    "group name",
+								Required: true,
 							},
-							&cli.StringFlag{
-								Name:     "name",
-								Usage:    "peer name",
-								Required: false,
+							&cli.StringSliceFlag{
+								Name:     "peer",
+								Usage:    "peer id or name, could be specified multiple times",
+								Required: true,
 							},
 						},
-						Before: func(c *cli.Context) error {
-							return a.initApiAndPeerId(c, false)
-						},
+						Before: a.initApiConnection,
 						Action: func(c *cli.Context) error {
-							return setProxy(a.api, c.String("pid"))
+							return updateGroupPeers(a.api, c.String("group"), c.StringSlice("peer"), true)
 						},
 					},
 				},
 			},
 			{
-				Name:  "peers",
-				Usage: "Group of commands to work with peers. Use to check friend requests and work with known peers",
+				Name:  "invite",
+				Usage: "Group of commands to manage invites. Peers which join with invite link are accepted automatically",
 				Subcommands: []*cli.Command{
 					{
-						Name:  "status",
-						Usage: "Print peers status",
+						Name:  "create",
+						Usage: "Creates invite and prints its link and QR code",
 						Flags: []cli.Flag{
-							&cli.StringFlag{
-								Name:     "format",
-								Aliases:  []string{"f"},
-								Required: false,
-								Value:    "npslucev",
-								Usage: "control table columns list and order.Each char add column, write column chars together without gap. Use these chars to add specific columns:\n   " +
-									"n - peers number\n   p - peers name, domain and ip address\n   i - peers id\n   s - peers status\n   l - peers last seen datetime\n   v - peers awl version" +
-									"\n   u - network usage by peer (in/out)\n   c - list of peers connections (IP address + protocol)\n   e - exit node status\n  ",
+							&cli.DurationFlag{
+								Name:  "expires",
+								Usage: "invite lifetime",
+								Value: 24 * time.Hour,
+							},
+							&cli.IntFlag{
+								Name:  "uses",
+								Usage: "number of peers which can join with invite, invite is single use by default, 0 means unlimited",
+								Value: 1,
+							},
+							&cli.BoolFlag{
+								Name:  "allow_exit_node",
+								Usage: "allow invited peers to use this node as exit node",
+							},
+							&cli.StringSliceFlag{
+								Name:  "group",
+								Usage: "add invited peers to group, could be specified multiple times",
 							},
 						},
 						Before: a.initApiConnection,
 						Action: func(c *cli.Context) error {
-							return printPeersStatus(a.api, c.String("format"))
+							return createInvite(a.api, c.Duration("expires"), c.Int("uses"), c.Bool("allow_exit_node"), c.StringSlice("group"))
 						},
 					},
 					{
-						Name:   "requests",
-						Usage:  "Print all incoming friend requests",
+						Name:   "list",
+						Usage:  "Prints issued invites",
 						Before: a.initApiConnection,
 						Action: func(c *cli.Context) error {
-							return printFriendRequests(a.api)
+							return printInvites(a.api)
 						},
 					},
 					{
-						Name:  "add",
-						Usage: "Invite peer or accept existing invitation from this peer",
+						Name:  "revoke",
+						Usage: "Revokes invite, it can't be used anymore",
 						Flags: []cli.Flag{
 							&cli.StringFlag{
-								Name:     "pid",
-								Usage:    "peer id",
+								Name:     "id",
+								Usage:    "invite id",
 								Required: true,
 							},
+						},
+						Before: a.initApiConnection,
+						Action: func(c *cli.Context) error {
+							return revokeInvite(a.api, c.String("id"))
+						},
+					},
+				},
+			},
+			{
+				Name:  "network",
+				Usage: "Group of commands to manage network membership. Members with certificates signed by network admin are accepted automatically",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "info",
+						Usage:  "Prints network membership info",
+						Before: a.initApiConnection,
+						Action: func(c *cli.Context) error {
+							return printNetworkInfo(a.api)
+						},
+					},
+					{
+						Name:  "create",
+						Usage: "Creates network, this device becomes its admin",
+						Flags: []cli.Flag{
 							&cli.StringFlag{
 								Name:     "name",
-								Usage:    "peer name",
+								Usage:    "network name",
 								Required: true,
 							},
 						},
 						Before: a.initApiConnection,
 						Action: func(c *cli.Context) error {
-							return addPeer(a.api, c.String("pid"), c.String("name"))
+							return createNetwork(a.api, c.String("name"))
 						},
 					},
 					{
-						Name:  "remove",
-						Usage: "Remove peer from the friends list",
+						Name:  "sign",
+						Usage: "Signs membership certificate for peer, only network admin can sign",
 						Flags: []cli.Flag{
 							&cli.StringFlag{
 								Name:     "pid",
 								Usage:    "peer id",
-								Required: false,
+								Required: true,
 							},
 							&cli.StringFlag{
-								Name:     "name",
-								Usage:    "peer name",
-								Required: false,
+								Name:  "name",
+								Usage: "peer name",
+							},
+							&cli.StringSliceFlag{
+								Name:  "group",
+								Usage: "add peer to group, could be specified multiple times",
+							},
+							&cli.DurationFlag{
+								Name:  "expires",
+								Usage: "certificate lifetime",
+								Value: 365 * 24 * time.Hour,
 							},
 						},
-						Before: a.initApiAndPeerIdRequired,
+						Before: a.initApiConnection,
 						Action: func(c *cli.Context) error {
-							return removePeer(a.api, c.String("pid"))
+							return signCertificate(a.api, entity.SignCertificateRequest{
+								PeerID:       c.String("pid"),
+								Name:         c.String("name"),
+								Groups:       c.StringSlice("group"),
+								ExpiresInSec: int64(c.Duration("expires").Seconds()),
+							})
 						},
 					},
 					{
-						Name:  "rename",
-						Usage: "Change known peer name",
+						Name:  "join",
+						Usage: "Joins network with membership certificate",
 						Flags: []cli.Flag{
 							&cli.StringFlag{
-								Name:     "pid",
-								Usage:    "peer id",
-								Required: false,
-							},
-							&cli.StringFlag{
-								Name:     "name",
-								Usage:    "peer name",
-								Required: false,
-							},
-							&cli.StringFlag{
-								Name:     "new_name",
-								Usage:    "peer new name",
+								Name:     "cert",
+								Usage:    "membership certificate",
 								Required: true,
 							},
 						},
-						Before: a.initApiAndPeerIdRequired,
+						Before: a.initApiConnection,
 						Action: func(c *cli.Context) error {
-							return changePeerAlias(a.api, c.String("pid"), c.String("new_name"))
+							return joinNetwork(a.api, c.String("cert"))
 						},
 					},
 					{
-						Name:  "update_domain",
-						Usage: "Change known peer domain name",
+						Name:  "revoke",
+						Usage: "Revokes peer membership, other members remove it",
 						Flags: []cli.Flag{
 							&cli.StringFlag{
 								Name:     "pid",
 								Usage:    "peer id",
-								Required: false,
+								Required: true,
 							},
-							&cli.StringFlag{
-								Name:     "name",
-								Usage:    "peer name",
-								Required: false,
+						},
+						Before: a.initApiConnection,
+						Action: func(c *cli.Context) error {
+							return revokeMembership(a.api, c.String("pid"))
+						},
+					},
+					{
+						Name:  "admins",
+						Usage: "Adds or removes network admins, only network admin can update them",
+						Flags: []cli.Flag{
+							&cli.StringSliceFlag{
+								Name:  "add",
+								Usage: "peer id of new admin, could be specified multiple times",
 							},
-							&cli.StringFlag{
-								Name:     "domain",
-								Usage:    "peer domain name",
-								Required: true,
+							&cli.StringSliceFlag{
+								Name:  "remove",
+								Usage: "peer id of removed admin, could be specified multiple times",
 							},
 						},
-						Before: a.initApiAndPeerIdRequired,
+						Before: a.initApiConnection,
 						Action: func(c *cli.Context) error {
-							return changePeerDomain(a.api, c.String("pid"), c.String("domain"))
+							return updateNetworkAdmins(a.api, c.StringSlice("add"), c.StringSlice("remove"))
 						},
 					},
 					{
-						Name:  "allow_exit_node",
-						Usage: "Allow known peer to use this device as exit node (as socks5 proxy)",
+						Name:   "leave",
+						Usage:  "Leaves network, known members stay in friends list",
+						Before: a.initApiConnection,
+						Action: func(c *cli.Context) error {
+							return leaveNetwork(a.api)
+						},
+					},
+				},
+			},
+			{
+				Name:  "bootstrap",
+				Usage: "Group of commands to manage bootstrap peers",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "list",
+						Usage:  "Prints bootstrap peers with their connection status",
+						Before: a.initApiConnection,
+						Action: func(c *cli.Context) error {
+							return printBootstrapPeers(a.api)
+						},
+					},
+					{
+						Name:  "add",
+						Usage: "Adds custom bootstrap peer and connects to it",
 						Flags: []cli.Flag{
 							&cli.StringFlag{
-								Name:     "pid",
-								Usage:    "peer id",
-								Required: false,
+								Name:     "addr",
+								Usage:    "multiaddr with peer id, e.g. /ip4/1.2.3.4/tcp/4363/p2p/12D3KooW...",
+								Required: true,
 							},
+						},
+						Before: a.initApiConnection,
+						Action: func(c *cli.Context) error {
+							return addBootstrapPeer(a.api, c.String("addr"))
+						},
+					},
+					{
+						Name:  "remove",
+						Usage: "Removes custom bootstrap peer",
+						Flags: []cli.Flag{
 							&cli.StringFlag{
-								Name:     "name",
-								Usage:    "peer name",
-								Required: false,
+								Name:     "addr",
+								Usage:    "multiaddr or peer id to remove all its addresses",
+								Required: true,
 							},
+						},
+						Before: a.initApiConnection,
+						Action: func(c *cli.Context) error {
+							return removeBootstrapPeer(a.api, c.String("addr"))
+						},
+					},
+					{
+						Name:  "defaults",
+						Usage: "Enables or disables default bootstrap peers",
+						Flags: []cli.Flag{
 							&cli.BoolFlag{
-								Name:     "allow",
-								Usage:    "allow",
+								Name:     "disable",
+								Usage:    "disable default bootstrap peers",
 								Required: false,
 							},
 						},
-						Before: a.initApiAndPeerIdRequired,
+						Before: a.initApiConnection,
+						Action: func(c *cli.Context) error {
+							return setDefaultBootstrapPeersDisabled(a.api, c.Bool("disable"))
+						},
+					},
+				},
+			},
+			{
+				Name:      "send",
+				Usage:     "Sends file to peer and prints progress, interrupted transfer continues from where it stopped when sent again",
+				ArgsUsage: "<file>",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "pid",
+						Usage:    "peer id",
+						Required: false,
+					},
+					&cli.StringFlag{
+						Name:     "name",
+						Usage:    "peer name",
+						Required: false,
+					},
+				},
+				Before: a.initApiAndPeerIdRequired,
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 1 {
+						return fmt.Errorf("file should be defined")
+					}
+					return sendFile(c.Context, a.api, c.String("pid"), c.Args().First())
+				},
+			},
+			{
+				Name:  "receive",
+				Usage: "Waits for files offered by peers and asks whether to accept them, see 'peers file_policy' to accept files automatically",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "list",
+						Usage: "print recent file transfers and exit",
+					},
+				},
+				Before: a.initApiConnection,
+				Action: func(c *cli.Context) error {
+					if c.Bool("list") {
+						return printFileTransfers(a.api)
+					}
+					return receiveFiles(c.Context, a.api, a.yesNoPrompt)
+				},
+			},
+			{
+				Name:  "events",
+				Usage: "Prints events as they happen: peers connected/disconnected, friend requests, proxy and reachability changes",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{
+						Name:  "types",
+						Usage: "filter events by type: " + strings.Join(entity.EventTypes, ", "),
+					},
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "print events as json, one per line",
+					},
+				},
+				Before: a.initApiConnection,
+				Action: func(c *cli.Context) error {
+					return printEvents(c.Context, a.api, c.StringSlice("types"), c.Bool("json"))
+				},
+			},
+			{
+				Name:  "diag",
+				Usage: "Group of commands for connectivity diagnostics",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "nat",
+						Usage:  "Prints NAT type, port mapping and hole punching report",
+						Before: a.initApiConnection,
 						Action: func(c *cli.Context) error {
-							return setAllowUsingAsExitNode(a.api, c.String("pid"), c.Bool("allow"))
+							return printNATDiagnostics(a.api)
+						},
+					},
+					{
+						Name:   "hooks",
+						Usage:  "Prints recent deliveries of events to hooks",
+						Before: a.initApiConnection,
+						Action: func(c *cli.Context) error {
+							return printHookDeliveries(a.api)
+						},
+					},
+					{
+						Name:   "dns",
+						Usage:  "Prints awl DNS cache stats and health of upstream resolvers",
+						Before: a.initApiConnection,
+						Action: func(c *cli.Context) error {
+							return printDNSDiagnostics(a.api)
 						},
 					},
 				},
@@ -418,6 +1416,10 @@ func (a *Application) initApiConnection(c *cli.Context) (err error) {
 		_, err2 := a.api.PeerInfo()
 		if err2 != nil {
 			err = fmt.Errorf("could not access api on address %s: %v", addr, err2)
+			return
+		}
+		if remote := c.String("remote"); remote != "" {
+			err = a.useRemotePeer(remote)
 		}
 	}()
 	if apiAddr != "" {
@@ -438,10 +1440,56 @@ func (a *Application) initApiConnection(c *cli.Context) (err error) {
 	return nil
 }
 
+// useRemotePeer switches api client to peer identified by id or name.
+func (a *Application) useRemotePeer(remote string) error {
+	peerID := remote
+	if _, err := peer.Decode(remote); err != nil {
+		peerID, err = getPeerIdByAlias(a.api, remote)
+		if err != nil {
+			return err
+		}
+	}
+
+	a.api.SetRemotePeer(peerID)
+	_, err := a.api.PeerInfo()
+	if err != nil {
+		return fmt.Errorf("could not access api of remote peer %s: %v", peerID, err)
+	}
+	return nil
+}
+
 func (a *Application) initApiAndPeerIdRequired(c *cli.Context) error {
 	return a.initApiAndPeerId(c, true)
 }
 
+func (a *Application) initApiAndPeerIdOrGroupRequired(c *cli.Context) error {
+	if c.String("group") != "" {
+		return a.initApiConnection(c)
+	}
+	return a.initApiAndPeerId(c, true)
+}
+
+// peersGroupFlag is used by commands which update settings of peer, see peerOrGroupAction.
+func peersGroupFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:     "group",
+		Usage:    "apply to all peers from group instead of a single peer",
+		Required: false,
+	}
+}
+
+// peerOrGroupAction calls action for peer from --pid or --name flags or for each peer from --group flag.
+func (a *Application) peerOrGroupAction(action func(c *cli.Context, peerID string) error) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		if group := c.String("group"); group != "" {
+			return forEachPeerInGroup(a.api, group, func(peerID string) error {
+				return action(c, peerID)
+			})
+		}
+		return action(c, c.String("pid"))
+	}
+}
+
 func (a *Application) initApiAndPeerId(c *cli.Context, isRequired bool) error {
 	err := a.initApiConnection(c)
 	if err != nil {
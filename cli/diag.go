@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+
+	"github.com/anywherelan/awl/api/apiclient"
+)
+
+func printNATDiagnostics(api *apiclient.Client) error {
+	diag, err := api.NATDiagnostics()
+	if err != nil {
+		return err
+	}
+
+	transports := make([]string, 0, len(diag.ObservedAddrs))
+	for transport := range diag.ObservedAddrs {
+		transports = append(transports, transport)
+	}
+	sort.Strings(transports)
+	observed := make([]string, 0, len(transports))
+	for _, transport := range transports {
+		observed = append(observed, fmt.Sprintf("%s: %s", transport, strings.Join(diag.ObservedAddrs[transport], ", ")))
+	}
+	if len(observed) == 0 {
+		observed = append(observed, "-")
+	}
+
+	portMappings := make([]string, 0, len(diag.PortMappings))
+	for _, mapping := range diag.PortMappings {
+		portMappings = append(portMappings, fmt.Sprintf("%s -> %s", mapping.Internal, mapping.External))
+	}
+	portMappingStatus := "gateway not found"
+	if diag.PortMappingDiscovered {
+		portMappingStatus = "no mappings"
+		if len(portMappings) > 0 {
+			portMappingStatus = strings.Join(portMappings, "\n")
+		}
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.AppendBulk([][]string{
+		{"Reachability", strings.ToLower(diag.Reachability)},
+		{"Observed addresses", strings.Join(observed, "\n")},
+		{"TCP mapping behavior", diag.MappingBehavior["tcp"]},
+		{"UDP mapping behavior", diag.MappingBehavior["udp"]},
+		{"UPnP/NAT-PMP", portMappingStatus},
+	})
+	table.Render()
+
+	if len(diag.HolePunches) > 0 {
+		fmt.Println("Hole punching:")
+		table = tablewriter.NewWriter(os.Stdout)
+		table.SetBorders(tablewriter.Border{Left: false, Top: false, Right: false, Bottom: false})
+		table.SetHeader([]string{"peer", "result", "attempts", "elapsed", "time"})
+		for _, result := range diag.HolePunches {
+			name := result.DisplayName
+			if name == "" {
+				name = result.PeerID
+			}
+			status := "in progress"
+			switch {
+			case result.DirectDial:
+				status = "direct connection"
+			case result.Success:
+				status = "success"
+			case result.Error != "":
+				status = "failed: " + result.Error
+			}
+			table.Append([]string{
+				name,
+				status,
+				fmt.Sprint(result.Attempts),
+				result.Elapsed.Round(time.Millisecond).String(),
+				result.UpdatedAt.Format("2006-01-02 15:04:05"),
+			})
+		}
+		table.Render()
+	}
+
+	fmt.Printf("\nConclusion: %s\n", diag.Conclusion)
+
+	return nil
+}
+
+func printHookDeliveries(api *apiclient.Client) error {
+	deliveries, err := api.HookDeliveries()
+	if err != nil {
+		return err
+	}
+	if len(deliveries) == 0 {
+		fmt.Println("no hook deliveries yet")
+		return nil
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetBorders(tablewriter.Border{Left: false, Top: false, Right: false, Bottom: false})
+	table.SetHeader([]string{"time", "hook", "event", "attempts", "result"})
+	// print the newest deliveries first
+	for i := len(deliveries) - 1; i >= 0; i-- {
+		delivery := deliveries[i]
+		result := "delivered"
+		if !delivery.Success {
+			result = "failed: " + delivery.Error
+		}
+		table.Append([]string{
+			delivery.Time.Format("2006-01-02 15:04:05"),
+			delivery.Hook,
+			delivery.EventType,
+			fmt.Sprint(delivery.Attempts),
+			result,
+		})
+	}
+	table.Render()
+
+	return nil
+}
+
+func printDNSDiagnostics(api *apiclient.Client) error {
+	info, err := api.DNSDebugInfo()
+	if err != nil {
+		return err
+	}
+
+	address := info.AwlDNSAddress
+	if address == "" {
+		address = "not working"
+	}
+	cacheStatus := "disabled"
+	if info.CacheSize > 0 {
+		cacheStatus = fmt.Sprintf("%d of %d entries, %d hits, %d misses, %d evictions",
+			info.CacheEntries, info.CacheSize, info.CacheHits, info.CacheMisses, info.CacheEvictions)
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.AppendBulk([][]string{
+		{"Address", address},
+		{"Set as system DNS", formatWorkingStatus(info.IsAwlDNSSetAsSystem)},
+		{"Queries", fmt.Sprintf("%d local, %d reverse, %d upstream, %d upstream errors",
+			info.LocalQueries, info.ReverseQueries, info.UpstreamQueries, info.UpstreamErrors)},
+		{"Cache", cacheStatus},
+	})
+	table.Render()
+
+	fmt.Println("Upstreams:")
+	table = tablewriter.NewWriter(os.Stdout)
+	table.SetBorders(tablewriter.Border{Left: false, Top: false, Right: false, Bottom: false})
+	table.SetHeader([]string{"address", "protocol", "status", "queries", "errors", "last rtt", "last error"})
+	for _, upstream := range info.Upstreams {
+		status := "healthy"
+		if !upstream.Healthy {
+			status = fmt.Sprintf("unhealthy until %s", upstream.UnhealthyUntil.Format("15:04:05"))
+		}
+		table.Append([]string{
+			upstream.Address,
+			upstream.Protocol,
+			status,
+			fmt.Sprint(upstream.Queries),
+			fmt.Sprint(upstream.Errors),
+			upstream.LastRTT.Round(time.Millisecond).String(),
+			upstream.LastError,
+		})
+	}
+	table.Render()
+
+	if len(info.ForwardingRules) > 0 {
+		fmt.Println("Forwarding rules:")
+		table = tablewriter.NewWriter(os.Stdout)
+		table.SetBorders(tablewriter.Border{Left: false, Top: false, Right: false, Bottom: false})
+		table.SetHeader([]string{"zone", "forward to", "status", "queries", "errors", "last rtt", "last error"})
+		for _, rule := range info.ForwardingRules {
+			status := "healthy"
+			if !rule.Healthy {
+				status = "failing"
+			}
+			table.Append([]string{
+				rule.Zone,
+				rule.Address,
+				status,
+				fmt.Sprint(rule.Queries),
+				fmt.Sprint(rule.Errors),
+				rule.LastRTT.Round(time.Millisecond).String(),
+				rule.LastError,
+			})
+		}
+		table.Render()
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olekukonko/tablewriter"
+
+	"github.com/anywherelan/awl/api/apiclient"
+)
+
+func printDNSForwardingRules(api *apiclient.Client) error {
+	rules, err := api.DNSForwardingRules()
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		fmt.Println("no dns forwarding rules")
+		return nil
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetBorders(tablewriter.Border{Left: false, Top: false, Right: false, Bottom: false})
+	table.SetHeader([]string{"zone", "forward to"})
+	for _, rule := range rules {
+		target := rule.Upstream
+		if rule.PeerID != "" {
+			target = fmt.Sprintf("peer '%s' (%s)", rule.PeerName, rule.PeerID)
+		}
+		table.Append([]string{rule.Zone, target})
+	}
+	table.Render()
+
+	return nil
+}
+
+// setDNSForwardingRule forwards zone to peer with id or name, or to upstream.
+func setDNSForwardingRule(api *apiclient.Client, zone, peerIDOrName, upstream string) error {
+	if (peerIDOrName == "") == (upstream == "") {
+		return fmt.Errorf("either peer or upstream should be defined")
+	}
+	peerID := peerIDOrName
+	if peerIDOrName != "" {
+		if _, err := peer.Decode(peerIDOrName); err != nil {
+			peerID, err = getPeerIdByAlias(api, peerIDOrName)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err := api.SetDNSForwardingRule(zone, peerID, upstream)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("dns forwarding rule updated successfully")
+	return nil
+}
+
+func removeDNSForwardingRule(api *apiclient.Client, zone string) error {
+	err := api.RemoveDNSForwardingRule(zone)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("dns forwarding rule removed successfully")
+	return nil
+}
+
+func setDNSQueriesPermission(api *apiclient.Client, peerID string, allow bool) error {
+	err := api.UpdateDNSQueriesPermission(peerID, allow)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("dns queries permission updated successfully")
+	return nil
+}
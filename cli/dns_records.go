@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+
+	"github.com/anywherelan/awl/api/apiclient"
+	"github.com/anywherelan/awl/awldns"
+	"github.com/anywherelan/awl/config"
+)
+
+func printDNSRecords(api *apiclient.Client, peerID string) error {
+	pcfg, err := api.KnownPeerConfig(peerID)
+	if err != nil {
+		return err
+	}
+	if len(pcfg.DNSRecords) == 0 {
+		fmt.Println("no dns records")
+		return nil
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetBorders(tablewriter.Border{Left: false, Top: false, Right: false, Bottom: false})
+	table.SetHeader([]string{"name", "type", "value"})
+	for _, record := range pcfg.DNSRecords {
+		name := pcfg.DomainName + "." + awldns.LocalDomain
+		if record.Name != "" {
+			name = record.Name + "." + name
+		}
+		value := fmt.Sprintf("%q", record.Text)
+		if record.Type == config.DNSRecordSRV {
+			target := record.Target
+			if target == "" {
+				target = pcfg.DomainName
+			}
+			if !strings.HasSuffix(target, ".") {
+				target += "." + awldns.LocalDomain
+			}
+			value = fmt.Sprintf("priority %d, weight %d, port %d, target %s", record.Priority, record.Weight, record.Port, target)
+		}
+		table.Append([]string{name, record.Type, value})
+	}
+	table.Render()
+
+	return nil
+}
+
+func addDNSRecord(api *apiclient.Client, peerID string, record config.DNSRecord) error {
+	record.Type = strings.ToUpper(record.Type)
+	err := record.Validate()
+	if err != nil {
+		return err
+	}
+	pcfg, err := api.KnownPeerConfig(peerID)
+	if err != nil {
+		return err
+	}
+
+	err = api.UpdateDNSRecords(peerID, append(pcfg.DNSRecords, record))
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("dns record added successfully")
+	return nil
+}
+
+func removeDNSRecords(api *apiclient.Client, peerID, recordType, name string) error {
+	pcfg, err := api.KnownPeerConfig(peerID)
+	if err != nil {
+		return err
+	}
+
+	records := slices.DeleteFunc(pcfg.DNSRecords, func(record config.DNSRecord) bool {
+		return strings.EqualFold(record.Type, recordType) && record.Name == name
+	})
+	removed := len(pcfg.DNSRecords) - len(records)
+	if removed == 0 {
+		return fmt.Errorf("no %s records with name %q", strings.ToUpper(recordType), name)
+	}
+	err = api.UpdateDNSRecords(peerID, records)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d dns records removed successfully\n", removed)
+	return nil
+}
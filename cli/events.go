@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/anywherelan/awl/api/apiclient"
+	"github.com/anywherelan/awl/entity"
+)
+
+func printEvents(ctx context.Context, api *apiclient.Client, types []string, asJSON bool) error {
+	return api.SubscribeEvents(ctx, types, func(event entity.Event) {
+		if asJSON {
+			data, _ := json.Marshal(event)
+			fmt.Println(string(data))
+			return
+		}
+		fmt.Printf("%s %s%s\n", event.Time.Format("2006-01-02 15:04:05"), event.Type, formatEventDetails(event))
+	})
+}
+
+func formatEventDetails(event entity.Event) string {
+	details := make([]string, 0, 2)
+	if event.PeerName != "" {
+		details = append(details, fmt.Sprintf("'%s'", event.PeerName))
+	}
+	if event.PeerID != "" {
+		details = append(details, event.PeerID)
+	}
+	switch event.Type {
+	case entity.EventProxyChanged:
+		if event.PeerID == "" {
+			details = append(details, "proxy disabled")
+		}
+	case entity.EventReachabilityChanged:
+		details = append(details, strings.ToLower(event.Reachability))
+	case entity.EventUpdateAvailable:
+		details = append(details, event.Version)
+	case entity.EventFileTransfer:
+		if event.FileTransfer != nil {
+			details = append(details, fmt.Sprintf("%s '%s' %s", event.FileTransfer.Direction, event.FileTransfer.Name, event.FileTransfer.Status))
+		}
+	case entity.EventAuthRequest:
+		if event.AuthRequest != nil && event.AuthRequest.Message != "" {
+			details = append(details, fmt.Sprintf("message: %q", event.AuthRequest.Message))
+		}
+	}
+	if len(details) == 0 {
+		return ""
+	}
+	return " " + strings.Join(details, " ")
+}
@@ -0,0 +1,201 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+
+	"github.com/anywherelan/awl/api/apiclient"
+	"github.com/anywherelan/awl/config"
+	"github.com/anywherelan/awl/entity"
+)
+
+const fileProgressInterval = 500 * time.Millisecond
+
+// sendFile sends file and prints progress until transfer finishes.
+func sendFile(ctx context.Context, api *apiclient.Client, peerID, path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	transfer, err := api.SendFile(peerID, path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("sending '%s' (%s) to '%s'\n", transfer.Name, formatFileSize(transfer.Size), transfer.PeerName)
+
+	ticker := time.NewTicker(fileProgressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println()
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		transfer, err = findFileTransfer(api, transfer.ID)
+		if err != nil {
+			fmt.Println()
+			return err
+		}
+		switch transfer.Status {
+		case entity.FileTransferPending:
+			fmt.Printf("\rwaiting for peer to accept")
+		case entity.FileTransferInProgress:
+			fmt.Printf("\r%s", formatFileProgress(*transfer))
+		case entity.FileTransferCompleted:
+			fmt.Printf("\r%s\nfile sent successfully\n", formatFileProgress(*transfer))
+			return nil
+		case entity.FileTransferDeclined:
+			fmt.Println()
+			return fmt.Errorf("peer declined file: %s", transfer.Error)
+		default:
+			fmt.Println()
+			return fmt.Errorf("send file: %s", transfer.Error)
+		}
+	}
+}
+
+// receiveFiles asks whether to accept files offered by peers until ctx is done.
+func receiveFiles(ctx context.Context, api *apiclient.Client, prompt func(message string, def bool) (bool, error)) error {
+	decide := func(transfer entity.FileTransfer) {
+		accept, err := prompt(fmt.Sprintf("'%s' (%s) offers file '%s' (%s), accept?",
+			transfer.PeerName, transfer.PeerID, transfer.Name, formatFileSize(transfer.Size)), false)
+		if err != nil {
+			fmt.Printf("prompt: %v\n", err)
+			return
+		}
+		if accept {
+			err = api.AcceptFile(transfer.ID)
+		} else {
+			err = api.DeclineFile(transfer.ID)
+		}
+		if err != nil {
+			fmt.Printf("answer offer: %v\n", err)
+		}
+	}
+
+	transfers, err := api.FileTransfers()
+	if err != nil {
+		return err
+	}
+	for _, transfer := range transfers {
+		if transfer.Direction == entity.FileTransferIncoming && transfer.Status == entity.FileTransferPending {
+			decide(transfer)
+		}
+	}
+
+	fmt.Println("waiting for files, press Ctrl+C to stop")
+	return api.SubscribeEvents(ctx, []string{entity.EventFileTransfer}, func(event entity.Event) {
+		if event.FileTransfer == nil || event.FileTransfer.Direction != entity.FileTransferIncoming {
+			return
+		}
+		if event.FileTransfer.Status == entity.FileTransferPending {
+			decide(*event.FileTransfer)
+			return
+		}
+		transfer, err := findFileTransfer(api, event.FileTransfer.ID)
+		if err != nil {
+			fmt.Printf("get file transfer: %v\n", err)
+			return
+		}
+		switch transfer.Status {
+		case entity.FileTransferCompleted:
+			fmt.Printf("received '%s' from '%s': %s\n", transfer.Name, transfer.PeerName, transfer.Path)
+		case entity.FileTransferFailed:
+			fmt.Printf("failed to receive '%s' from '%s': %s\n", transfer.Name, transfer.PeerName, transfer.Error)
+		}
+	})
+}
+
+func printFileTransfers(api *apiclient.Client) error {
+	transfers, err := api.FileTransfers()
+	if err != nil {
+		return err
+	}
+	if len(transfers) == 0 {
+		fmt.Println("no file transfers yet")
+		return nil
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetBorders(tablewriter.Border{Left: false, Top: false, Right: false, Bottom: false})
+	table.SetHeader([]string{"time", "id", "direction", "peer", "file", "size", "status"})
+	// print the newest transfers first
+	for i := len(transfers) - 1; i >= 0; i-- {
+		transfer := transfers[i]
+		status := transfer.Status
+		switch {
+		case transfer.Status == entity.FileTransferInProgress:
+			status = formatFileProgress(transfer)
+		case transfer.Error != "":
+			status += ": " + transfer.Error
+		case transfer.Path != "" && transfer.Direction == entity.FileTransferIncoming:
+			status += ": " + transfer.Path
+		}
+		table.Append([]string{
+			transfer.CreatedAt.Format("2006-01-02 15:04:05"),
+			transfer.ID,
+			transfer.Direction,
+			transfer.PeerName,
+			transfer.Name,
+			formatFileSize(transfer.Size),
+			status,
+		})
+	}
+	table.Render()
+
+	return nil
+}
+
+func setFileReceivePolicy(api *apiclient.Client, peerID string, policy string) error {
+	err := api.UpdateFileReceivePolicy(peerID, config.FileReceivePolicy(policy))
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("file receive policy updated successfully")
+	return nil
+}
+
+func findFileTransfer(api *apiclient.Client, id string) (*entity.FileTransfer, error) {
+	transfers, err := api.FileTransfers()
+	if err != nil {
+		return nil, err
+	}
+	for _, transfer := range transfers {
+		if transfer.ID == id {
+			return &transfer, nil
+		}
+	}
+	return nil, fmt.Errorf("file transfer %s not found", id)
+}
+
+func formatFileProgress(transfer entity.FileTransfer) string {
+	percent := 100
+	if transfer.Size > 0 {
+		percent = int(transfer.Transferred * 100 / transfer.Size)
+	}
+	progress := fmt.Sprintf("%d%% (%s of %s)", percent, formatFileSize(transfer.Transferred), formatFileSize(transfer.Size))
+	if transfer.ResumedFrom > 0 {
+		progress += fmt.Sprintf(", resumed from %s", formatFileSize(transfer.ResumedFrom))
+	}
+	return progress
+}
+
+func formatFileSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+
+	"github.com/anywherelan/awl/api/apiclient"
+	"github.com/anywherelan/awl/entity"
+)
+
+func printGroups(api *apiclient.Client) error {
+	groups, err := api.Groups()
+	if err != nil {
+		return err
+	}
+	if len(groups) == 0 {
+		fmt.Println("no groups")
+		return nil
+	}
+
+	peers, err := api.KnownPeers()
+	if err != nil {
+		return err
+	}
+	peerNames := make(map[string]string, len(peers))
+	for _, peer := range peers {
+		peerNames[peer.PeerID] = peer.DisplayName
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetBorders(tablewriter.Border{Left: false, Top: false, Right: false, Bottom: false})
+	table.SetRowLine(true)
+	table.SetHeader([]string{"group", "description", "allow exit node", "hide from dns", "trust introductions", "block inbound", "peers"})
+	for _, group := range groups {
+		names := make([]string, 0, len(group.PeerIDs))
+		for _, peerID := range group.PeerIDs {
+			names = append(names, peerNames[peerID])
+		}
+		table.Append([]string{
+			group.Name,
+			group.Description,
+			fmt.Sprint(group.AllowUsingAsExitNode),
+			fmt.Sprint(group.HideFromDNS),
+			fmt.Sprint(group.TrustIntroductions),
+			fmt.Sprint(group.BlockInboundAccess),
+			strings.Join(names, "\n"),
+		})
+	}
+	table.Render()
+
+	return nil
+}
+
+func upsertGroup(api *apiclient.Client, request entity.UpsertGroupRequest) error {
+	err := api.UpsertGroup(request)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("group saved successfully")
+
+	return nil
+}
+
+func removeGroup(api *apiclient.Client, name string) error {
+	err := api.RemoveGroup(name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("group removed successfully")
+
+	return nil
+}
+
+// updateGroupPeers adds or removes peers, they are identified by peer id or name.
+func updateGroupPeers(api *apiclient.Client, group string, peers []string, remove bool) error {
+	knownPeers, err := api.KnownPeers()
+	if err != nil {
+		return err
+	}
+	peerIDs := make([]string, 0, len(peers))
+	for _, val := range peers {
+		found := false
+		for _, knownPeer := range knownPeers {
+			if knownPeer.PeerID == val || knownPeer.Alias == val {
+				peerIDs = append(peerIDs, knownPeer.PeerID)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("can't find peer with id or name \"%s\"", val)
+		}
+	}
+
+	if remove {
+		err = api.UpdateGroupPeers(group, nil, peerIDs)
+	} else {
+		err = api.UpdateGroupPeers(group, peerIDs, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("group peers updated successfully")
+
+	return nil
+}
+
+// forEachPeerInGroup calls action for each peer from group, it stops on the first error.
+func forEachPeerInGroup(api *apiclient.Client, group string, action func(peerID string) error) error {
+	peers, err := api.KnownPeersInGroup(group)
+	if err != nil {
+		return err
+	}
+	if len(peers) == 0 {
+		return fmt.Errorf("group \"%s\" has no peers", group)
+	}
+
+	for _, peer := range peers {
+		err = action(peer.PeerID)
+		if err != nil {
+			return fmt.Errorf("peer %s: %v", peer.DisplayName, err)
+		}
+	}
+
+	return nil
+}
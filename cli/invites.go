@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/mdp/qrterminal/v3"
+	"github.com/olekukonko/tablewriter"
+
+	"github.com/anywherelan/awl/api/apiclient"
+)
+
+func createInvite(api *apiclient.Client, expiresIn time.Duration, maxUses int, allowUsingAsExitNode bool, groups []string) error {
+	invite, err := api.CreateInvite(expiresIn, maxUses, allowUsingAsExitNode, groups)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("invite %s expires at %s\n", invite.ID, invite.ExpiresAt.Format(time.DateTime))
+	fmt.Println("share this link, peers which join with it are accepted automatically:")
+	fmt.Println(invite.URL)
+
+	qrterminal.GenerateHalfBlock(invite.URL, qrterminal.L, os.Stdout)
+
+	return nil
+}
+
+func printInvites(api *apiclient.Client) error {
+	invites, err := api.Invites()
+	if err != nil {
+		return err
+	}
+	if len(invites) == 0 {
+		fmt.Println("no invites")
+		return nil
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetBorders(tablewriter.Border{Left: false, Top: false, Right: false, Bottom: false})
+	table.SetHeader([]string{"id", "created at", "expires at", "uses", "allow exit node"})
+	for _, invite := range invites {
+		expiresAt := invite.ExpiresAt.Format(time.DateTime)
+		if invite.Expired {
+			expiresAt += " (expired)"
+		}
+		maxUses := "unlimited"
+		if invite.MaxUses != 0 {
+			maxUses = strconv.Itoa(invite.MaxUses)
+		}
+		table.Append([]string{
+			invite.ID,
+			invite.CreatedAt.Format(time.DateTime),
+			expiresAt,
+			fmt.Sprintf("%d/%s", invite.Uses, maxUses),
+			fmt.Sprint(invite.AllowUsingAsExitNode),
+		})
+	}
+	table.Render()
+
+	return nil
+}
+
+func revokeInvite(api *apiclient.Client, id string) error {
+	err := api.RevokeInvite(id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("invite revoked successfully")
+
+	return nil
+}
+
+func joinByInvite(api *apiclient.Client, token, alias string) error {
+	err := api.JoinByInvite(token, alias)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("peer added, it will accept you automatically when it is online")
+
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/olekukonko/tablewriter"
 
 	"github.com/anywherelan/awl/api/apiclient"
+	"github.com/anywherelan/awl/config"
 )
 
 func printStatus(api *apiclient.Client) error {
@@ -67,6 +68,19 @@ func renameMe(api *apiclient.Client, newName string) error {
 	return nil
 }
 
+func rotateKey(api *apiclient.Client, gracePeriod time.Duration) error {
+	resp, err := api.RotateIdentityKey(gracePeriod)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("new peer id: %s\n", resp.NewPeerID)
+	fmt.Println("restart awl to start using it, friends will follow new id keeping their settings")
+	fmt.Printf("old peer id %s will be blocked by friends after %s\n", resp.OldPeerID, resp.BlockOldAfter.Format(time.DateTime))
+
+	return nil
+}
+
 func listProxies(api *apiclient.Client) error {
 	proxies, err := api.ListAvailableProxies()
 	if err != nil {
@@ -96,3 +110,54 @@ func setProxy(api *apiclient.Client, peerID string) error {
 
 	return nil
 }
+
+func updateStatusSharing(api *apiclient.Client, update func(sharing *config.StatusSharingConfig) bool) error {
+	sharing, err := api.StatusSharing()
+	if err != nil {
+		return err
+	}
+
+	if update(sharing) {
+		err = api.UpdateStatusSharing(*sharing)
+		if err != nil {
+			return err
+		}
+		fmt.Println("status sharing updated successfully")
+	}
+
+	fmt.Printf("hostname: %v\nos:       %v\nversion:  %v\nuptime:   %v\nhealth:   %v\nnote:     %q\n",
+		sharing.Hostname, sharing.OS, sharing.Version, sharing.Uptime, sharing.Health, sharing.Note)
+	return nil
+}
+
+func printRemoteAdminAudit(api *apiclient.Client) error {
+	entries, err := api.RemoteAdminAudit()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("no remote admin requests")
+		return nil
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetBorders(tablewriter.Border{Left: false, Top: false, Right: false, Bottom: false})
+	table.SetHeader([]string{"time", "peer", "request", "status"})
+	// print the newest entries first
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		status := fmt.Sprint(entry.Status)
+		if entry.Error != "" {
+			status += " " + entry.Error
+		}
+		table.Append([]string{
+			entry.Time.Format("2006-01-02 15:04:05"),
+			entry.PeerName,
+			entry.Method + " " + entry.Path,
+			status,
+		})
+	}
+	table.Render()
+
+	return nil
+}
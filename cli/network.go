@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anywherelan/awl/api/apiclient"
+	"github.com/anywherelan/awl/entity"
+)
+
+func printNetworkInfo(api *apiclient.Client) error {
+	info, err := api.NetworkInfo()
+	if err != nil {
+		return err
+	}
+	if info.Name == "" {
+		fmt.Println("not a member of any network")
+		return nil
+	}
+
+	fmt.Printf("network: %s\n", info.Name)
+	fmt.Printf("admin: %v\n", info.IsAdmin)
+	fmt.Printf("admins: %s\n", strings.Join(info.AdminKeys, ", "))
+	fmt.Printf("groups: %s\n", strings.Join(info.Groups, ", "))
+	if !info.ExpiresAt.IsZero() {
+		fmt.Printf("certificate expires at: %s\n", info.ExpiresAt.Format(time.DateTime))
+	}
+	fmt.Printf("known members: %d\n", len(info.MemberPeerIDs))
+	fmt.Printf("revoked: %d\n", len(info.RevokedPeerIDs))
+
+	return nil
+}
+
+func createNetwork(api *apiclient.Client, name string) error {
+	err := api.CreateNetwork(name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("network created successfully, sign certificates for other peers with 'network sign'")
+
+	return nil
+}
+
+func signCertificate(api *apiclient.Client, request entity.SignCertificateRequest) error {
+	resp, err := api.SignCertificate(request)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("certificate expires at %s\n", resp.ExpiresAt.Format(time.DateTime))
+	fmt.Println("run 'network join' with this certificate on peer:")
+	fmt.Println(resp.Certificate)
+
+	return nil
+}
+
+func joinNetwork(api *apiclient.Client, certificate string) error {
+	err := api.JoinNetwork(certificate)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("joined network successfully, members will accept you automatically")
+
+	return nil
+}
+
+func revokeMembership(api *apiclient.Client, peerID string) error {
+	err := api.RevokeMembership(peerID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("membership revoked successfully")
+
+	return nil
+}
+
+func updateNetworkAdmins(api *apiclient.Client, add, remove []string) error {
+	if len(add) == 0 && len(remove) == 0 {
+		return errors.New("specify admins to add or remove")
+	}
+	err := api.UpdateNetworkAdmins(add, remove)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("admins updated successfully, members will receive new admin list")
+
+	return nil
+}
+
+func leaveNetwork(api *apiclient.Client) error {
+	err := api.LeaveNetwork()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("left network successfully")
+
+	return nil
+}
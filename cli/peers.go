@@ -6,15 +6,17 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/olekukonko/tablewriter"
 
 	"github.com/anywherelan/awl/api/apiclient"
 	"github.com/anywherelan/awl/awldns"
+	"github.com/anywherelan/awl/config"
 	"github.com/anywherelan/awl/entity"
 )
 
-func printPeersStatus(api *apiclient.Client, format string) error {
+func printPeersStatus(api *apiclient.Client, format, group string) error {
 	const (
 		TableFormatRowNumber    = "n"
 		TableFormatPeer         = "p"
@@ -25,6 +27,7 @@ func printPeersStatus(api *apiclient.Client, format string) error {
 		TableFormatConnection   = "c"
 		TableFormatVersion      = "v"
 		TableFormatExitNode     = "e"
+		TableFormatDetails      = "d"
 	)
 
 	fHeaderMap := map[string]string{
@@ -37,6 +40,7 @@ func printPeersStatus(api *apiclient.Client, format string) error {
 		TableFormatConnection:   "connections\naddress | protocol",
 		TableFormatVersion:      "version",
 		TableFormatExitNode:     "exit node",
+		TableFormatDetails:      "shared details",
 	}
 
 	if len(format) < 1 {
@@ -61,7 +65,7 @@ func printPeersStatus(api *apiclient.Client, format string) error {
 		columns = append(columns, fcs)
 	}
 
-	peers, err := api.KnownPeers()
+	peers, err := api.KnownPeersInGroup(group)
 	if err != nil {
 		return err
 	}
@@ -126,6 +130,8 @@ func printPeersStatus(api *apiclient.Client, format string) error {
 				row = append(row, peer.Version)
 			case TableFormatExitNode:
 				row = append(row, fmt.Sprintf("we allow:     %v\npeer allowed: %v", peer.WeAllowUsingAsExitNode, peer.AllowedUsingAsExitNode))
+			case TableFormatDetails:
+				row = append(row, formatSharedStatus(peer.SharedStatus))
 			}
 		}
 		table.Append(row)
@@ -134,6 +140,36 @@ func printPeersStatus(api *apiclient.Client, format string) error {
 	return nil
 }
 
+func formatSharedStatus(status entity.PeerSharedStatus) string {
+	details := make([]string, 0, 5)
+	if status.Hostname != "" {
+		details = append(details, status.Hostname)
+	}
+	if status.OS != "" {
+		details = append(details, status.OS+"/"+status.Arch)
+	}
+	if status.Version != "" {
+		details = append(details, status.Version)
+	}
+	if status.Uptime != 0 {
+		details = append(details, "up "+status.Uptime.Round(time.Minute).String())
+	}
+	if status.HealthShared {
+		details = append(details, fmt.Sprintf("tun: %s, dns: %s", formatUp(status.TunUp), formatUp(status.DNSUp)))
+	}
+	if status.Note != "" {
+		details = append(details, fmt.Sprintf("%q", status.Note))
+	}
+	return strings.Join(details, "\n")
+}
+
+func formatUp(up bool) string {
+	if up {
+		return "up"
+	}
+	return "down"
+}
+
 func printFriendRequests(api *apiclient.Client) error {
 	authRequests, err := api.AuthRequests()
 	if err != nil {
@@ -144,12 +180,80 @@ func printFriendRequests(api *apiclient.Client) error {
 		return nil
 	}
 	for _, req := range authRequests {
-		fmt.Printf("Name: '%s' peerID: %s\n", req.Name, req.PeerID)
+		received := req.ReceivedAt.Format("2006-01-02 15:04:05")
+		if req.IntroducedBy != "" {
+			fmt.Printf("Name: '%s' peerID: %s received: %s introduced by '%s'\n", req.Name, req.PeerID, received, req.IntroducedByName)
+		} else {
+			fmt.Printf("Name: '%s' peerID: %s received: %s\n", req.Name, req.PeerID, received)
+		}
+		if req.OS != "" || req.Version != "" {
+			fmt.Printf("\tdevice: %s %s\n", req.OS, req.Version)
+		}
+		if req.ProposedAlias != "" {
+			fmt.Printf("\tnames you as: '%s'\n", req.ProposedAlias)
+		}
+		if req.Message != "" {
+			fmt.Printf("\tmessage: %q\n", req.Message)
+		}
 	}
 
 	return nil
 }
 
+func dismissFriendRequest(api *apiclient.Client, peerID string) error {
+	err := api.DismissAuthRequest(peerID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("friend request dismissed, peer is not blocked and could send a new one")
+	return nil
+}
+
+func printPeerHistory(api *apiclient.Client, peerID string) error {
+	history, err := api.PeerHistory(peerID)
+	if err != nil {
+		return err
+	}
+	if len(history.Events) == 0 {
+		fmt.Printf("no connection history for peer '%s'\n", history.DisplayName)
+		return nil
+	}
+
+	fmt.Printf("Peer '%s', uptime %.2f%% since %s\n", history.DisplayName, history.UptimePercent,
+		history.TrackedSince.Format("2006-01-02 15:04:05"))
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetBorders(tablewriter.Border{Left: false, Top: false, Right: false, Bottom: false})
+	table.SetRowLine(true)
+	table.SetHeader([]string{"connected", "disconnected", "duration", "direction", "connection", "reason"})
+	// print the newest events first
+	for i := len(history.Events) - 1; i >= 0; i-- {
+		event := history.Events[i]
+		disconnected := "-"
+		duration := time.Since(event.ConnectedAt)
+		if !event.DisconnectedAt.IsZero() {
+			disconnected = event.DisconnectedAt.Format("2006-01-02\n15:04:05")
+			duration = event.DisconnectedAt.Sub(event.ConnectedAt)
+		}
+		connection := fmt.Sprintf("%s | %s", event.RemoteAddress, event.Transport)
+		if event.ThroughRelay {
+			connection = "through relay\n" + event.RelayPeerID
+		}
+		table.Append([]string{
+			event.ConnectedAt.Format("2006-01-02\n15:04:05"),
+			disconnected,
+			duration.Round(time.Second).String(),
+			event.Direction,
+			connection,
+			event.DisconnectReason,
+		})
+	}
+	table.Render()
+
+	return nil
+}
+
 func getPeerIdByAlias(api *apiclient.Client, alias string) (string, error) {
 	if alias == "" {
 		return "", errors.New("name is empty")
@@ -168,7 +272,7 @@ func getPeerIdByAlias(api *apiclient.Client, alias string) (string, error) {
 	return "", fmt.Errorf("can't find peer with name \"%s\"", alias)
 }
 
-func addPeer(api *apiclient.Client, peerID, alias string) error {
+func addPeer(api *apiclient.Client, peerID, alias, message string) error {
 	authRequests, err := api.AuthRequests()
 	if err != nil {
 		return err
@@ -190,7 +294,7 @@ func addPeer(api *apiclient.Client, peerID, alias string) error {
 		return nil
 	}
 
-	err = api.SendFriendRequest(peerID, alias)
+	err = api.SendFriendRequest(peerID, alias, message)
 	if err != nil {
 		return err
 	}
@@ -219,6 +323,7 @@ func changePeerAlias(api *apiclient.Client, peerID, newAlias string) error {
 		Alias:                newAlias,
 		DomainName:           pcfg.DomainName,
 		AllowUsingAsExitNode: pcfg.WeAllowUsingAsExitNode,
+		TrustIntroductions:   pcfg.TrustIntroductions,
 	})
 	if err != nil {
 		return err
@@ -239,6 +344,7 @@ func changePeerDomain(api *apiclient.Client, peerID, newDomain string) error {
 		Alias:                pcfg.Alias,
 		DomainName:           newDomain,
 		AllowUsingAsExitNode: pcfg.WeAllowUsingAsExitNode,
+		TrustIntroductions:   pcfg.TrustIntroductions,
 	})
 	if err != nil {
 		return err
@@ -259,6 +365,7 @@ func setAllowUsingAsExitNode(api *apiclient.Client, peerID string, allow bool) e
 		Alias:                pcfg.Alias,
 		DomainName:           pcfg.DomainName,
 		AllowUsingAsExitNode: allow,
+		TrustIntroductions:   pcfg.TrustIntroductions,
 	})
 	if err != nil {
 		return err
@@ -267,3 +374,62 @@ func setAllowUsingAsExitNode(api *apiclient.Client, peerID string, allow bool) e
 	fmt.Println("AllowUsingAsExitNode config updated successfully")
 	return nil
 }
+
+func setExitNodeRules(api *apiclient.Client, peerID string, rules config.ExitNodeRules) error {
+	err := api.UpdateExitNodeRules(peerID, rules)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("exit node rules updated successfully")
+	return nil
+}
+
+func setRemoteAdmin(api *apiclient.Client, peerID string, allow bool) error {
+	err := api.UpdateRemoteAdmin(peerID, allow)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("remote admin permission updated successfully")
+	return nil
+}
+
+func setTrustIntroductions(api *apiclient.Client, peerID string, trust bool) error {
+	pcfg, err := api.KnownPeerConfig(peerID)
+	if err != nil {
+		return err
+	}
+
+	err = api.UpdatePeerSettings(entity.UpdatePeerSettingsRequest{
+		PeerID:               peerID,
+		Alias:                pcfg.Alias,
+		DomainName:           pcfg.DomainName,
+		AllowUsingAsExitNode: pcfg.WeAllowUsingAsExitNode,
+		TrustIntroductions:   trust,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("TrustIntroductions config updated successfully")
+	return nil
+}
+
+func introducePeer(api *apiclient.Client, peerID, introducedPeerID, introducedName string) error {
+	if introducedPeerID == "" {
+		var err error
+		introducedPeerID, err = getPeerIdByAlias(api, introducedName)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := api.IntroducePeer(peerID, introducedPeerID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("peer introduced successfully, it will see introduced peer in its friend requests")
+	return nil
+}
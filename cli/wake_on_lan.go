@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/olekukonko/tablewriter"
+
+	"github.com/anywherelan/awl/api/apiclient"
+	"github.com/anywherelan/awl/config"
+)
+
+func wakeOnLAN(api *apiclient.Client, via, mac, target string) error {
+	if mac == "" && target == "" {
+		return fmt.Errorf("mac or target should be defined")
+	}
+	peerID := via
+	if _, err := peer.Decode(via); err != nil {
+		peerID, err = getPeerIdByAlias(api, via)
+		if err != nil {
+			return err
+		}
+	}
+
+	response, err := api.WakeOnLAN(peerID, mac, target)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("magic packet for %s sent by '%s' on %s\n", response.MAC, via, strings.Join(response.Interfaces, ", "))
+	return nil
+}
+
+func setWakeOnLANPermission(api *apiclient.Client, peerID string, allow bool) error {
+	err := api.UpdateWakeOnLANPermission(peerID, allow)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("wake on lan permission updated successfully")
+	return nil
+}
+
+// updateWakeOnLANTargets adds targets in format name=mac and removes targets by name, then prints targets of peer.
+func updateWakeOnLANTargets(api *apiclient.Client, peerID string, add, remove []string) error {
+	pcfg, err := api.KnownPeerConfig(peerID)
+	if err != nil {
+		return err
+	}
+
+	targets := pcfg.WakeOnLANTargets
+	if len(add) > 0 || len(remove) > 0 {
+		for _, name := range remove {
+			targets = slices.DeleteFunc(targets, func(target config.WakeOnLANTarget) bool {
+				return target.Name == name
+			})
+		}
+		for _, val := range add {
+			name, mac, found := strings.Cut(val, "=")
+			if !found || name == "" || mac == "" {
+				return fmt.Errorf("invalid target %q, expected name=mac", val)
+			}
+			targets = slices.DeleteFunc(targets, func(target config.WakeOnLANTarget) bool {
+				return target.Name == name
+			})
+			targets = append(targets, config.WakeOnLANTarget{Name: name, MAC: mac})
+		}
+		err = api.UpdateWakeOnLANTargets(peerID, targets)
+		if err != nil {
+			return err
+		}
+		pcfg, err = api.KnownPeerConfig(peerID)
+		if err != nil {
+			return err
+		}
+		targets = pcfg.WakeOnLANTargets
+	}
+
+	if len(targets) == 0 {
+		fmt.Println("no wake on lan targets")
+		return nil
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetBorders(tablewriter.Border{Left: false, Top: false, Right: false, Bottom: false})
+	table.SetHeader([]string{"target", "mac"})
+	for _, target := range targets {
+		table.Append([]string{target.Name, target.MAC})
+	}
+	table.Render()
+
+	return nil
+}
@@ -153,7 +153,14 @@ func subscribeToNotifications(app *awl.Application) {
 		if authRequest.Name != "" {
 			title = fmt.Sprintf("Anywherelan: friend request from %s", authRequest.Name)
 		}
-		notifyErr := beeep.Notify(title, "PeerID: \n"+authRequest.PeerID, embeds.GetIconPath())
+		message := "PeerID: \n" + authRequest.PeerID
+		if authRequest.OS != "" || authRequest.Version != "" {
+			message += fmt.Sprintf("\nDevice: %s %s", authRequest.OS, authRequest.Version)
+		}
+		if authRequest.Message != "" {
+			message += "\nMessage: " + authRequest.Message
+		}
+		notifyErr := beeep.Notify(title, message, embeds.GetIconPath())
 		if notifyErr != nil {
 			logger.Errorf("show notification: incoming friend request: %v", notifyErr)
 		}
@@ -8,6 +8,8 @@ import (
 	"runtime"
 	"slices"
 	"sort"
+	"strings"
+	"time"
 
 	"fyne.io/systray"
 	"github.com/GrigoryKrasnochub/updaterini"
@@ -190,29 +192,38 @@ func refreshPeersCounterOnPeersConnectionChanged(peerID *string) {
 }
 
 var peersSubmenus []*systray.MenuItem
-var previousOnlinePeers []string
-var previousOfflinePeers []string
+var previousOnlinePeers []peerMenuItem
+var previousOfflinePeers []peerMenuItem
+
+type peerMenuItem struct {
+	title   string
+	tooltip string
+}
 
 // TODO: submenus on linux doesn't work reliably
 func refreshPeersSubmenus() {
 	app.Conf.RLock()
-	onlinePeers := make([]string, 0)
-	offlinePeers := make([]string, 0)
+	onlinePeers := make([]peerMenuItem, 0)
+	offlinePeers := make([]peerMenuItem, 0)
 	for _, knownPeer := range app.Conf.KnownPeers {
 		online := app.P2p.IsConnected(knownPeer.PeerId())
 		peerName := knownPeer.DisplayName()
 		if peerName == "" {
 			peerName = knownPeer.PeerID
 		}
+		item := peerMenuItem{title: peerName, tooltip: sharedStatusTooltip(knownPeer.SharedStatus, online)}
+		if knownPeer.SharedStatus.Note != "" {
+			item.title = fmt.Sprintf("%s (%s)", peerName, knownPeer.SharedStatus.Note)
+		}
 		if online {
-			onlinePeers = append(onlinePeers, peerName)
+			onlinePeers = append(onlinePeers, item)
 		} else {
-			offlinePeers = append(offlinePeers, peerName)
+			offlinePeers = append(offlinePeers, item)
 		}
 	}
 	app.Conf.RUnlock()
-	sort.Strings(onlinePeers)
-	sort.Strings(offlinePeers)
+	sortPeerMenuItems(onlinePeers)
+	sortPeerMenuItems(offlinePeers)
 
 	if slices.Equal(previousOnlinePeers, onlinePeers) && slices.Equal(previousOfflinePeers, offlinePeers) {
 		return
@@ -228,8 +239,8 @@ func refreshPeersSubmenus() {
 
 	onlineSubmenu := peersMenu.AddSubMenuItem("Online peers:", "")
 	peersSubmenus = append(peersSubmenus, onlineSubmenu)
-	for _, peerName := range onlinePeers {
-		submenu := peersMenu.AddSubMenuItem(peerName, "")
+	for _, item := range onlinePeers {
+		submenu := peersMenu.AddSubMenuItem(item.title, item.tooltip)
 		submenu.Disable()
 		peersSubmenus = append(peersSubmenus, submenu)
 	}
@@ -243,13 +254,40 @@ func refreshPeersSubmenus() {
 
 	offlineSubmenu := peersMenu.AddSubMenuItem("Offline peers:", "")
 	peersSubmenus = append(peersSubmenus, offlineSubmenu)
-	for _, peerName := range offlinePeers {
-		submenu := peersMenu.AddSubMenuItem(peerName, "")
+	for _, item := range offlinePeers {
+		submenu := peersMenu.AddSubMenuItem(item.title, item.tooltip)
 		submenu.Disable()
 		peersSubmenus = append(peersSubmenus, submenu)
 	}
 }
 
+func sortPeerMenuItems(items []peerMenuItem) {
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].title < items[j].title
+	})
+}
+
+// sharedStatusTooltip describes details shared by peer, uptime is rounded to hours to not rebuild menu too often.
+func sharedStatusTooltip(status config.PeerSharedStatus, online bool) string {
+	details := make([]string, 0, 5)
+	if status.Hostname != "" {
+		details = append(details, status.Hostname)
+	}
+	if status.OS != "" {
+		details = append(details, status.OS+"/"+status.Arch)
+	}
+	if status.Version != "" {
+		details = append(details, status.Version)
+	}
+	if online && !status.StartedAt.IsZero() {
+		details = append(details, fmt.Sprintf("up %dh", int(time.Since(status.StartedAt).Hours())))
+	}
+	if status.HealthShared {
+		details = append(details, fmt.Sprintf("tun up: %v, dns up: %v", status.TunUp, status.DNSUp))
+	}
+	return strings.Join(details, ", ")
+}
+
 var proxySubmenus []*systray.MenuItem
 var previousProxies []entity.AvailableProxy
 var previousProxyPeerID string
@@ -360,6 +398,9 @@ func checkForUpdatesWithDesktopNotification() {
 	if !updStatus {
 		return
 	}
+	if app != nil {
+		app.NotifyUpdateAvailable(updService.NewVersion.VersionTag())
+	}
 
 	notifyErr := beeep.Notify("Anywherelan: new version available!",
 		fmt.Sprintf("Version %s: %s available for installation!\nUse tray menu option %q\n",
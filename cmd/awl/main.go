@@ -52,9 +52,9 @@ func main() {
 			}
 			ticker := time.NewTicker(interval)
 			defer ticker.Stop()
-			checkForUpdates(app.Conf, logger)
+			checkForUpdates(app, logger)
 			for range ticker.C {
-				checkForUpdates(app.Conf, logger)
+				checkForUpdates(app, logger)
 			}
 		}()
 	}
@@ -81,8 +81,8 @@ func main() {
 	logger.Info("exited normally")
 }
 
-func checkForUpdates(conf *config.Config, logger *log.ZapEventLogger) {
-	updService, err := update.NewUpdateService(conf, logger, update.AppTypeAwl)
+func checkForUpdates(app *awl.Application, logger *log.ZapEventLogger) {
+	updService, err := update.NewUpdateService(app.Conf, logger, update.AppTypeAwl)
 	if err != nil {
 		logger.Errorf("update auto check: creating update service: %v", err)
 		return
@@ -97,4 +97,5 @@ func checkForUpdates(conf *config.Config, logger *log.ZapEventLogger) {
 	}
 
 	logger.Infof("New version available: %s, current version: %s", updService.NewVersion.VersionTag(), config.Version)
+	app.NotifyUpdateAvailable(updService.NewVersion.VersionTag())
 }
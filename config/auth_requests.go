@@ -0,0 +1,114 @@
+package config
+
+import (
+	"slices"
+	"time"
+)
+
+// maxIngoingAuthRequests limits number of pending requests, so unknown peers can't flood config.
+const maxIngoingAuthRequests = 100
+
+// IngoingAuthRequest is a pending friend request, it is kept until accepted, declined, dismissed or expired.
+type IngoingAuthRequest struct {
+	PeerID string `json:"peerId"`
+	Name   string `json:"name"`
+	// Introduction of peer signed by our friend, see protocol.Introduction
+	Introduction string `json:"introduction,omitempty"`
+	// Introduced means that request was received from introducer, not from peer itself
+	Introduced bool `json:"introduced"`
+	// Metadata sent by peer, see protocol.AuthPeer
+	Message       string    `json:"message,omitempty"`
+	OS            string    `json:"os,omitempty"`
+	Version       string    `json:"version,omitempty"`
+	ProposedAlias string    `json:"proposedAlias,omitempty"`
+	ReceivedAt    time.Time `json:"receivedAt"`
+	ExpiresAt     time.Time `json:"expiresAt"`
+}
+
+func (r IngoingAuthRequest) Expired(now time.Time) bool {
+	return now.After(r.ExpiresAt)
+}
+
+// UpsertIngoingAuthRequest stores request and reports whether it is a new one.
+// Repeated request keeps its ReceivedAt. When there are too many pending requests the oldest one is evicted,
+// so flooding can't lock out new peers, and a legitimate peer gets back after its next retry.
+func (c *Config) UpsertIngoingAuthRequest(req IngoingAuthRequest) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	existing, exists := c.IngoingAuthRequests[req.PeerID]
+	if exists && !existing.Expired(req.ReceivedAt) {
+		req.ReceivedAt = existing.ReceivedAt
+	} else {
+		exists = false
+		c.deleteExpiredAuthRequests(req.ReceivedAt)
+		if len(c.IngoingAuthRequests) >= maxIngoingAuthRequests {
+			c.deleteOldestAuthRequest()
+		}
+	}
+	c.IngoingAuthRequests[req.PeerID] = req
+	c.save()
+
+	return !exists
+}
+
+func (c *Config) GetIngoingAuthRequest(peerID string) (IngoingAuthRequest, bool) {
+	c.RLock()
+	defer c.RUnlock()
+
+	req, exists := c.IngoingAuthRequests[peerID]
+	if !exists || req.Expired(time.Now()) {
+		return IngoingAuthRequest{}, false
+	}
+	return req, true
+}
+
+// ListIngoingAuthRequests returns not expired requests sorted from the oldest to the newest.
+func (c *Config) ListIngoingAuthRequests() []IngoingAuthRequest {
+	now := time.Now()
+	c.RLock()
+	requests := make([]IngoingAuthRequest, 0, len(c.IngoingAuthRequests))
+	for _, req := range c.IngoingAuthRequests {
+		if !req.Expired(now) {
+			requests = append(requests, req)
+		}
+	}
+	c.RUnlock()
+
+	slices.SortFunc(requests, func(a, b IngoingAuthRequest) int {
+		return a.ReceivedAt.Compare(b.ReceivedAt)
+	})
+
+	return requests
+}
+
+func (c *Config) RemoveIngoingAuthRequest(peerID string) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	_, exists := c.IngoingAuthRequests[peerID]
+	if exists {
+		delete(c.IngoingAuthRequests, peerID)
+		c.save()
+	}
+
+	return exists
+}
+
+func (c *Config) deleteExpiredAuthRequests(now time.Time) {
+	for peerID, req := range c.IngoingAuthRequests {
+		if req.Expired(now) {
+			delete(c.IngoingAuthRequests, peerID)
+		}
+	}
+}
+
+func (c *Config) deleteOldestAuthRequest() {
+	var oldest IngoingAuthRequest
+	for _, req := range c.IngoingAuthRequests {
+		if oldest.PeerID == "" || req.ReceivedAt.Before(oldest.ReceivedAt) {
+			oldest = req
+		}
+	}
+	delete(c.IngoingAuthRequests, oldest.PeerID)
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/p2p/host/eventbus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIngoingAuthRequests(t *testing.T) {
+	a := require.New(t)
+	conf := NewConfig(eventbus.NewBus())
+	conf.dataDir = t.TempDir()
+
+	now := time.Now()
+	isNew := conf.UpsertIngoingAuthRequest(IngoingAuthRequest{PeerID: "peer1", Name: "one", ReceivedAt: now, ExpiresAt: now.Add(time.Hour)})
+	a.True(isNew)
+	// repeated request keeps the first receive time
+	isNew = conf.UpsertIngoingAuthRequest(IngoingAuthRequest{PeerID: "peer1", Name: "renamed", ReceivedAt: now.Add(time.Minute), ExpiresAt: now.Add(2 * time.Hour)})
+	a.False(isNew)
+	req, exists := conf.GetIngoingAuthRequest("peer1")
+	a.True(exists)
+	a.Equal("renamed", req.Name)
+	a.True(now.Equal(req.ReceivedAt))
+
+	conf.IngoingAuthRequests["expired"] = IngoingAuthRequest{PeerID: "expired", ReceivedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour)}
+	_, exists = conf.GetIngoingAuthRequest("expired")
+	a.False(exists)
+	a.Len(conf.ListIngoingAuthRequests(), 1)
+
+	// the oldest request is evicted when there are too many pending ones
+	for i := 1; i < maxIngoingAuthRequests; i++ {
+		conf.UpsertIngoingAuthRequest(IngoingAuthRequest{PeerID: fmt.Sprint(i), ReceivedAt: now.Add(time.Duration(i) * time.Second), ExpiresAt: now.Add(time.Hour)})
+	}
+	isNew = conf.UpsertIngoingAuthRequest(IngoingAuthRequest{PeerID: "overflow", ReceivedAt: now.Add(time.Hour / 2), ExpiresAt: now.Add(time.Hour)})
+	a.True(isNew)
+	a.Len(conf.IngoingAuthRequests, maxIngoingAuthRequests)
+	_, exists = conf.IngoingAuthRequests["peer1"]
+	a.False(exists)
+	_, exists = conf.IngoingAuthRequests["overflow"]
+	a.True(exists)
+
+	a.True(conf.RemoveIngoingAuthRequest("overflow"))
+	a.False(conf.RemoveIngoingAuthRequest("overflow"))
+}
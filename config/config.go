@@ -6,6 +6,7 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
 	"time"
 
@@ -45,30 +46,85 @@ type (
 		dataDir      string
 		emitter      awlevent.Emitter
 
-		Version               string                 `json:"version"`
-		LoggerLevel           string                 `json:"loggerLevel"`
-		HttpListenAddress     string                 `json:"httpListenAddress"`
-		HttpListenOnAdminHost bool                   `json:"httpListenOnAdminHost"`
-		P2pNode               P2pNodeConfig          `json:"p2pNode"`
-		VPNConfig             VPNConfig              `json:"vpn"`
-		SOCKS5                SOCKS5Config           `json:"socks5"`
-		KnownPeers            map[string]KnownPeer   `json:"knownPeers"`
-		BlockedPeers          map[string]BlockedPeer `json:"blockedPeers"`
-		Update                UpdateConfig           `json:"update"`
+		Version               string                        `json:"version"`
+		LoggerLevel           string                        `json:"loggerLevel"`
+		HttpListenAddress     string                        `json:"httpListenAddress"`
+		HttpListenOnAdminHost bool                          `json:"httpListenOnAdminHost"`
+		P2pNode               P2pNodeConfig                 `json:"p2pNode"`
+		VPNConfig             VPNConfig                     `json:"vpn"`
+		SOCKS5                SOCKS5Config                  `json:"socks5"`
+		KnownPeers            map[string]KnownPeer          `json:"knownPeers"`
+		BlockedPeers          map[string]BlockedPeer        `json:"blockedPeers"`
+		Invites               map[string]Invite             `json:"invites"`
+		Groups                map[string]PeerGroup          `json:"groups"`
+		Network               NetworkConfig                 `json:"network"`
+		KeyRotations          map[string]KeyRotation        `json:"keyRotations"`
+		IngoingAuthRequests   map[string]IngoingAuthRequest `json:"ingoingAuthRequests"`
+		Update                UpdateConfig                  `json:"update"`
+		Hooks                 []Hook                        `json:"hooks"`
+		StatusSharing         StatusSharingConfig           `json:"statusSharing"`
+		FileTransfer          FileTransferConfig            `json:"fileTransfer"`
+		DNS                   DNSConfig                     `json:"dns"`
 	}
 	P2pNodeConfig struct {
 		// Hex-encoded multihash representing a peer ID, calculated from Identity
-		PeerID                  string        `json:"peerId"`
-		Name                    string        `json:"name"`
-		Identity                string        `json:"identity"`
-		BootstrapPeers          []string      `json:"bootstrapPeers"`
-		ListenAddresses         []string      `json:"listenAddresses"`
-		ReconnectionIntervalSec time.Duration `json:"reconnectionIntervalSec" swaggertype:"primitive,integer"`
-		AutoAcceptAuthRequests  bool          `json:"autoAcceptAuthRequests"`
+		PeerID   string `json:"peerId"`
+		Name     string `json:"name"`
+		Identity string `json:"identity"`
+		// NextIdentity is generated by key rotation, it replaces Identity on the next start
+		NextIdentity string `json:"nextIdentity,omitempty"`
+		// KeyHandover is signed with the previous identity key, it's sent to known peers and cleared when its grace period ends
+		KeyHandover    string   `json:"keyHandover,omitempty"`
+		BootstrapPeers []string `json:"bootstrapPeers"`
+		// DisableDefaultBootstrapPeers excludes DefaultBootstrapPeers, only BootstrapPeers are used
+		DisableDefaultBootstrapPeers bool          `json:"disableDefaultBootstrapPeers"`
+		ListenAddresses              []string      `json:"listenAddresses"`
+		ReconnectionIntervalSec      time.Duration `json:"reconnectionIntervalSec" swaggertype:"primitive,integer"`
+		// AutoAcceptAuthRequests accepts friend requests from everyone.
+		// Invites are a safer option, they are accepted automatically only from peers which received invite token.
+		AutoAcceptAuthRequests bool `json:"autoAcceptAuthRequests"`
+		// Transports are always enabled, but their listen addresses are used only if ListenAddresses is empty
+		Transports   TransportsConfig   `json:"transports"`
+		RelayService RelayServiceConfig `json:"relayService"`
 
 		UseDedicatedConnForEachStream bool `json:"useDedicatedConnForEachStream"`
 		ParallelSendingStreamsCount   int  `json:"parallelSendingStreamsCount"`
 	}
+	TransportsConfig struct {
+		TCP          TransportConfig          `json:"tcp"`
+		QUIC         TransportConfig          `json:"quic"`
+		WebSocket    WebSocketTransportConfig `json:"webSocket"`
+		WebTransport TransportConfig          `json:"webTransport"`
+	}
+	TransportConfig struct {
+		Enabled bool `json:"enabled"`
+		// Port for default listen addresses, 0 means default port 4363 for tcp/quic and random port for websocket/webtransport
+		Port int `json:"port"`
+		// ListenAddresses override default listen addresses built from Port
+		ListenAddresses []string `json:"listenAddresses"`
+		// DialPriority defines order of dialing peer addresses, transports with lower value are dialed first.
+		// Transports with the same priority are dialed simultaneously
+		DialPriority int `json:"dialPriority"`
+	}
+	WebSocketTransportConfig struct {
+		TransportConfig
+		// Certificate and key for secure websocket (wss) listener, plain websocket is used if empty
+		TLSCertFile string `json:"tlsCertFile"`
+		TLSKeyFile  string `json:"tlsKeyFile"`
+	}
+	// RelayServiceConfig configures relay for known peers, it works only if node is publicly reachable
+	RelayServiceConfig struct {
+		Enabled bool `json:"enabled"`
+		// MaxReservations is the maximum number of peers which can use relay at the same time, default is 32
+		MaxReservations int `json:"maxReservations"`
+		// MaxCircuits is the maximum number of relayed connections for each peer, 0 means libp2p default
+		MaxCircuits int `json:"maxCircuits"`
+		// LimitDurationSec is the maximum duration of relayed connection, default is 1 hour, negative value means no limit
+		LimitDurationSec time.Duration `json:"limitDurationSec" swaggertype:"primitive,integer"`
+		// LimitDataBytes is the maximum amount of data relayed in each direction of connection, default is 1 GiB,
+		// negative value means no limit
+		LimitDataBytes int64 `json:"limitDataBytes"`
+	}
 	VPNConfig struct {
 		InterfaceName string `json:"interfaceName"`
 		IPNet         string `json:"ipNet"`
@@ -102,6 +158,38 @@ type (
 		Declined               bool `json:"declined"`
 		WeAllowUsingAsExitNode bool `json:"weAllowUsingAsExitNode"`
 		AllowedUsingAsExitNode bool `json:"allowedUsingAsExitNode"`
+		// InviteToken of remote peer, it is sent with our invitation until remote peer confirms it
+		InviteToken string `json:"inviteToken,omitempty"`
+		// ProtocolVersion negotiated with peer during the last status exchange
+		ProtocolVersion string `json:"protocolVersion"`
+		// Capabilities supported by both us and peer
+		Capabilities []string `json:"capabilities"`
+		// Groups which peer belongs to, see PeerGroup
+		Groups []string `json:"groups"`
+		// Certificate is membership certificate of peer in our network, see NetworkConfig
+		Certificate string `json:"certificate,omitempty"`
+		// Introduction of us to remote peer, it is sent with our invitation until remote peer confirms it
+		Introduction string `json:"introduction,omitempty"`
+		// TrustIntroductions accepts peers introduced by this peer automatically
+		TrustIntroductions bool `json:"trustIntroductions"`
+		// AuthMessage is sent with our invitation until remote peer confirms it
+		AuthMessage string `json:"authMessage,omitempty"`
+		// ExitNodeRules restrict destinations available to peer when it uses us as exit node
+		ExitNodeRules ExitNodeRules `json:"exitNodeRules"`
+		// SharedStatus is details which peer shares with us, see StatusSharingConfig
+		SharedStatus PeerSharedStatus `json:"sharedStatus"`
+		// AllowRemoteAdmin lets peer manage this device through remote admin protocol
+		AllowRemoteAdmin bool `json:"allowRemoteAdmin"`
+		// FileReceivePolicy decides what to do with files sent by peer, empty means FileReceivePolicyAsk
+		FileReceivePolicy FileReceivePolicy `json:"fileReceivePolicy,omitempty"`
+		// AllowWakeOnLAN lets peer ask us to send Wake-on-LAN packets to our LAN
+		AllowWakeOnLAN bool `json:"allowWakeOnLAN"`
+		// WakeOnLANTargets are devices in LAN of peer which we wake through it
+		WakeOnLANTargets []WakeOnLANTarget `json:"wakeOnLANTargets"`
+		// DNSRecords are TXT and SRV records under DomainName in awl DNS
+		DNSRecords []DNSRecord `json:"dnsRecords"`
+		// AllowDNSQueries lets peer resolve names with our DNS upstreams, it's used by its DNSForwardingRule
+		AllowDNSQueries bool `json:"allowDNSQueries"`
 	}
 	BlockedPeer struct {
 		// Hex-encoded multihash representing a peer ID
@@ -234,6 +322,7 @@ func (c *Config) SetIdentity(key crypto.PrivKey, id peer.ID) {
 
 	c.P2pNode.Identity = identity
 	c.P2pNode.PeerID = id.String()
+	c.P2pNode.NextIdentity = ""
 	c.save()
 	c.Unlock()
 }
@@ -242,10 +331,14 @@ func (c *Config) PrivKey() []byte {
 	c.RLock()
 	defer c.RUnlock()
 
-	if c.P2pNode.Identity == "" {
+	identity := c.P2pNode.Identity
+	if c.P2pNode.NextIdentity != "" {
+		identity = c.P2pNode.NextIdentity
+	}
+	if identity == "" {
 		return nil
 	}
-	b, err := base58.Decode(c.P2pNode.Identity)
+	b, err := base58.Decode(identity)
 	if err != nil {
 		return nil
 	}
@@ -264,9 +357,12 @@ func (c *Config) GetBootstrapPeers() []peer.AddrInfo {
 
 		allMultiaddrs = append(allMultiaddrs, newMultiaddr)
 	}
+	disableDefaults := c.P2pNode.DisableDefaultBootstrapPeers
 	c.RUnlock()
 
-	allMultiaddrs = append(allMultiaddrs, DefaultBootstrapPeers...)
+	if !disableDefaults {
+		allMultiaddrs = append(allMultiaddrs, DefaultBootstrapPeers...)
+	}
 	addrInfos, err := peer.AddrInfosFromP2pAddrs(allMultiaddrs...)
 	if err != nil {
 		logger.Warnf("invalid one or more bootstrap addr info from config: %v", err)
@@ -279,6 +375,49 @@ func (c *Config) GetBootstrapPeers() []peer.AddrInfo {
 	return addrInfos
 }
 
+// AddBootstrapPeer returns false if address is already added.
+func (c *Config) AddBootstrapPeer(addr multiaddr.Multiaddr) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	if slices.Contains(c.P2pNode.BootstrapPeers, addr.String()) {
+		return false
+	}
+	c.P2pNode.BootstrapPeers = append(c.P2pNode.BootstrapPeers, addr.String())
+	c.save()
+
+	return true
+}
+
+// RemoveBootstrapPeer removes custom bootstrap peer addresses equal to addrOrPeerID or which belong to peer with such ID.
+// It returns false if nothing was removed.
+func (c *Config) RemoveBootstrapPeer(addrOrPeerID string) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	oldLen := len(c.P2pNode.BootstrapPeers)
+	c.P2pNode.BootstrapPeers = slices.DeleteFunc(c.P2pNode.BootstrapPeers, func(val string) bool {
+		if val == addrOrPeerID {
+			return true
+		}
+		addrInfo, err := peer.AddrInfoFromString(val)
+		return err == nil && addrInfo.ID.String() == addrOrPeerID
+	})
+	if len(c.P2pNode.BootstrapPeers) == oldLen {
+		return false
+	}
+	c.save()
+
+	return true
+}
+
+func (c *Config) SetDefaultBootstrapPeersDisabled(disabled bool) {
+	c.Lock()
+	c.P2pNode.DisableDefaultBootstrapPeers = disabled
+	c.save()
+	c.Unlock()
+}
+
 func (c *Config) SetListenAddresses(multiaddrs []multiaddr.Multiaddr) {
 	c.Lock()
 	result := make([]string, 0, len(multiaddrs))
@@ -313,15 +452,18 @@ func (c *Config) VPNLocalIPMask() (net.IP, net.IPMask) {
 	return localIP.To4(), ipNet.Mask
 }
 
-func (c *Config) DNSNamesMapping() map[string]string {
-	mapping := make(map[string]string)
+func (c *Config) DNSNamesMapping() map[string][]string {
+	mapping := make(map[string][]string)
 	c.RLock()
 	defer c.RUnlock()
 
 	for _, knownPeer := range c.KnownPeers {
-		mapping[knownPeer.PeerID] = knownPeer.IPAddr
+		if c.anyPeerGroup(knownPeer, func(group PeerGroup) bool { return group.HideFromDNS }) {
+			continue
+		}
+		mapping[knownPeer.PeerID] = []string{knownPeer.IPAddr}
 		if knownPeer.DomainName != "" {
-			mapping[knownPeer.DomainName] = knownPeer.IPAddr
+			mapping[knownPeer.DomainName] = []string{knownPeer.IPAddr}
 		}
 	}
 
@@ -418,6 +560,10 @@ func (kp KnownPeer) PeerId() peer.ID {
 	return peerID
 }
 
+func (kp KnownPeer) HasCapability(capability string) bool {
+	return slices.Contains(kp.Capabilities, capability)
+}
+
 func (kp KnownPeer) DisplayName() string {
 	name := kp.Name
 	if kp.Alias != "" {
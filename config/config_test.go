@@ -2,6 +2,8 @@ package config
 
 import (
 	"testing"
+
+	"github.com/libp2p/go-libp2p/p2p/host/eventbus"
 )
 
 func TestConfig_GetBootstrapPeers(t *testing.T) {
@@ -11,3 +13,28 @@ func TestConfig_GetBootstrapPeers(t *testing.T) {
 		t.Fatal()
 	}
 }
+
+func TestSetDefaultsRelayService(t *testing.T) {
+	cfg := &Config{}
+	cfg.P2pNode.RelayService = RelayServiceConfig{Enabled: true, LimitDataBytes: -1}
+	setDefaults(cfg, eventbus.NewBus())
+
+	relay := cfg.P2pNode.RelayService
+	if relay.MaxReservations != 32 || relay.LimitDurationSec != 60*60 || relay.LimitDataBytes != -1 {
+		t.Errorf("unexpected relay service defaults: %+v", relay)
+	}
+}
+
+func TestSetDefaultsFileTransfer(t *testing.T) {
+	cfg := &Config{}
+	setDefaults(cfg, eventbus.NewBus())
+	if !cfg.IsAutoAcceptAllowed(1<<30) || cfg.IsAutoAcceptAllowed(1<<30+1) {
+		t.Errorf("unexpected max auto accept size: %d", cfg.FileTransfer.MaxAutoAcceptBytes)
+	}
+
+	cfg.FileTransfer.MaxAutoAcceptBytes = -1
+	setDefaults(cfg, eventbus.NewBus())
+	if !cfg.IsAutoAcceptAllowed(1 << 40) {
+		t.Errorf("negative max auto accept size should mean no limit")
+	}
+}
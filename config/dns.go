@@ -0,0 +1,145 @@
+package config
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	"github.com/anywherelan/awl/awldns"
+	"github.com/anywherelan/awl/awlevent"
+)
+
+var ErrDNSForwardingRuleNotFound = errors.New("dns forwarding rule not found")
+
+// DNSConfig configures how awl DNS resolves names outside of awl zone.
+type DNSConfig struct {
+	// Upstreams are used in the given order with failover. Empty means nameservers of OS if awl DNS replaces them,
+	// otherwise awldns.DefaultUpstreamDNSAddress.
+	// Supported formats: "1.1.1.1", "1.1.1.1:53", "tls://1.1.1.1", "tls://dns.example.com:853", "https://cloudflare-dns.com/dns-query"
+	Upstreams []string `json:"upstreams"`
+	// CacheSize is the maximum number of cached responses, 0 means awldns.DefaultCacheSize, negative disables cache
+	CacheSize int `json:"cacheSize"`
+	// ForwardingRules send queries for private zones to peers or resolvers reachable through tunnel
+	ForwardingRules []DNSForwardingRule `json:"forwardingRules"`
+}
+
+// DNSForwardingRule forwards queries for Zone and its subdomains to resolver of peer over p2p, or to Upstream.
+// Zones are added to matching domains of OS DNS configuration.
+type DNSForwardingRule struct {
+	// Zone like "corp.internal", without trailing dot
+	Zone string `json:"zone"`
+	// PeerID of peer which resolves queries with its upstreams, it should allow it with KnownPeer AllowDNSQueries
+	PeerID string `json:"peerId,omitempty"`
+	// Upstream is address of resolver, e.g. "10.66.0.3" of peer in awl network, see awldns.ValidateUpstream
+	Upstream string `json:"upstream,omitempty"`
+}
+
+func (r DNSForwardingRule) Validate() error {
+	err := awldns.ValidateForwardingZone(r.Zone)
+	if err != nil {
+		return err
+	}
+	switch {
+	case r.PeerID != "" && r.Upstream != "":
+		return errors.New("either peer or upstream should be defined, not both")
+	case r.PeerID == "" && r.Upstream == "":
+		return errors.New("peer or upstream should be defined")
+	case r.Upstream != "":
+		return awldns.ValidateUpstream(r.Upstream)
+	}
+	return nil
+}
+
+func (c *Config) DNSUpstreams() []string {
+	c.RLock()
+	defer c.RUnlock()
+	return append([]string(nil), c.DNS.Upstreams...)
+}
+
+func (c *Config) DNSCacheSize() int {
+	c.RLock()
+	defer c.RUnlock()
+	switch {
+	case c.DNS.CacheSize == 0:
+		return awldns.DefaultCacheSize
+	case c.DNS.CacheSize < 0:
+		return 0
+	}
+	return c.DNS.CacheSize
+}
+
+func (c *Config) DNSForwardingRules() []DNSForwardingRule {
+	c.RLock()
+	defer c.RUnlock()
+	return append([]DNSForwardingRule(nil), c.DNS.ForwardingRules...)
+}
+
+// SetDNSForwardingRule adds rule or replaces existing rule for the same zone.
+func (c *Config) SetDNSForwardingRule(rule DNSForwardingRule) error {
+	rule.Zone = normalizeDNSZone(rule.Zone)
+	err := rule.Validate()
+	if err != nil {
+		return err
+	}
+
+	c.Lock()
+	if rule.PeerID != "" {
+		if _, exists := c.KnownPeers[rule.PeerID]; !exists {
+			c.Unlock()
+			return ErrPeerNotFound
+		}
+	}
+	index := slices.IndexFunc(c.DNS.ForwardingRules, func(existing DNSForwardingRule) bool {
+		return existing.Zone == rule.Zone
+	})
+	if index >= 0 {
+		c.DNS.ForwardingRules[index] = rule
+	} else {
+		c.DNS.ForwardingRules = append(c.DNS.ForwardingRules, rule)
+	}
+	c.save()
+	c.Unlock()
+
+	_ = c.emitter.Emit(awlevent.KnownPeerChanged{})
+	return nil
+}
+
+func (c *Config) RemoveDNSForwardingRule(zone string) error {
+	zone = normalizeDNSZone(zone)
+	c.Lock()
+	index := slices.IndexFunc(c.DNS.ForwardingRules, func(existing DNSForwardingRule) bool {
+		return existing.Zone == zone
+	})
+	if index < 0 {
+		c.Unlock()
+		return ErrDNSForwardingRuleNotFound
+	}
+	c.DNS.ForwardingRules = slices.Delete(c.DNS.ForwardingRules, index, index+1)
+	c.save()
+	c.Unlock()
+
+	_ = c.emitter.Emit(awlevent.KnownPeerChanged{})
+	return nil
+}
+
+func (c *Config) SetDNSQueriesAllowed(peerID string, allow bool) error {
+	c.Lock()
+	knownPeer, exists := c.KnownPeers[peerID]
+	if !exists {
+		c.Unlock()
+		return ErrPeerNotFound
+	}
+	knownPeer.AllowDNSQueries = allow
+	c.KnownPeers[peerID] = knownPeer
+	c.save()
+	c.Unlock()
+
+	_ = c.emitter.Emit(awlevent.KnownPeerChanged{})
+	return nil
+}
+
+func normalizeDNSZone(zone string) string {
+	return strings.TrimSuffix(dns.CanonicalName(strings.TrimSpace(zone)), ".")
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/miekg/dns"
+
+	"github.com/anywherelan/awl/awldns"
+	"github.com/anywherelan/awl/awlevent"
+)
+
+const (
+	DNSRecordTXT = "TXT"
+	DNSRecordSRV = "SRV"
+)
+
+// DNSRecord is user defined record in awl DNS under domain of peer.
+type DNSRecord struct {
+	Type string `json:"type" enums:"TXT,SRV"`
+	// Name is prepended to domain of peer, e.g. "_http._tcp" for SRV record. Empty means domain of peer itself
+	Name string `json:"name"`
+	// Text of TXT record
+	Text string `json:"text,omitempty"`
+	// Priority, Weight, Port and Target are used by SRV record
+	Priority uint16 `json:"priority,omitempty"`
+	Weight   uint16 `json:"weight,omitempty"`
+	Port     uint16 `json:"port,omitempty"`
+	// Target is domain name inside awl zone without suffix, or fully qualified name with trailing dot.
+	// Empty means domain of peer
+	Target string `json:"target,omitempty"`
+}
+
+func (r DNSRecord) Validate() error {
+	if r.Name != "" {
+		if _, ok := dns.IsDomainName(r.Name + ".peer." + awldns.LocalDomain); !ok {
+			return fmt.Errorf("invalid record name %q", r.Name)
+		}
+	}
+	switch r.Type {
+	case DNSRecordTXT:
+		if r.Text == "" {
+			return errors.New("TXT record text is empty")
+		}
+	case DNSRecordSRV:
+		if r.Name == "" {
+			return errors.New("SRV record name is empty, it should be like _service._proto")
+		}
+		if r.Port == 0 {
+			return errors.New("SRV record port is empty")
+		}
+		if r.Target != "" {
+			if _, ok := dns.IsDomainName(r.Target); !ok {
+				return fmt.Errorf("invalid SRV record target %q", r.Target)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported record type %q", r.Type)
+	}
+	return nil
+}
+
+// SetDNSRecords replaces user defined DNS records of peer.
+func (c *Config) SetDNSRecords(peerID string, records []DNSRecord) error {
+	for _, record := range records {
+		err := record.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	c.Lock()
+	knownPeer, exists := c.KnownPeers[peerID]
+	if !exists {
+		c.Unlock()
+		return ErrPeerNotFound
+	}
+	knownPeer.DNSRecords = records
+	c.KnownPeers[peerID] = knownPeer
+	c.save()
+	c.Unlock()
+
+	_ = c.emitter.Emit(awlevent.KnownPeerChanged{})
+	return nil
+}
+
+// DNSRecords returns user defined records of peers which are visible in awl DNS.
+func (c *Config) DNSRecords() []awldns.Record {
+	c.RLock()
+	defer c.RUnlock()
+
+	result := make([]awldns.Record, 0)
+	for _, knownPeer := range c.KnownPeers {
+		if knownPeer.DomainName == "" || c.anyPeerGroup(knownPeer, func(group PeerGroup) bool { return group.HideFromDNS }) {
+			continue
+		}
+		for _, record := range knownPeer.DNSRecords {
+			name := knownPeer.DomainName
+			if record.Name != "" {
+				name = record.Name + "." + name
+			}
+			switch record.Type {
+			case DNSRecordTXT:
+				result = append(result, awldns.Record{Name: name, Type: dns.TypeTXT, Text: record.Text})
+			case DNSRecordSRV:
+				target := record.Target
+				if target == "" {
+					target = knownPeer.DomainName
+				}
+				result = append(result, awldns.Record{
+					Name:     name,
+					Type:     dns.TypeSRV,
+					Priority: record.Priority,
+					Weight:   record.Weight,
+					Port:     record.Port,
+					Target:   target,
+				})
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/libp2p/go-libp2p/p2p/host/eventbus"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anywherelan/awl/awldns"
+)
+
+func TestDNSRecords(t *testing.T) {
+	a := require.New(t)
+	conf := NewConfig(eventbus.NewBus())
+	conf.dataDir = t.TempDir()
+	conf.KnownPeers["peer1"] = KnownPeer{PeerID: "peer1", IPAddr: "10.66.0.2", DomainName: "laptop"}
+
+	a.ErrorIs(conf.SetDNSRecords("unknown", nil), ErrPeerNotFound)
+	a.Error(conf.SetDNSRecords("peer1", []DNSRecord{{Type: "MX", Text: "mail"}}))
+	a.Error(conf.SetDNSRecords("peer1", []DNSRecord{{Type: DNSRecordTXT}}))
+	a.Error(conf.SetDNSRecords("peer1", []DNSRecord{{Type: DNSRecordSRV, Port: 80}}))
+	a.Error(conf.SetDNSRecords("peer1", []DNSRecord{{Type: DNSRecordSRV, Name: "_http._tcp"}}))
+
+	a.NoError(conf.SetDNSRecords("peer1", []DNSRecord{
+		{Type: DNSRecordTXT, Text: "hello"},
+		{Type: DNSRecordSRV, Name: "_http._tcp", Port: 8080},
+	}))
+	a.ElementsMatch([]awldns.Record{
+		{Name: "laptop", Type: dns.TypeTXT, Text: "hello"},
+		{Name: "_http._tcp.laptop", Type: dns.TypeSRV, Port: 8080, Target: "laptop"},
+	}, conf.DNSRecords())
+
+	conf.UpsertGroup(PeerGroup{Name: "hidden", HideFromDNS: true})
+	a.NoError(conf.UpdateGroupPeers("hidden", []string{"peer1"}, nil))
+	a.Empty(conf.DNSRecords())
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/libp2p/go-libp2p/p2p/host/eventbus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNSForwardingRules(t *testing.T) {
+	a := require.New(t)
+	conf := NewConfig(eventbus.NewBus())
+	conf.dataDir = t.TempDir()
+	conf.KnownPeers["peer1"] = KnownPeer{PeerID: "peer1", IPAddr: "10.66.0.2", DomainName: "office"}
+
+	a.Error(conf.SetDNSForwardingRule(DNSForwardingRule{Zone: "corp.internal"}))
+	a.Error(conf.SetDNSForwardingRule(DNSForwardingRule{Zone: "corp.internal", PeerID: "peer1", Upstream: "10.66.0.2"}))
+	a.Error(conf.SetDNSForwardingRule(DNSForwardingRule{Zone: "office.awl", PeerID: "peer1"}))
+	a.Error(conf.SetDNSForwardingRule(DNSForwardingRule{Zone: "corp.internal", Upstream: "quic://10.66.0.2"}))
+	a.ErrorIs(conf.SetDNSForwardingRule(DNSForwardingRule{Zone: "corp.internal", PeerID: "unknown"}), ErrPeerNotFound)
+
+	a.NoError(conf.SetDNSForwardingRule(DNSForwardingRule{Zone: "Corp.Internal.", PeerID: "peer1"}))
+	a.NoError(conf.SetDNSForwardingRule(DNSForwardingRule{Zone: "lab.local", Upstream: "10.66.0.2"}))
+	a.Equal([]DNSForwardingRule{
+		{Zone: "corp.internal", PeerID: "peer1"},
+		{Zone: "lab.local", Upstream: "10.66.0.2"},
+	}, conf.DNSForwardingRules())
+
+	// rule for the same zone is replaced
+	a.NoError(conf.SetDNSForwardingRule(DNSForwardingRule{Zone: "corp.internal", Upstream: "tls://10.66.0.2"}))
+	a.Equal(DNSForwardingRule{Zone: "corp.internal", Upstream: "tls://10.66.0.2"}, conf.DNSForwardingRules()[0])
+
+	a.ErrorIs(conf.RemoveDNSForwardingRule("unknown.zone"), ErrDNSForwardingRuleNotFound)
+	a.NoError(conf.RemoveDNSForwardingRule("corp.internal."))
+	a.Equal([]DNSForwardingRule{{Zone: "lab.local", Upstream: "10.66.0.2"}}, conf.DNSForwardingRules())
+
+	a.ErrorIs(conf.SetDNSQueriesAllowed("unknown", true), ErrPeerNotFound)
+	a.NoError(conf.SetDNSQueriesAllowed("peer1", true))
+	knownPeer, _ := conf.GetPeer("peer1")
+	a.True(knownPeer.AllowDNSQueries)
+}
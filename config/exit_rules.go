@@ -0,0 +1,34 @@
+package config
+
+import (
+	"github.com/anywherelan/awl/awlevent"
+)
+
+// ExitNodeRules restrict destinations which peer can reach using this device as exit node.
+// Private networks (RFC1918, link-local) and our vpn subnet are denied unless they are in AllowedCIDRs.
+type ExitNodeRules struct {
+	AllowedCIDRs []string `json:"allowedCidrs,omitempty"`
+	DeniedCIDRs  []string `json:"deniedCidrs,omitempty"`
+	// Domain patterns: "example.com" or "*.example.com" for subdomains
+	AllowedDomains []string `json:"allowedDomains,omitempty"`
+	DeniedDomains  []string `json:"deniedDomains,omitempty"`
+	// Ports: "443" or "8000-9000"
+	AllowedPorts []string `json:"allowedPorts,omitempty"`
+	DeniedPorts  []string `json:"deniedPorts,omitempty"`
+}
+
+func (c *Config) SetExitNodeRules(peerID string, rules ExitNodeRules) error {
+	c.Lock()
+	knownPeer, exists := c.KnownPeers[peerID]
+	if !exists {
+		c.Unlock()
+		return ErrPeerNotFound
+	}
+	knownPeer.ExitNodeRules = rules
+	c.KnownPeers[peerID] = knownPeer
+	c.save()
+	c.Unlock()
+
+	_ = c.emitter.Emit(awlevent.KnownPeerChanged{})
+	return nil
+}
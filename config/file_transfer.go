@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/anywherelan/awl/awlevent"
+)
+
+// FileReceivePolicy decides what to do with files offered by peer.
+type FileReceivePolicy string
+
+const (
+	// FileReceivePolicyAsk keeps offer pending until user accepts or declines it
+	FileReceivePolicyAsk FileReceivePolicy = "ask"
+	// FileReceivePolicyAuto accepts files into FileTransferConfig DownloadDir,
+	// files bigger than FileTransferConfig MaxAutoAcceptBytes are asked for as with FileReceivePolicyAsk
+	FileReceivePolicyAuto FileReceivePolicy = "auto"
+	// FileReceivePolicyDeny declines all files
+	FileReceivePolicyDeny FileReceivePolicy = "deny"
+
+	defaultDownloadDirectory = "downloads"
+)
+
+type FileTransferConfig struct {
+	// DownloadDir for received files, directory "downloads" inside data dir is used if empty
+	DownloadDir string `json:"downloadDir"`
+	// MaxAutoAcceptBytes is the maximum size of file accepted with FileReceivePolicyAuto, default is 1 GiB,
+	// negative value means no limit
+	MaxAutoAcceptBytes int64 `json:"maxAutoAcceptBytes"`
+}
+
+func (p FileReceivePolicy) IsValid() bool {
+	switch p {
+	case "", FileReceivePolicyAsk, FileReceivePolicyAuto, FileReceivePolicyDeny:
+		return true
+	}
+	return false
+}
+
+func (kp KnownPeer) GetFileReceivePolicy() FileReceivePolicy {
+	if kp.FileReceivePolicy == "" {
+		return FileReceivePolicyAsk
+	}
+	return kp.FileReceivePolicy
+}
+
+func (c *Config) DownloadDir() string {
+	c.RLock()
+	defer c.RUnlock()
+	if c.FileTransfer.DownloadDir != "" {
+		return c.FileTransfer.DownloadDir
+	}
+	return filepath.Join(c.dataDir, defaultDownloadDirectory)
+}
+
+// IsAutoAcceptAllowed reports whether file of given size can be accepted without asking user.
+func (c *Config) IsAutoAcceptAllowed(size int64) bool {
+	c.RLock()
+	defer c.RUnlock()
+	maxSize := c.FileTransfer.MaxAutoAcceptBytes
+	return maxSize < 0 || size <= maxSize
+}
+
+func (c *Config) SetFileReceivePolicy(peerID string, policy FileReceivePolicy) error {
+	if !policy.IsValid() {
+		return fmt.Errorf("invalid file receive policy %q", policy)
+	}
+	c.Lock()
+	knownPeer, exists := c.KnownPeers[peerID]
+	if !exists {
+		c.Unlock()
+		return ErrPeerNotFound
+	}
+	knownPeer.FileReceivePolicy = policy
+	c.KnownPeers[peerID] = knownPeer
+	c.save()
+	c.Unlock()
+
+	_ = c.emitter.Emit(awlevent.KnownPeerChanged{})
+	return nil
+}
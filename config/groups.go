@@ -0,0 +1,204 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/anywherelan/awl/awlevent"
+)
+
+var (
+	ErrGroupNotFound = errors.New("group not found")
+
+	groupNameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+)
+
+// PeerGroup is a named set of known peers, its policy applies to all peers in group in addition to their own settings.
+// Groups are also used as tags: peer can be in any number of groups and group without policy is just a label.
+type PeerGroup struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"createdAt"`
+	// AllowUsingAsExitNode allows all peers in group to use this device as exit node
+	AllowUsingAsExitNode bool `json:"allowUsingAsExitNode"`
+	// HideFromDNS excludes peers in group from awl DNS, they are reachable only by ip address
+	HideFromDNS bool `json:"hideFromDNS"`
+	// TrustIntroductions accepts peers introduced by peers in group automatically, see KnownPeer TrustIntroductions
+	TrustIntroductions bool `json:"trustIntroductions"`
+	// BlockInboundAccess drops tunnel traffic from peers in group and rejects their requests to our services,
+	// e.g. exit node. It takes precedence over permissions of peers and other groups
+	BlockInboundAccess bool `json:"blockInboundAccess"`
+}
+
+// IsValidGroupName allows lowercase letters, digits, '-' and '_', e.g. "ops", "ci-runners".
+func IsValidGroupName(name string) bool {
+	return groupNameRegexp.MatchString(name)
+}
+
+func (kp KnownPeer) InGroup(name string) bool {
+	return slices.Contains(kp.Groups, name)
+}
+
+// ListGroups returns groups sorted by name.
+func (c *Config) ListGroups() []PeerGroup {
+	c.RLock()
+	groups := make([]PeerGroup, 0, len(c.Groups))
+	for _, group := range c.Groups {
+		groups = append(groups, group)
+	}
+	c.RUnlock()
+
+	slices.SortFunc(groups, func(a, b PeerGroup) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return groups
+}
+
+func (c *Config) GetGroup(name string) (PeerGroup, bool) {
+	c.RLock()
+	group, ok := c.Groups[name]
+	c.RUnlock()
+	return group, ok
+}
+
+// UpsertGroup creates group or updates its description and policy.
+func (c *Config) UpsertGroup(group PeerGroup) {
+	c.Lock()
+	if existing, exists := c.Groups[group.Name]; exists {
+		group.CreatedAt = existing.CreatedAt
+	} else {
+		group.CreatedAt = time.Now()
+	}
+	c.Groups[group.Name] = group
+	c.save()
+	c.Unlock()
+
+	_ = c.emitter.Emit(awlevent.KnownPeerChanged{})
+}
+
+// RemoveGroup removes group and excludes all peers from it.
+func (c *Config) RemoveGroup(name string) bool {
+	c.Lock()
+	_, exists := c.Groups[name]
+	if exists {
+		delete(c.Groups, name)
+		for peerID, knownPeer := range c.KnownPeers {
+			if knownPeer.InGroup(name) {
+				knownPeer.Groups = slices.DeleteFunc(knownPeer.Groups, func(val string) bool { return val == name })
+				c.KnownPeers[peerID] = knownPeer
+			}
+		}
+		c.save()
+	}
+	c.Unlock()
+
+	if exists {
+		_ = c.emitter.Emit(awlevent.KnownPeerChanged{})
+	}
+
+	return exists
+}
+
+// UpdateGroupPeers adds peers to group and removes peers from it. All peers should be known.
+func (c *Config) UpdateGroupPeers(name string, addPeerIDs, removePeerIDs []string) error {
+	err := c.updateGroupPeers(name, addPeerIDs, removePeerIDs)
+	if err != nil {
+		return err
+	}
+	_ = c.emitter.Emit(awlevent.KnownPeerChanged{})
+
+	return nil
+}
+
+func (c *Config) updateGroupPeers(name string, addPeerIDs, removePeerIDs []string) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if _, exists := c.Groups[name]; !exists {
+		return ErrGroupNotFound
+	}
+	for _, peerID := range slices.Concat(addPeerIDs, removePeerIDs) {
+		if _, exists := c.KnownPeers[peerID]; !exists {
+			return fmt.Errorf("peer %s not found", peerID)
+		}
+	}
+
+	for _, peerID := range addPeerIDs {
+		knownPeer := c.KnownPeers[peerID]
+		if !knownPeer.InGroup(name) {
+			knownPeer.Groups = append(knownPeer.Groups, name)
+			slices.Sort(knownPeer.Groups)
+		}
+		c.KnownPeers[peerID] = knownPeer
+	}
+	for _, peerID := range removePeerIDs {
+		knownPeer := c.KnownPeers[peerID]
+		knownPeer.Groups = slices.DeleteFunc(knownPeer.Groups, func(val string) bool { return val == name })
+		c.KnownPeers[peerID] = knownPeer
+	}
+	c.save()
+
+	return nil
+}
+
+// GroupPeers returns ids of peers in group.
+func (c *Config) GroupPeers(name string) []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	peers := make([]string, 0)
+	for peerID, knownPeer := range c.KnownPeers {
+		if knownPeer.InGroup(name) {
+			peers = append(peers, peerID)
+		}
+	}
+	slices.Sort(peers)
+
+	return peers
+}
+
+// IsExitNodeAllowed reports whether peer is allowed to use this device as exit node by its own settings or by any of its groups.
+func (c *Config) IsExitNodeAllowed(knownPeer KnownPeer) bool {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.anyPeerGroup(knownPeer, func(group PeerGroup) bool { return group.BlockInboundAccess }) {
+		return false
+	}
+	return knownPeer.WeAllowUsingAsExitNode || c.anyPeerGroup(knownPeer, func(group PeerGroup) bool { return group.AllowUsingAsExitNode })
+}
+
+// IsInboundAllowed reports whether peer can send traffic to us and use our services, it's blocked by PeerGroup BlockInboundAccess.
+func (c *Config) IsInboundAllowed(knownPeer KnownPeer) bool {
+	c.RLock()
+	defer c.RUnlock()
+
+	return !c.anyPeerGroup(knownPeer, func(group PeerGroup) bool { return group.BlockInboundAccess })
+}
+
+// IsIntroductionTrusted reports whether peers introduced by knownPeer are accepted automatically
+// by its own TrustIntroductions or by any of its groups.
+func (c *Config) IsIntroductionTrusted(knownPeer KnownPeer) bool {
+	if knownPeer.TrustIntroductions {
+		return true
+	}
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.anyPeerGroup(knownPeer, func(group PeerGroup) bool { return group.TrustIntroductions })
+}
+
+func (c *Config) anyPeerGroup(knownPeer KnownPeer, policy func(group PeerGroup) bool) bool {
+	for _, name := range knownPeer.Groups {
+		group, exists := c.Groups[name]
+		if exists && policy(group) {
+			return true
+		}
+	}
+	return false
+}
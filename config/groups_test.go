@@ -0,0 +1,69 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/libp2p/go-libp2p/p2p/host/eventbus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerGroups(t *testing.T) {
+	a := require.New(t)
+	conf := NewConfig(eventbus.NewBus())
+	conf.dataDir = t.TempDir()
+	conf.KnownPeers["peer1"] = KnownPeer{PeerID: "peer1", IPAddr: "10.66.0.2", DomainName: "peer1"}
+	conf.KnownPeers["peer2"] = KnownPeer{PeerID: "peer2", IPAddr: "10.66.0.3", DomainName: "peer2"}
+
+	a.True(IsValidGroupName("ci-runners"))
+	a.False(IsValidGroupName("Ops"))
+	a.False(IsValidGroupName(""))
+
+	a.ErrorIs(conf.UpdateGroupPeers("ops", []string{"peer1"}, nil), ErrGroupNotFound)
+	conf.UpsertGroup(PeerGroup{Name: "ops", AllowUsingAsExitNode: true})
+	conf.UpsertGroup(PeerGroup{Name: "hidden", HideFromDNS: true})
+	a.Error(conf.UpdateGroupPeers("ops", []string{"unknown"}, nil))
+	a.NoError(conf.UpdateGroupPeers("ops", []string{"peer1", "peer2"}, nil))
+	a.NoError(conf.UpdateGroupPeers("hidden", []string{"peer2"}, nil))
+	a.Equal([]string{"peer1", "peer2"}, conf.GroupPeers("ops"))
+
+	peer1, _ := conf.GetPeer("peer1")
+	a.True(conf.IsExitNodeAllowed(peer1))
+	mapping := conf.DNSNamesMapping()
+	a.Contains(mapping, "peer1")
+	a.NotContains(mapping, "peer2")
+
+	a.NoError(conf.UpdateGroupPeers("ops", nil, []string{"peer1"}))
+	peer1, _ = conf.GetPeer("peer1")
+	a.False(conf.IsExitNodeAllowed(peer1))
+	a.Empty(peer1.Groups)
+
+	conf.UpsertGroup(PeerGroup{Name: "ops", AllowUsingAsExitNode: true})
+	conf.UpsertGroup(PeerGroup{Name: "guests", BlockInboundAccess: true})
+	a.NoError(conf.UpdateGroupPeers("ops", []string{"peer1"}, nil))
+	peer1, _ = conf.GetPeer("peer1")
+	a.True(conf.IsExitNodeAllowed(peer1))
+	a.True(conf.IsInboundAllowed(peer1))
+	a.NoError(conf.UpdateGroupPeers("guests", []string{"peer1"}, nil))
+	peer1, _ = conf.GetPeer("peer1")
+	a.False(conf.IsInboundAllowed(peer1))
+	a.False(conf.IsExitNodeAllowed(peer1))
+	a.NoError(conf.UpdateGroupPeers("ops", nil, []string{"peer1"}))
+	a.True(conf.RemoveGroup("guests"))
+	peer1, _ = conf.GetPeer("peer1")
+	a.False(conf.IsExitNodeAllowed(peer1))
+	a.True(conf.IsInboundAllowed(peer1))
+
+	conf.UpsertGroup(PeerGroup{Name: "trusted", TrustIntroductions: true})
+	a.False(conf.IsIntroductionTrusted(peer1))
+	a.NoError(conf.UpdateGroupPeers("trusted", []string{"peer1"}, nil))
+	peer1, _ = conf.GetPeer("peer1")
+	a.True(conf.IsIntroductionTrusted(peer1))
+	a.True(conf.RemoveGroup("trusted"))
+
+	a.True(conf.RemoveGroup("hidden"))
+	a.False(conf.RemoveGroup("hidden"))
+	peer2, _ := conf.GetPeer("peer2")
+	a.Equal([]string{"ops"}, peer2.Groups)
+	a.Contains(conf.DNSNamesMapping(), "peer2")
+	a.Len(conf.ListGroups(), 1)
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"slices"
+)
+
+// Hook delivers events to webhook URL or local executable.
+// Exactly one of URL or Command should be set.
+type Hook struct {
+	Name string `json:"name"`
+	// Event types to deliver, all events if empty. See entity.EventTypes
+	Events []string `json:"events,omitempty"`
+	// URL receives event as JSON in POST request body
+	URL string `json:"url,omitempty"`
+	// Secret is used to sign webhook body with HMAC-SHA256
+	Secret string `json:"secret,omitempty"`
+	// Command is executable which gets event in environment variables
+	Command string   `json:"command,omitempty"`
+	Args    []string `json:"args,omitempty"`
+	// MaxRetries is number of retries after failed delivery, 0 means default
+	MaxRetries int `json:"maxRetries,omitempty"`
+}
+
+func (h Hook) Subscribed(eventType string) bool {
+	return len(h.Events) == 0 || slices.Contains(h.Events, eventType)
+}
+
+func (c *Config) GetHooks() []Hook {
+	c.RLock()
+	hooks := slices.Clone(c.Hooks)
+	c.RUnlock()
+	return hooks
+}
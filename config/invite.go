@@ -0,0 +1,91 @@
+package config
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+var (
+	ErrInviteNotFound   = errors.New("invite not found or revoked")
+	ErrInviteExpired    = errors.New("invite has expired")
+	ErrInviteUsedUp     = errors.New("invite has reached max uses")
+	ErrInviteUsedByPeer = errors.New("invite has already been used by this peer")
+)
+
+// Invite is issued by us, peers presenting a valid token of it are accepted automatically.
+type Invite struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	// MaxUses is the number of peers which can be accepted with invite, 0 means unlimited
+	MaxUses int      `json:"maxUses"`
+	UsedBy  []string `json:"usedBy"`
+	// AllowUsingAsExitNode is granted to peers accepted with invite
+	AllowUsingAsExitNode bool `json:"allowUsingAsExitNode"`
+	// Groups which peers accepted with invite are added to
+	Groups []string `json:"groups"`
+}
+
+func (i Invite) Expired(now time.Time) bool {
+	return now.After(i.ExpiresAt)
+}
+
+func (c *Config) AddInvite(invite Invite) {
+	c.Lock()
+	c.Invites[invite.ID] = invite
+	c.save()
+	c.Unlock()
+}
+
+// ListInvites returns issued invites sorted from the newest to the oldest.
+func (c *Config) ListInvites() []Invite {
+	c.RLock()
+	invites := make([]Invite, 0, len(c.Invites))
+	for _, invite := range c.Invites {
+		invites = append(invites, invite)
+	}
+	c.RUnlock()
+
+	slices.SortFunc(invites, func(a, b Invite) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+
+	return invites
+}
+
+func (c *Config) RevokeInvite(id string) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	_, exists := c.Invites[id]
+	if exists {
+		delete(c.Invites, id)
+		c.save()
+	}
+
+	return exists
+}
+
+// UseInvite checks that invite is still valid and records its usage by peerID.
+func (c *Config) UseInvite(id, peerID string, now time.Time) (Invite, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	invite, exists := c.Invites[id]
+	switch {
+	case !exists:
+		return Invite{}, ErrInviteNotFound
+	case invite.Expired(now):
+		return Invite{}, ErrInviteExpired
+	case slices.Contains(invite.UsedBy, peerID):
+		return Invite{}, ErrInviteUsedByPeer
+	case invite.MaxUses != 0 && len(invite.UsedBy) >= invite.MaxUses:
+		return Invite{}, ErrInviteUsedUp
+	}
+	invite.UsedBy = append(invite.UsedBy, peerID)
+	c.Invites[id] = invite
+	c.save()
+
+	return invite, nil
+}
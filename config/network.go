@@ -0,0 +1,119 @@
+package config
+
+import (
+	"slices"
+	"time"
+)
+
+// NetworkConfig is an optional organization which peer is a member of.
+// Members present certificates signed by network admins and accept each other automatically.
+type NetworkConfig struct {
+	// Name is empty if peer is not a member of any network
+	Name string `json:"name"`
+	// AdminKeys are peer ids of admins, only certificates and revocation lists signed by them are trusted
+	AdminKeys []string `json:"adminKeys"`
+	// Certificate is our membership certificate
+	Certificate string `json:"certificate"`
+	// RevocationList is the latest signed revocation list, it's distributed to other members
+	RevocationList         string    `json:"revocationList"`
+	RevocationListIssuedAt time.Time `json:"revocationListIssuedAt"`
+	RevokedPeerIDs         []string  `json:"revokedPeerIDs"`
+	// AdminList is the latest signed list of admins, it replaces AdminKeys and is distributed to other members
+	AdminList         string    `json:"adminList"`
+	AdminListIssuedAt time.Time `json:"adminListIssuedAt"`
+}
+
+func (n NetworkConfig) Enabled() bool {
+	return n.Name != ""
+}
+
+func (n NetworkConfig) IsAdmin(peerID string) bool {
+	return slices.Contains(n.AdminKeys, peerID)
+}
+
+func (n NetworkConfig) IsRevoked(peerID string) bool {
+	return slices.Contains(n.RevokedPeerIDs, peerID)
+}
+
+func (c *Config) GetNetwork() NetworkConfig {
+	c.RLock()
+	defer c.RUnlock()
+
+	network := c.Network
+	network.AdminKeys = slices.Clone(network.AdminKeys)
+	network.RevokedPeerIDs = slices.Clone(network.RevokedPeerIDs)
+
+	return network
+}
+
+// SetNetwork replaces network membership, empty NetworkConfig means leaving network.
+func (c *Config) SetNetwork(network NetworkConfig) {
+	c.Lock()
+	c.Network = network
+	c.save()
+	c.Unlock()
+}
+
+// UpdateRevocationList stores revocation list if it's newer than the current one.
+func (c *Config) UpdateRevocationList(raw string, issuedAt time.Time, revokedPeerIDs []string) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	if !issuedAt.After(c.Network.RevocationListIssuedAt) {
+		return false
+	}
+	c.Network.RevocationList = raw
+	c.Network.RevocationListIssuedAt = issuedAt
+	c.Network.RevokedPeerIDs = revokedPeerIDs
+	c.save()
+
+	return true
+}
+
+// UpdateAdminList stores admin list and replaces admins if it's newer than the current one.
+func (c *Config) UpdateAdminList(raw string, issuedAt time.Time, admins []string) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	if !issuedAt.After(c.Network.AdminListIssuedAt) {
+		return false
+	}
+	c.Network.AdminList = raw
+	c.Network.AdminListIssuedAt = issuedAt
+	c.Network.AdminKeys = admins
+	c.save()
+
+	return true
+}
+
+// NetworkMemberCertificates returns certificates of known peers which joined us as network members.
+func (c *Config) NetworkMemberCertificates() []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	certs := make([]string, 0)
+	for _, knownPeer := range c.KnownPeers {
+		if knownPeer.Certificate != "" && !c.Network.IsRevoked(knownPeer.PeerID) {
+			certs = append(certs, knownPeer.Certificate)
+		}
+	}
+	slices.Sort(certs)
+
+	return certs
+}
+
+// NetworkMembers returns ids of known peers which were accepted as network members.
+func (c *Config) NetworkMembers() []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	peers := make([]string, 0)
+	for peerID, knownPeer := range c.KnownPeers {
+		if knownPeer.Certificate != "" {
+			peers = append(peers, peerID)
+		}
+	}
+	slices.Sort(peers)
+
+	return peers
+}
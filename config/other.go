@@ -3,13 +3,16 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
 	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strconv"
+	"time"
 
 	"github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/libp2p/go-libp2p/p2p/host/eventbus"
 	"github.com/multiformats/go-multiaddr"
 
@@ -48,6 +51,16 @@ func init() {
 	}
 }
 
+func IsDefaultBootstrapPeer(peerID peer.ID) bool {
+	for _, addr := range DefaultBootstrapPeers {
+		addrInfo, err := peer.AddrInfoFromP2pAddr(addr)
+		if err == nil && addrInfo.ID == peerID {
+			return true
+		}
+	}
+	return false
+}
+
 func CalcAppDataDir() string {
 	if envDir := os.Getenv(AppDataDirEnvKey); envDir != "" {
 		err := os.MkdirAll(envDir, dirsPerm)
@@ -147,6 +160,25 @@ func setDefaults(conf *Config, bus awlevent.Bus) {
 	if conf.P2pNode.ParallelSendingStreamsCount == 0 {
 		conf.P2pNode.ParallelSendingStreamsCount = 1
 	}
+	if transports := conf.P2pNode.Transports; !transports.TCP.Enabled && !transports.QUIC.Enabled &&
+		!transports.WebSocket.Enabled && !transports.WebTransport.Enabled {
+		conf.P2pNode.Transports.TCP.Enabled = true
+		conf.P2pNode.Transports.QUIC.Enabled = true
+	}
+	if conf.P2pNode.RelayService.MaxReservations == 0 {
+		conf.P2pNode.RelayService.MaxReservations = 32
+	}
+	if conf.P2pNode.RelayService.LimitDurationSec == 0 {
+		conf.P2pNode.RelayService.LimitDurationSec = 60 * 60
+	}
+	if conf.P2pNode.RelayService.LimitDataBytes == 0 {
+		conf.P2pNode.RelayService.LimitDataBytes = 1 << 30
+	}
+
+	// FileTransfer
+	if conf.FileTransfer.MaxAutoAcceptBytes == 0 {
+		conf.FileTransfer.MaxAutoAcceptBytes = 1 << 30
+	}
 
 	// Other
 	if conf.LoggerLevel == "" {
@@ -204,6 +236,26 @@ func setDefaults(conf *Config, bus awlevent.Bus) {
 	if conf.BlockedPeers == nil {
 		conf.BlockedPeers = make(map[string]BlockedPeer)
 	}
+	if conf.Groups == nil {
+		conf.Groups = make(map[string]PeerGroup)
+	}
+	if conf.KeyRotations == nil {
+		conf.KeyRotations = make(map[string]KeyRotation)
+	}
+	if conf.Invites == nil {
+		conf.Invites = make(map[string]Invite)
+	}
+	if conf.IngoingAuthRequests == nil {
+		conf.IngoingAuthRequests = make(map[string]IngoingAuthRequest)
+	}
+	if conf.Hooks == nil {
+		conf.Hooks = make([]Hook, 0)
+	}
+	now := time.Now()
+	maps.DeleteFunc(conf.Invites, func(_ string, invite Invite) bool {
+		return invite.Expired(now)
+	})
+	conf.deleteExpiredAuthRequests(now)
 
 	if conf.dataDir == "" {
 		conf.dataDir = CalcAppDataDir()
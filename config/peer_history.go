@@ -0,0 +1,237 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	PeerHistoryFilename = "peers_history.json"
+
+	// MaxPeerHistoryEvents is the maximum number of connection events stored for each peer.
+	MaxPeerHistoryEvents = 100
+)
+
+const (
+	DisconnectReasonClosed          = "closed"
+	DisconnectReasonShutdown        = "shutdown"
+	DisconnectReasonUncleanShutdown = "unclean shutdown"
+)
+
+type (
+	PeerConnectionEvent struct {
+		// ConnID identifies connection within current run, it is used to match disconnect with connect
+		ConnID         string    `json:"-"`
+		ConnectedAt    time.Time `json:"connectedAt"`
+		DisconnectedAt time.Time `json:"disconnectedAt"`
+		Direction      string    `json:"direction"`
+		RemoteAddress  string    `json:"remoteAddress"`
+		ThroughRelay   bool      `json:"throughRelay"`
+		RelayPeerID    string    `json:"relayPeerId,omitempty"`
+		Transport      string    `json:"transport"`
+		// DisconnectReason is empty for connections which are still open
+		DisconnectReason string `json:"disconnectReason,omitempty"`
+	}
+
+	// PeerHistory stores bounded connection history for known peers.
+	// It is persisted in a separate file to keep config small.
+	PeerHistory struct {
+		lock  sync.RWMutex
+		path  string
+		dirty bool
+		peers map[string][]PeerConnectionEvent
+	}
+)
+
+func NewPeerHistory(dataDir string) *PeerHistory {
+	return &PeerHistory{
+		path:  filepath.Join(dataDir, PeerHistoryFilename),
+		peers: make(map[string][]PeerConnectionEvent),
+	}
+}
+
+// LoadPeerHistory reads history from data directory.
+// Connections which were not closed (e.g. app crashed) are marked as closed at the time of the last save.
+func LoadPeerHistory(dataDir string) *PeerHistory {
+	history := NewPeerHistory(dataDir)
+
+	stat, err := os.Stat(history.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return history
+	} else if err != nil {
+		logger.Warnf("stat peer history file: %v", err)
+		return history
+	}
+	data, err := os.ReadFile(history.path)
+	if err != nil {
+		logger.Warnf("read peer history file: %v", err)
+		return history
+	}
+	peers := make(map[string][]PeerConnectionEvent)
+	err = json.Unmarshal(data, &peers)
+	if err != nil {
+		logger.Warnf("invalid peer history file, starting from scratch: %v", err)
+		return history
+	}
+
+	for peerID, events := range peers {
+		for i := range events {
+			if events[i].DisconnectedAt.IsZero() {
+				events[i].DisconnectedAt = stat.ModTime()
+				events[i].DisconnectReason = DisconnectReasonUncleanShutdown
+			}
+		}
+		history.peers[peerID] = events
+	}
+
+	return history
+}
+
+// AddConnected does nothing if open connection with the same ConnID already exists.
+func (h *PeerHistory) AddConnected(peerID string, event PeerConnectionEvent) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for _, existing := range h.peers[peerID] {
+		if existing.ConnID == event.ConnID && existing.DisconnectedAt.IsZero() {
+			return
+		}
+	}
+	events := append(h.peers[peerID], event)
+	if len(events) > MaxPeerHistoryEvents {
+		events = slices.Clone(events[len(events)-MaxPeerHistoryEvents:])
+	}
+	h.peers[peerID] = events
+	h.dirty = true
+}
+
+func (h *PeerHistory) SetDisconnected(peerID, connID string, disconnectedAt time.Time, reason string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	events := h.peers[peerID]
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].ConnID == connID && events[i].DisconnectedAt.IsZero() {
+			events[i].DisconnectedAt = disconnectedAt
+			events[i].DisconnectReason = reason
+			h.dirty = true
+			return
+		}
+	}
+}
+
+// CloseAll marks all open connections as closed with provided reason.
+func (h *PeerHistory) CloseAll(reason string) {
+	now := time.Now()
+	h.lock.Lock()
+	for _, events := range h.peers {
+		for i := range events {
+			if events[i].DisconnectedAt.IsZero() {
+				events[i].DisconnectedAt = now
+				events[i].DisconnectReason = reason
+				h.dirty = true
+			}
+		}
+	}
+	h.lock.Unlock()
+}
+
+// Get returns events sorted from the oldest to the newest.
+func (h *PeerHistory) Get(peerID string) []PeerConnectionEvent {
+	h.lock.RLock()
+	events := slices.Clone(h.peers[peerID])
+	h.lock.RUnlock()
+
+	if events == nil {
+		events = make([]PeerConnectionEvent, 0)
+	}
+	return events
+}
+
+func (h *PeerHistory) Remove(peerID string) {
+	h.lock.Lock()
+	if _, exists := h.peers[peerID]; exists {
+		delete(h.peers, peerID)
+		h.dirty = true
+	}
+	h.lock.Unlock()
+}
+
+// Save writes history to disk if it has been changed since the last save.
+func (h *PeerHistory) Save() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if !h.dirty {
+		return
+	}
+
+	data, err := json.Marshal(h.peers)
+	if err != nil {
+		logger.DPanicf("Marshal peer history: %v", err)
+		return
+	}
+	err = os.WriteFile(h.path, data, filesPerm)
+	if err != nil {
+		logger.Errorf("Save peer history: %v", err)
+		return
+	}
+	ChownFileIfNeeded(h.path)
+	h.dirty = false
+}
+
+// UptimeRatio returns part of time in [since, now] when peer had at least one open connection.
+func UptimeRatio(events []PeerConnectionEvent, since, now time.Time) float64 {
+	total := now.Sub(since)
+	if total <= 0 {
+		return 0
+	}
+
+	type interval struct {
+		start, end time.Time
+	}
+	intervals := make([]interval, 0, len(events))
+	for _, event := range events {
+		start, end := event.ConnectedAt, event.DisconnectedAt
+		if end.IsZero() || end.After(now) {
+			end = now
+		}
+		if start.Before(since) {
+			start = since
+		}
+		if !end.After(start) {
+			continue
+		}
+		intervals = append(intervals, interval{start: start, end: end})
+	}
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].start.Before(intervals[j].start)
+	})
+
+	var connected time.Duration
+	var current interval
+	for i, iv := range intervals {
+		if i == 0 {
+			current = iv
+			continue
+		}
+		if !iv.start.After(current.end) {
+			if iv.end.After(current.end) {
+				current.end = iv.end
+			}
+			continue
+		}
+		connected += current.end.Sub(current.start)
+		current = iv
+	}
+	if len(intervals) > 0 {
+		connected += current.end.Sub(current.start)
+	}
+
+	return float64(connected) / float64(total)
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerHistory(t *testing.T) {
+	a := require.New(t)
+	dataDir := t.TempDir()
+	const peerID = "peer"
+
+	history := NewPeerHistory(dataDir)
+	for i := 0; i < MaxPeerHistoryEvents+10; i++ {
+		history.AddConnected(peerID, PeerConnectionEvent{ConnID: strconv.Itoa(i), ConnectedAt: time.Now()})
+	}
+	events := history.Get(peerID)
+	a.Len(events, MaxPeerHistoryEvents)
+	a.Equal("10", events[0].ConnID)
+
+	history.SetDisconnected(peerID, "20", time.Now(), DisconnectReasonClosed)
+	history.Save()
+
+	loaded := LoadPeerHistory(dataDir)
+	events = loaded.Get(peerID)
+	a.Len(events, MaxPeerHistoryEvents)
+	for i, event := range events {
+		a.False(event.DisconnectedAt.IsZero())
+		if i == 10 {
+			a.Equal(DisconnectReasonClosed, event.DisconnectReason)
+		} else {
+			a.Equal(DisconnectReasonUncleanShutdown, event.DisconnectReason)
+		}
+	}
+
+	loaded.Remove(peerID)
+	a.Empty(loaded.Get(peerID))
+}
+
+func TestUptimeRatio(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	since := now.Add(-10 * time.Hour)
+	at := func(hoursAgo int) time.Time {
+		return now.Add(-time.Duration(hoursAgo) * time.Hour)
+	}
+
+	tests := []struct {
+		name   string
+		events []PeerConnectionEvent
+		want   float64
+	}{
+		{name: "empty", events: nil, want: 0},
+		{
+			name:   "open connection",
+			events: []PeerConnectionEvent{{ConnectedAt: at(5)}},
+			want:   0.5,
+		},
+		{
+			name: "overlapping connections",
+			events: []PeerConnectionEvent{
+				{ConnectedAt: at(10), DisconnectedAt: at(6)},
+				{ConnectedAt: at(8), DisconnectedAt: at(5)},
+				{ConnectedAt: at(2), DisconnectedAt: at(1)},
+			},
+			want: 0.6,
+		},
+		{
+			name:   "before window",
+			events: []PeerConnectionEvent{{ConnectedAt: at(20), DisconnectedAt: at(8)}},
+			want:   0.2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.InDelta(t, tt.want, UptimeRatio(tt.events, since, now), 0.0001)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/anywherelan/awl/awlevent"
+)
+
+const (
+	RemoteAdminAuditFilename = "remote_admin_audit.json"
+
+	// MaxRemoteAdminAuditEntries is the maximum number of stored remote admin requests.
+	MaxRemoteAdminAuditEntries = 500
+)
+
+type (
+	RemoteAdminAuditEntry struct {
+		Time     time.Time `json:"time"`
+		PeerID   string    `json:"peerId"`
+		PeerName string    `json:"peerName"`
+		Method   string    `json:"method"`
+		Path     string    `json:"path"`
+		// Status is HTTP status of response, 403 for requests which were denied
+		Status int    `json:"status"`
+		Error  string `json:"error,omitempty"`
+	}
+
+	// RemoteAdminAudit stores requests made by peers through remote admin protocol.
+	// It is persisted in a separate file after each request.
+	RemoteAdminAudit struct {
+		lock    sync.RWMutex
+		path    string
+		entries []RemoteAdminAuditEntry
+	}
+)
+
+func LoadRemoteAdminAudit(dataDir string) *RemoteAdminAudit {
+	audit := &RemoteAdminAudit{
+		path:    filepath.Join(dataDir, RemoteAdminAuditFilename),
+		entries: make([]RemoteAdminAuditEntry, 0),
+	}
+
+	data, err := os.ReadFile(audit.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return audit
+	} else if err != nil {
+		logger.Warnf("read remote admin audit file: %v", err)
+		return audit
+	}
+	err = json.Unmarshal(data, &audit.entries)
+	if err != nil {
+		logger.Warnf("invalid remote admin audit file, starting from scratch: %v", err)
+		audit.entries = make([]RemoteAdminAuditEntry, 0)
+	}
+
+	return audit
+}
+
+func (a *RemoteAdminAudit) Add(entry RemoteAdminAuditEntry) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.entries = append(a.entries, entry)
+	if len(a.entries) > MaxRemoteAdminAuditEntries {
+		a.entries = slices.Clone(a.entries[len(a.entries)-MaxRemoteAdminAuditEntries:])
+	}
+
+	data, err := json.Marshal(a.entries)
+	if err != nil {
+		logger.DPanicf("Marshal remote admin audit: %v", err)
+		return
+	}
+	err = os.WriteFile(a.path, data, filesPerm)
+	if err != nil {
+		logger.Errorf("Save remote admin audit: %v", err)
+		return
+	}
+	ChownFileIfNeeded(a.path)
+}
+
+// Entries returns entries sorted from the oldest to the newest.
+func (a *RemoteAdminAudit) Entries() []RemoteAdminAuditEntry {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	return slices.Clone(a.entries)
+}
+
+func (c *Config) SetRemoteAdminAllowed(peerID string, allow bool) error {
+	c.Lock()
+	knownPeer, exists := c.KnownPeers[peerID]
+	if !exists {
+		c.Unlock()
+		return ErrPeerNotFound
+	}
+	knownPeer.AllowRemoteAdmin = allow
+	c.KnownPeers[peerID] = knownPeer
+	c.save()
+	c.Unlock()
+
+	_ = c.emitter.Emit(awlevent.KnownPeerChanged{})
+	return nil
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"errors"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/mr-tron/base58/base58"
+
+	"github.com/anywherelan/awl/awlevent"
+)
+
+var (
+	ErrPeerNotFound     = errors.New("peer not found")
+	ErrPeerAlreadyKnown = errors.New("peer is already known")
+)
+
+// KeyRotation records that known peer has rotated its identity key.
+type KeyRotation struct {
+	OldPeerID string    `json:"oldPeerId"`
+	NewPeerID string    `json:"newPeerId"`
+	RotatedAt time.Time `json:"rotatedAt"`
+	// BlockAt is the end of grace period, old peer id is blocked after it
+	BlockAt time.Time `json:"blockAt"`
+}
+
+// RotateIdentity stores new identity key which is used on the next start and handover signed with the current key.
+func (c *Config) RotateIdentity(newKey crypto.PrivKey, handover string) error {
+	by, err := newKey.Raw()
+	if err != nil {
+		return err
+	}
+	c.Lock()
+	c.P2pNode.NextIdentity = base58.Encode(by)
+	c.P2pNode.KeyHandover = handover
+	c.save()
+	c.Unlock()
+
+	return nil
+}
+
+// ClearKeyHandover stops sending key handover to known peers, it's called when its grace period ends.
+func (c *Config) ClearKeyHandover() {
+	c.Lock()
+	c.P2pNode.KeyHandover = ""
+	c.save()
+	c.Unlock()
+}
+
+// RotatePeerKey moves known peer to its new id keeping alias, ip address, domain name and permissions.
+func (c *Config) RotatePeerKey(oldPeerID, newPeerID string, blockAt time.Time) (KnownPeer, error) {
+	knownPeer, err := c.rotatePeerKey(oldPeerID, newPeerID, blockAt)
+	if err != nil {
+		return KnownPeer{}, err
+	}
+	_ = c.emitter.Emit(awlevent.KnownPeerChanged{})
+
+	return knownPeer, nil
+}
+
+func (c *Config) rotatePeerKey(oldPeerID, newPeerID string, blockAt time.Time) (KnownPeer, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	knownPeer, exists := c.KnownPeers[oldPeerID]
+	if !exists {
+		return KnownPeer{}, ErrPeerNotFound
+	}
+	if _, exists := c.KnownPeers[newPeerID]; exists {
+		return KnownPeer{}, ErrPeerAlreadyKnown
+	}
+	delete(c.KnownPeers, oldPeerID)
+	delete(c.BlockedPeers, newPeerID)
+	knownPeer.PeerID = newPeerID
+	// certificate is issued for the old peer id
+	knownPeer.Certificate = ""
+	c.KnownPeers[newPeerID] = knownPeer
+	if c.SOCKS5.UsingPeerID == oldPeerID {
+		c.SOCKS5.UsingPeerID = newPeerID
+	}
+	c.KeyRotations[oldPeerID] = KeyRotation{
+		OldPeerID: oldPeerID,
+		NewPeerID: newPeerID,
+		RotatedAt: time.Now(),
+		BlockAt:   blockAt,
+	}
+	c.save()
+
+	return knownPeer, nil
+}
+
+// BlockRotatedPeers blocks old ids of peers which grace period has ended, returns blocked ids.
+func (c *Config) BlockRotatedPeers(now time.Time) []string {
+	c.Lock()
+	defer c.Unlock()
+
+	blocked := make([]string, 0)
+	for oldPeerID, rotation := range c.KeyRotations {
+		if now.Before(rotation.BlockAt) {
+			continue
+		}
+		displayName := rotation.NewPeerID
+		if knownPeer, exists := c.KnownPeers[rotation.NewPeerID]; exists {
+			displayName = knownPeer.DisplayName()
+		}
+		c.BlockedPeers[oldPeerID] = BlockedPeer{
+			PeerID:      oldPeerID,
+			DisplayName: displayName,
+			CreatedAt:   now,
+		}
+		delete(c.KeyRotations, oldPeerID)
+		blocked = append(blocked, oldPeerID)
+	}
+	if len(blocked) > 0 {
+		c.save()
+	}
+
+	return blocked
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/p2p/host/eventbus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotatePeerKey(t *testing.T) {
+	a := require.New(t)
+	conf := NewConfig(eventbus.NewBus())
+	conf.dataDir = t.TempDir()
+	conf.KnownPeers["old"] = KnownPeer{PeerID: "old", Alias: "laptop", IPAddr: "10.66.0.2", DomainName: "laptop", WeAllowUsingAsExitNode: true, Certificate: "cert"}
+	conf.KnownPeers["other"] = KnownPeer{PeerID: "other", IPAddr: "10.66.0.3", DomainName: "other"}
+	conf.SOCKS5.UsingPeerID = "old"
+
+	now := time.Now()
+	_, err := conf.RotatePeerKey("unknown", "new", now)
+	a.ErrorIs(err, ErrPeerNotFound)
+	_, err = conf.RotatePeerKey("old", "other", now)
+	a.ErrorIs(err, ErrPeerAlreadyKnown)
+
+	knownPeer, err := conf.RotatePeerKey("old", "new", now.Add(time.Hour))
+	a.NoError(err)
+	a.Equal("new", knownPeer.PeerID)
+	a.Equal("laptop", knownPeer.Alias)
+	a.Equal("10.66.0.2", knownPeer.IPAddr)
+	a.True(knownPeer.WeAllowUsingAsExitNode)
+	a.Empty(knownPeer.Certificate)
+	_, exists := conf.GetPeer("old")
+	a.False(exists)
+	a.Equal("new", conf.SOCKS5.UsingPeerID)
+
+	a.Empty(conf.BlockRotatedPeers(now))
+	_, blocked := conf.GetBlockedPeer("old")
+	a.False(blocked)
+	a.Equal([]string{"old"}, conf.BlockRotatedPeers(now.Add(2*time.Hour)))
+	blockedPeer, blocked := conf.GetBlockedPeer("old")
+	a.True(blocked)
+	a.Equal("laptop", blockedPeer.DisplayName)
+	a.Empty(conf.KeyRotations)
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"time"
+)
+
+// StatusSharingConfig controls which details about this device are sent to known peers in status exchange.
+// Everything is disabled by default.
+type StatusSharingConfig struct {
+	Hostname bool `json:"hostname"`
+	// OS enables sharing of operating system and architecture
+	OS      bool `json:"os"`
+	Version bool `json:"version"`
+	Uptime  bool `json:"uptime"`
+	// Health enables sharing whether TUN interface and awl DNS are working
+	Health bool `json:"health"`
+	// Note is shared if not empty, e.g. "on vacation"
+	Note string `json:"note" validate:"max=128"`
+}
+
+// PeerSharedStatus contains details which peer shared with us during the last status exchange.
+type PeerSharedStatus struct {
+	Hostname string `json:"hostname,omitempty"`
+	OS       string `json:"os,omitempty"`
+	Arch     string `json:"arch,omitempty"`
+	Version  string `json:"version,omitempty"`
+	// StartedAt is calculated from peer uptime, zero if uptime is not shared
+	StartedAt    time.Time `json:"startedAt"`
+	HealthShared bool      `json:"healthShared,omitempty"`
+	TunUp        bool      `json:"tunUp,omitempty"`
+	DNSUp        bool      `json:"dnsUp,omitempty"`
+	Note         string    `json:"note,omitempty"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+func (c *Config) GetStatusSharing() StatusSharingConfig {
+	c.RLock()
+	defer c.RUnlock()
+	return c.StatusSharing
+}
+
+func (c *Config) SetStatusSharing(sharing StatusSharingConfig) {
+	c.Lock()
+	c.StatusSharing = sharing
+	c.save()
+	c.Unlock()
+}
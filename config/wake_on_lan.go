@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/anywherelan/awl/awlevent"
+)
+
+// WakeOnLANTarget is device which could be woken by peer from the same LAN.
+type WakeOnLANTarget struct {
+	Name string `json:"name" validate:"required"`
+	MAC  string `json:"mac" validate:"required"`
+}
+
+// ParseWakeOnLANMAC parses 48-bit MAC address of Wake-on-LAN target.
+func ParseWakeOnLANMAC(mac string) (net.HardwareAddr, error) {
+	addr, err := net.ParseMAC(mac)
+	if err != nil {
+		return nil, err
+	}
+	if len(addr) != 6 {
+		return nil, fmt.Errorf("invalid MAC address %s: only 48-bit addresses are supported", mac)
+	}
+	return addr, nil
+}
+
+// WakeOnLANTarget returns target of peer by name.
+func (kp KnownPeer) WakeOnLANTarget(name string) (WakeOnLANTarget, bool) {
+	for _, target := range kp.WakeOnLANTargets {
+		if target.Name == name {
+			return target, true
+		}
+	}
+	return WakeOnLANTarget{}, false
+}
+
+func (c *Config) SetWakeOnLANAllowed(peerID string, allow bool) error {
+	c.Lock()
+	knownPeer, exists := c.KnownPeers[peerID]
+	if !exists {
+		c.Unlock()
+		return ErrPeerNotFound
+	}
+	knownPeer.AllowWakeOnLAN = allow
+	c.KnownPeers[peerID] = knownPeer
+	c.save()
+	c.Unlock()
+
+	_ = c.emitter.Emit(awlevent.KnownPeerChanged{})
+	return nil
+}
+
+// SetWakeOnLANTargets replaces targets of peer, MAC addresses are normalized.
+func (c *Config) SetWakeOnLANTargets(peerID string, targets []WakeOnLANTarget) error {
+	normalized := make([]WakeOnLANTarget, 0, len(targets))
+	names := make(map[string]struct{}, len(targets))
+	for _, target := range targets {
+		mac, err := ParseWakeOnLANMAC(target.MAC)
+		if err != nil {
+			return err
+		}
+		if _, exists := names[target.Name]; exists {
+			return fmt.Errorf("duplicate target name %q", target.Name)
+		}
+		names[target.Name] = struct{}{}
+		normalized = append(normalized, WakeOnLANTarget{Name: target.Name, MAC: mac.String()})
+	}
+
+	c.Lock()
+	knownPeer, exists := c.KnownPeers[peerID]
+	if !exists {
+		c.Unlock()
+		return ErrPeerNotFound
+	}
+	knownPeer.WakeOnLANTargets = normalized
+	c.KnownPeers[peerID] = knownPeer
+	c.save()
+	c.Unlock()
+
+	_ = c.emitter.Emit(awlevent.KnownPeerChanged{})
+	return nil
+}
@@ -6,6 +6,8 @@ import (
 	kbucket "github.com/libp2p/go-libp2p-kbucket"
 	"github.com/libp2p/go-libp2p/core/metrics"
 
+	"github.com/anywherelan/awl/awldns"
+	"github.com/anywherelan/awl/config"
 	"github.com/anywherelan/awl/p2p"
 	"github.com/anywherelan/awl/protocol"
 )
@@ -19,28 +21,162 @@ type (
 	FriendRequest struct {
 		PeerID string `validate:"required"`
 		Alias  string `validate:"required,trimmed_str_not_empty"`
+		// Message is shown to remote peer along with request
+		Message string `validate:"max=256"`
 	}
 	FriendRequestReply struct {
 		PeerID  string `validate:"required"`
 		Alias   string `validate:"required,trimmed_str_not_empty"`
 		Decline bool
 	}
+	KnownPeersRequest struct {
+		// Group filters peers which belong to group
+		Group string `url:"group,omitempty" query:"group"`
+	}
 	PeerIDRequest struct {
 		PeerID string `validate:"required"`
 	}
+	ExitNodeRulesRequest struct {
+		PeerID string `validate:"required"`
+		config.ExitNodeRules
+	}
+	RemoteAdminRequest struct {
+		PeerID string `validate:"required"`
+		Allow  bool
+	}
+	WakeOnLANPermissionRequest struct {
+		PeerID string `validate:"required"`
+		Allow  bool
+	}
+	DNSQueriesPermissionRequest struct {
+		PeerID string `validate:"required"`
+		Allow  bool
+	}
+	DNSForwardingRuleRequest struct {
+		// Zone like "corp.internal"
+		Zone string `validate:"required"`
+		// PeerID of peer which resolves queries, either PeerID or Upstream should be set
+		PeerID string
+		// Upstream is address of resolver, e.g. "10.66.0.3" of peer in awl network
+		Upstream string
+	}
+	RemoveDNSForwardingRuleRequest struct {
+		Zone string `validate:"required"`
+	}
+	DNSRecordsRequest struct {
+		PeerID  string             `validate:"required"`
+		Records []config.DNSRecord `validate:"dive"`
+	}
+	WakeOnLANTargetsRequest struct {
+		PeerID  string                   `validate:"required"`
+		Targets []config.WakeOnLANTarget `validate:"dive"`
+	}
+	// WakeOnLANRequest asks peer PeerID to wake device with MAC or saved Target of this peer
+	WakeOnLANRequest struct {
+		PeerID string `validate:"required"`
+		MAC    string `validate:"required_without=Target"`
+		Target string `validate:"required_without=MAC"`
+	}
+	WakeOnLANResponse struct {
+		MAC string
+		// Interfaces of peer where magic packet was sent
+		Interfaces []string
+	}
 	UpdatePeerSettingsRequest struct {
 		PeerID               string `validate:"required"`
 		Alias                string `validate:"required,trimmed_str_not_empty"`
 		DomainName           string `validate:"required,trimmed_str_not_empty"`
 		AllowUsingAsExitNode bool
+		// TrustIntroductions accepts peers introduced by this peer automatically
+		TrustIntroductions bool
 	}
 	UpdateMySettingsRequest struct {
 		Name string
 	}
 
+	RotateIdentityKeyRequest struct {
+		// GracePeriodSec is time after which friends block the old peer id, 0 means 7 days
+		GracePeriodSec int64 `validate:"gte=0"`
+	}
+
 	UpdateProxySettingsRequest struct {
 		UsingPeerID string
 	}
+
+	BootstrapPeerRequest struct {
+		// Multiaddr with peer id, e.g. /ip4/1.2.3.4/tcp/4363/p2p/12D3KooW...
+		Address string `validate:"required"`
+	}
+	RemoveBootstrapPeerRequest struct {
+		// Multiaddr or peer id, all addresses of peer are removed in the latter case
+		Address string `validate:"required"`
+	}
+	UpdateBootstrapSettingsRequest struct {
+		DisableDefaultPeers bool
+	}
+
+	CreateInviteRequest struct {
+		// ExpiresInSec is invite lifetime, 0 means 24 hours
+		ExpiresInSec int64 `validate:"gte=0"`
+		// MaxUses is the number of peers which can use invite, 0 means unlimited
+		MaxUses int `validate:"gte=0"`
+		// AllowUsingAsExitNode is granted to invited peers
+		AllowUsingAsExitNode bool
+		// Groups which invited peers are added to
+		Groups []string
+	}
+	InviteIDRequest struct {
+		ID string `validate:"required"`
+	}
+	// UpsertGroupRequest changes only fields which are set, others keep their current values
+	UpsertGroupRequest struct {
+		Name                 string `validate:"required"`
+		Description          *string
+		AllowUsingAsExitNode *bool
+		HideFromDNS          *bool
+		TrustIntroductions   *bool
+		BlockInboundAccess   *bool
+	}
+	GroupNameRequest struct {
+		Name string `validate:"required"`
+	}
+	UpdateGroupPeersRequest struct {
+		Name          string `validate:"required"`
+		AddPeerIDs    []string
+		RemovePeerIDs []string
+	}
+	JoinByInviteRequest struct {
+		// Invite token or link
+		Token string `validate:"required"`
+		// Alias of inviting peer, its name is used if empty
+		Alias string
+	}
+	IntroducePeerRequest struct {
+		// PeerID of peer which receives introduction
+		PeerID string `validate:"required"`
+		// IntroducedPeerID is introduced to PeerID
+		IntroducedPeerID string `validate:"required"`
+	}
+	CreateNetworkRequest struct {
+		Name string `validate:"required"`
+	}
+	SignCertificateRequest struct {
+		PeerID string `validate:"required"`
+		Name   string
+		// Groups which peer is added to by other members
+		Groups []string
+		// ExpiresInSec is certificate lifetime, 0 means 1 year
+		ExpiresInSec int64 `validate:"gte=0"`
+	}
+	JoinNetworkRequest struct {
+		Certificate string `validate:"required"`
+	}
+	UpdateNetworkAdminsRequest struct {
+		// Add are peer ids of new admins
+		Add []string
+		// Remove are peer ids of admins which are removed, admin can remove itself if there are other admins
+		Remove []string
+	}
 )
 
 // Responses
@@ -58,10 +194,44 @@ type (
 		Declined               bool
 		WeAllowUsingAsExitNode bool
 		AllowedUsingAsExitNode bool
-		LastSeen               time.Time
+		TrustIntroductions     bool
+		// AllowRemoteAdmin is set when we allow peer to manage this device
+		AllowRemoteAdmin  bool
+		FileReceivePolicy config.FileReceivePolicy `enums:"ask,auto,deny"`
+		// AllowWakeOnLAN is set when we allow peer to wake devices in our LAN
+		AllowWakeOnLAN bool
+		// WakeOnLANTargets are devices in LAN of peer which we wake through it
+		WakeOnLANTargets []config.WakeOnLANTarget
+		// DNSRecords are user defined TXT and SRV records under DomainName
+		DNSRecords []config.DNSRecord
+		// AllowDNSQueries is set when we allow peer to resolve names with our DNS upstreams
+		AllowDNSQueries bool
+		LastSeen        time.Time
+		// ProtocolVersion negotiated with peer, empty if status was not exchanged yet
+		ProtocolVersion string
+		// Capabilities supported by both us and peer
+		Capabilities           []string
+		Groups                 []string
 		Connections            []p2p.ConnectionInfo
 		NetworkStats           metrics.Stats
 		NetworkStatsInIECUnits StatsInUnits
+		// SharedStatus contains details which peer shares with us, empty fields are not shared
+		SharedStatus PeerSharedStatus
+	}
+
+	PeerSharedStatus struct {
+		Hostname string
+		OS       string
+		Arch     string
+		Version  string
+		// Uptime of peer awl, it's zero if peer is offline or doesn't share it
+		Uptime time.Duration `swaggertype:"primitive,integer"`
+		// HealthShared is set when TunUp and DNSUp are shared
+		HealthShared bool
+		TunUp        bool
+		DNSUp        bool
+		Note         string
+		UpdatedAt    time.Time
 	}
 
 	PeerInfo struct {
@@ -97,6 +267,67 @@ type (
 	AuthRequest struct {
 		PeerID string
 		protocol.AuthPeer
+		// IntroducedBy is peer id of our friend who introduced peer, empty if peer was not introduced
+		IntroducedBy     string
+		IntroducedByName string
+		ReceivedAt       time.Time
+		// Request is dropped after ExpiresAt if it is not accepted or dismissed
+		ExpiresAt time.Time
+	}
+
+	PeerHistoryResponse struct {
+		PeerID      string
+		DisplayName string
+		Connected   bool
+		// Time of the oldest stored event, zero if there is no history
+		TrackedSince time.Time
+		// Percentage of time since TrackedSince when peer was connected
+		UptimePercent float64
+		// Sorted from the oldest to the newest
+		Events []config.PeerConnectionEvent
+	}
+
+	InviteInfo struct {
+		ID                   string
+		CreatedAt            time.Time
+		ExpiresAt            time.Time
+		Expired              bool
+		MaxUses              int
+		Uses                 int
+		AllowUsingAsExitNode bool
+		Groups               []string
+	}
+	PeerGroupResponse struct {
+		config.PeerGroup
+		// PeerIDs of peers in group
+		PeerIDs []string
+	}
+	NetworkInfoResponse struct {
+		// Name is empty if we are not a member of any network
+		Name           string
+		AdminKeys      []string
+		IsAdmin        bool
+		Groups         []string
+		ExpiresAt      time.Time
+		RevokedPeerIDs []string
+		// MemberPeerIDs are known peers accepted as members of network
+		MemberPeerIDs []string
+	}
+	SignCertificateResponse struct {
+		Certificate string
+		ExpiresAt   time.Time
+	}
+	RotateIdentityKeyResponse struct {
+		OldPeerID string
+		// NewPeerID is used after restart
+		NewPeerID     string
+		BlockOldAfter time.Time
+	}
+	CreateInviteResponse struct {
+		InviteInfo
+		Token string
+		// URL is the invite link, it could be rendered as QR code
+		URL string
 	}
 
 	ListAvailableProxiesResponse struct {
@@ -116,6 +347,39 @@ type (
 		Bandwidth   BandwidthDebugInfo
 	}
 
+	BootstrapPeer struct {
+		PeerID      string
+		Addresses   []string
+		IsDefault   bool
+		Connected   bool
+		Error       string
+		Connections []string
+	}
+	BootstrapPeersResponse struct {
+		DefaultPeersDisabled bool
+		Peers                []BootstrapPeer
+	}
+
+	NATDiagnosticsResponse struct {
+		p2p.NATDiagnostics
+		HolePunches []HolePunchInfo
+	}
+	HolePunchInfo struct {
+		p2p.HolePunchResult
+		// DisplayName is empty for unknown peers
+		DisplayName string
+	}
+	DNSForwardingRule struct {
+		config.DNSForwardingRule
+		// PeerName is display name of peer from PeerID
+		PeerName string
+	}
+	DNSDebugInfo struct {
+		awldns.Stats
+		AwlDNSAddress       string
+		IsAwlDNSSetAsSystem bool
+	}
+
 	GeneralDebugInfo struct {
 		Version string
 		Uptime  string
@@ -146,3 +410,95 @@ type (
 		RateOut  string
 	}
 )
+
+// Event types streamed by events API
+const (
+	EventPeerConnected       = "peer_connected"
+	EventPeerDisconnected    = "peer_disconnected"
+	EventPeerChanged         = "peer_changed"
+	EventAuthRequest         = "auth_request"
+	EventProxyChanged        = "proxy_changed"
+	EventReachabilityChanged = "reachability_changed"
+	EventUpdateAvailable     = "update_available"
+	EventFileTransfer        = "file_transfer"
+)
+
+var EventTypes = []string{
+	EventPeerConnected, EventPeerDisconnected, EventPeerChanged, EventAuthRequest,
+	EventProxyChanged, EventReachabilityChanged, EventUpdateAvailable, EventFileTransfer,
+}
+
+type (
+	EventsRequest struct {
+		// Types filters events, all events are streamed if empty
+		Types []string `url:"types,comma,omitempty" query:"types"`
+	}
+	Event struct {
+		Type string `enums:"peer_connected,peer_disconnected,peer_changed,auth_request,proxy_changed,reachability_changed,update_available,file_transfer"`
+		Time time.Time
+		// PeerID is set for peer events, for proxy_changed it is peer used as exit node, empty if proxy is disabled
+		PeerID   string `json:",omitempty"`
+		PeerName string `json:",omitempty"`
+		// AuthRequest is set for auth_request
+		AuthRequest  *AuthRequest `json:",omitempty"`
+		Reachability string       `json:",omitempty" enums:"Unknown,Public,Private"`
+		// Version is set for update_available
+		Version string `json:",omitempty"`
+		// FileTransfer is set for file_transfer, it's emitted when peer offers file and when transfer finishes
+		FileTransfer *FileTransfer `json:",omitempty"`
+	}
+	HookDelivery struct {
+		Hook      string
+		EventType string
+		Time      time.Time
+		Attempts  int
+		Success   bool
+		Error     string `json:",omitempty"`
+	}
+)
+
+// File transfer directions and statuses
+const (
+	FileTransferOutgoing = "outgoing"
+	FileTransferIncoming = "incoming"
+
+	FileTransferPending    = "pending"
+	FileTransferInProgress = "in_progress"
+	FileTransferCompleted  = "completed"
+	FileTransferFailed     = "failed"
+	FileTransferDeclined   = "declined"
+)
+
+type (
+	SendFileRequest struct {
+		PeerID string `validate:"required"`
+		// Path of file on this device
+		Path string `validate:"required"`
+	}
+	FileTransferRequest struct {
+		ID string `validate:"required"`
+	}
+	FileReceivePolicyRequest struct {
+		PeerID string                   `validate:"required"`
+		Policy config.FileReceivePolicy `validate:"required,oneof=ask auto deny" enums:"ask,auto,deny"`
+	}
+	FileTransfer struct {
+		ID        string
+		PeerID    string
+		PeerName  string
+		Direction string `enums:"outgoing,incoming"`
+		Name      string
+		Size      int64
+		SHA256    string
+		Status    string `enums:"pending,in_progress,completed,failed,declined"`
+		// ResumedFrom is size of partially received file from previous attempt
+		ResumedFrom int64
+		// Transferred includes ResumedFrom
+		Transferred int64
+		Error       string `json:",omitempty"`
+		// Path of sent file or received file, received file is moved there only after integrity check
+		Path       string `json:",omitempty"`
+		CreatedAt  time.Time
+		FinishedAt time.Time
+	}
+)
@@ -44,24 +44,39 @@ func (p *P2p) PeerUserAgent(peerID peer.ID) string {
 }
 
 func (p *P2p) PeerConnectionsInfo(peerID peer.ID) []ConnectionInfo {
-	conns := p.connsToPeer(peerID)
+	conns := p.ConnsToPeer(peerID)
 	infos := make([]ConnectionInfo, 0, len(conns))
 	for _, conn := range conns {
-		addr := conn.RemoteMultiaddr()
-		info, parsed := parseMultiaddrToInfo(addr)
+		info, parsed := NewConnectionInfo(conn)
 		if !parsed {
-			p.logger.DPanicf("could not parse multiaddr %s", addr)
+			p.logger.DPanicf("could not parse multiaddr %s", conn.RemoteMultiaddr())
 			// still add unparsed info with multiaddr
 		}
-		stat := conn.Stat()
-		info.Direction = strings.ToLower(stat.Direction.String())
-		info.Opened = stat.Opened
-		info.Transient = stat.Limited
 		infos = append(infos, info)
 	}
 	return infos
 }
 
+// NewConnectionInfo returns false if connection multiaddr could not be parsed, info is still filled with all other data.
+func NewConnectionInfo(conn network.Conn) (ConnectionInfo, bool) {
+	info, parsed := parseMultiaddrToInfo(conn.RemoteMultiaddr())
+	stat := conn.Stat()
+	info.Direction = strings.ToLower(stat.Direction.String())
+	info.Opened = stat.Opened
+	info.Transient = stat.Limited
+
+	return info, parsed
+}
+
+// ConnectionInfo returns details of opened connection, open time is set to now if it's unknown.
+func (p *P2p) ConnectionInfo(conn network.Conn) ConnectionInfo {
+	info, _ := NewConnectionInfo(conn)
+	if info.Opened.IsZero() {
+		info.Opened = time.Now()
+	}
+	return info
+}
+
 func (p *P2p) ConnectedPeersCount() int {
 	return len(p.host.Network().Peers())
 }
@@ -153,14 +168,15 @@ func (p *P2p) NetworkStatsForPeer(peerID peer.ID) metrics.Stats {
 
 // BootstrapPeersStats returns total peers count and connected count.
 func (p *P2p) BootstrapPeersStats() (int, int) {
+	bootstrapPeers := p.BootstrapPeers()
 	connected := 0
-	for _, peerAddr := range p.bootstrapPeers {
+	for _, peerAddr := range bootstrapPeers {
 		if p.IsConnected(peerAddr.ID) {
 			connected += 1
 		}
 	}
 
-	return len(p.bootstrapPeers), connected
+	return len(bootstrapPeers), connected
 }
 
 func (p *P2p) BootstrapPeersStatsDetailed() map[string]BootstrapPeerDebugInfo {
@@ -173,23 +189,33 @@ func (p *P2p) BootstrapPeersStatsDetailed() map[string]BootstrapPeerDebugInfo {
 
 func parseMultiaddrToInfo(addr multiaddr.Multiaddr) (ConnectionInfo, bool) {
 	info := ConnectionInfo{Multiaddr: addr.String()}
-	protocols := addr.Protocols()
-	if len(protocols) == 2 && protocols[1].Code == multiaddr.P_TCP {
-		info.Protocol = protocols[1].Name
-		ip, _ := addr.ValueForProtocol(protocols[0].Code)
-		port, _ := addr.ValueForProtocol(protocols[1].Code)
-		info.Address = net.JoinHostPort(ip, port)
-	} else if len(protocols) == 3 &&
-		(protocols[2].Code == multiaddr.P_QUIC || protocols[2].Code == multiaddr.P_QUIC_V1) {
-		info.Protocol = "quic"
-		ip, _ := addr.ValueForProtocol(protocols[0].Code)
-		port, _ := addr.ValueForProtocol(protocols[1].Code)
-		info.Address = net.JoinHostPort(ip, port)
-	} else if _, err := addr.ValueForProtocol(multiaddr.P_CIRCUIT); err == nil {
+	if _, err := addr.ValueForProtocol(multiaddr.P_CIRCUIT); err == nil {
 		info.ThroughRelay = true
 		info.RelayPeerID, _ = addr.ValueForProtocol(multiaddr.P_P2P)
-	} else {
+		return info, true
+	}
+
+	info.Protocol = TransportFromMultiaddr(addr)
+	if info.Protocol == "" {
+		return info, false
+	}
+
+	var host, port string
+	multiaddr.ForEach(addr, func(c multiaddr.Component) bool {
+		switch c.Protocol().Code {
+		case multiaddr.P_IP4, multiaddr.P_IP6, multiaddr.P_DNS, multiaddr.P_DNS4, multiaddr.P_DNS6:
+			host = c.Value()
+		case multiaddr.P_TCP, multiaddr.P_UDP:
+			port = c.Value()
+			return false
+		}
+		return true
+	})
+	if host == "" || port == "" {
+		info.Protocol = ""
 		return info, false
 	}
+	info.Address = net.JoinHostPort(host, port)
+
 	return info, true
 }
@@ -53,6 +53,36 @@ func Test_parseMultiaddrToInfo(t *testing.T) {
 			},
 			want1: true,
 		},
+		{
+			name: "websocket",
+			args: args{addr: mustNewMultiaddr("/ip4/192.168.1.21/tcp/443/ws")},
+			want: ConnectionInfo{
+				Multiaddr: "/ip4/192.168.1.21/tcp/443/ws",
+				Address:   "192.168.1.21:443",
+				Protocol:  "ws",
+			},
+			want1: true,
+		},
+		{
+			name: "secure websocket",
+			args: args{addr: mustNewMultiaddr("/ip6/::1/tcp/443/tls/sni/example.com/ws")},
+			want: ConnectionInfo{
+				Multiaddr: "/ip6/::1/tcp/443/tls/sni/example.com/ws",
+				Address:   "[::1]:443",
+				Protocol:  "wss",
+			},
+			want1: true,
+		},
+		{
+			name: "webtransport",
+			args: args{addr: mustNewMultiaddr("/ip4/192.168.1.21/udp/443/quic-v1/webtransport")},
+			want: ConnectionInfo{
+				Multiaddr: "/ip4/192.168.1.21/udp/443/quic-v1/webtransport",
+				Address:   "192.168.1.21:443",
+				Protocol:  "webtransport",
+			},
+			want1: true,
+		},
 		{
 			name: "relay",
 			args: args{addr: mustNewMultiaddr("/ip4/192.168.1.21/udp/6150/quic-v1/p2p/12D3KooWNWa2r6dJVogbjNf1CKrKNttVAhKZr1PpWRPJYX7o4t4M/p2p-circuit")},
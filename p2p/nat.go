@@ -0,0 +1,259 @@
+package p2p
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/event"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	basichost "github.com/libp2p/go-libp2p/p2p/host/basic"
+	"github.com/libp2p/go-libp2p/p2p/protocol/holepunch"
+)
+
+const (
+	MappingBehaviorUnknown             = "unknown"
+	MappingBehaviorEndpointIndependent = "endpoint-independent"
+	MappingBehaviorEndpointDependent   = "endpoint-dependent"
+
+	// maxHolePunchResults limits number of peers for which the last hole punch result is stored.
+	maxHolePunchResults = 100
+)
+
+type (
+	NATDiagnostics struct {
+		Reachability string
+		// ObservedAddrs are our addresses reported by other peers grouped by transport
+		ObservedAddrs map[string][]string
+		// MappingBehavior for "tcp" and "udp", it is determined only when we are behind NAT
+		MappingBehavior map[string]string
+		// PortMappingDiscovered is true if UPnP or NAT-PMP capable gateway was found
+		PortMappingDiscovered bool
+		PortMappings          []PortMapping
+		HolePunches           []HolePunchResult
+		Conclusion            string
+	}
+	PortMapping struct {
+		Internal string
+		External string
+	}
+	HolePunchResult struct {
+		PeerID string
+		// DirectDial is true if peer was connected directly without hole punching
+		DirectDial bool
+		Success    bool
+		Attempts   int
+		Error      string        `json:",omitempty"`
+		Elapsed    time.Duration `swaggertype:"primitive,integer"`
+		UpdatedAt  time.Time
+	}
+)
+
+// NewNATManager is a constructor for libp2p.NATManager option, it saves manager to report port mappings.
+func (p *P2p) NewNATManager(net network.Network) basichost.NATManager {
+	manager := basichost.NewNATManager(net)
+	p.natManager.Store(&manager)
+	return manager
+}
+
+// HolePunchTracer should be passed to holepunch.WithTracer to collect hole punch results.
+func (p *P2p) HolePunchTracer() holepunch.EventTracer {
+	return p.holePunches
+}
+
+func (p *P2p) NATDiagnostics() NATDiagnostics {
+	diag := NATDiagnostics{
+		Reachability:    p.Reachability().String(),
+		ObservedAddrs:   make(map[string][]string),
+		MappingBehavior: make(map[string]string),
+		PortMappings:    make([]PortMapping, 0),
+		HolePunches:     p.holePunches.Results(),
+	}
+
+	for _, addr := range p.OwnObservedAddrs() {
+		transport := TransportFromMultiaddr(addr)
+		if transport == "" {
+			transport = "other"
+		}
+		diag.ObservedAddrs[transport] = append(diag.ObservedAddrs[transport], addr.String())
+	}
+
+	p.natTypesLock.RLock()
+	diag.MappingBehavior["tcp"] = mappingBehavior(p.natTypes[network.NATTransportTCP])
+	diag.MappingBehavior["udp"] = mappingBehavior(p.natTypes[network.NATTransportUDP])
+	p.natTypesLock.RUnlock()
+
+	if manager := p.natManager.Load(); manager != nil && (*manager).HasDiscoveredNAT() {
+		diag.PortMappingDiscovered = true
+		for _, listenAddr := range p.host.Network().ListenAddresses() {
+			mapped := (*manager).GetMapping(listenAddr)
+			if mapped == nil {
+				continue
+			}
+			diag.PortMappings = append(diag.PortMappings, PortMapping{Internal: listenAddr.String(), External: mapped.String()})
+		}
+	}
+
+	diag.Conclusion = natConclusion(diag)
+
+	return diag
+}
+
+func (p *P2p) subscribeNATDeviceType() error {
+	sub, err := p.host.EventBus().Subscribe(new(event.EvtNATDeviceTypeChanged))
+	if err != nil {
+		return err
+	}
+	go func() {
+		defer sub.Close()
+		for {
+			select {
+			case <-p.ctx.Done():
+				return
+			case e, ok := <-sub.Out():
+				if !ok {
+					return
+				}
+				evt := e.(event.EvtNATDeviceTypeChanged)
+				p.natTypesLock.Lock()
+				p.natTypes[evt.TransportProtocol] = evt.NatDeviceType
+				p.natTypesLock.Unlock()
+			}
+		}
+	}()
+
+	return nil
+}
+
+func mappingBehavior(natType network.NATDeviceType) string {
+	switch natType {
+	case network.NATDeviceTypeCone:
+		return MappingBehaviorEndpointIndependent
+	case network.NATDeviceTypeSymmetric:
+		return MappingBehaviorEndpointDependent
+	default:
+		return MappingBehaviorUnknown
+	}
+}
+
+func natConclusion(diag NATDiagnostics) string {
+	var parts []string
+	switch diag.Reachability {
+	case network.ReachabilityPublic.String():
+		parts = append(parts, "Your node is publicly reachable, peers can connect to it directly.")
+	case network.ReachabilityPrivate.String():
+		parts = append(parts, "Your node is behind NAT or firewall and is not reachable from the internet.")
+	default:
+		parts = append(parts, "Reachability is not determined yet, try again in a few minutes.")
+	}
+
+	if diag.PortMappingDiscovered {
+		if len(diag.PortMappings) > 0 {
+			parts = append(parts, "Your router supports UPnP/NAT-PMP and ports were mapped successfully.")
+		} else {
+			parts = append(parts, "Your router supports UPnP/NAT-PMP, but port mapping failed.")
+		}
+	} else if diag.Reachability != network.ReachabilityPublic.String() {
+		parts = append(parts, "UPnP/NAT-PMP is not available, enabling it on your router could make the node reachable.")
+	}
+
+	symmetric := diag.MappingBehavior["tcp"] == MappingBehaviorEndpointDependent || diag.MappingBehavior["udp"] == MappingBehaviorEndpointDependent
+	independent := diag.MappingBehavior["tcp"] == MappingBehaviorEndpointIndependent || diag.MappingBehavior["udp"] == MappingBehaviorEndpointIndependent
+	switch {
+	case symmetric:
+		parts = append(parts, "Your NAT uses endpoint-dependent (symmetric) mapping, hole punching will fail and connections to peers behind NAT will go through relays.")
+	case independent:
+		parts = append(parts, "Your NAT uses endpoint-independent mapping, hole punching should work with peers which are not behind symmetric NAT.")
+	}
+
+	var succeeded, failed int
+	for _, result := range diag.HolePunches {
+		// skip direct connections and hole punches in progress
+		if result.DirectDial || (!result.Success && result.Error == "") {
+			continue
+		}
+		if result.Success {
+			succeeded++
+		} else {
+			failed++
+		}
+	}
+	switch {
+	case succeeded > 0 && failed == 0:
+		parts = append(parts, "All hole punch attempts succeeded.")
+	case succeeded == 0 && failed > 0:
+		parts = append(parts, "All hole punch attempts failed, direct connections to peers behind NAT are unlikely.")
+	case succeeded > 0 && failed > 0:
+		parts = append(parts, "Some hole punch attempts failed, it probably depends on NAT of remote peers.")
+	}
+
+	return strings.Join(parts, " ")
+}
+
+type holePunchTracer struct {
+	lock    sync.RWMutex
+	results map[peer.ID]HolePunchResult
+}
+
+func newHolePunchTracer() *holePunchTracer {
+	return &holePunchTracer{results: make(map[peer.ID]HolePunchResult)}
+}
+
+func (t *holePunchTracer) Trace(evt *holepunch.Event) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	result := t.results[evt.Remote]
+	result.PeerID = evt.Remote.String()
+	switch e := evt.Evt.(type) {
+	case *holepunch.DirectDialEvt:
+		if !e.Success {
+			return
+		}
+		result = HolePunchResult{PeerID: result.PeerID, DirectDial: true, Success: true, Elapsed: e.EllapsedTime}
+	case *holepunch.StartHolePunchEvt:
+		result = HolePunchResult{PeerID: result.PeerID}
+	case *holepunch.HolePunchAttemptEvt:
+		result.Attempts = e.Attempt
+	case *holepunch.EndHolePunchEvt:
+		result.Success = e.Success
+		result.Error = e.Error
+		result.Elapsed = e.EllapsedTime
+	case *holepunch.ProtocolErrorEvt:
+		result.Success = false
+		result.Error = e.Error
+	default:
+		return
+	}
+	result.UpdatedAt = time.Unix(0, evt.Timestamp)
+	t.results[evt.Remote] = result
+
+	if len(t.results) > maxHolePunchResults {
+		var oldestID peer.ID
+		var oldest time.Time
+		for id, res := range t.results {
+			if oldest.IsZero() || res.UpdatedAt.Before(oldest) {
+				oldestID, oldest = id, res.UpdatedAt
+			}
+		}
+		delete(t.results, oldestID)
+	}
+}
+
+// Results returns the last hole punch result for each peer, the newest first.
+func (t *holePunchTracer) Results() []HolePunchResult {
+	t.lock.RLock()
+	results := make([]HolePunchResult, 0, len(t.results))
+	for _, result := range t.results {
+		results = append(results, result)
+	}
+	t.lock.RUnlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].UpdatedAt.After(results[j].UpdatedAt)
+	})
+
+	return results
+}
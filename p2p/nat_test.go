@@ -0,0 +1,53 @@
+package p2p
+
+import (
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/p2p/protocol/holepunch"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHolePunchTracer(t *testing.T) {
+	a := require.New(t)
+	tracer := newHolePunchTracer()
+	const peer1, peer2 = peer.ID("peer1"), peer.ID("peer2")
+	now := time.Now()
+	trace := func(remote peer.ID, evt any) {
+		now = now.Add(time.Second)
+		tracer.Trace(&holepunch.Event{Timestamp: now.UnixNano(), Remote: remote, Evt: evt})
+	}
+
+	trace(peer1, &holepunch.StartHolePunchEvt{})
+	trace(peer1, &holepunch.HolePunchAttemptEvt{Attempt: 2})
+	trace(peer1, &holepunch.EndHolePunchEvt{Success: false, Error: "timeout"})
+	trace(peer2, &holepunch.DirectDialEvt{Success: false})
+	trace(peer2, &holepunch.DirectDialEvt{Success: true})
+
+	results := tracer.Results()
+	a.Len(results, 2)
+	a.Equal(peer2.String(), results[0].PeerID)
+	a.True(results[0].DirectDial)
+	a.Equal(peer1.String(), results[1].PeerID)
+	a.False(results[1].Success)
+	a.Equal(2, results[1].Attempts)
+	a.Equal("timeout", results[1].Error)
+}
+
+func TestNATConclusion(t *testing.T) {
+	a := require.New(t)
+	conclusion := natConclusion(NATDiagnostics{
+		Reachability:    network.ReachabilityPrivate.String(),
+		MappingBehavior: map[string]string{"tcp": MappingBehaviorUnknown, "udp": MappingBehaviorEndpointDependent},
+		HolePunches:     []HolePunchResult{{Error: "timeout"}},
+	})
+	a.Contains(conclusion, "not reachable")
+	a.Contains(conclusion, "UPnP/NAT-PMP is not available")
+	a.Contains(conclusion, "symmetric")
+	a.Contains(conclusion, "All hole punch attempts failed")
+
+	conclusion = natConclusion(NATDiagnostics{Reachability: network.ReachabilityPublic.String()})
+	a.Equal("Your node is publicly reachable, peers can connect to it directly.", conclusion)
+}
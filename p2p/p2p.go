@@ -6,7 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"net"
+	"maps"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -26,8 +27,6 @@ import (
 	basichost "github.com/libp2p/go-libp2p/p2p/host/basic"
 	"github.com/libp2p/go-libp2p/p2p/net/connmgr"
 	"github.com/libp2p/go-libp2p/p2p/net/swarm"
-	libp2pquic "github.com/libp2p/go-libp2p/p2p/transport/quic"
-	"github.com/libp2p/go-libp2p/p2p/transport/tcp"
 	"github.com/multiformats/go-multiaddr"
 	msmux "github.com/multiformats/go-multistream"
 	"go.uber.org/multierr"
@@ -48,8 +47,11 @@ const (
 )
 
 type HostConfig struct {
-	PrivKeyBytes   []byte
-	ListenAddrs    []multiaddr.Multiaddr
+	PrivKeyBytes []byte
+	// ListenAddrs override listen addresses of all transports
+	ListenAddrs []multiaddr.Multiaddr
+	// Transports are DefaultTransports if empty
+	Transports     []TransportConfig
 	UserAgent      string
 	BootstrapPeers []peer.AddrInfo
 
@@ -82,17 +84,26 @@ type P2p struct {
 	dht              *dht.IpfsDHT
 	bandwidthCounter metrics.Reporter
 	connManager      *connmgr.BasicConnMgr
-	bootstrapPeers   []peer.AddrInfo
 	startedAt        time.Time
-	bootstrapsInfo   atomic.Pointer[map[string]BootstrapPeerDebugInfo]
+
+	bootstrapPeersLock sync.RWMutex
+	bootstrapPeers     []peer.AddrInfo
+	bootstrapsInfo     atomic.Pointer[map[string]BootstrapPeerDebugInfo]
+
+	natManager   atomic.Pointer[basichost.NATManager]
+	natTypesLock sync.RWMutex
+	natTypes     map[network.NATTransportProtocol]network.NATDeviceType
+	holePunches  *holePunchTracer
 }
 
 func NewP2p(ctx context.Context) *P2p {
 	newCtx, ctxCancel := context.WithCancel(ctx)
 	return &P2p{
-		ctx:       newCtx,
-		ctxCancel: ctxCancel,
-		logger:    log.Logger("awl/p2p"),
+		ctx:         newCtx,
+		ctxCancel:   ctxCancel,
+		logger:      log.Logger("awl/p2p"),
+		natTypes:    make(map[network.NATTransportProtocol]network.NATDeviceType),
+		holePunches: newHolePunchTracer(),
 	}
 }
 
@@ -123,9 +134,22 @@ func (p *P2p) InitHost(hostConfig HostConfig) (host.Host, error) {
 		return nil, fmt.Errorf("new conn manager: %v", err)
 	}
 
+	transports := hostConfig.Transports
+	if len(transports) == 0 {
+		transports = DefaultTransports()
+	}
+	transportsOpt, err := transportsOption(transports)
+	if err != nil {
+		return nil, err
+	}
+	var swarmOpts []swarm.Option
+	if dialRanker := newDialRanker(transports); dialRanker != nil {
+		swarmOpts = append(swarmOpts, swarm.WithDialRanker(dialRanker))
+	}
+
 	listenAddrs := hostConfig.ListenAddrs
 	if len(listenAddrs) == 0 {
-		listenAddrs = findListenAddrs()
+		listenAddrs = transportsListenAddrs(transports)
 	}
 
 	p2pHost, err := libp2p.New(
@@ -135,10 +159,8 @@ func (p *P2p) InitHost(hostConfig HostConfig) (host.Host, error) {
 		libp2p.BandwidthReporter(p.bandwidthCounter),
 		libp2p.ConnectionManager(p.connManager),
 		libp2p.ListenAddrs(listenAddrs...),
-		libp2p.ChainOptions(
-			libp2p.Transport(libp2pquic.NewTransport),
-			libp2p.Transport(tcp.NewTCPTransport),
-		),
+		transportsOpt,
+		libp2p.SwarmOpts(swarmOpts...),
 		libp2p.Routing(func(h host.Host) (routing.PeerRouting, error) {
 			opts := []dht.Option{
 				dht.Datastore(hostConfig.DHTDatastore),
@@ -161,6 +183,11 @@ func (p *P2p) InitHost(hostConfig HostConfig) (host.Host, error) {
 	p.host = p2pHost
 	p.startedAt = time.Now()
 
+	err = p.subscribeNATDeviceType()
+	if err != nil {
+		return nil, fmt.Errorf("subscribe to nat device type: %v", err)
+	}
+
 	return p2pHost, nil
 }
 
@@ -211,9 +238,10 @@ func (p *P2p) FindPeer(ctx context.Context, id peer.ID) (peer.AddrInfo, error) {
 	return p.dht.FindPeer(ctx, id)
 }
 
-func (p *P2p) NewStream(ctx context.Context, id peer.ID, proto protocol.ID) (network.Stream, error) {
+// NewStream opens stream with the first of protos supported by peer.
+func (p *P2p) NewStream(ctx context.Context, id peer.ID, protos ...protocol.ID) (network.Stream, error) {
 	ctx = network.WithAllowLimitedConn(ctx, "awl")
-	return p.host.NewStream(ctx, id, proto)
+	return p.host.NewStream(ctx, id, protos...)
 }
 
 func (p *P2p) NewStreamWithDedicatedConn(ctx context.Context, id peer.ID, proto protocol.ID) (network.Stream, error) {
@@ -271,7 +299,7 @@ func (p *P2p) Bootstrap() error {
 	defer cancel()
 	var wg sync.WaitGroup
 
-	for _, peerAddr := range p.bootstrapPeers {
+	for _, peerAddr := range p.BootstrapPeers() {
 		wg.Add(1)
 		p.host.ConnManager().Protect(peerAddr.ID, protectedBootstrapPeerTag)
 
@@ -337,7 +365,7 @@ func (p *P2p) connectToKnownPeers(ctx context.Context, timeout time.Duration, pe
 	bootstrapsInfo := make(map[string]BootstrapPeerDebugInfo)
 	var mu sync.Mutex
 
-	for _, peerAddr := range p.bootstrapPeers {
+	for _, peerAddr := range p.BootstrapPeers() {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
@@ -363,52 +391,79 @@ func (p *P2p) connectToKnownPeers(ctx context.Context, timeout time.Duration, pe
 	p.bootstrapsInfo.Store(&bootstrapsInfo)
 }
 
-func (p *P2p) connsToPeer(peerID peer.ID) []network.Conn {
-	return p.host.Network().ConnsToPeer(peerID)
+func (p *P2p) BootstrapPeers() []peer.AddrInfo {
+	p.bootstrapPeersLock.RLock()
+	defer p.bootstrapPeersLock.RUnlock()
+	return slices.Clone(p.bootstrapPeers)
 }
 
-func (p *P2p) peerAddressesString(peerID peer.ID) []string {
-	conns := p.connsToPeer(peerID)
-	addrs := make([]string, 0, len(conns))
-	for _, conn := range conns {
-		addrs = append(addrs, conn.RemoteMultiaddr().String())
+// SetBootstrapPeers replaces bootstrap peers at runtime and returns peers which were not used before.
+// New peers are not connected automatically, use ConnectBootstrapPeer.
+func (p *P2p) SetBootstrapPeers(peers []peer.AddrInfo) []peer.AddrInfo {
+	newPeers := make(map[peer.ID]struct{}, len(peers))
+	for _, peerAddr := range peers {
+		newPeers[peerAddr.ID] = struct{}{}
 	}
-	return addrs
+
+	p.bootstrapPeersLock.Lock()
+	oldPeers := make(map[peer.ID]struct{}, len(p.bootstrapPeers))
+	for _, peerAddr := range p.bootstrapPeers {
+		oldPeers[peerAddr.ID] = struct{}{}
+		if _, exists := newPeers[peerAddr.ID]; !exists {
+			p.host.ConnManager().Unprotect(peerAddr.ID, protectedBootstrapPeerTag)
+		}
+	}
+	p.bootstrapPeers = slices.Clone(peers)
+	p.bootstrapPeersLock.Unlock()
+
+	var added []peer.AddrInfo
+	for _, peerAddr := range peers {
+		if _, exists := oldPeers[peerAddr.ID]; !exists {
+			added = append(added, peerAddr)
+		}
+	}
+
+	return added
 }
 
-func findListenAddrs() []multiaddr.Multiaddr {
-	// check if default port is open on tcp and udp
-	tcpListener, err := net.ListenTCP("tcp", &net.TCPAddr{Port: defaultP2pPort})
+// ConnectBootstrapPeer connects to bootstrap peer immediately and updates its debug info.
+func (p *P2p) ConnectBootstrapPeer(ctx context.Context, peerAddr peer.AddrInfo) BootstrapPeerDebugInfo {
+	p.host.ConnManager().Protect(peerAddr.ID, protectedBootstrapPeerTag)
+	p.ClearBackoff(peerAddr.ID)
+
+	var info BootstrapPeerDebugInfo
+	err := p.host.Connect(ctx, peerAddr)
 	if err != nil {
-		return UnicastListenAddrs()
+		info.Error = err.Error()
 	}
-	_ = tcpListener.Close()
+	info.Connections = p.peerAddressesString(peerAddr.ID)
 
-	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{Port: defaultP2pPort})
-	if err != nil {
-		return UnicastListenAddrs()
+	for {
+		old := p.bootstrapsInfo.Load()
+		bootstrapsInfo := make(map[string]BootstrapPeerDebugInfo)
+		if old != nil {
+			maps.Copy(bootstrapsInfo, *old)
+		}
+		bootstrapsInfo[peerAddr.ID.String()] = info
+		if p.bootstrapsInfo.CompareAndSwap(old, &bootstrapsInfo) {
+			break
+		}
 	}
-	_ = udpConn.Close()
 
-	return DefaultListenAddrs()
+	return info
 }
 
-func UnicastListenAddrs() []multiaddr.Multiaddr {
-	return []multiaddr.Multiaddr{
-		multiaddr.StringCast("/ip4/0.0.0.0/tcp/0"),
-		multiaddr.StringCast("/ip6/::/tcp/0"),
-		multiaddr.StringCast("/ip4/0.0.0.0/udp/0/quic-v1"),
-		multiaddr.StringCast("/ip6/::/udp/0/quic-v1"),
-	}
+func (p *P2p) ConnsToPeer(peerID peer.ID) []network.Conn {
+	return p.host.Network().ConnsToPeer(peerID)
 }
 
-func DefaultListenAddrs() []multiaddr.Multiaddr {
-	return []multiaddr.Multiaddr{
-		multiaddr.StringCast(fmt.Sprintf("/ip4/0.0.0.0/tcp/%d", defaultP2pPort)),
-		multiaddr.StringCast(fmt.Sprintf("/ip6/::/tcp/%d", defaultP2pPort)),
-		multiaddr.StringCast(fmt.Sprintf("/ip4/0.0.0.0/udp/%d/quic-v1", defaultP2pPort)),
-		multiaddr.StringCast(fmt.Sprintf("/ip6/::/udp/%d/quic-v1", defaultP2pPort)),
+func (p *P2p) peerAddressesString(peerID peer.ID) []string {
+	conns := p.ConnsToPeer(peerID)
+	addrs := make([]string, 0, len(conns))
+	for _, conn := range conns {
+		addrs = append(addrs, conn.RemoteMultiaddr().String())
 	}
+	return addrs
 }
 
 // copied from
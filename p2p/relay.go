@@ -0,0 +1,111 @@
+package p2p
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/p2p/host/autorelay"
+	"github.com/libp2p/go-libp2p/p2p/protocol/circuitv2/proto"
+	relayv2 "github.com/libp2p/go-libp2p/p2p/protocol/circuitv2/relay"
+	"github.com/multiformats/go-multiaddr"
+)
+
+// unlimitedRelayDuration is sent to peers in seconds as uint32, so it should not be too big.
+const unlimitedRelayDuration = 365 * 24 * time.Hour
+
+type RelayServiceConfig struct {
+	// MaxReservations is the maximum number of active relay slots, 0 means libp2p default
+	MaxReservations int
+	// MaxCircuits is the maximum number of open relay connections for each peer, 0 means libp2p default
+	MaxCircuits int
+	// LimitDuration of each relayed connection, 0 means no limit
+	LimitDuration time.Duration
+	// LimitData in bytes relayed in each direction of connection, 0 means no limit
+	LimitData int64
+}
+
+// RelayServiceOption enables relay service which serves only peers accepted by isFriend.
+func RelayServiceOption(conf RelayServiceConfig, isFriend func(peer.ID) bool) libp2p.Option {
+	resources := relayv2.DefaultResources()
+	resources.Limit = nil
+	if conf.LimitDuration != 0 || conf.LimitData != 0 {
+		// libp2p treats zero values of a set limit literally, so replace them with practically unlimited values
+		resources.Limit = &relayv2.RelayLimit{
+			Duration: conf.LimitDuration,
+			Data:     conf.LimitData,
+		}
+		if resources.Limit.Duration == 0 {
+			resources.Limit.Duration = unlimitedRelayDuration
+		}
+		if resources.Limit.Data == 0 {
+			resources.Limit.Data = math.MaxInt64
+		}
+	}
+	if conf.MaxReservations != 0 {
+		resources.MaxReservations = conf.MaxReservations
+	}
+	if conf.MaxCircuits != 0 {
+		resources.MaxCircuits = conf.MaxCircuits
+	}
+
+	return libp2p.EnableRelayService(
+		relayv2.WithResources(resources),
+		relayv2.WithACL(friendsRelayACL{isFriend: isFriend}),
+	)
+}
+
+type friendsRelayACL struct {
+	isFriend func(peer.ID) bool
+}
+
+func (f friendsRelayACL) AllowReserve(p peer.ID, _ multiaddr.Multiaddr) bool {
+	return f.isFriend(p)
+}
+
+func (f friendsRelayACL) AllowConnect(src peer.ID, _ multiaddr.Multiaddr, dest peer.ID) bool {
+	return f.isFriend(src) || f.isFriend(dest)
+}
+
+// RelayPeerSource returns relay candidates for autorelay.
+// Connected known peers which run relay service are preferred, bootstrap peers are used only to fill up to DesiredRelays.
+func (p *P2p) RelayPeerSource(knownPeersIdsFunc func() []peer.ID) autorelay.PeerSource {
+	return func(ctx context.Context, num int) <-chan peer.AddrInfo {
+		candidates := p.friendRelays(knownPeersIdsFunc())
+		for _, relay := range p.BootstrapPeers() {
+			if len(candidates) >= DesiredRelays {
+				break
+			}
+			candidates = append(candidates, relay)
+		}
+		if len(candidates) > num {
+			candidates = candidates[:num]
+		}
+
+		result := make(chan peer.AddrInfo, len(candidates))
+		for _, candidate := range candidates {
+			result <- candidate
+		}
+		close(result)
+
+		return result
+	}
+}
+
+func (p *P2p) friendRelays(peerIds []peer.ID) []peer.AddrInfo {
+	var relays []peer.AddrInfo
+	for _, peerID := range peerIds {
+		if !p.IsConnected(peerID) {
+			continue
+		}
+		supported, err := p.host.Peerstore().SupportsProtocols(peerID, proto.ProtoIDv2Hop)
+		if err != nil || len(supported) == 0 {
+			continue
+		}
+		relays = append(relays, p.host.Peerstore().PeerInfo(peerID))
+	}
+
+	return relays
+}
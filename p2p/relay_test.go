@@ -0,0 +1,23 @@
+package p2p
+
+import (
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFriendsRelayACL(t *testing.T) {
+	a := require.New(t)
+	const friend, stranger, otherStranger = peer.ID("friend"), peer.ID("stranger"), peer.ID("other")
+	acl := friendsRelayACL{isFriend: func(id peer.ID) bool {
+		return id == friend
+	}}
+
+	a.True(acl.AllowReserve(friend, nil))
+	a.False(acl.AllowReserve(stranger, nil))
+
+	a.True(acl.AllowConnect(stranger, nil, friend))
+	a.True(acl.AllowConnect(friend, nil, stranger))
+	a.False(acl.AllowConnect(stranger, nil, otherStranger))
+}
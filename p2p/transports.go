@@ -0,0 +1,206 @@
+package p2p
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"slices"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/p2p/net/swarm"
+	libp2pquic "github.com/libp2p/go-libp2p/p2p/transport/quic"
+	"github.com/libp2p/go-libp2p/p2p/transport/tcp"
+	"github.com/libp2p/go-libp2p/p2p/transport/websocket"
+	libp2pwebtransport "github.com/libp2p/go-libp2p/p2p/transport/webtransport"
+	"github.com/multiformats/go-multiaddr"
+)
+
+const (
+	TransportTCP          = "tcp"
+	TransportQUIC         = "quic"
+	TransportWebSocket    = "ws"
+	TransportWebSocketTLS = "wss"
+	TransportWebTransport = "webtransport"
+
+	// dialPriorityDelay is added for each next dial priority level.
+	dialPriorityDelay = 300 * time.Millisecond
+)
+
+type TransportConfig struct {
+	// Name is one of TransportTCP, TransportQUIC, TransportWebSocket, TransportWebTransport
+	Name string
+	// ListenAddrs override default listen addresses built from Port
+	ListenAddrs []multiaddr.Multiaddr
+	// Port is used for default listen addresses, 0 means default port for transport
+	Port int
+	// DialPriority defines order of dialing peer addresses, transports with lower value are dialed first
+	DialPriority int
+	// TLSConfig enables secure websocket listener, it is used only for TransportWebSocket
+	TLSConfig *tls.Config
+}
+
+func DefaultTransports() []TransportConfig {
+	return []TransportConfig{
+		{Name: TransportTCP},
+		{Name: TransportQUIC},
+	}
+}
+
+func transportsOption(transports []TransportConfig) (libp2p.Option, error) {
+	opts := make([]libp2p.Option, 0, len(transports))
+	for _, transport := range transports {
+		switch transport.Name {
+		case TransportTCP:
+			opts = append(opts, libp2p.Transport(tcp.NewTCPTransport))
+		case TransportQUIC:
+			opts = append(opts, libp2p.Transport(libp2pquic.NewTransport))
+		case TransportWebSocket:
+			if transport.TLSConfig != nil {
+				opts = append(opts, libp2p.Transport(websocket.New, websocket.WithTLSConfig(transport.TLSConfig)))
+			} else {
+				opts = append(opts, libp2p.Transport(websocket.New))
+			}
+		case TransportWebTransport:
+			opts = append(opts, libp2p.Transport(libp2pwebtransport.New))
+		default:
+			return nil, fmt.Errorf("unknown transport %q", transport.Name)
+		}
+	}
+	if len(opts) == 0 {
+		return nil, fmt.Errorf("no transports enabled")
+	}
+
+	return libp2p.ChainOptions(opts...), nil
+}
+
+func transportsListenAddrs(transports []TransportConfig) []multiaddr.Multiaddr {
+	var listenAddrs []multiaddr.Multiaddr
+	defaultPort := -1
+	for _, transport := range transports {
+		if len(transport.ListenAddrs) != 0 {
+			listenAddrs = append(listenAddrs, transport.ListenAddrs...)
+			continue
+		}
+
+		// websocket and webtransport listen on random port by default, e.g. 443 should be set explicitly
+		// because it requires privileges and it's usually taken by HTTPS server
+		port := transport.Port
+		if port == 0 && (transport.Name == TransportTCP || transport.Name == TransportQUIC) {
+			if defaultPort == -1 {
+				defaultPort = findListenPort()
+			}
+			port = defaultPort
+		}
+
+		var suffix string
+		switch transport.Name {
+		case TransportTCP:
+			suffix = fmt.Sprintf("/tcp/%d", port)
+		case TransportQUIC:
+			suffix = fmt.Sprintf("/udp/%d/quic-v1", port)
+		case TransportWebSocket:
+			suffix = fmt.Sprintf("/tcp/%d/ws", port)
+			if transport.TLSConfig != nil {
+				suffix = fmt.Sprintf("/tcp/%d/tls/ws", port)
+			}
+		case TransportWebTransport:
+			suffix = fmt.Sprintf("/udp/%d/quic-v1/webtransport", port)
+		default:
+			continue
+		}
+		listenAddrs = append(listenAddrs,
+			multiaddr.StringCast("/ip4/0.0.0.0"+suffix),
+			multiaddr.StringCast("/ip6/::"+suffix),
+		)
+	}
+
+	return listenAddrs
+}
+
+// newDialRanker returns nil if all transports have the same priority, so default ranker should be used.
+// Otherwise, it delays dialing addresses of less preferred transports on top of default ranker delays.
+func newDialRanker(transports []TransportConfig) network.DialRanker {
+	priorities := make([]int, 0, len(transports))
+	for _, transport := range transports {
+		priorities = append(priorities, transport.DialPriority)
+	}
+	slices.Sort(priorities)
+	priorities = slices.Compact(priorities)
+	if len(priorities) <= 1 {
+		return nil
+	}
+
+	delays := make(map[string]time.Duration, len(transports))
+	for _, transport := range transports {
+		level := slices.Index(priorities, transport.DialPriority)
+		delays[transport.Name] = time.Duration(level) * dialPriorityDelay
+	}
+	// secure websocket is the same transport for dialing
+	if delay, ok := delays[TransportWebSocket]; ok {
+		delays[TransportWebSocketTLS] = delay
+	}
+
+	return func(addrs []multiaddr.Multiaddr) []network.AddrDelay {
+		ranked := swarm.DefaultDialRanker(addrs)
+		for i := range ranked {
+			ranked[i].Delay += delays[TransportFromMultiaddr(ranked[i].Addr)]
+		}
+		return ranked
+	}
+}
+
+// TransportFromMultiaddr returns transport name for direct connections or empty string if it is unknown.
+func TransportFromMultiaddr(addr multiaddr.Multiaddr) string {
+	var hasTCP, hasQUIC, hasWS, hasWSS, hasTLS, hasWebTransport bool
+	multiaddr.ForEach(addr, func(c multiaddr.Component) bool {
+		switch c.Protocol().Code {
+		case multiaddr.P_TCP:
+			hasTCP = true
+		case multiaddr.P_QUIC, multiaddr.P_QUIC_V1:
+			hasQUIC = true
+		case multiaddr.P_WS:
+			hasWS = true
+		case multiaddr.P_WSS:
+			hasWSS = true
+		case multiaddr.P_TLS:
+			hasTLS = true
+		case multiaddr.P_WEBTRANSPORT:
+			hasWebTransport = true
+		}
+		return true
+	})
+
+	switch {
+	case hasWebTransport:
+		return TransportWebTransport
+	case hasWSS, hasWS && hasTLS:
+		return TransportWebSocketTLS
+	case hasWS:
+		return TransportWebSocket
+	case hasQUIC:
+		return TransportQUIC
+	case hasTCP:
+		return TransportTCP
+	default:
+		return ""
+	}
+}
+
+// findListenPort returns default port if it is available both on tcp and udp, otherwise 0 to use random port.
+func findListenPort() int {
+	tcpListener, err := net.ListenTCP("tcp", &net.TCPAddr{Port: defaultP2pPort})
+	if err != nil {
+		return 0
+	}
+	_ = tcpListener.Close()
+
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{Port: defaultP2pPort})
+	if err != nil {
+		return 0
+	}
+	_ = udpConn.Close()
+
+	return defaultP2pPort
+}
@@ -0,0 +1,55 @@
+package p2p
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+
+	"github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransportsListenAddrs(t *testing.T) {
+	a := require.New(t)
+	custom := multiaddr.StringCast("/ip4/127.0.0.1/tcp/8080/ws")
+	addrs := transportsListenAddrs([]TransportConfig{
+		{Name: TransportTCP, Port: 1234},
+		{Name: TransportWebSocket, TLSConfig: &tls.Config{}},
+		{Name: TransportWebTransport, Port: 8443},
+		{Name: TransportWebSocket, ListenAddrs: []multiaddr.Multiaddr{custom}},
+	})
+
+	var strs []string
+	for _, addr := range addrs {
+		strs = append(strs, addr.String())
+	}
+	a.Equal([]string{
+		"/ip4/0.0.0.0/tcp/1234",
+		"/ip6/::/tcp/1234",
+		"/ip4/0.0.0.0/tcp/0/tls/ws",
+		"/ip6/::/tcp/0/tls/ws",
+		"/ip4/0.0.0.0/udp/8443/quic-v1/webtransport",
+		"/ip6/::/udp/8443/quic-v1/webtransport",
+		"/ip4/127.0.0.1/tcp/8080/ws",
+	}, strs)
+}
+
+func TestNewDialRanker(t *testing.T) {
+	a := require.New(t)
+	a.Nil(newDialRanker(DefaultTransports()))
+
+	ranker := newDialRanker([]TransportConfig{
+		{Name: TransportQUIC, DialPriority: 1},
+		{Name: TransportWebSocket, DialPriority: 0},
+	})
+	a.NotNil(ranker)
+
+	wss := multiaddr.StringCast("/ip4/1.2.3.4/tcp/443/tls/ws")
+	quic := multiaddr.StringCast("/ip4/1.2.3.4/udp/4363/quic-v1")
+	delays := make(map[string]time.Duration)
+	for _, addrDelay := range ranker([]multiaddr.Multiaddr{wss, quic}) {
+		delays[addrDelay.Addr.String()] = addrDelay.Delay
+	}
+	a.GreaterOrEqual(delays[quic.String()], dialPriorityDelay)
+	a.Less(delays[wss.String()], delays[quic.String()])
+}
@@ -0,0 +1,65 @@
+package protocol
+
+import (
+	"slices"
+)
+
+// LegacyVersion is assumed for peers which don't send supported versions in PeerStatusInfo.
+const LegacyVersion = "0.3.0"
+
+// Capabilities are optional features, they are used with a peer only if both sides support them.
+const (
+	// CapabilityInviteTokens means that peer accepts AuthPeer.InviteToken
+	CapabilityInviteTokens = "invite-tokens"
+	// CapabilityNetworkCertificates means that peer accepts membership certificates and revocation lists
+	CapabilityNetworkCertificates = "network-certificates"
+	// CapabilityKeyHandover means that peer follows our identity key rotation
+	CapabilityKeyHandover = "key-handover"
+	// CapabilityIntroductions means that peer accepts IntroducePeer and AuthPeer.Introduction
+	CapabilityIntroductions = "introductions"
+	// CapabilityTunnelVarintFraming means that peer accepts tunnel packets on TunnelVarintPacketMethod
+	CapabilityTunnelVarintFraming = "tunnel-varint-framing"
+)
+
+var (
+	// SupportedVersions are ordered from the most preferred
+	SupportedVersions = []string{controlVersion, version}
+	// SupportedCapabilities are sent to peers during status exchange
+	SupportedCapabilities = []string{
+		CapabilityInviteTokens,
+		CapabilityNetworkCertificates,
+		CapabilityKeyHandover,
+		CapabilityIntroductions,
+		CapabilityTunnelVarintFraming,
+	}
+)
+
+// Negotiated is the result of capabilities negotiation with a peer.
+type Negotiated struct {
+	// ProtocolVersion is the most preferred version supported by both peers, empty if there is no common version
+	ProtocolVersion string
+	Capabilities    []string
+}
+
+// Negotiate picks mutually supported protocol version and capabilities from our and remote peer lists.
+func Negotiate(localVersions, localCapabilities []string, remote PeerStatusInfo) Negotiated {
+	remoteVersions := remote.ProtocolVersions
+	if len(remoteVersions) == 0 {
+		remoteVersions = []string{LegacyVersion}
+	}
+
+	result := Negotiated{Capabilities: make([]string, 0)}
+	for _, ver := range localVersions {
+		if slices.Contains(remoteVersions, ver) {
+			result.ProtocolVersion = ver
+			break
+		}
+	}
+	for _, capability := range localCapabilities {
+		if slices.Contains(remote.Capabilities, capability) {
+			result.Capabilities = append(result.Capabilities, capability)
+		}
+	}
+
+	return result
+}
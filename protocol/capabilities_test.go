@@ -0,0 +1,28 @@
+package protocol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiate(t *testing.T) {
+	a := require.New(t)
+	localVersions := []string{"0.4.0", "0.3.0"}
+	localCapabilities := []string{"a", "b", "c"}
+
+	// legacy peer sends neither versions nor capabilities
+	negotiated := Negotiate(localVersions, localCapabilities, PeerStatusInfo{})
+	a.Equal(LegacyVersion, negotiated.ProtocolVersion)
+	a.Empty(negotiated.Capabilities)
+
+	negotiated = Negotiate(localVersions, localCapabilities, PeerStatusInfo{
+		ProtocolVersions: []string{"0.3.0", "0.4.0", "0.5.0"},
+		Capabilities:     []string{"c", "a", "d"},
+	})
+	a.Equal("0.4.0", negotiated.ProtocolVersion)
+	a.Equal([]string{"a", "c"}, negotiated.Capabilities)
+
+	negotiated = Negotiate(localVersions, localCapabilities, PeerStatusInfo{ProtocolVersions: []string{"0.5.0"}})
+	a.Equal("", negotiated.ProtocolVersion)
+}
@@ -0,0 +1,77 @@
+package protocol
+
+import (
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// DNSQueryMethod asks peer to resolve DNS query with its resolver, it's used for DNS forwarding rules.
+const DNSQueryMethod protocol.ID = controlBasePath + "/dns_query/"
+
+// MaxDNSMessageSize leaves room for other fields in MaxMessageSize.
+const MaxDNSMessageSize = MaxMessageSize - 1024
+
+type (
+	DNSQuery struct {
+		// Message is packed DNS message
+		Message []byte
+	}
+	DNSQueryResponse struct {
+		Message []byte
+		Error   string
+	}
+)
+
+func ReceiveDNSQuery(stream network.Stream) (DNSQuery, error) {
+	msg := DNSQuery{}
+	err := receiveMessage(stream, &msg)
+	return msg, err
+}
+
+func SendDNSQuery(stream network.Stream, msg DNSQuery) error {
+	return sendMessage(stream, &msg)
+}
+
+func ReceiveDNSQueryResponse(stream network.Stream) (DNSQueryResponse, error) {
+	response := DNSQueryResponse{}
+	err := receiveMessage(stream, &response)
+	return response, err
+}
+
+func SendDNSQueryResponse(stream network.Stream, response DNSQueryResponse) error {
+	return sendMessage(stream, &response)
+}
+
+// DNSQuery fields: 1 - Message.
+func (m *DNSQuery) appendBinary(b []byte) []byte {
+	return appendBytes(b, 1, m.Message)
+}
+
+func (m *DNSQuery) unmarshalBinary(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num == 1 {
+			return consumeBytes(typ, b, &m.Message)
+		}
+		return 0, nil
+	})
+}
+
+// DNSQueryResponse fields: 1 - Message, 2 - Error.
+func (m *DNSQueryResponse) appendBinary(b []byte) []byte {
+	b = appendBytes(b, 1, m.Message)
+	b = appendString(b, 2, m.Error)
+	return b
+}
+
+func (m *DNSQueryResponse) unmarshalBinary(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeBytes(typ, b, &m.Message)
+		case 2:
+			return consumeString(typ, b, &m.Error)
+		}
+		return 0, nil
+	})
+}
@@ -0,0 +1,347 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	// MaxMessageSize limits size of auth and status messages
+	MaxMessageSize = 64 * 1024
+	// MessageTimeout is a deadline for reading or writing auth and status message
+	MessageTimeout = 10 * time.Second
+)
+
+var ErrMessageTooLarge = errors.New("message is too large")
+
+// message is encoded as protobuf message prefixed with uvarint length.
+type message interface {
+	appendBinary(b []byte) []byte
+	unmarshalBinary(b []byte) error
+}
+
+func isLegacyProtocol(stream network.Stream) bool {
+	return strings.HasPrefix(string(stream.Protocol()), basePath+"/")
+}
+
+func receiveMessage(stream network.Stream, msg message) error {
+	_ = stream.SetReadDeadline(time.Now().Add(MessageTimeout))
+	defer func() {
+		_ = stream.SetReadDeadline(time.Time{})
+	}()
+
+	if isLegacyProtocol(stream) {
+		return json.NewDecoder(io.LimitReader(stream, MaxMessageSize)).Decode(msg)
+	}
+	return readMessage(stream, msg)
+}
+
+func sendMessage(stream network.Stream, msg message) error {
+	_ = stream.SetWriteDeadline(time.Now().Add(MessageTimeout))
+	defer func() {
+		_ = stream.SetWriteDeadline(time.Time{})
+	}()
+
+	if isLegacyProtocol(stream) {
+		return json.NewEncoder(stream).Encode(msg)
+	}
+	return writeMessage(stream, msg)
+}
+
+func readMessage(stream io.Reader, msg message) error {
+	size, err := binary.ReadUvarint(byteReader{stream})
+	if err != nil {
+		return err
+	}
+	if size > MaxMessageSize {
+		return fmt.Errorf("%w: %d bytes", ErrMessageTooLarge, size)
+	}
+	data := make([]byte, size)
+	_, err = io.ReadFull(stream, data)
+	if err != nil {
+		return err
+	}
+
+	return msg.unmarshalBinary(data)
+}
+
+func writeMessage(stream io.Writer, msg message) error {
+	payload := msg.appendBinary(nil)
+	if len(payload) > MaxMessageSize {
+		return fmt.Errorf("%w: %d bytes", ErrMessageTooLarge, len(payload))
+	}
+	data := protowire.AppendVarint(make([]byte, 0, binary.MaxVarintLen64+len(payload)), uint64(len(payload)))
+	data = append(data, payload...)
+	_, err := stream.Write(data)
+
+	return err
+}
+
+type byteReader struct {
+	io.Reader
+}
+
+func (r byteReader) ReadByte() (byte, error) {
+	var b [1]byte
+	_, err := io.ReadFull(r.Reader, b[:])
+	return b[0], err
+}
+
+// consumeFields calls field for each field of protobuf message.
+// field returns number of consumed bytes, 0 means unknown field which is skipped.
+func consumeFields(b []byte, field func(num protowire.Number, typ protowire.Type, b []byte) (int, error)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		n, err := field(num, typ, b)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+
+	return nil
+}
+
+func consumeString(typ protowire.Type, b []byte, value *string) (int, error) {
+	if typ != protowire.BytesType {
+		return 0, fmt.Errorf("unexpected wire type %d for string field", typ)
+	}
+	val, n := protowire.ConsumeString(b)
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+	*value = val
+	return n, nil
+}
+
+func consumeBytes(typ protowire.Type, b []byte, value *[]byte) (int, error) {
+	if typ != protowire.BytesType {
+		return 0, fmt.Errorf("unexpected wire type %d for bytes field", typ)
+	}
+	val, n := protowire.ConsumeBytes(b)
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+	*value = append([]byte(nil), val...)
+	return n, nil
+}
+
+func consumeBool(typ protowire.Type, b []byte, value *bool) (int, error) {
+	if typ != protowire.VarintType {
+		return 0, fmt.Errorf("unexpected wire type %d for bool field", typ)
+	}
+	val, n := protowire.ConsumeVarint(b)
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+	*value = protowire.DecodeBool(val)
+	return n, nil
+}
+
+func consumeUint64(typ protowire.Type, b []byte, value *uint64) (int, error) {
+	if typ != protowire.VarintType {
+		return 0, fmt.Errorf("unexpected wire type %d for uint64 field", typ)
+	}
+	val, n := protowire.ConsumeVarint(b)
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+	*value = val
+	return n, nil
+}
+
+func appendString(b []byte, num protowire.Number, value string) []byte {
+	if value == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+func appendBytes(b []byte, num protowire.Number, value []byte) []byte {
+	if len(value) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, value)
+}
+
+func appendBool(b []byte, num protowire.Number, value bool) []byte {
+	if !value {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, protowire.EncodeBool(value))
+}
+
+func appendUint64(b []byte, num protowire.Number, value uint64) []byte {
+	if value == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, value)
+}
+
+func appendStrings(b []byte, num protowire.Number, values []string) []byte {
+	for _, value := range values {
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		b = protowire.AppendString(b, value)
+	}
+	return b
+}
+
+// PeerStatusInfo fields:
+// 1 - Name, 2 - Declined, 3 - AllowUsingAsExitNode, 4 - ProtocolVersions, 5 - Capabilities,
+// 6 - Certificate, 7 - MemberCertificates, 8 - RevocationList, 9 - AdminList, 10 - KeyHandover, 11 - Hostname,
+// 12 - OS, 13 - Arch, 14 - Version, 15 - UptimeSec, 16 - HealthShared, 17 - TunUp, 18 - DNSUp, 19 - Note.
+func (m *PeerStatusInfo) appendBinary(b []byte) []byte {
+	b = appendString(b, 1, m.Name)
+	b = appendBool(b, 2, m.Declined)
+	b = appendBool(b, 3, m.AllowUsingAsExitNode)
+	b = appendStrings(b, 4, m.ProtocolVersions)
+	b = appendStrings(b, 5, m.Capabilities)
+	b = appendString(b, 6, m.Certificate)
+	b = appendStrings(b, 7, m.MemberCertificates)
+	b = appendString(b, 8, m.RevocationList)
+	b = appendString(b, 9, m.AdminList)
+	b = appendString(b, 10, m.KeyHandover)
+	b = appendString(b, 11, m.Hostname)
+	b = appendString(b, 12, m.OS)
+	b = appendString(b, 13, m.Arch)
+	b = appendString(b, 14, m.Version)
+	b = appendUint64(b, 15, m.UptimeSec)
+	b = appendBool(b, 16, m.HealthShared)
+	b = appendBool(b, 17, m.TunUp)
+	b = appendBool(b, 18, m.DNSUp)
+	b = appendString(b, 19, m.Note)
+	return b
+}
+
+func (m *PeerStatusInfo) unmarshalBinary(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		var value string
+		switch num {
+		case 1:
+			return consumeString(typ, b, &m.Name)
+		case 2:
+			return consumeBool(typ, b, &m.Declined)
+		case 3:
+			return consumeBool(typ, b, &m.AllowUsingAsExitNode)
+		case 4:
+			n, err := consumeString(typ, b, &value)
+			m.ProtocolVersions = append(m.ProtocolVersions, value)
+			return n, err
+		case 5:
+			n, err := consumeString(typ, b, &value)
+			m.Capabilities = append(m.Capabilities, value)
+			return n, err
+		case 6:
+			return consumeString(typ, b, &m.Certificate)
+		case 7:
+			n, err := consumeString(typ, b, &value)
+			m.MemberCertificates = append(m.MemberCertificates, value)
+			return n, err
+		case 8:
+			return consumeString(typ, b, &m.RevocationList)
+		case 9:
+			return consumeString(typ, b, &m.AdminList)
+		case 10:
+			return consumeString(typ, b, &m.KeyHandover)
+		case 11:
+			return consumeString(typ, b, &m.Hostname)
+		case 12:
+			return consumeString(typ, b, &m.OS)
+		case 13:
+			return consumeString(typ, b, &m.Arch)
+		case 14:
+			return consumeString(typ, b, &m.Version)
+		case 15:
+			return consumeUint64(typ, b, &m.UptimeSec)
+		case 16:
+			return consumeBool(typ, b, &m.HealthShared)
+		case 17:
+			return consumeBool(typ, b, &m.TunUp)
+		case 18:
+			return consumeBool(typ, b, &m.DNSUp)
+		case 19:
+			return consumeString(typ, b, &m.Note)
+		}
+		return 0, nil
+	})
+}
+
+// AuthPeer fields: 1 - Name, 2 - InviteToken, 3 - Certificate, 4 - Introduction, 5 - Message, 6 - OS, 7 - Version,
+// 8 - ProposedAlias.
+func (m *AuthPeer) appendBinary(b []byte) []byte {
+	b = appendString(b, 1, m.Name)
+	b = appendString(b, 2, m.InviteToken)
+	b = appendString(b, 3, m.Certificate)
+	b = appendString(b, 4, m.Introduction)
+	b = appendString(b, 5, m.Message)
+	b = appendString(b, 6, m.OS)
+	b = appendString(b, 7, m.Version)
+	b = appendString(b, 8, m.ProposedAlias)
+	return b
+}
+
+func (m *AuthPeer) unmarshalBinary(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeString(typ, b, &m.Name)
+		case 2:
+			return consumeString(typ, b, &m.InviteToken)
+		case 3:
+			return consumeString(typ, b, &m.Certificate)
+		case 4:
+			return consumeString(typ, b, &m.Introduction)
+		case 5:
+			return consumeString(typ, b, &m.Message)
+		case 6:
+			return consumeString(typ, b, &m.OS)
+		case 7:
+			return consumeString(typ, b, &m.Version)
+		case 8:
+			return consumeString(typ, b, &m.ProposedAlias)
+		}
+		return 0, nil
+	})
+}
+
+// AuthPeerResponse fields: 1 - Confirmed, 2 - Declined.
+func (m *AuthPeerResponse) appendBinary(b []byte) []byte {
+	b = appendBool(b, 1, m.Confirmed)
+	b = appendBool(b, 2, m.Declined)
+	return b
+}
+
+func (m *AuthPeerResponse) unmarshalBinary(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeBool(typ, b, &m.Confirmed)
+		case 2:
+			return consumeBool(typ, b, &m.Declined)
+		}
+		return 0, nil
+	})
+}
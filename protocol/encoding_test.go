@@ -0,0 +1,154 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestBinaryMessages(t *testing.T) {
+	a := require.New(t)
+	buf := new(bytes.Buffer)
+
+	status := PeerStatusInfo{
+		Name:                 "peer",
+		AllowUsingAsExitNode: true,
+		ProtocolVersions:     []string{"0.4.0", "0.3.0"},
+		Capabilities:         []string{CapabilityInviteTokens},
+		Certificate:          "cert",
+		MemberCertificates:   []string{"cert1", "cert2"},
+		RevocationList:       "list",
+		AdminList:            "admins",
+		KeyHandover:          "handover",
+		Hostname:             "laptop",
+		OS:                   "linux",
+		Arch:                 "amd64",
+		Version:              "v0.12.0",
+		UptimeSec:            3600,
+		HealthShared:         true,
+		DNSUp:                true,
+		Note:                 "on vacation",
+	}
+	a.NoError(writeMessage(buf, &status))
+	a.NoError(writeMessage(buf, &AuthPeer{Name: "peer", InviteToken: "token", Message: "hi", OS: "linux", Version: "v0.12.0", ProposedAlias: "laptop"}))
+	a.NoError(writeMessage(buf, &AuthPeerResponse{Declined: true}))
+
+	receivedStatus := PeerStatusInfo{}
+	a.NoError(readMessage(buf, &receivedStatus))
+	a.Equal(status, receivedStatus)
+	receivedAuth := AuthPeer{}
+	a.NoError(readMessage(buf, &receivedAuth))
+	a.Equal(AuthPeer{Name: "peer", InviteToken: "token", Message: "hi", OS: "linux", Version: "v0.12.0", ProposedAlias: "laptop"}, receivedAuth)
+	receivedResponse := AuthPeerResponse{}
+	a.NoError(readMessage(buf, &receivedResponse))
+	a.Equal(AuthPeerResponse{Declined: true}, receivedResponse)
+	a.Zero(buf.Len())
+
+	offer := FileOffer{Name: "photo.jpg", Size: 1 << 40, SHA256: "abcdef"}
+	a.NoError(writeMessage(buf, &offer))
+	a.NoError(writeMessage(buf, &FileOfferResponse{Accepted: true, Offset: 4096}))
+	a.NoError(writeMessage(buf, &FileTransferResult{Error: "hash mismatch"}))
+	receivedOffer := FileOffer{}
+	a.NoError(readMessage(buf, &receivedOffer))
+	a.Equal(offer, receivedOffer)
+	receivedOfferResponse := FileOfferResponse{}
+	a.NoError(readMessage(buf, &receivedOfferResponse))
+	a.Equal(FileOfferResponse{Accepted: true, Offset: 4096}, receivedOfferResponse)
+	receivedResult := FileTransferResult{}
+	a.NoError(readMessage(buf, &receivedResult))
+	a.Equal(FileTransferResult{Error: "hash mismatch"}, receivedResult)
+	a.Zero(buf.Len())
+
+	a.NoError(writeMessage(buf, &WakeOnLAN{MAC: "aa:bb:cc:dd:ee:ff"}))
+	a.NoError(writeMessage(buf, &WakeOnLANResponse{Interfaces: []string{"eth0", "wlan0"}}))
+	receivedWake := WakeOnLAN{}
+	a.NoError(readMessage(buf, &receivedWake))
+	a.Equal(WakeOnLAN{MAC: "aa:bb:cc:dd:ee:ff"}, receivedWake)
+	receivedWakeResponse := WakeOnLANResponse{}
+	a.NoError(readMessage(buf, &receivedWakeResponse))
+	a.Equal(WakeOnLANResponse{Interfaces: []string{"eth0", "wlan0"}}, receivedWakeResponse)
+	a.Zero(buf.Len())
+
+	query := DNSQuery{Message: []byte{0x12, 0x34, 0x01, 0x00, 0x00, 0x01}}
+	a.NoError(writeMessage(buf, &query))
+	a.NoError(writeMessage(buf, &DNSQueryResponse{Error: "not allowed"}))
+	receivedQuery := DNSQuery{}
+	a.NoError(readMessage(buf, &receivedQuery))
+	a.Equal(query, receivedQuery)
+	receivedQueryResponse := DNSQueryResponse{}
+	a.NoError(readMessage(buf, &receivedQueryResponse))
+	a.Equal(DNSQueryResponse{Error: "not allowed"}, receivedQueryResponse)
+	a.Zero(buf.Len())
+
+	// unknown fields from newer peers are skipped
+	payload := (&AuthPeerResponse{Confirmed: true}).appendBinary(nil)
+	payload = protowire.AppendTag(payload, 100, protowire.BytesType)
+	payload = protowire.AppendString(payload, "new field")
+	payload = protowire.AppendTag(payload, 101, protowire.VarintType)
+	payload = protowire.AppendVarint(payload, 42)
+	buf.Write(protowire.AppendVarint(nil, uint64(len(payload))))
+	buf.Write(payload)
+	receivedResponse = AuthPeerResponse{}
+	a.NoError(readMessage(buf, &receivedResponse))
+	a.True(receivedResponse.Confirmed)
+
+	// size is checked before reading message
+	buf.Reset()
+	buf.Write(protowire.AppendVarint(nil, MaxMessageSize+1))
+	a.ErrorIs(readMessage(buf, &AuthPeer{}), ErrMessageTooLarge)
+	a.ErrorIs(writeMessage(buf, &AuthPeer{Name: string(make([]byte, MaxMessageSize))}), ErrMessageTooLarge)
+
+	// truncated message
+	buf.Reset()
+	a.NoError(writeMessage(buf, &AuthPeer{Name: "peer"}))
+	buf.Truncate(buf.Len() - 1)
+	a.Error(readMessage(buf, &AuthPeer{}))
+}
+
+func TestAuthPeerSanitize(t *testing.T) {
+	a := require.New(t)
+	authPeer := AuthPeer{
+		Name:          "peer\x00\n",
+		Message:       "  hello\n\tfrom\x1b[31m me\u202e  " + strings.Repeat("я", MaxAuthMessageLength),
+		OS:            "linux\x00",
+		Version:       "v0.12.0\xff",
+		ProposedAlias: strings.Repeat("a", 100),
+	}
+	authPeer.Sanitize()
+	a.Equal("peer", authPeer.Name)
+	a.True(strings.HasPrefix(authPeer.Message, "hello from [31m me я"))
+	a.Equal(MaxAuthMessageLength, utf8.RuneCountInString(authPeer.Message))
+	a.Equal("linux", authPeer.OS)
+	a.Equal("v0.12.0", authPeer.Version)
+	a.Len(authPeer.ProposedAlias, maxAuthFieldLength)
+}
+
+func TestPeerStatusInfoSanitize(t *testing.T) {
+	a := require.New(t)
+	info := PeerStatusInfo{Hostname: "host\x00", Note: "note\n", UptimeSec: 1 << 63}
+	info.Sanitize()
+	a.Equal("host", info.Hostname)
+	a.Equal("note", info.Note)
+	a.Equal(uint64(maxUptimeSec), info.UptimeSec)
+	a.Positive(time.Duration(info.UptimeSec) * time.Second)
+}
+
+func TestWriteVarintPacketToBuf(t *testing.T) {
+	a := require.New(t)
+	packet := bytes.Repeat([]byte{1}, 1400)
+	buf := make([]byte, 2048)
+	framed := WriteVarintPacketToBuf(buf, packet)
+	a.Len(framed, len(packet)+2)
+
+	reader := bytes.NewReader(framed)
+	size, err := binary.ReadUvarint(reader)
+	a.NoError(err)
+	a.Equal(uint64(len(packet)), size)
+	a.Equal(len(packet), reader.Len())
+}
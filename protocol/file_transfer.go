@@ -0,0 +1,134 @@
+package protocol
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// FileTransferMethod carries single file: sender writes FileOffer, receiver answers with FileOfferResponse,
+// sender writes file contents starting from FileOfferResponse Offset and receiver answers with FileTransferResult.
+const FileTransferMethod protocol.ID = controlBasePath + "/file/"
+
+const MaxFileNameLength = 255
+
+type (
+	FileOffer struct {
+		Name string
+		Size uint64
+		// SHA256 of whole file in hex, it's used to verify file and to find partially received file for resume
+		SHA256 string
+	}
+	FileOfferResponse struct {
+		Accepted bool
+		// Offset from which sender should continue, it's size of partially received file
+		Offset uint64
+		Reason string
+	}
+	FileTransferResult struct {
+		OK    bool
+		Error string
+	}
+)
+
+func ReceiveFileOffer(stream network.Stream) (FileOffer, error) {
+	offer := FileOffer{}
+	err := receiveMessage(stream, &offer)
+	offer.Name = sanitizeText(offer.Name, MaxFileNameLength)
+	return offer, err
+}
+
+func SendFileOffer(stream network.Stream, offer FileOffer) error {
+	return sendMessage(stream, &offer)
+}
+
+// ReceiveFileOfferResponse waits for response up to timeout, it's longer than MessageTimeout because receiver may ask user.
+func ReceiveFileOfferResponse(stream network.Stream, timeout time.Duration) (FileOfferResponse, error) {
+	_ = stream.SetReadDeadline(time.Now().Add(timeout))
+	defer func() {
+		_ = stream.SetReadDeadline(time.Time{})
+	}()
+
+	response := FileOfferResponse{}
+	err := readMessage(stream, &response)
+	return response, err
+}
+
+func SendFileOfferResponse(stream network.Stream, response FileOfferResponse) error {
+	return sendMessage(stream, &response)
+}
+
+func ReceiveFileTransferResult(stream network.Stream) (FileTransferResult, error) {
+	result := FileTransferResult{}
+	err := receiveMessage(stream, &result)
+	return result, err
+}
+
+func SendFileTransferResult(stream network.Stream, result FileTransferResult) error {
+	return sendMessage(stream, &result)
+}
+
+// FileOffer fields: 1 - Name, 2 - Size, 3 - SHA256.
+func (m *FileOffer) appendBinary(b []byte) []byte {
+	b = appendString(b, 1, m.Name)
+	b = appendUint64(b, 2, m.Size)
+	b = appendString(b, 3, m.SHA256)
+	return b
+}
+
+func (m *FileOffer) unmarshalBinary(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeString(typ, b, &m.Name)
+		case 2:
+			return consumeUint64(typ, b, &m.Size)
+		case 3:
+			return consumeString(typ, b, &m.SHA256)
+		}
+		return 0, nil
+	})
+}
+
+// FileOfferResponse fields: 1 - Accepted, 2 - Offset, 3 - Reason.
+func (m *FileOfferResponse) appendBinary(b []byte) []byte {
+	b = appendBool(b, 1, m.Accepted)
+	b = appendUint64(b, 2, m.Offset)
+	b = appendString(b, 3, m.Reason)
+	return b
+}
+
+func (m *FileOfferResponse) unmarshalBinary(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeBool(typ, b, &m.Accepted)
+		case 2:
+			return consumeUint64(typ, b, &m.Offset)
+		case 3:
+			return consumeString(typ, b, &m.Reason)
+		}
+		return 0, nil
+	})
+}
+
+// FileTransferResult fields: 1 - OK, 2 - Error.
+func (m *FileTransferResult) appendBinary(b []byte) []byte {
+	b = appendBool(b, 1, m.OK)
+	b = appendString(b, 2, m.Error)
+	return b
+}
+
+func (m *FileTransferResult) unmarshalBinary(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeBool(typ, b, &m.OK)
+		case 2:
+			return consumeString(typ, b, &m.Error)
+		}
+		return 0, nil
+	})
+}
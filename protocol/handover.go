@@ -0,0 +1,44 @@
+package protocol
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+)
+
+const keyHandoverVersion = 1
+
+// KeyHandover is signed with the old identity key when peer rotates it.
+// Friends verify it when NewPeerID presents it and move known peer to the new id keeping its settings.
+type KeyHandover struct {
+	Version   int
+	OldPeerID string
+	NewPeerID string
+	IssuedAt  time.Time
+	// BlockOldAfter is the end of grace period, friends block old peer id after it
+	BlockOldAfter time.Time
+}
+
+func EncodeKeyHandover(handover KeyHandover, oldKey crypto.PrivKey) (string, error) {
+	handover.Version = keyHandoverVersion
+	return encodeSigned(handover, oldKey)
+}
+
+// DecodeKeyHandover parses handover and verifies that it is signed by the old identity key.
+func DecodeKeyHandover(raw string) (KeyHandover, error) {
+	handover := KeyHandover{}
+	verify, err := decodeSigned(raw, &handover)
+	if err != nil {
+		return KeyHandover{}, fmt.Errorf("invalid key handover: %v", err)
+	}
+	if handover.Version != keyHandoverVersion {
+		return KeyHandover{}, fmt.Errorf("unsupported key handover version %d", handover.Version)
+	}
+	err = verify(handover.OldPeerID)
+	if err != nil {
+		return KeyHandover{}, fmt.Errorf("invalid key handover: %v", err)
+	}
+
+	return handover, nil
+}
@@ -0,0 +1,41 @@
+package protocol
+
+import (
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyHandover(t *testing.T) {
+	a := require.New(t)
+	oldKey, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	a.NoError(err)
+	oldID, err := peer.IDFromPrivateKey(oldKey)
+	a.NoError(err)
+
+	now := time.Now()
+	handover := KeyHandover{
+		OldPeerID:     oldID.String(),
+		NewPeerID:     "new",
+		IssuedAt:      now,
+		BlockOldAfter: now.Add(time.Hour),
+	}
+	encoded, err := EncodeKeyHandover(handover, oldKey)
+	a.NoError(err)
+	decoded, err := DecodeKeyHandover(encoded)
+	a.NoError(err)
+	a.Equal(handover.NewPeerID, decoded.NewPeerID)
+	a.True(handover.BlockOldAfter.Equal(decoded.BlockOldAfter))
+
+	// only owner of the old key can hand over its identity
+	otherKey, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	a.NoError(err)
+	forged, err := EncodeKeyHandover(handover, otherKey)
+	a.NoError(err)
+	_, err = DecodeKeyHandover(forged)
+	a.Error(err)
+}
@@ -0,0 +1,84 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/network"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const introductionVersion = 1
+
+var ErrIntroductionExpired = errors.New("introduction has expired")
+
+type (
+	// Introduction is signed by Introducer which knows both PeerID and Recipient.
+	// Recipient sends it with auth request to PeerID, so both peers know who introduced them.
+	Introduction struct {
+		Version    int
+		Introducer string
+		// PeerID is introduced to Recipient
+		PeerID    string
+		Name      string
+		Recipient string
+		IssuedAt  time.Time
+		ExpiresAt time.Time
+	}
+	// IntroducePeer is sent by introducer to recipient of Introduction.
+	IntroducePeer struct {
+		Introduction string
+	}
+)
+
+func EncodeIntroduction(introduction Introduction, introducerKey crypto.PrivKey) (string, error) {
+	introduction.Version = introductionVersion
+	return encodeSigned(introduction, introducerKey)
+}
+
+// DecodeIntroduction parses introduction and verifies its signature and expiration.
+func DecodeIntroduction(raw string, now time.Time) (Introduction, error) {
+	introduction := Introduction{}
+	verify, err := decodeSigned(raw, &introduction)
+	if err != nil {
+		return Introduction{}, fmt.Errorf("invalid introduction: %v", err)
+	}
+	if introduction.Version != introductionVersion {
+		return Introduction{}, fmt.Errorf("unsupported introduction version %d", introduction.Version)
+	}
+	err = verify(introduction.Introducer)
+	if err != nil {
+		return Introduction{}, fmt.Errorf("invalid introduction: %v", err)
+	}
+	if now.After(introduction.ExpiresAt) {
+		return Introduction{}, ErrIntroductionExpired
+	}
+
+	return introduction, nil
+}
+
+func ReceiveIntroducePeer(stream network.Stream) (IntroducePeer, error) {
+	msg := IntroducePeer{}
+	err := receiveMessage(stream, &msg)
+	return msg, err
+}
+
+func SendIntroducePeer(stream network.Stream, msg IntroducePeer) error {
+	return sendMessage(stream, &msg)
+}
+
+// IntroducePeer fields: 1 - Introduction.
+func (m *IntroducePeer) appendBinary(b []byte) []byte {
+	return appendString(b, 1, m.Introduction)
+}
+
+func (m *IntroducePeer) unmarshalBinary(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num == 1 {
+			return consumeString(typ, b, &m.Introduction)
+		}
+		return 0, nil
+	})
+}
@@ -0,0 +1,45 @@
+package protocol
+
+import (
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntroduction(t *testing.T) {
+	a := require.New(t)
+	introducerKey, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	a.NoError(err)
+	introducerID, err := peer.IDFromPrivateKey(introducerKey)
+	a.NoError(err)
+
+	now := time.Now()
+	introduction := Introduction{
+		Introducer: introducerID.String(),
+		PeerID:     "introduced",
+		Name:       "laptop",
+		Recipient:  "recipient",
+		IssuedAt:   now,
+		ExpiresAt:  now.Add(time.Hour),
+	}
+	encoded, err := EncodeIntroduction(introduction, introducerKey)
+	a.NoError(err)
+
+	decoded, err := DecodeIntroduction(encoded, now)
+	a.NoError(err)
+	a.Equal(introduction.PeerID, decoded.PeerID)
+	a.Equal(introduction.Recipient, decoded.Recipient)
+	_, err = DecodeIntroduction(encoded, now.Add(2*time.Hour))
+	a.ErrorIs(err, ErrIntroductionExpired)
+
+	otherKey, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	a.NoError(err)
+	forged, err := EncodeIntroduction(introduction, otherKey)
+	a.NoError(err)
+	_, err = DecodeIntroduction(forged, now)
+	a.Error(err)
+}
@@ -0,0 +1,84 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+)
+
+const (
+	// InviteURLPrefix is used to render invite token as a link.
+	InviteURLPrefix = "awl://invite/"
+
+	inviteTokenVersion = 1
+)
+
+var ErrInviteExpired = errors.New("invite token has expired")
+
+type (
+	// InviteToken is created and signed by inviting peer, it is passed to another peer out of band (link, QR code).
+	InviteToken struct {
+		Version int
+		// ID identifies invite on the issuer side, it is used to count usages and revoke invite
+		ID        string
+		PeerID    string
+		Name      string
+		Addrs     []string
+		ExpiresAt time.Time
+		MaxUses   int
+		// Permissions are granted to invited peer right after it is accepted
+		Permissions InvitePermissions
+	}
+	InvitePermissions struct {
+		AllowUsingAsExitNode bool
+	}
+)
+
+// EncodeInviteToken returns token signed with issuer's private key.
+func EncodeInviteToken(token InviteToken, privKey crypto.PrivKey) (string, error) {
+	token.Version = inviteTokenVersion
+	return encodeSigned(token, privKey)
+}
+
+// DecodeInviteToken parses token or invite link and verifies its signature and expiration.
+func DecodeInviteToken(rawToken string, now time.Time) (InviteToken, error) {
+	rawToken = strings.TrimPrefix(strings.TrimSpace(rawToken), InviteURLPrefix)
+	token := InviteToken{}
+	verify, err := decodeSigned(rawToken, &token)
+	if err != nil {
+		return InviteToken{}, fmt.Errorf("invalid invite token: %v", err)
+	}
+	if token.Version != inviteTokenVersion {
+		return InviteToken{}, fmt.Errorf("unsupported invite token version %d", token.Version)
+	}
+	err = verify(token.PeerID)
+	if errors.Is(err, ErrInvalidSignature) {
+		return InviteToken{}, errors.New("invalid invite token signature")
+	} else if err != nil {
+		return InviteToken{}, fmt.Errorf("invalid invite token: %v", err)
+	}
+
+	if now.After(token.ExpiresAt) {
+		return InviteToken{}, ErrInviteExpired
+	}
+
+	return token, nil
+}
+
+func (t InviteToken) AddrInfo() peer.AddrInfo {
+	info := peer.AddrInfo{}
+	info.ID, _ = peer.Decode(t.PeerID)
+	for _, val := range t.Addrs {
+		addr, err := multiaddr.NewMultiaddr(val)
+		if err != nil {
+			continue
+		}
+		info.Addrs = append(info.Addrs, addr)
+	}
+	return info
+}
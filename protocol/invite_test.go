@@ -0,0 +1,65 @@
+package protocol
+
+import (
+	"crypto/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInviteToken(t *testing.T) {
+	a := require.New(t)
+	privKey, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	a.NoError(err)
+	peerID, err := peer.IDFromPrivateKey(privKey)
+	a.NoError(err)
+
+	now := time.Now()
+	token := InviteToken{
+		ID:          "1234",
+		PeerID:      peerID.String(),
+		Name:        "peer",
+		Addrs:       []string{"/ip4/1.2.3.4/tcp/4363", "invalid"},
+		ExpiresAt:   now.Add(time.Hour),
+		MaxUses:     1,
+		Permissions: InvitePermissions{AllowUsingAsExitNode: true},
+	}
+	encoded, err := EncodeInviteToken(token, privKey)
+	a.NoError(err)
+
+	decoded, err := DecodeInviteToken(InviteURLPrefix+encoded, now)
+	a.NoError(err)
+	a.Equal(token.ID, decoded.ID)
+	a.Equal(token.Name, decoded.Name)
+	a.True(decoded.Permissions.AllowUsingAsExitNode)
+	addrInfo := decoded.AddrInfo()
+	a.Equal(peerID, addrInfo.ID)
+	a.Len(addrInfo.Addrs, 1)
+
+	_, err = DecodeInviteToken(encoded, now.Add(2*time.Hour))
+	a.ErrorIs(err, ErrInviteExpired)
+
+	// token signed by another peer
+	otherKey, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	a.NoError(err)
+	forged, err := EncodeInviteToken(token, otherKey)
+	a.NoError(err)
+	_, err = DecodeInviteToken(forged, now)
+	a.EqualError(err, "invalid invite token signature")
+
+	// payload modified after signing
+	token.MaxUses = 100
+	modified, err := EncodeInviteToken(token, privKey)
+	a.NoError(err)
+	payload, _, _ := strings.Cut(modified, ".")
+	_, signature, _ := strings.Cut(encoded, ".")
+	_, err = DecodeInviteToken(payload+"."+signature, now)
+	a.EqualError(err, "invalid invite token signature")
+
+	_, err = DecodeInviteToken("invalid", now)
+	a.Error(err)
+}
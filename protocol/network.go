@@ -0,0 +1,150 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+)
+
+const networkDocumentVersion = 1
+
+var (
+	ErrCertificateExpired = errors.New("certificate has expired")
+	ErrCertificateRevoked = errors.New("certificate has been revoked")
+)
+
+type (
+	// MembershipCertificate is signed by network admin, peers accept each other automatically if both have valid certificates.
+	MembershipCertificate struct {
+		Version int
+		Network string
+		PeerID  string
+		Name    string
+		// Groups are assigned to peer on acceptance
+		Groups    []string
+		IssuedAt  time.Time
+		ExpiresAt time.Time
+		// Issuer is peer id of admin who signed certificate
+		Issuer string
+		// Admins of network known to issuer, members trust certificates signed by them
+		Admins []string
+	}
+	// RevocationList is signed by network admin, it replaces previous list with older IssuedAt.
+	RevocationList struct {
+		Version        int
+		Network        string
+		IssuedAt       time.Time
+		Issuer         string
+		RevokedPeerIDs []string
+	}
+	// AdminList is signed by network admin, it replaces admins of network and previous list with older IssuedAt.
+	AdminList struct {
+		Version  int
+		Network  string
+		IssuedAt time.Time
+		Issuer   string
+		Admins   []string
+	}
+)
+
+func EncodeCertificate(cert MembershipCertificate, adminKey crypto.PrivKey) (string, error) {
+	cert.Version = networkDocumentVersion
+	return encodeSigned(cert, adminKey)
+}
+
+// DecodeCertificate parses certificate and verifies that it is signed by its issuer.
+// Use Verify to check that issuer is trusted.
+func DecodeCertificate(raw string) (MembershipCertificate, error) {
+	cert := MembershipCertificate{}
+	verify, err := decodeSigned(raw, &cert)
+	if err != nil {
+		return MembershipCertificate{}, fmt.Errorf("invalid certificate: %v", err)
+	}
+	if cert.Version != networkDocumentVersion {
+		return MembershipCertificate{}, fmt.Errorf("unsupported certificate version %d", cert.Version)
+	}
+	err = verify(cert.Issuer)
+	if err != nil {
+		return MembershipCertificate{}, fmt.Errorf("invalid certificate: %v", err)
+	}
+
+	return cert, nil
+}
+
+// Verify checks that certificate belongs to peerID, it's issued for network by one of trusted admins and is not expired or revoked.
+func (c MembershipCertificate) Verify(network string, admins []string, peerID string, revoked []string, now time.Time) error {
+	switch {
+	case c.Network != network:
+		return fmt.Errorf("certificate is issued for another network %q", c.Network)
+	case !slices.Contains(admins, c.Issuer):
+		return fmt.Errorf("certificate is issued by untrusted peer %s", c.Issuer)
+	case c.PeerID != peerID:
+		return fmt.Errorf("certificate is issued for another peer %s", c.PeerID)
+	case now.After(c.ExpiresAt):
+		return ErrCertificateExpired
+	case slices.Contains(revoked, c.PeerID):
+		return ErrCertificateRevoked
+	}
+	return nil
+}
+
+func EncodeRevocationList(list RevocationList, adminKey crypto.PrivKey) (string, error) {
+	list.Version = networkDocumentVersion
+	return encodeSigned(list, adminKey)
+}
+
+// DecodeRevocationList parses revocation list and verifies that it is signed by one of trusted admins of network.
+func DecodeRevocationList(raw, network string, admins []string) (RevocationList, error) {
+	list := RevocationList{}
+	verify, err := decodeSigned(raw, &list)
+	if err != nil {
+		return RevocationList{}, fmt.Errorf("invalid revocation list: %v", err)
+	}
+	switch {
+	case list.Version != networkDocumentVersion:
+		return RevocationList{}, fmt.Errorf("unsupported revocation list version %d", list.Version)
+	case list.Network != network:
+		return RevocationList{}, fmt.Errorf("revocation list is issued for another network %q", list.Network)
+	case !slices.Contains(admins, list.Issuer):
+		return RevocationList{}, fmt.Errorf("revocation list is issued by untrusted peer %s", list.Issuer)
+	}
+	err = verify(list.Issuer)
+	if err != nil {
+		return RevocationList{}, fmt.Errorf("invalid revocation list: %v", err)
+	}
+
+	return list, nil
+}
+
+func EncodeAdminList(list AdminList, adminKey crypto.PrivKey) (string, error) {
+	list.Version = networkDocumentVersion
+	return encodeSigned(list, adminKey)
+}
+
+// DecodeAdminList parses admin list and verifies that it is signed by one of current admins of network.
+func DecodeAdminList(raw, network string, admins []string) (AdminList, error) {
+	list := AdminList{}
+	verify, err := decodeSigned(raw, &list)
+	if err != nil {
+		return AdminList{}, fmt.Errorf("invalid admin list: %v", err)
+	}
+	switch {
+	case list.Version != networkDocumentVersion:
+		return AdminList{}, fmt.Errorf("unsupported admin list version %d", list.Version)
+	case list.Network != network:
+		return AdminList{}, fmt.Errorf("admin list is issued for another network %q", list.Network)
+	case !slices.Contains(admins, list.Issuer):
+		return AdminList{}, fmt.Errorf("admin list is issued by untrusted peer %s", list.Issuer)
+	case len(list.Admins) == 0:
+		return AdminList{}, errors.New("admin list is empty")
+	}
+	err = verify(list.Issuer)
+	if err != nil {
+		return AdminList{}, fmt.Errorf("invalid admin list: %v", err)
+	}
+
+	return list, nil
+}
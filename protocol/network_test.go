@@ -0,0 +1,89 @@
+package protocol
+
+import (
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMembershipCertificate(t *testing.T) {
+	a := require.New(t)
+	adminKey, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	a.NoError(err)
+	adminID, err := peer.IDFromPrivateKey(adminKey)
+	a.NoError(err)
+	admins := []string{adminID.String()}
+
+	now := time.Now()
+	cert := MembershipCertificate{
+		Network:   "team",
+		PeerID:    "member",
+		Name:      "laptop",
+		Groups:    []string{"ops"},
+		IssuedAt:  now,
+		ExpiresAt: now.Add(time.Hour),
+		Issuer:    adminID.String(),
+		Admins:    admins,
+	}
+	encoded, err := EncodeCertificate(cert, adminKey)
+	a.NoError(err)
+
+	decoded, err := DecodeCertificate(encoded)
+	a.NoError(err)
+	a.Equal(cert.Groups, decoded.Groups)
+	a.NoError(decoded.Verify("team", admins, "member", nil, now))
+	a.ErrorIs(decoded.Verify("team", admins, "member", nil, now.Add(2*time.Hour)), ErrCertificateExpired)
+	a.ErrorIs(decoded.Verify("team", admins, "member", []string{"member"}, now), ErrCertificateRevoked)
+	a.Error(decoded.Verify("other", admins, "member", nil, now))
+	a.Error(decoded.Verify("team", admins, "another", nil, now))
+	a.Error(decoded.Verify("team", []string{"untrusted"}, "member", nil, now))
+
+	// certificate signed by key which doesn't match issuer
+	otherKey, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	a.NoError(err)
+	forged, err := EncodeCertificate(cert, otherKey)
+	a.NoError(err)
+	_, err = DecodeCertificate(forged)
+	a.Error(err)
+
+	list := RevocationList{
+		Network:        "team",
+		IssuedAt:       now,
+		Issuer:         adminID.String(),
+		RevokedPeerIDs: []string{"member"},
+	}
+	encoded, err = EncodeRevocationList(list, adminKey)
+	a.NoError(err)
+	decodedList, err := DecodeRevocationList(encoded, "team", admins)
+	a.NoError(err)
+	a.Equal(list.RevokedPeerIDs, decodedList.RevokedPeerIDs)
+	_, err = DecodeRevocationList(encoded, "other", admins)
+	a.Error(err)
+	_, err = DecodeRevocationList(encoded, "team", []string{"untrusted"})
+	a.Error(err)
+
+	adminList := AdminList{
+		Network:  "team",
+		IssuedAt: now,
+		Issuer:   adminID.String(),
+		Admins:   []string{adminID.String(), "second-admin"},
+	}
+	encoded, err = EncodeAdminList(adminList, adminKey)
+	a.NoError(err)
+	decodedAdmins, err := DecodeAdminList(encoded, "team", admins)
+	a.NoError(err)
+	a.Equal(adminList.Admins, decodedAdmins.Admins)
+	_, err = DecodeAdminList(encoded, "other", admins)
+	a.Error(err)
+	_, err = DecodeAdminList(encoded, "team", []string{"untrusted"})
+	a.Error(err)
+	adminList.Admins = nil
+	encoded, err = EncodeAdminList(adminList, adminKey)
+	a.NoError(err)
+	_, err = DecodeAdminList(encoded, "team", admins)
+	a.Error(err)
+}
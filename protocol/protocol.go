@@ -2,21 +2,38 @@ package protocol
 
 import (
 	"encoding/binary"
-	"encoding/json"
 	"fmt"
 	"io"
+	"strings"
+	"unicode"
 
+	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/protocol"
 )
 
 const (
 	version  = "0.3.0"
 	basePath = "/awl/" + version
-
-	AuthMethod         protocol.ID = basePath + "/auth/"
-	GetStatusMethod    protocol.ID = basePath + "/status/"
+	// controlVersion is used by protocols with length-prefixed binary encoding, e.g. auth and status,
+	// peers of version 0.3.0 use JSON encoding on protocols with basePath
+	controlVersion  = "0.4.0"
+	controlBasePath = "/awl/" + controlVersion
+
+	AuthMethod         protocol.ID = controlBasePath + "/auth/"
+	GetStatusMethod    protocol.ID = controlBasePath + "/status/"
+	IntroduceMethod    protocol.ID = controlBasePath + "/introduce/"
 	TunnelPacketMethod protocol.ID = basePath + "/tunnel/"
 	Socks5PacketMethod protocol.ID = basePath + "/socks5/"
+
+	// TunnelVarintPacketMethod prefixes packets with uvarint length, see CapabilityTunnelVarintFraming
+	TunnelVarintPacketMethod protocol.ID = controlBasePath + "/tunnel/"
+
+	// RemoteAdminMethod carries single HTTP/1.1 request to api of peer and its response
+	RemoteAdminMethod protocol.ID = controlBasePath + "/remote_admin/"
+
+	// LegacyAuthMethod and LegacyGetStatusMethod use JSON encoding, they are kept for compatibility with 0.3.0 peers
+	LegacyAuthMethod      protocol.ID = basePath + "/auth/"
+	LegacyGetStatusMethod protocol.ID = basePath + "/status/"
 )
 
 type (
@@ -24,22 +41,101 @@ type (
 		Name                 string
 		Declined             bool
 		AllowUsingAsExitNode bool
+		// ProtocolVersions supported by peer, empty for peers older than capabilities negotiation
+		ProtocolVersions []string `json:",omitempty"`
+		Capabilities     []string `json:",omitempty"`
+		// Certificate is our MembershipCertificate, it's sent only to members of the same network
+		Certificate string `json:",omitempty"`
+		// MemberCertificates of other known members, they are used to discover and auto accept each other
+		MemberCertificates []string `json:",omitempty"`
+		RevocationList     string   `json:",omitempty"`
+		// AdminList is the latest signed list of network admins, it's sent only to members of the same network
+		AdminList string `json:",omitempty"`
+		// KeyHandover is sent by peer after identity key rotation, see KeyHandover
+		KeyHandover string `json:",omitempty"`
+
+		// Fields below are optional details which peer decided to share, empty if not shared
+		Hostname  string `json:",omitempty"`
+		OS        string `json:",omitempty"`
+		Arch      string `json:",omitempty"`
+		Version   string `json:",omitempty"`
+		UptimeSec uint64 `json:",omitempty"`
+		// HealthShared is set when TunUp and DNSUp are shared
+		HealthShared bool   `json:",omitempty"`
+		TunUp        bool   `json:",omitempty"`
+		DNSUp        bool   `json:",omitempty"`
+		Note         string `json:",omitempty"`
 	}
 )
 
-func ReceiveStatus(stream io.Reader) (PeerStatusInfo, error) {
+func ReceiveStatus(stream network.Stream) (PeerStatusInfo, error) {
 	statusInfo := PeerStatusInfo{}
-	err := json.NewDecoder(stream).Decode(&statusInfo)
+	err := receiveMessage(stream, &statusInfo)
+	statusInfo.Sanitize()
 	return statusInfo, err
 }
 
-func SendStatus(stream io.Writer, statusInfo PeerStatusInfo) error {
-	err := json.NewEncoder(stream).Encode(&statusInfo)
-	return err
+func SendStatus(stream network.Stream, statusInfo PeerStatusInfo) error {
+	return sendMessage(stream, &statusInfo)
 }
 
 type AuthPeer struct {
 	Name string
+	// InviteToken issued by remote peer, peer is accepted automatically if it is valid
+	InviteToken string `json:",omitempty"`
+	// Certificate is our MembershipCertificate, peer is accepted automatically if it trusts its issuer
+	Certificate string `json:",omitempty"`
+	// Introduction of us to remote peer signed by peer known to both of us
+	Introduction string `json:",omitempty"`
+	// Message is optional free text from requester, all fields below are untrusted and shown to user as is
+	Message string `json:",omitempty"`
+	// OS and Version of requester's awl
+	OS      string `json:",omitempty"`
+	Version string `json:",omitempty"`
+	// ProposedAlias is alias which requester gave to remote peer, it helps to recognize the request
+	ProposedAlias string `json:",omitempty"`
+}
+
+const (
+	MaxAuthMessageLength = 256
+	maxAuthFieldLength   = 64
+	MaxStatusNoteLength  = 128
+	// maxUptimeSec is 100 years, larger uptime would overflow time.Duration
+	maxUptimeSec = 100 * 365 * 24 * 60 * 60
+)
+
+// Sanitize bounds lengths of metadata fields and removes control characters from them.
+func (m *AuthPeer) Sanitize() {
+	m.Name = sanitizeText(m.Name, maxAuthFieldLength)
+	m.Message = sanitizeText(m.Message, MaxAuthMessageLength)
+	m.OS = sanitizeText(m.OS, maxAuthFieldLength)
+	m.Version = sanitizeText(m.Version, maxAuthFieldLength)
+	m.ProposedAlias = sanitizeText(m.ProposedAlias, maxAuthFieldLength)
+}
+
+// Sanitize bounds lengths of shared details and removes control characters from them.
+func (m *PeerStatusInfo) Sanitize() {
+	m.Hostname = sanitizeText(m.Hostname, maxAuthFieldLength)
+	m.OS = sanitizeText(m.OS, maxAuthFieldLength)
+	m.Arch = sanitizeText(m.Arch, maxAuthFieldLength)
+	m.Version = sanitizeText(m.Version, maxAuthFieldLength)
+	m.Note = sanitizeText(m.Note, MaxStatusNoteLength)
+	m.UptimeSec = min(m.UptimeSec, maxUptimeSec)
+}
+
+func sanitizeText(text string, maxLength int) string {
+	text = strings.ToValidUTF8(text, "")
+	text = strings.Map(func(r rune) rune {
+		if !unicode.IsPrint(r) {
+			return ' '
+		}
+		return r
+	}, text)
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > maxLength {
+		text = strings.TrimSpace(string(runes[:maxLength]))
+	}
+	return text
 }
 
 type AuthPeerResponse struct {
@@ -47,26 +143,25 @@ type AuthPeerResponse struct {
 	Declined  bool
 }
 
-func ReceiveAuth(stream io.Reader) (AuthPeer, error) {
+func ReceiveAuth(stream network.Stream) (AuthPeer, error) {
 	authPeer := AuthPeer{}
-	err := json.NewDecoder(stream).Decode(&authPeer)
+	err := receiveMessage(stream, &authPeer)
+	authPeer.Sanitize()
 	return authPeer, err
 }
 
-func SendAuth(stream io.Writer, authPeer AuthPeer) error {
-	err := json.NewEncoder(stream).Encode(&authPeer)
-	return err
+func SendAuth(stream network.Stream, authPeer AuthPeer) error {
+	return sendMessage(stream, &authPeer)
 }
 
-func ReceiveAuthResponse(stream io.Reader) (AuthPeerResponse, error) {
+func ReceiveAuthResponse(stream network.Stream) (AuthPeerResponse, error) {
 	response := AuthPeerResponse{}
-	err := json.NewDecoder(stream).Decode(&response)
+	err := receiveMessage(stream, &response)
 	return response, err
 }
 
-func SendAuthResponse(stream io.Writer, response AuthPeerResponse) error {
-	err := json.NewEncoder(stream).Encode(&response)
-	return err
+func SendAuthResponse(stream network.Stream, response AuthPeerResponse) error {
+	return sendMessage(stream, &response)
 }
 
 func ReadUint64(stream io.Reader) (uint64, error) {
@@ -90,3 +185,11 @@ func WritePacketToBuf(buf, packet []byte) []byte {
 
 	return buf[:lenBytesCount+n]
 }
+
+// WriteVarintPacketToBuf is like WritePacketToBuf, but packet length takes 2 bytes for usual MTU instead of 8.
+func WriteVarintPacketToBuf(buf, packet []byte) []byte {
+	lenBytesCount := binary.PutUvarint(buf, uint64(len(packet)))
+	n := copy(buf[lenBytesCount:], packet)
+
+	return buf[:lenBytesCount+n]
+}
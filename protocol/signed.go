@@ -0,0 +1,68 @@
+package protocol
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// encodeSigned returns JSON encoded value and its signature, both in base64url joined with ".".
+func encodeSigned(value any, privKey crypto.PrivKey) (string, error) {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	signature, err := privKey.Sign(payload)
+	if err != nil {
+		return "", fmt.Errorf("sign: %v", err)
+	}
+
+	encoding := base64.RawURLEncoding
+	return encoding.EncodeToString(payload) + "." + encoding.EncodeToString(signature), nil
+}
+
+// decodeSigned decodes value encoded by encodeSigned, returned verify checks that it was signed by signer peer id.
+func decodeSigned(raw string, value any) (verify func(signer string) error, err error) {
+	encodedPayload, encodedSignature, found := strings.Cut(strings.TrimSpace(raw), ".")
+	if !found {
+		return nil, errors.New("invalid format")
+	}
+	encoding := base64.RawURLEncoding
+	payload, err := encoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload: %v", err)
+	}
+	signature, err := encoding.DecodeString(encodedSignature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature encoding: %v", err)
+	}
+	err = json.Unmarshal(payload, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload: %v", err)
+	}
+
+	verify = func(signer string) error {
+		signerID, err := peer.Decode(signer)
+		if err != nil {
+			return fmt.Errorf("invalid signer peer id: %v", err)
+		}
+		pubKey, err := signerID.ExtractPublicKey()
+		if err != nil {
+			return fmt.Errorf("extract public key from peer id: %v", err)
+		}
+		valid, err := pubKey.Verify(payload, signature)
+		if err != nil || !valid {
+			return ErrInvalidSignature
+		}
+		return nil
+	}
+
+	return verify, nil
+}
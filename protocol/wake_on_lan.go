@@ -0,0 +1,77 @@
+package protocol
+
+import (
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// WakeOnLANMethod asks peer to broadcast Wake-on-LAN magic packet on its LAN interfaces.
+const WakeOnLANMethod protocol.ID = controlBasePath + "/wake_on_lan/"
+
+type (
+	WakeOnLAN struct {
+		MAC string
+	}
+	WakeOnLANResponse struct {
+		// Interfaces where magic packet was sent
+		Interfaces []string
+		Error      string
+	}
+)
+
+func ReceiveWakeOnLAN(stream network.Stream) (WakeOnLAN, error) {
+	msg := WakeOnLAN{}
+	err := receiveMessage(stream, &msg)
+	return msg, err
+}
+
+func SendWakeOnLAN(stream network.Stream, msg WakeOnLAN) error {
+	return sendMessage(stream, &msg)
+}
+
+func ReceiveWakeOnLANResponse(stream network.Stream) (WakeOnLANResponse, error) {
+	response := WakeOnLANResponse{}
+	err := receiveMessage(stream, &response)
+	return response, err
+}
+
+func SendWakeOnLANResponse(stream network.Stream, response WakeOnLANResponse) error {
+	return sendMessage(stream, &response)
+}
+
+// WakeOnLAN fields: 1 - MAC.
+func (m *WakeOnLAN) appendBinary(b []byte) []byte {
+	return appendString(b, 1, m.MAC)
+}
+
+func (m *WakeOnLAN) unmarshalBinary(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num == 1 {
+			return consumeString(typ, b, &m.MAC)
+		}
+		return 0, nil
+	})
+}
+
+// WakeOnLANResponse fields: 1 - Interfaces, 2 - Error.
+func (m *WakeOnLANResponse) appendBinary(b []byte) []byte {
+	b = appendStrings(b, 1, m.Interfaces)
+	b = appendString(b, 2, m.Error)
+	return b
+}
+
+func (m *WakeOnLANResponse) unmarshalBinary(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			var value string
+			n, err := consumeString(typ, b, &value)
+			m.Interfaces = append(m.Interfaces, value)
+			return n, err
+		case 2:
+			return consumeString(typ, b, &m.Error)
+		}
+		return 0, nil
+	})
+}
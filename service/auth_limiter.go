@@ -0,0 +1,65 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// each unknown peer could send a few auth requests in a row, then one per unknownPeerAuthInterval
+	unknownPeerAuthInterval = 20 * time.Second
+	unknownPeerAuthBurst    = 3
+	// all unknown peers together are limited to allUnknownPeersAuthRate
+	allUnknownPeersAuthRate  = rate.Limit(1)
+	allUnknownPeersAuthBurst = 20
+	// maxTrackedUnknownPeers limits memory used by per peer limiters
+	maxTrackedUnknownPeers = 1000
+)
+
+// authLimiter limits auth attempts of unknown peers per peer and globally.
+type authLimiter struct {
+	lock   sync.Mutex
+	global *rate.Limiter
+	peers  map[peer.ID]*rate.Limiter
+}
+
+func newAuthLimiter() *authLimiter {
+	return &authLimiter{
+		global: rate.NewLimiter(allUnknownPeersAuthRate, allUnknownPeersAuthBurst),
+		peers:  make(map[peer.ID]*rate.Limiter),
+	}
+}
+
+func (l *authLimiter) Allow(peerID peer.ID) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	limiter, exists := l.peers[peerID]
+	if !exists {
+		if len(l.peers) >= maxTrackedUnknownPeers {
+			l.pruneIdle()
+		}
+		limiter = rate.NewLimiter(rate.Every(unknownPeerAuthInterval), unknownPeerAuthBurst)
+		l.peers[peerID] = limiter
+	}
+	if !limiter.Allow() {
+		return false
+	}
+
+	return l.global.Allow()
+}
+
+// pruneIdle removes limiters which have fully recovered, they are equal to new ones.
+func (l *authLimiter) pruneIdle() {
+	for peerID, limiter := range l.peers {
+		if limiter.Tokens() >= unknownPeerAuthBurst {
+			delete(l.peers, peerID)
+		}
+	}
+	if len(l.peers) >= maxTrackedUnknownPeers {
+		clear(l.peers)
+	}
+}
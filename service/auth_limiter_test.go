@@ -0,0 +1,28 @@
+package service
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthLimiter(t *testing.T) {
+	limiter := newAuthLimiter()
+	peer1 := peer.ID("peer1")
+	peer2 := peer.ID("peer2")
+
+	for range unknownPeerAuthBurst {
+		require.True(t, limiter.Allow(peer1))
+	}
+	require.False(t, limiter.Allow(peer1))
+	// other peers are limited separately
+	require.True(t, limiter.Allow(peer2))
+
+	// global limit
+	for i := 0; limiter.global.Tokens() >= 1; i++ {
+		require.True(t, limiter.Allow(peer.ID(strconv.Itoa(i))))
+	}
+	require.False(t, limiter.Allow(peer.ID("peer3")))
+}
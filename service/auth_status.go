@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"maps"
+	"runtime"
 	"strings"
 	"sync"
 	"time"
@@ -16,31 +17,44 @@ import (
 	"github.com/anywherelan/awl/awldns"
 	"github.com/anywherelan/awl/awlevent"
 	"github.com/anywherelan/awl/config"
+	"github.com/anywherelan/awl/p2p"
 	"github.com/anywherelan/awl/protocol"
 )
 
 const (
 	backgroundExchangeStatusInfoInterval = 5 * time.Minute
 	backgroundRetryAuthRequests          = 5 * time.Minute
+	backgroundSavePeerHistoryInterval    = time.Minute
+	ingoingAuthRequestLifetime           = 7 * 24 * time.Hour
 )
 
 type P2p interface {
+	PeerID() peer.ID
 	ConnectPeer(ctx context.Context, peerID peer.ID) error
 	IsConnected(peerID peer.ID) bool
-	NewStream(ctx context.Context, id peer.ID, proto libp2pProtocol.ID) (network.Stream, error)
+	NewStream(ctx context.Context, id peer.ID, protos ...libp2pProtocol.ID) (network.Stream, error)
 	NewStreamWithDedicatedConn(ctx context.Context, id peer.ID, proto libp2pProtocol.ID) (network.Stream, error)
 	SubscribeConnectionEvents(onConnected, onDisconnected func(network.Network, network.Conn))
+	ConnsToPeer(peerID peer.ID) []network.Conn
+	ConnectionInfo(conn network.Conn) p2p.ConnectionInfo
 	ProtectPeer(id peer.ID)
+	UnprotectPeer(id peer.ID)
 }
 
 type AuthStatus struct {
-	ingoingAuths  map[peer.ID]protocol.AuthPeer
 	outgoingAuths map[peer.ID]protocol.AuthPeer
 	authsLock     sync.RWMutex
+	authLimiter   *authLimiter
 	logger        *log.ZapEventLogger
 	p2p           P2p
 	conf          *config.Config
 	authsEmitter  awlevent.Emitter
+	proxyEmitter  awlevent.Emitter
+	peerHistory   *config.PeerHistory
+	startedAt     time.Time
+
+	healthCheckLock sync.RWMutex
+	healthCheck     HealthCheck
 }
 
 func NewAuthStatus(p2pService P2p, conf *config.Config, eventbus awlevent.Bus) *AuthStatus {
@@ -48,14 +62,21 @@ func NewAuthStatus(p2pService P2p, conf *config.Config, eventbus awlevent.Bus) *
 	if err != nil {
 		panic(err)
 	}
+	proxyEmitter, err := eventbus.Emitter(new(awlevent.ProxyChanged))
+	if err != nil {
+		panic(err)
+	}
 
 	auth := &AuthStatus{
-		ingoingAuths:  make(map[peer.ID]protocol.AuthPeer),
 		outgoingAuths: make(map[peer.ID]protocol.AuthPeer),
+		authLimiter:   newAuthLimiter(),
 		logger:        log.Logger("awl/service/status"),
 		p2p:           p2pService,
 		conf:          conf,
 		authsEmitter:  emitter,
+		proxyEmitter:  proxyEmitter,
+		peerHistory:   config.LoadPeerHistory(conf.DataDir()),
+		startedAt:     time.Now(),
 	}
 	auth.restoreOutgoingAuths()
 	p2pService.SubscribeConnectionEvents(auth.onPeerConnected, auth.onPeerDisconnected)
@@ -71,10 +92,6 @@ func (s *AuthStatus) StatusStreamHandler(stream network.Stream) {
 	peerID := remotePeer.String()
 	knownPeer, known := s.conf.GetPeer(peerID)
 	_, isBlocked := s.conf.GetBlockedPeer(peerID)
-	if !known && !isBlocked {
-		s.logger.Infof("Unknown peer %s tried to exchange status info", peerID)
-		return
-	}
 
 	// Receiving info
 	oppositePeerInfo, err := protocol.ReceiveStatus(stream)
@@ -82,9 +99,18 @@ func (s *AuthStatus) StatusStreamHandler(stream network.Stream) {
 		s.logger.Errorf("receiving status info from %s: %v", peerID, err)
 		return
 	}
+	if !known && !isBlocked && oppositePeerInfo.KeyHandover != "" {
+		knownPeer, known = s.followKeyHandover(remotePeer, oppositePeerInfo.KeyHandover)
+	}
+	if !known && !isBlocked {
+		s.logger.Infof("Unknown peer %s tried to exchange status info", peerID)
+		return
+	}
 	s.authsLock.Lock()
 	delete(s.outgoingAuths, remotePeer)
 	s.authsLock.Unlock()
+	// answer with features which are supported by remote peer right now, even if it was just updated
+	knownPeer.Capabilities = protocol.Negotiate(protocol.SupportedVersions, protocol.SupportedCapabilities, oppositePeerInfo).Capabilities
 
 	// Sending info
 	myPeerInfo := s.createPeerInfo(knownPeer, s.conf.P2pNode.Name, isBlocked)
@@ -106,16 +132,14 @@ func (s *AuthStatus) StatusStreamHandler(stream network.Stream) {
 }
 
 func (s *AuthStatus) ExchangeNewStatusInfo(ctx context.Context, remotePeerID peer.ID, knownPeer config.KnownPeer) error {
-	s.authsLock.Lock()
-	delete(s.ingoingAuths, remotePeerID)
-	s.authsLock.Unlock()
+	s.conf.RemoveIngoingAuthRequest(remotePeerID.String())
 
 	err := s.p2p.ConnectPeer(ctx, remotePeerID)
 	if err != nil {
 		return err
 	}
 
-	stream, err := s.p2p.NewStream(ctx, remotePeerID, protocol.GetStatusMethod)
+	stream, err := s.p2p.NewStream(ctx, remotePeerID, protocol.GetStatusMethod, protocol.LegacyGetStatusMethod)
 	if err != nil {
 		return err
 	}
@@ -149,6 +173,7 @@ func (s *AuthStatus) ExchangeNewStatusInfo(ctx context.Context, remotePeerID pee
 }
 
 func (s *AuthStatus) BlockPeer(peerID peer.ID, name string) {
+	s.conf.RemoveIngoingAuthRequest(peerID.String())
 	s.conf.UpsertBlockedPeer(peerID.String(), name)
 	go func() {
 		_ = s.ExchangeNewStatusInfo(context.Background(), peerID, config.KnownPeer{})
@@ -163,8 +188,18 @@ func (s *AuthStatus) createPeerInfo(peer config.KnownPeer, myPeerName string, de
 	}
 	myPeerInfo := protocol.PeerStatusInfo{
 		Name:                 myPeerName,
-		AllowUsingAsExitNode: peer.WeAllowUsingAsExitNode,
+		AllowUsingAsExitNode: s.conf.IsExitNodeAllowed(peer),
+		ProtocolVersions:     protocol.SupportedVersions,
+		Capabilities:         protocol.SupportedCapabilities,
+	}
+	s.conf.RLock()
+	// handover is sent only after restart with the new identity
+	if s.conf.P2pNode.NextIdentity == "" && peer.HasCapability(protocol.CapabilityKeyHandover) {
+		myPeerInfo.KeyHandover = s.conf.P2pNode.KeyHandover
 	}
+	s.conf.RUnlock()
+	s.addNetworkInfo(&myPeerInfo, peer)
+	s.addSharedStatus(&myPeerInfo)
 
 	return myPeerInfo
 }
@@ -175,17 +210,21 @@ func (s *AuthStatus) processPeerStatusInfo(peer config.KnownPeer, peerInfo proto
 		peer.Declined = true
 		s.conf.UpsertPeer(peer)
 
-		s.conf.Lock()
-		defer s.conf.Unlock()
-		if s.conf.SOCKS5.UsingPeerID == peer.PeerID {
-			s.conf.SOCKS5.UsingPeerID = ""
-		}
+		s.updateProxyPeer(func(usingPeerID string) string {
+			if usingPeerID == peer.PeerID {
+				return ""
+			}
+			return usingPeerID
+		})
 
 		return
 	}
 	peer.Name = peerInfo.Name
 	peer.Confirmed = true
 	peer.Declined = false
+	peer.InviteToken = ""
+	peer.Introduction = ""
+	peer.AuthMessage = ""
 	if peer.DomainName == "" {
 		peer.DomainName = awldns.TrimDomainName(peer.DisplayName())
 	}
@@ -193,18 +232,41 @@ func (s *AuthStatus) processPeerStatusInfo(peer config.KnownPeer, peerInfo proto
 		peer.Alias = s.conf.GenUniqPeerAlias(peer.Name, peer.Alias)
 	}
 	peer.AllowedUsingAsExitNode = peerInfo.AllowUsingAsExitNode
+	peer.SharedStatus = makePeerSharedStatus(peerInfo)
+	negotiated := protocol.Negotiate(protocol.SupportedVersions, protocol.SupportedCapabilities, peerInfo)
+	if negotiated.ProtocolVersion == "" {
+		s.logger.Warnf("peer %s (%s) has no common protocol version with us, it supports %v", peer.DisplayName(), peer.PeerID, peerInfo.ProtocolVersions)
+	}
+	peer.ProtocolVersion = negotiated.ProtocolVersion
+	peer.Capabilities = negotiated.Capabilities
+	s.processPeerCertificate(&peer, peerInfo.Certificate)
 
 	s.conf.UpsertPeer(peer)
+	if peer.Certificate != "" {
+		s.processNetworkInfo(peerInfo)
+	}
 
-	s.conf.Lock()
-	defer s.conf.Unlock()
+	s.updateProxyPeer(func(usingPeerID string) string {
+		if peer.AllowedUsingAsExitNode && usingPeerID == "" {
+			return peer.PeerID
+		}
+		if !peer.AllowedUsingAsExitNode && usingPeerID == peer.PeerID {
+			return ""
+		}
+		return usingPeerID
+	})
+}
 
-	if peer.AllowedUsingAsExitNode && s.conf.SOCKS5.UsingPeerID == "" {
-		s.conf.SOCKS5.UsingPeerID = peer.PeerID
-	}
+// updateProxyPeer changes peer used as exit node by our socks5 proxy, update is called with config locked.
+func (s *AuthStatus) updateProxyPeer(update func(usingPeerID string) string) {
+	s.conf.Lock()
+	oldPeerID := s.conf.SOCKS5.UsingPeerID
+	newPeerID := update(oldPeerID)
+	s.conf.SOCKS5.UsingPeerID = newPeerID
+	s.conf.Unlock()
 
-	if !peer.AllowedUsingAsExitNode && s.conf.SOCKS5.UsingPeerID == peer.PeerID {
-		s.conf.SOCKS5.UsingPeerID = ""
+	if newPeerID != oldPeerID {
+		_ = s.proxyEmitter.Emit(awlevent.ProxyChanged{UsingPeerID: newPeerID})
 	}
 }
 
@@ -215,31 +277,98 @@ func (s *AuthStatus) AuthStreamHandler(stream network.Stream) {
 
 	remotePeer := stream.Conn().RemotePeer()
 	peerID := remotePeer.String()
+	_, isBlocked := s.conf.GetBlockedPeer(peerID)
+	_, confirmed := s.conf.GetPeer(peerID)
+	if !confirmed && !s.authLimiter.Allow(remotePeer) {
+		s.logger.Warnf("too many auth requests from unknown peers, rejecting request from %s", peerID)
+		_ = stream.Reset()
+		return
+	}
+
 	authPeer, err := protocol.ReceiveAuth(stream)
 	if err != nil {
 		s.logger.Errorf("receiving auth from %s: %v", peerID, err)
 		return
 	}
-
-	_, isBlocked := s.conf.GetBlockedPeer(peerID)
-	_, confirmed := s.conf.GetPeer(peerID)
 	s.conf.RLock()
 	autoAccept := s.conf.P2pNode.AutoAcceptAuthRequests
 	s.conf.RUnlock()
 
+	var invite config.Invite
+	if !confirmed && !isBlocked && authPeer.InviteToken != "" {
+		invite, err = s.useInvite(remotePeer, authPeer.InviteToken)
+		if err != nil {
+			s.logger.Warnf("peer %s presented invalid invite token: %v", peerID, err)
+		} else {
+			s.logger.Infof("accepting peer %s (%s) with invite %s", authPeer.Name, peerID, invite.ID)
+			autoAccept = true
+			confirmed = true
+		}
+	}
+	var cert protocol.MembershipCertificate
+	if !confirmed && !isBlocked && authPeer.Certificate != "" {
+		cert, err = s.verifyMemberCertificate(authPeer.Certificate, peerID)
+		if err != nil {
+			s.logger.Warnf("peer %s presented invalid membership certificate: %v", peerID, err)
+		} else {
+			s.logger.Infof("accepting peer %s (%s) as member of network %s", authPeer.Name, peerID, cert.Network)
+			autoAccept = true
+			confirmed = true
+		}
+	}
+	if !confirmed && !isBlocked && authPeer.Introduction != "" {
+		introduction, introducer, err := s.DecodeIntroduction(authPeer.Introduction)
+		switch {
+		case err != nil:
+			s.logger.Warnf("peer %s presented invalid introduction: %v", peerID, err)
+			authPeer.Introduction = ""
+		case introduction.PeerID != s.p2p.PeerID().String() || introduction.Recipient != peerID:
+			s.logger.Warnf("peer %s presented introduction issued for other peers", peerID)
+			authPeer.Introduction = ""
+		case s.conf.IsIntroductionTrusted(introducer):
+			s.logger.Infof("accepting peer %s (%s) introduced by %s", authPeer.Name, peerID, introducer.DisplayName())
+			autoAccept = true
+			confirmed = true
+		}
+	}
+
 	if !confirmed && !isBlocked && !autoAccept {
-		s.authsLock.Lock()
-		s.ingoingAuths[remotePeer] = authPeer
-		s.authsLock.Unlock()
-		_ = s.authsEmitter.Emit(awlevent.ReceivedAuthRequest{
-			AuthPeer: authPeer,
-			PeerID:   peerID,
+		now := time.Now()
+		isNew := s.conf.UpsertIngoingAuthRequest(config.IngoingAuthRequest{
+			PeerID:        peerID,
+			Name:          authPeer.Name,
+			Introduction:  authPeer.Introduction,
+			Message:       authPeer.Message,
+			OS:            authPeer.OS,
+			Version:       authPeer.Version,
+			ProposedAlias: authPeer.ProposedAlias,
+			ReceivedAt:    now,
+			ExpiresAt:     now.Add(ingoingAuthRequestLifetime),
 		})
+		if isNew {
+			_ = s.authsEmitter.Emit(awlevent.ReceivedAuthRequest{
+				AuthPeer: authPeer,
+				PeerID:   peerID,
+			})
+		}
 	}
-	if !confirmed && !isBlocked && autoAccept {
-		defer func() {
-			s.AddPeer(context.Background(), remotePeer, authPeer.Name, s.conf.GenUniqPeerAlias(authPeer.Name, ""), true)
-		}()
+	if !isBlocked && autoAccept {
+		if _, known := s.conf.GetPeer(peerID); !known {
+			defer func() {
+				newPeer := s.newKnownPeer(remotePeer, authPeer.Name, s.conf.GenUniqPeerAlias(authPeer.Name, ""), true)
+				newPeer.WeAllowUsingAsExitNode = invite.AllowUsingAsExitNode
+				for _, group := range invite.Groups {
+					if _, exists := s.conf.GetGroup(group); exists {
+						newPeer.Groups = append(newPeer.Groups, group)
+					}
+				}
+				if cert.PeerID != "" {
+					newPeer.Certificate = authPeer.Certificate
+					newPeer.Groups = s.addMemberGroups(newPeer.Groups, cert)
+				}
+				s.addPeer(context.Background(), newPeer)
+			}()
+		}
 	}
 
 	authResponse := protocol.AuthPeerResponse{Confirmed: confirmed, Declined: isBlocked}
@@ -262,7 +391,7 @@ func (s *AuthStatus) SendAuthRequest(ctx context.Context, peerID peer.ID, req pr
 		return err
 	}
 
-	stream, err := s.p2p.NewStream(ctx, peerID, protocol.AuthMethod)
+	stream, err := s.p2p.NewStream(ctx, peerID, protocol.AuthMethod, protocol.LegacyAuthMethod)
 	if err != nil {
 		return err
 	}
@@ -298,6 +427,36 @@ func (s *AuthStatus) SendAuthRequest(ctx context.Context, peerID peer.ID, req pr
 }
 
 func (s *AuthStatus) AddPeer(ctx context.Context, peerID peer.ID, name, uniqAlias string, confirmed bool) {
+	s.addRequestedPeer(ctx, s.newKnownPeer(peerID, name, uniqAlias, confirmed))
+}
+
+// SendFriendRequest adds peer and sends it auth request with message, or accepts its pending request.
+func (s *AuthStatus) SendFriendRequest(ctx context.Context, peerID peer.ID, uniqAlias, message string) {
+	newPeer := s.newKnownPeer(peerID, "", uniqAlias, false)
+	newPeer.AuthMessage = message
+	s.addRequestedPeer(ctx, newPeer)
+}
+
+func (s *AuthStatus) addRequestedPeer(ctx context.Context, newPeer config.KnownPeer) {
+	peerID := newPeer.PeerId()
+	req, requested := s.conf.GetIngoingAuthRequest(peerID.String())
+	s.conf.RemoveIngoingAuthRequest(peerID.String())
+	if requested && req.Introduced {
+		// introduced peer hasn't sent us auth request, it accepts our request with introduction
+		newPeer.Confirmed = false
+		newPeer.Introduction = req.Introduction
+	}
+	s.addPeer(ctx, newPeer)
+}
+
+// AddPeerWithInvite adds inviting peer and sends it invite token, so it accepts us automatically.
+func (s *AuthStatus) AddPeerWithInvite(ctx context.Context, invite protocol.InviteToken, rawToken, uniqAlias string) {
+	newPeer := s.newKnownPeer(invite.AddrInfo().ID, invite.Name, uniqAlias, false)
+	newPeer.InviteToken = rawToken
+	s.addPeer(ctx, newPeer)
+}
+
+func (s *AuthStatus) newKnownPeer(peerID peer.ID, name, uniqAlias string, confirmed bool) config.KnownPeer {
 	s.conf.RLock()
 	ipAddr := s.conf.GenerateNextIpAddr()
 	s.conf.RUnlock()
@@ -310,18 +469,25 @@ func (s *AuthStatus) AddPeer(ctx context.Context, peerID peer.ID, name, uniqAlia
 		CreatedAt: time.Now(),
 	}
 	newPeerConfig.DomainName = awldns.TrimDomainName(newPeerConfig.DisplayName())
+
+	return newPeerConfig
+}
+
+func (s *AuthStatus) addPeer(ctx context.Context, newPeerConfig config.KnownPeer) {
+	peerID := newPeerConfig.PeerId()
 	s.conf.RemoveBlockedPeer(peerID.String())
 	s.conf.UpsertPeer(newPeerConfig)
 	s.p2p.ProtectPeer(peerID)
+	// connections opened before peer became known are not in history yet
+	for _, conn := range s.p2p.ConnsToPeer(peerID) {
+		s.addConnectionToHistory(peerID, conn)
+	}
 
 	go func() {
 		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 		defer cancel()
-		if !confirmed {
-			authPeer := protocol.AuthPeer{
-				Name: s.conf.P2pNode.Name,
-			}
-			_ = s.SendAuthRequest(ctx, peerID, authPeer)
+		if !newPeerConfig.Confirmed {
+			_ = s.SendAuthRequest(ctx, peerID, s.newAuthRequest(newPeerConfig))
 		}
 
 		knownPeer, _ := s.conf.GetPeer(peerID.String())
@@ -329,6 +495,43 @@ func (s *AuthStatus) AddPeer(ctx context.Context, peerID peer.ID, name, uniqAlia
 	}()
 }
 
+func (s *AuthStatus) newAuthRequest(knownPeer config.KnownPeer) protocol.AuthPeer {
+	s.conf.RLock()
+	defer s.conf.RUnlock()
+
+	return s.newAuthRequestLocked(knownPeer)
+}
+
+// newAuthRequestLocked should be called with config locked.
+func (s *AuthStatus) newAuthRequestLocked(knownPeer config.KnownPeer) protocol.AuthPeer {
+	return protocol.AuthPeer{
+		Name:          s.conf.P2pNode.Name,
+		InviteToken:   knownPeer.InviteToken,
+		Certificate:   s.conf.Network.Certificate,
+		Introduction:  knownPeer.Introduction,
+		Message:       knownPeer.AuthMessage,
+		OS:            runtime.GOOS,
+		Version:       config.Version,
+		ProposedAlias: knownPeer.Alias,
+	}
+}
+
+func (s *AuthStatus) useInvite(remotePeer peer.ID, rawToken string) (config.Invite, error) {
+	now := time.Now()
+	token, err := protocol.DecodeInviteToken(rawToken, now)
+	if err != nil {
+		return config.Invite{}, err
+	}
+	s.conf.RLock()
+	myPeerID := s.conf.P2pNode.PeerID
+	s.conf.RUnlock()
+	if token.PeerID != myPeerID {
+		return config.Invite{}, fmt.Errorf("invite is issued by another peer %s", token.PeerID)
+	}
+
+	return s.conf.UseInvite(token.ID, remotePeer.String(), now)
+}
+
 func (s *AuthStatus) ExchangeStatusInfoWithAllKnownPeers(ctx context.Context) {
 	s.conf.RLock()
 	peers := make([]string, 0, len(s.conf.KnownPeers))
@@ -380,34 +583,56 @@ func (s *AuthStatus) BackgroundExchangeStatusInfo(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
+			s.blockRotatedPeers()
+			s.clearExpiredKeyHandover()
+			s.removeExpiredMembers()
 			s.ExchangeStatusInfoWithAllKnownPeers(ctx)
 			ticker.Reset(backgroundExchangeStatusInfoInterval)
 		}
 	}
 }
 
-func (s *AuthStatus) GetIngoingAuthRequests() map[string]protocol.AuthPeer {
-	s.authsLock.RLock()
-	defer s.authsLock.RUnlock()
+func (s *AuthStatus) BackgroundSavePeerHistory(ctx context.Context) {
+	ticker := time.NewTicker(backgroundSavePeerHistoryInterval)
+	defer ticker.Stop()
 
-	result := make(map[string]protocol.AuthPeer, len(s.ingoingAuths))
-	for peerID, auth := range s.ingoingAuths {
-		result[peerID.String()] = auth
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.peerHistory.Save()
+		}
 	}
-	return result
+}
+
+func (s *AuthStatus) PeerHistory() *config.PeerHistory {
+	return s.peerHistory
+}
+
+// Close marks all open connections in peers history as closed and saves it.
+func (s *AuthStatus) Close() {
+	s.peerHistory.CloseAll(config.DisconnectReasonShutdown)
+	s.peerHistory.Save()
+}
+
+func (s *AuthStatus) GetIngoingAuthRequests() []config.IngoingAuthRequest {
+	return s.conf.ListIngoingAuthRequests()
+}
+
+// DismissAuthRequest removes pending auth request without blocking peer, so it could send a new one.
+func (s *AuthStatus) DismissAuthRequest(peerID peer.ID) bool {
+	return s.conf.RemoveIngoingAuthRequest(peerID.String())
 }
 
 func (s *AuthStatus) restoreOutgoingAuths() {
 	s.conf.RLock()
 	defer s.conf.RUnlock()
 
-	peerName := s.conf.P2pNode.Name
 	outgoingAuths := make(map[peer.ID]protocol.AuthPeer)
 	for _, knownPeer := range s.conf.KnownPeers {
 		if !knownPeer.Confirmed && !knownPeer.Declined {
-			outgoingAuths[knownPeer.PeerId()] = protocol.AuthPeer{
-				Name: peerName,
-			}
+			outgoingAuths[knownPeer.PeerId()] = s.newAuthRequestLocked(knownPeer)
 		}
 	}
 	s.outgoingAuths = outgoingAuths
@@ -424,6 +649,9 @@ func (s *AuthStatus) onPeerConnected(_ network.Network, conn network.Conn) {
 		return
 	}
 	s.conf.UpdatePeerLastSeen(peerID.String())
+	if known {
+		s.addConnectionToHistory(peerID, conn)
+	}
 
 	go func() {
 		if hasOutgAuth {
@@ -445,6 +673,19 @@ func (s *AuthStatus) onPeerConnected(_ network.Network, conn network.Conn) {
 	}()
 }
 
+func (s *AuthStatus) addConnectionToHistory(peerID peer.ID, conn network.Conn) {
+	info := s.p2p.ConnectionInfo(conn)
+	s.peerHistory.AddConnected(peerID.String(), config.PeerConnectionEvent{
+		ConnID:        conn.ID(),
+		ConnectedAt:   info.Opened,
+		Direction:     info.Direction,
+		RemoteAddress: info.Multiaddr,
+		ThroughRelay:  info.ThroughRelay,
+		RelayPeerID:   info.RelayPeerID,
+		Transport:     info.Protocol,
+	})
+}
+
 func (s *AuthStatus) onPeerDisconnected(_ network.Network, conn network.Conn) {
 	peerID := conn.RemotePeer()
 	knownPeer, known := s.conf.GetPeer(peerID.String())
@@ -452,5 +693,6 @@ func (s *AuthStatus) onPeerDisconnected(_ network.Network, conn network.Conn) {
 		return
 	}
 	s.conf.UpdatePeerLastSeen(peerID.String())
+	s.peerHistory.SetDisconnected(peerID.String(), conn.ID(), time.Now(), config.DisconnectReasonClosed)
 	s.logger.Infof("peer '%s' disconnected, address %s", knownPeer.DisplayName(), conn.RemoteMultiaddr())
 }
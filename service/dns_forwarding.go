@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/miekg/dns"
+
+	"github.com/anywherelan/awl/awldns"
+	"github.com/anywherelan/awl/config"
+	"github.com/anywherelan/awl/protocol"
+)
+
+var ErrDNSResolverNotRunning = errors.New("dns resolver is not running")
+
+// DNSForwarding resolves DNS queries of peers with config.KnownPeer AllowDNSQueries,
+// and sends queries matched by our config.DNSForwardingRule to peers.
+type DNSForwarding struct {
+	logger *log.ZapEventLogger
+	p2p    P2p
+	conf   *config.Config
+
+	resolverLock sync.RWMutex
+	resolve      awldns.ExchangeFunc
+}
+
+func NewDNSForwarding(p2pService P2p, conf *config.Config) *DNSForwarding {
+	return &DNSForwarding{
+		logger: log.Logger("awl/service/dns_forwarding"),
+		p2p:    p2pService,
+		conf:   conf,
+	}
+}
+
+// SetResolver sets resolver for queries from peers, e.g. awldns.Resolver ExchangeUpstream.
+func (d *DNSForwarding) SetResolver(resolve awldns.ExchangeFunc) {
+	d.resolverLock.Lock()
+	d.resolve = resolve
+	d.resolverLock.Unlock()
+}
+
+func (d *DNSForwarding) StreamHandler(stream network.Stream) {
+	defer func() {
+		_ = stream.Close()
+	}()
+
+	peerID := stream.Conn().RemotePeer().String()
+	knownPeer, known := d.conf.GetPeer(peerID)
+	if !known {
+		d.logger.Warnf("Unknown peer %s tried to resolve dns query", peerID)
+		_ = stream.Reset()
+		return
+	}
+
+	query, err := protocol.ReceiveDNSQuery(stream)
+	if err != nil {
+		d.logger.Errorf("receive dns query from %s: %v", peerID, err)
+		_ = stream.Reset()
+		return
+	}
+
+	response := protocol.DNSQueryResponse{}
+	req := new(dns.Msg)
+	switch {
+	case !knownPeer.Confirmed || !knownPeer.AllowDNSQueries || !d.conf.IsInboundAllowed(knownPeer):
+		response.Error = "dns queries are not allowed for this peer"
+		d.logger.Warnf("dns query from %s (%s) is not allowed", knownPeer.DisplayName(), peerID)
+	default:
+		err = req.Unpack(query.Message)
+		if err != nil {
+			response.Error = fmt.Sprintf("unpack query: %v", err)
+			break
+		}
+		response.Message, err = d.resolveQuery(context.Background(), req)
+		if err != nil {
+			response.Error = err.Error()
+		}
+	}
+
+	err = protocol.SendDNSQueryResponse(stream, response)
+	if err != nil {
+		d.logger.Errorf("send dns query response to %s: %v", peerID, err)
+	}
+}
+
+func (d *DNSForwarding) resolveQuery(ctx context.Context, req *dns.Msg) ([]byte, error) {
+	d.resolverLock.RLock()
+	resolve := d.resolve
+	d.resolverLock.RUnlock()
+	if resolve == nil {
+		return nil, ErrDNSResolverNotRunning
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, protocol.MessageTimeout)
+	defer cancel()
+	resp, err := resolve(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Truncate(protocol.MaxDNSMessageSize)
+	return resp.Pack()
+}
+
+// Query sends DNS query to peer and returns its response.
+func (d *DNSForwarding) Query(ctx context.Context, peerID peer.ID, req *dns.Msg) (*dns.Msg, error) {
+	if _, known := d.conf.GetPeer(peerID.String()); !known {
+		return nil, config.ErrPeerNotFound
+	}
+	packed, err := req.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, protocol.MessageTimeout)
+	defer cancel()
+	err = d.p2p.ConnectPeer(ctx, peerID)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := d.p2p.NewStream(ctx, peerID, protocol.DNSQueryMethod)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = stream.Close()
+	}()
+	// messages have their own timeouts, so we reset stream to return as soon as ctx is done
+	stop := context.AfterFunc(ctx, func() {
+		_ = stream.Reset()
+	})
+	defer stop()
+
+	err = protocol.SendDNSQuery(stream, protocol.DNSQuery{Message: packed})
+	if err != nil {
+		_ = stream.Reset()
+		return nil, fmt.Errorf("send query: %v", err)
+	}
+	response, err := protocol.ReceiveDNSQueryResponse(stream)
+	if err != nil {
+		return nil, fmt.Errorf("receive response: %v", err)
+	}
+	if response.Error != "" {
+		return nil, errors.New(response.Error)
+	}
+
+	resp := new(dns.Msg)
+	err = resp.Unpack(response.Message)
+	if err != nil {
+		return nil, fmt.Errorf("unpack response: %v", err)
+	}
+	resp.Id = req.Id
+	return resp, nil
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"time"
+
+	"github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p/core/event"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/p2p/host/eventbus"
+
+	"github.com/anywherelan/awl/awlevent"
+	"github.com/anywherelan/awl/config"
+	"github.com/anywherelan/awl/entity"
+)
+
+const eventsBufferSize = 64
+
+var eventsLogger = log.Logger("awl/service/events")
+
+// SubscribeEvents merges awl and libp2p events into entity.Event stream, returned func closes subscription.
+func SubscribeEvents(conf *config.Config, awlBus, p2pBus awlevent.Bus) (<-chan entity.Event, func(), error) {
+	sub, err := awlBus.Subscribe([]interface{}{
+		new(awlevent.KnownPeerChanged),
+		new(awlevent.ReceivedAuthRequest),
+		new(awlevent.ProxyChanged),
+		new(awlevent.UpdateAvailable),
+		new(awlevent.FileTransferChanged),
+	}, eventbus.BufSize(eventsBufferSize))
+	if err != nil {
+		return nil, nil, err
+	}
+	p2pSub, err := p2pBus.Subscribe([]interface{}{
+		new(event.EvtPeerConnectednessChanged),
+		new(event.EvtLocalReachabilityChanged),
+	}, eventbus.BufSize(eventsBufferSize))
+	if err != nil {
+		_ = sub.Close()
+		return nil, nil, err
+	}
+
+	events := make(chan entity.Event, eventsBufferSize)
+	done := make(chan struct{})
+	go func() {
+		defer close(events)
+		for {
+			var evt interface{}
+			select {
+			case <-done:
+				return
+			case evt = <-sub.Out():
+			case evt = <-p2pSub.Out():
+			}
+			apiEvent, ok := convertEvent(conf, evt)
+			if !ok {
+				continue
+			}
+			// slow subscriber should not block event bus, so events are dropped when buffer is full
+			select {
+			case events <- apiEvent:
+			default:
+				eventsLogger.Warnf("events subscriber is too slow, dropped event %s", apiEvent.Type)
+			}
+		}
+	}()
+	closeSub := func() {
+		close(done)
+		_ = sub.Close()
+		_ = p2pSub.Close()
+	}
+
+	return events, closeSub, nil
+}
+
+func convertEvent(conf *config.Config, evt interface{}) (entity.Event, bool) {
+	result := entity.Event{Time: time.Now()}
+	switch evt := evt.(type) {
+	case awlevent.KnownPeerChanged:
+		result.Type = entity.EventPeerChanged
+	case awlevent.ReceivedAuthRequest:
+		result.Type = entity.EventAuthRequest
+		result.PeerID = evt.PeerID
+		result.PeerName = evt.Name
+		result.AuthRequest = &entity.AuthRequest{
+			PeerID:   evt.PeerID,
+			AuthPeer: evt.AuthPeer,
+		}
+	case awlevent.ProxyChanged:
+		result.Type = entity.EventProxyChanged
+		result.PeerID = evt.UsingPeerID
+		if knownPeer, known := conf.GetPeer(evt.UsingPeerID); known {
+			result.PeerName = knownPeer.DisplayName()
+		}
+	case awlevent.UpdateAvailable:
+		result.Type = entity.EventUpdateAvailable
+		result.Version = evt.Version
+	case awlevent.FileTransferChanged:
+		result.Type = entity.EventFileTransfer
+		result.PeerID = evt.PeerID
+		if knownPeer, known := conf.GetPeer(evt.PeerID); known {
+			result.PeerName = knownPeer.DisplayName()
+		}
+		result.FileTransfer = &entity.FileTransfer{
+			ID:        evt.ID,
+			PeerID:    evt.PeerID,
+			PeerName:  result.PeerName,
+			Direction: evt.Direction,
+			Name:      evt.Name,
+			Size:      evt.Size,
+			Status:    evt.Status,
+		}
+	case event.EvtLocalReachabilityChanged:
+		result.Type = entity.EventReachabilityChanged
+		result.Reachability = evt.Reachability.String()
+	case event.EvtPeerConnectednessChanged:
+		knownPeer, known := conf.GetPeer(evt.Peer.String())
+		if !known {
+			return entity.Event{}, false
+		}
+		switch evt.Connectedness {
+		case network.Connected:
+			result.Type = entity.EventPeerConnected
+		case network.NotConnected:
+			result.Type = entity.EventPeerDisconnected
+		default:
+			return entity.Event{}, false
+		}
+		result.PeerID = knownPeer.PeerID
+		result.PeerName = knownPeer.DisplayName()
+	default:
+		return entity.Event{}, false
+	}
+
+	return result, true
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/p2p/host/eventbus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anywherelan/awl/awlevent"
+	"github.com/anywherelan/awl/config"
+	"github.com/anywherelan/awl/entity"
+)
+
+func TestSubscribeEventsSlowSubscriber(t *testing.T) {
+	a := require.New(t)
+	bus := eventbus.NewBus()
+	events, closeEvents, err := SubscribeEvents(&config.Config{}, bus, eventbus.NewBus())
+	a.NoError(err)
+	defer closeEvents()
+	emitter, err := bus.Emitter(new(awlevent.UpdateAvailable))
+	a.NoError(err)
+
+	emitted := make(chan struct{})
+	go func() {
+		for i := 0; i < eventsBufferSize*4; i++ {
+			_ = emitter.Emit(awlevent.UpdateAvailable{Version: "v1.0.0"})
+		}
+		close(emitted)
+	}()
+	select {
+	case <-emitted:
+	case <-time.After(10 * time.Second):
+		a.FailNow("event bus is blocked by subscriber which doesn't read events")
+	}
+
+	a.Eventually(func() bool { return len(events) == eventsBufferSize }, 5*time.Second, 10*time.Millisecond)
+	evt := <-events
+	a.Equal(entity.EventUpdateAvailable, evt.Type)
+}
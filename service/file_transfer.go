@@ -0,0 +1,580 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+
+	"github.com/anywherelan/awl/awlevent"
+	"github.com/anywherelan/awl/config"
+	"github.com/anywherelan/awl/entity"
+	"github.com/anywherelan/awl/protocol"
+)
+
+const (
+	fileChunkSize = 64 << 10
+	// fileChunkTimeout is a deadline for each chunk, it's refreshed after every chunk so big files are not limited
+	fileChunkTimeout = time.Minute
+	// fileOfferTimeout is how long offer waits for user decision with config.FileReceivePolicyAsk
+	fileOfferTimeout     = 5 * time.Minute
+	maxPendingFileOffers = 10
+	fileTransfersLogSize = 100
+	partialFileSuffix    = ".awlpart"
+
+	downloadDirPerm  = 0755
+	downloadFilePerm = 0644
+)
+
+var (
+	ErrFileTransferNotFound = errors.New("file transfer not found")
+	ErrFileOfferNotPending  = errors.New("file transfer is not pending")
+)
+
+// FileTransfer sends files to peers and receives files from them according to config.KnownPeer FileReceivePolicy.
+// Partially received files are kept in download dir, so interrupted transfer continues from where it stopped
+// when the same file is sent again.
+type FileTransfer struct {
+	logger  *log.ZapEventLogger
+	p2p     P2p
+	conf    *config.Config
+	emitter awlevent.Emitter
+
+	lock      sync.Mutex
+	transfers []*fileTransfer
+	// receiving contains partial file paths which are being written now
+	receiving map[string]struct{}
+}
+
+type fileTransfer struct {
+	info entity.FileTransfer
+	// decision receives user decision for pending incoming offer
+	decision chan bool
+}
+
+func NewFileTransfer(p2pService P2p, conf *config.Config, eventbus awlevent.Bus) *FileTransfer {
+	emitter, err := eventbus.Emitter(new(awlevent.FileTransferChanged))
+	if err != nil {
+		panic(err)
+	}
+
+	return &FileTransfer{
+		logger:    log.Logger("awl/service/file_transfer"),
+		p2p:       p2pService,
+		conf:      conf,
+		emitter:   emitter,
+		transfers: make([]*fileTransfer, 0),
+		receiving: make(map[string]struct{}),
+	}
+}
+
+// Transfers returns recent transfers, the newest last.
+func (f *FileTransfer) Transfers() []entity.FileTransfer {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	result := make([]entity.FileTransfer, 0, len(f.transfers))
+	for _, transfer := range f.transfers {
+		result = append(result, transfer.info)
+	}
+	return result
+}
+
+// Accept accepts pending incoming offer, file is saved into download dir.
+func (f *FileTransfer) Accept(id string) error {
+	return f.decide(id, true)
+}
+
+// Decline declines pending incoming offer.
+func (f *FileTransfer) Decline(id string) error {
+	return f.decide(id, false)
+}
+
+func (f *FileTransfer) decide(id string, accept bool) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	transfer := f.find(id)
+	if transfer == nil {
+		return ErrFileTransferNotFound
+	}
+	if transfer.decision == nil || transfer.info.Status != entity.FileTransferPending {
+		return ErrFileOfferNotPending
+	}
+	select {
+	case transfer.decision <- accept:
+	default:
+		return ErrFileOfferNotPending
+	}
+	return nil
+}
+
+// SendFile checks file and starts sending it in background, progress is available in Transfers.
+func (f *FileTransfer) SendFile(peerID peer.ID, path string) (entity.FileTransfer, error) {
+	knownPeer, known := f.conf.GetPeer(peerID.String())
+	if !known {
+		return entity.FileTransfer{}, config.ErrPeerNotFound
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return entity.FileTransfer{}, err
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return entity.FileTransfer{}, err
+	}
+	if !stat.Mode().IsRegular() {
+		return entity.FileTransfer{}, fmt.Errorf("%s is not a regular file", path)
+	}
+
+	transfer := f.add(entity.FileTransfer{
+		PeerID:    knownPeer.PeerID,
+		PeerName:  knownPeer.DisplayName(),
+		Direction: entity.FileTransferOutgoing,
+		Name:      stat.Name(),
+		Size:      stat.Size(),
+		Status:    entity.FileTransferPending,
+		Path:      path,
+	}, nil)
+	go func() {
+		err := f.send(peerID, transfer)
+		f.finish(transfer, err)
+	}()
+
+	return transfer.info, nil
+}
+
+func (f *FileTransfer) send(peerID peer.ID, transfer *fileTransfer) error {
+	file, err := os.Open(transfer.info.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	_, err = io.Copy(hasher, file)
+	if err != nil {
+		return fmt.Errorf("hash file: %v", err)
+	}
+	sum := hex.EncodeToString(hasher.Sum(nil))
+	f.update(transfer, func(info *entity.FileTransfer) {
+		info.SHA256 = sum
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), protocol.MessageTimeout)
+	defer cancel()
+	err = f.p2p.ConnectPeer(ctx, peerID)
+	if err != nil {
+		return fmt.Errorf("connect to peer: %v", err)
+	}
+	stream, err := f.p2p.NewStream(ctx, peerID, protocol.FileTransferMethod)
+	if err != nil {
+		return fmt.Errorf("open stream: %v", err)
+	}
+	defer func() {
+		_ = stream.Close()
+	}()
+
+	err = protocol.SendFileOffer(stream, protocol.FileOffer{
+		Name:   transfer.info.Name,
+		Size:   uint64(transfer.info.Size),
+		SHA256: sum,
+	})
+	if err != nil {
+		_ = stream.Reset()
+		return fmt.Errorf("send offer: %v", err)
+	}
+	response, err := protocol.ReceiveFileOfferResponse(stream, fileOfferTimeout+protocol.MessageTimeout)
+	if err != nil {
+		_ = stream.Reset()
+		return fmt.Errorf("receive offer response: %v", err)
+	}
+	if !response.Accepted {
+		f.update(transfer, func(info *entity.FileTransfer) {
+			info.Status = entity.FileTransferDeclined
+			info.Error = response.Reason
+		})
+		return nil
+	}
+	if response.Offset > uint64(transfer.info.Size) {
+		_ = stream.Reset()
+		return fmt.Errorf("peer requested invalid offset %d", response.Offset)
+	}
+
+	offset := int64(response.Offset)
+	f.update(transfer, func(info *entity.FileTransfer) {
+		info.Status = entity.FileTransferInProgress
+		info.ResumedFrom = offset
+		info.Transferred = offset
+	})
+	_, err = file.Seek(offset, io.SeekStart)
+	if err != nil {
+		_ = stream.Reset()
+		return err
+	}
+	buf := make([]byte, fileChunkSize)
+	for offset < transfer.info.Size {
+		n, err := io.ReadFull(file, buf[:min(int64(len(buf)), transfer.info.Size-offset)])
+		if err != nil {
+			_ = stream.Reset()
+			return fmt.Errorf("read file: %v", err)
+		}
+		_ = stream.SetWriteDeadline(time.Now().Add(fileChunkTimeout))
+		_, err = stream.Write(buf[:n])
+		if err != nil {
+			_ = stream.Reset()
+			return fmt.Errorf("write to stream: %v", err)
+		}
+		offset += int64(n)
+		f.update(transfer, func(info *entity.FileTransfer) {
+			info.Transferred = offset
+		})
+	}
+	_ = stream.SetWriteDeadline(time.Time{})
+	_ = stream.CloseWrite()
+
+	result, err := protocol.ReceiveFileTransferResult(stream)
+	if err != nil {
+		return fmt.Errorf("receive result: %v", err)
+	}
+	if !result.OK {
+		return fmt.Errorf("peer failed to receive file: %s", result.Error)
+	}
+	return nil
+}
+
+func (f *FileTransfer) StreamHandler(stream network.Stream) {
+	defer func() {
+		_ = stream.Close()
+	}()
+
+	peerID := stream.Conn().RemotePeer().String()
+	knownPeer, known := f.conf.GetPeer(peerID)
+	if !known || !knownPeer.Confirmed {
+		f.logger.Warnf("Unknown peer %s tried to send file", peerID)
+		_ = stream.Reset()
+		return
+	}
+
+	offer, err := protocol.ReceiveFileOffer(stream)
+	if err != nil {
+		f.logger.Errorf("receive file offer from %s: %v", peerID, err)
+		_ = stream.Reset()
+		return
+	}
+	name := sanitizeFileName(offer.Name)
+	sum, err := hex.DecodeString(offer.SHA256)
+	if name == "" || err != nil || len(sum) != sha256.Size || offer.Size > 1<<62 {
+		f.logger.Warnf("invalid file offer from %s: %q", peerID, offer.Name)
+		_ = protocol.SendFileOfferResponse(stream, protocol.FileOfferResponse{Reason: "invalid offer"})
+		return
+	}
+
+	info := entity.FileTransfer{
+		PeerID:    peerID,
+		PeerName:  knownPeer.DisplayName(),
+		Direction: entity.FileTransferIncoming,
+		Name:      name,
+		Size:      int64(offer.Size),
+		SHA256:    offer.SHA256,
+		Status:    entity.FileTransferPending,
+	}
+	policy := knownPeer.GetFileReceivePolicy()
+	if !f.conf.IsInboundAllowed(knownPeer) {
+		policy = config.FileReceivePolicyDeny
+	} else if policy == config.FileReceivePolicyAuto && !f.conf.IsAutoAcceptAllowed(info.Size) {
+		policy = config.FileReceivePolicyAsk
+	}
+	var transfer *fileTransfer
+	switch policy {
+	case config.FileReceivePolicyDeny:
+		transfer = f.add(info, nil)
+		f.decline(stream, transfer, "peer doesn't accept files")
+		return
+	case config.FileReceivePolicyAuto:
+		transfer = f.add(info, nil)
+	default:
+		if f.pendingCount() >= maxPendingFileOffers {
+			transfer = f.add(info, nil)
+			f.decline(stream, transfer, "too many pending offers")
+			return
+		}
+		transfer = f.add(info, make(chan bool, 1))
+		f.emit(transfer.info)
+		f.logger.Infof("peer %s (%s) offers file '%s' (%d bytes)", info.PeerName, peerID, name, info.Size)
+
+		select {
+		case accepted := <-transfer.decision:
+			if !accepted {
+				f.decline(stream, transfer, "declined by user")
+				return
+			}
+		case <-time.After(fileOfferTimeout):
+			f.decline(stream, transfer, "offer has expired")
+			return
+		}
+	}
+
+	err = f.receive(stream, transfer)
+	if err != nil {
+		_ = protocol.SendFileTransferResult(stream, protocol.FileTransferResult{Error: err.Error()})
+	} else {
+		err = protocol.SendFileTransferResult(stream, protocol.FileTransferResult{OK: true})
+		if err != nil {
+			f.logger.Warnf("send file transfer result to %s: %v", peerID, err)
+		}
+		err = nil
+	}
+	f.finish(transfer, err)
+}
+
+func (f *FileTransfer) receive(stream network.Stream, transfer *fileTransfer) error {
+	downloadDir := f.conf.DownloadDir()
+	err := os.MkdirAll(downloadDir, downloadDirPerm)
+	if err != nil {
+		return fmt.Errorf("create download dir: %v", err)
+	}
+	config.ChownFileIfNeeded(downloadDir)
+
+	partialPath := filepath.Join(downloadDir, "."+transfer.info.SHA256[:16]+partialFileSuffix)
+	if !f.startReceiving(partialPath) {
+		return errors.New("the same file is being received now")
+	}
+	defer f.stopReceiving(partialPath)
+
+	file, hasher, offset, err := openPartialFile(partialPath, transfer.info.Size)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	config.ChownFileIfNeeded(partialPath)
+
+	err = protocol.SendFileOfferResponse(stream, protocol.FileOfferResponse{Accepted: true, Offset: uint64(offset)})
+	if err != nil {
+		return fmt.Errorf("send offer response: %v", err)
+	}
+	f.update(transfer, func(info *entity.FileTransfer) {
+		info.Status = entity.FileTransferInProgress
+		info.ResumedFrom = offset
+		info.Transferred = offset
+	})
+
+	// partial file is kept on errors below, so the next attempt continues from it
+	buf := make([]byte, fileChunkSize)
+	for offset < transfer.info.Size {
+		_ = stream.SetReadDeadline(time.Now().Add(fileChunkTimeout))
+		n, err := stream.Read(buf[:min(int64(len(buf)), transfer.info.Size-offset)])
+		if n > 0 {
+			_, writeErr := file.Write(buf[:n])
+			if writeErr != nil {
+				return fmt.Errorf("write file: %v", writeErr)
+			}
+			hasher.Write(buf[:n])
+			offset += int64(n)
+			f.update(transfer, func(info *entity.FileTransfer) {
+				info.Transferred = offset
+			})
+		}
+		if errors.Is(err, io.EOF) && offset < transfer.info.Size {
+			return fmt.Errorf("transfer interrupted at %d of %d bytes", offset, transfer.info.Size)
+		} else if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("read from stream: %v", err)
+		}
+	}
+	_ = stream.SetReadDeadline(time.Time{})
+
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("write file: %v", err)
+	}
+	if hex.EncodeToString(hasher.Sum(nil)) != transfer.info.SHA256 {
+		_ = os.Remove(partialPath)
+		return errors.New("hash mismatch, file is corrupted")
+	}
+
+	path, err := moveToUniquePath(partialPath, downloadDir, transfer.info.Name)
+	if err != nil {
+		return fmt.Errorf("save file: %v", err)
+	}
+	f.update(transfer, func(info *entity.FileTransfer) {
+		info.Path = path
+	})
+	return nil
+}
+
+// openPartialFile opens partially received file and hashes its content, file is truncated if it's bigger than size.
+func openPartialFile(path string, size int64) (*os.File, hash.Hash, int64, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, downloadFilePerm)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("open file: %v", err)
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, nil, 0, fmt.Errorf("open file: %v", err)
+	}
+	offset := stat.Size()
+	if offset > size {
+		offset = 0
+		err = file.Truncate(0)
+		if err != nil {
+			_ = file.Close()
+			return nil, nil, 0, fmt.Errorf("truncate file: %v", err)
+		}
+	}
+
+	hasher := sha256.New()
+	_, err = io.CopyN(hasher, file, offset)
+	if err != nil {
+		_ = file.Close()
+		return nil, nil, 0, fmt.Errorf("hash partial file: %v", err)
+	}
+
+	return file, hasher, offset, nil
+}
+
+// moveToUniquePath renames file to name in dir, " (N)" is added to name if file already exists.
+func moveToUniquePath(oldPath, dir, name string) (string, error) {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 0; ; i++ {
+		path := filepath.Join(dir, name)
+		if i > 0 {
+			path = filepath.Join(dir, fmt.Sprintf("%s (%d)%s", base, i, ext))
+		}
+		_, err := os.Lstat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return path, os.Rename(oldPath, path)
+		} else if err != nil {
+			return "", err
+		}
+	}
+}
+
+// sanitizeFileName returns base name without path separators, empty if name can't be used.
+func sanitizeFileName(name string) string {
+	name = strings.NewReplacer("/", "_", "\\", "_").Replace(name)
+	name = strings.TrimLeft(strings.TrimSpace(name), ".")
+	if name == "" || strings.HasSuffix(name, partialFileSuffix) {
+		return ""
+	}
+	return name
+}
+
+func (f *FileTransfer) decline(stream network.Stream, transfer *fileTransfer, reason string) {
+	err := protocol.SendFileOfferResponse(stream, protocol.FileOfferResponse{Reason: reason})
+	if err != nil {
+		f.logger.Warnf("send file offer response to %s: %v", transfer.info.PeerID, err)
+	}
+	f.update(transfer, func(info *entity.FileTransfer) {
+		info.Status = entity.FileTransferDeclined
+		info.Error = reason
+	})
+	f.finish(transfer, nil)
+}
+
+func (f *FileTransfer) add(info entity.FileTransfer, decision chan bool) *fileTransfer {
+	idBytes := make([]byte, 8)
+	_, _ = rand.Read(idBytes)
+	info.ID = hex.EncodeToString(idBytes)
+	info.CreatedAt = time.Now()
+	transfer := &fileTransfer{info: info, decision: decision}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	// drop the oldest finished transfers, unfinished are kept until they finish
+	for i := 0; len(f.transfers) >= fileTransfersLogSize && i < len(f.transfers); {
+		if f.transfers[i].info.FinishedAt.IsZero() {
+			i++
+			continue
+		}
+		f.transfers = append(f.transfers[:i], f.transfers[i+1:]...)
+	}
+	f.transfers = append(f.transfers, transfer)
+	return transfer
+}
+
+func (f *FileTransfer) update(transfer *fileTransfer, update func(info *entity.FileTransfer)) {
+	f.lock.Lock()
+	update(&transfer.info)
+	f.lock.Unlock()
+}
+
+func (f *FileTransfer) finish(transfer *fileTransfer, err error) {
+	f.lock.Lock()
+	if err != nil {
+		transfer.info.Status = entity.FileTransferFailed
+		transfer.info.Error = err.Error()
+	} else if transfer.info.Status != entity.FileTransferDeclined {
+		transfer.info.Status = entity.FileTransferCompleted
+	}
+	transfer.info.FinishedAt = time.Now()
+	info := transfer.info
+	f.lock.Unlock()
+
+	if err != nil {
+		f.logger.Errorf("%s file transfer '%s' with %s: %v", info.Direction, info.Name, info.PeerID, err)
+	} else {
+		f.logger.Infof("%s file transfer '%s' with %s: %s", info.Direction, info.Name, info.PeerID, info.Status)
+	}
+	f.emit(info)
+}
+
+func (f *FileTransfer) emit(info entity.FileTransfer) {
+	_ = f.emitter.Emit(awlevent.FileTransferChanged{
+		ID:        info.ID,
+		PeerID:    info.PeerID,
+		Direction: info.Direction,
+		Name:      info.Name,
+		Size:      info.Size,
+		Status:    info.Status,
+	})
+}
+
+func (f *FileTransfer) find(id string) *fileTransfer {
+	for _, transfer := range f.transfers {
+		if transfer.info.ID == id {
+			return transfer
+		}
+	}
+	return nil
+}
+
+func (f *FileTransfer) pendingCount() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	count := 0
+	for _, transfer := range f.transfers {
+		if transfer.decision != nil && transfer.info.Status == entity.FileTransferPending {
+			count++
+		}
+	}
+	return count
+}
+
+func (f *FileTransfer) startReceiving(path string) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if _, exists := f.receiving[path]; exists {
+		return false
+	}
+	f.receiving[path] = struct{}{}
+	return true
+}
+
+func (f *FileTransfer) stopReceiving(path string) {
+	f.lock.Lock()
+	delete(f.receiving, path)
+	f.lock.Unlock()
+}
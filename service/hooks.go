@@ -0,0 +1,189 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-log/v2"
+
+	"github.com/anywherelan/awl/config"
+	"github.com/anywherelan/awl/entity"
+)
+
+const (
+	HookSignatureHeader = "X-Awl-Signature"
+	HookEventHeader     = "X-Awl-Event"
+
+	hookTimeout           = 30 * time.Second
+	hookDefaultMaxRetries = 3
+	hookInitialBackoff    = time.Second
+	hookMaxBackoff        = time.Minute
+	hookDeliveryLogSize   = 100
+	hookOutputLimit       = 1 << 10
+)
+
+// Hooks delivers events to webhooks and local executables configured in config.Config Hooks.
+type Hooks struct {
+	logger     *log.ZapEventLogger
+	conf       *config.Config
+	httpClient *http.Client
+
+	deliveriesLock sync.Mutex
+	deliveries     []entity.HookDelivery
+}
+
+func NewHooks(conf *config.Config) *Hooks {
+	return &Hooks{
+		logger:     log.Logger("awl/service/hooks"),
+		conf:       conf,
+		httpClient: &http.Client{Timeout: hookTimeout},
+		deliveries: make([]entity.HookDelivery, 0),
+	}
+}
+
+// Run delivers events until channel is closed. Each delivery runs in its own goroutine to not block others while retrying.
+func (h *Hooks) Run(ctx context.Context, events <-chan entity.Event) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for evt := range events {
+		for _, hook := range h.conf.GetHooks() {
+			if !hook.Subscribed(evt.Type) {
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				h.deliver(ctx, hook, evt)
+			}()
+		}
+	}
+}
+
+// Deliveries returns recent deliveries, the newest last.
+func (h *Hooks) Deliveries() []entity.HookDelivery {
+	h.deliveriesLock.Lock()
+	defer h.deliveriesLock.Unlock()
+	result := make([]entity.HookDelivery, len(h.deliveries))
+	copy(result, h.deliveries)
+	return result
+}
+
+func (h *Hooks) deliver(ctx context.Context, hook config.Hook, evt entity.Event) {
+	maxRetries := hook.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = hookDefaultMaxRetries
+	}
+	body, err := json.Marshal(evt)
+	if err != nil {
+		h.logger.Errorf("marshal event %s: %v", evt.Type, err)
+		return
+	}
+
+	delivery := entity.HookDelivery{
+		Hook:      hook.Name,
+		EventType: evt.Type,
+		Time:      time.Now(),
+	}
+	backoff := hookInitialBackoff
+	for {
+		delivery.Attempts++
+		switch {
+		case hook.URL != "":
+			err = h.sendWebhook(ctx, hook, evt, body)
+		case hook.Command != "":
+			err = h.runCommand(ctx, hook, evt, body)
+		default:
+			err = errors.New("hook has neither url nor command")
+		}
+		if err == nil || delivery.Attempts > maxRetries || ctx.Err() != nil {
+			break
+		}
+
+		h.logger.Warnf("deliver event %s to hook '%s', attempt %d: %v", evt.Type, hook.Name, delivery.Attempts, err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, hookMaxBackoff)
+	}
+
+	delivery.Success = err == nil
+	if err != nil {
+		delivery.Error = err.Error()
+		h.logger.Errorf("deliver event %s to hook '%s' failed after %d attempts: %v", evt.Type, hook.Name, delivery.Attempts, err)
+	}
+	h.addDelivery(delivery)
+}
+
+func (h *Hooks) sendWebhook(ctx context.Context, hook config.Hook, evt entity.Event, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HookEventHeader, evt.Type)
+	if hook.Secret != "" {
+		req.Header.Set(HookSignatureHeader, SignHookBody(hook.Secret, body))
+	}
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, hookOutputLimit))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+	return nil
+}
+
+func (h *Hooks) runCommand(ctx context.Context, hook config.Hook, evt entity.Event, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, hookTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, hook.Command, hook.Args...)
+	cmd.Env = append(os.Environ(),
+		"AWL_EVENT_TYPE="+evt.Type,
+		"AWL_EVENT_TIME="+evt.Time.Format(time.RFC3339),
+		"AWL_PEER_ID="+evt.PeerID,
+		"AWL_PEER_NAME="+evt.PeerName,
+		"AWL_EVENT_JSON="+string(body),
+	)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if len(output) > hookOutputLimit {
+			output = output[:hookOutputLimit]
+		}
+		return fmt.Errorf("%v: %s", err, bytes.TrimSpace(output))
+	}
+	return nil
+}
+
+func (h *Hooks) addDelivery(delivery entity.HookDelivery) {
+	h.deliveriesLock.Lock()
+	defer h.deliveriesLock.Unlock()
+	if len(h.deliveries) >= hookDeliveryLogSize {
+		h.deliveries = h.deliveries[1:]
+	}
+	h.deliveries = append(h.deliveries, delivery)
+}
+
+// SignHookBody returns value of HookSignatureHeader: "sha256=" followed by hex encoded HMAC-SHA256 of body.
+func SignHookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
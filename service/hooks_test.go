@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/anywherelan/awl/config"
+	"github.com/anywherelan/awl/entity"
+)
+
+func TestHooksCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	a := require.New(t)
+	output := filepath.Join(t.TempDir(), "out")
+	hooks := NewHooks(&config.Config{Hooks: []config.Hook{
+		{Name: "script", Command: "sh", Args: []string{"-c", `echo "$AWL_EVENT_TYPE $AWL_PEER_ID" > ` + output}},
+		{Name: "other", Events: []string{entity.EventUpdateAvailable}, Command: "false"},
+	}})
+
+	events := make(chan entity.Event, 1)
+	events <- entity.Event{Type: entity.EventPeerConnected, Time: time.Now(), PeerID: "peer1"}
+	close(events)
+	hooks.Run(context.Background(), events)
+
+	data, err := os.ReadFile(output)
+	a.NoError(err)
+	a.Equal("peer_connected peer1\n", string(data))
+	deliveries := hooks.Deliveries()
+	a.Len(deliveries, 1)
+	a.Equal("script", deliveries[0].Hook)
+	a.True(deliveries[0].Success)
+	a.Equal(1, deliveries[0].Attempts)
+}
+
+func TestSignHookBody(t *testing.T) {
+	// echo -n '{}' | openssl dgst -sha256 -hmac secret
+	require.Equal(t, "sha256=77325902caca812dc259733aacd046b73817372c777b8d95b402647474516e13", SignHookBody("secret", []byte("{}")))
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+
+	"github.com/anywherelan/awl/awlevent"
+	"github.com/anywherelan/awl/config"
+	"github.com/anywherelan/awl/protocol"
+)
+
+const introductionLifetime = 7 * 24 * time.Hour
+
+// IntroducePeer sends recipient signed introduction of another known peer.
+func (s *AuthStatus) IntroducePeer(ctx context.Context, privKey crypto.PrivKey, recipientID, introducedID peer.ID) error {
+	recipient, recipientKnown := s.conf.GetPeer(recipientID.String())
+	introduced, introducedKnown := s.conf.GetPeer(introducedID.String())
+	switch {
+	case !recipientKnown || !introducedKnown:
+		return errors.New("both peers should be known")
+	case recipientID == introducedID:
+		return errors.New("peer can't be introduced to itself")
+	case !recipient.Confirmed || !introduced.Confirmed:
+		return errors.New("both peers should confirm our friend request")
+	case !recipient.HasCapability(protocol.CapabilityIntroductions) || !introduced.HasCapability(protocol.CapabilityIntroductions):
+		return errors.New("both peers should support introductions")
+	}
+
+	now := time.Now()
+	raw, err := protocol.EncodeIntroduction(protocol.Introduction{
+		Introducer: s.p2p.PeerID().String(),
+		PeerID:     introduced.PeerID,
+		Name:       introduced.Name,
+		Recipient:  recipient.PeerID,
+		IssuedAt:   now,
+		ExpiresAt:  now.Add(introductionLifetime),
+	}, privKey)
+	if err != nil {
+		return err
+	}
+
+	err = s.p2p.ConnectPeer(ctx, recipientID)
+	if err != nil {
+		return err
+	}
+	stream, err := s.p2p.NewStream(ctx, recipientID, protocol.IntroduceMethod)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = stream.Close()
+	}()
+
+	err = protocol.SendIntroducePeer(stream, protocol.IntroducePeer{Introduction: raw})
+	if err != nil {
+		return fmt.Errorf("sending introduction: %v", err)
+	}
+	s.logger.Infof("introduced %s to %s", introduced.DisplayName(), recipient.DisplayName())
+
+	return nil
+}
+
+func (s *AuthStatus) IntroductionStreamHandler(stream network.Stream) {
+	defer func() {
+		_ = stream.Close()
+	}()
+
+	remotePeer := stream.Conn().RemotePeer()
+	peerID := remotePeer.String()
+	msg, err := protocol.ReceiveIntroducePeer(stream)
+	if err != nil {
+		s.logger.Errorf("receiving introduction from %s: %v", peerID, err)
+		return
+	}
+
+	introduction, introducer, err := s.DecodeIntroduction(msg.Introduction)
+	switch {
+	case err != nil:
+		s.logger.Warnf("peer %s sent invalid introduction: %v", peerID, err)
+		return
+	case introduction.Introducer != peerID || introduction.Recipient != s.p2p.PeerID().String() || introduction.PeerID == peerID:
+		s.logger.Warnf("peer %s sent introduction issued for other peers", peerID)
+		return
+	}
+	introducedID, err := peer.Decode(introduction.PeerID)
+	if err != nil || introducedID == s.p2p.PeerID() {
+		return
+	}
+	if _, known := s.conf.GetPeer(introduction.PeerID); known {
+		return
+	}
+	if _, blocked := s.conf.GetBlockedPeer(introduction.PeerID); blocked {
+		return
+	}
+
+	if s.conf.IsIntroductionTrusted(introducer) {
+		s.logger.Infof("adding peer %s (%s) introduced by %s", introduction.Name, introduction.PeerID, introducer.DisplayName())
+		newPeer := s.newKnownPeer(introducedID, introduction.Name, s.conf.GenUniqPeerAlias(introduction.Name, ""), false)
+		newPeer.Introduction = msg.Introduction
+		s.addPeer(context.Background(), newPeer)
+		return
+	}
+
+	if _, requested := s.conf.GetIngoingAuthRequest(introduction.PeerID); requested {
+		return
+	}
+	now := time.Now()
+	s.conf.UpsertIngoingAuthRequest(config.IngoingAuthRequest{
+		PeerID:       introduction.PeerID,
+		Name:         introduction.Name,
+		Introduction: msg.Introduction,
+		Introduced:   true,
+		ReceivedAt:   now,
+		ExpiresAt:    now.Add(ingoingAuthRequestLifetime),
+	})
+	_ = s.authsEmitter.Emit(awlevent.ReceivedAuthRequest{
+		AuthPeer: protocol.AuthPeer{
+			Name:         introduction.Name,
+			Introduction: msg.Introduction,
+		},
+		PeerID: introduction.PeerID,
+	})
+	s.logger.Infof("peer %s (%s) introduced by %s", introduction.Name, introduction.PeerID, introducer.DisplayName())
+}
+
+// DecodeIntroduction parses introduction and checks that introducer is our confirmed friend.
+func (s *AuthStatus) DecodeIntroduction(raw string) (protocol.Introduction, config.KnownPeer, error) {
+	introduction, err := protocol.DecodeIntroduction(raw, time.Now())
+	if err != nil {
+		return protocol.Introduction{}, config.KnownPeer{}, err
+	}
+	introducer, known := s.conf.GetPeer(introduction.Introducer)
+	if !known || !introducer.Confirmed || introducer.Declined {
+		return protocol.Introduction{}, config.KnownPeer{}, fmt.Errorf("introducer %s is not our friend", introduction.Introducer)
+	}
+
+	return introduction, introducer, nil
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+
+	"github.com/anywherelan/awl/config"
+	"github.com/anywherelan/awl/protocol"
+)
+
+var ErrNotNetworkMember = errors.New("not a member of any network")
+
+// JoinNetwork adds issuer of our certificate as a peer, it accepts us automatically and shares certificates of other members.
+func (s *AuthStatus) JoinNetwork(ctx context.Context, cert protocol.MembershipCertificate) {
+	issuerID, err := peer.Decode(cert.Issuer)
+	if err != nil || cert.Issuer == cert.PeerID {
+		return
+	}
+	if knownPeer, known := s.conf.GetPeer(cert.Issuer); known {
+		go func() {
+			_ = s.ExchangeNewStatusInfo(ctx, issuerID, knownPeer)
+		}()
+		return
+	}
+	s.AddPeer(ctx, issuerID, "", s.conf.GenUniqPeerAlias("", ""), false)
+}
+
+// ApplyAdminList replaces admins of network if admin list is newer than the current one.
+func (s *AuthStatus) ApplyAdminList(raw string) error {
+	network := s.conf.GetNetwork()
+	if !network.Enabled() {
+		return ErrNotNetworkMember
+	}
+	list, err := protocol.DecodeAdminList(raw, network.Name, network.AdminKeys)
+	if err != nil {
+		return err
+	}
+	if s.conf.UpdateAdminList(raw, list.IssuedAt, list.Admins) {
+		s.logger.Infof("admins of network %s are updated by %s: %v", network.Name, list.Issuer, list.Admins)
+	}
+
+	return nil
+}
+
+// ApplyRevocationList stores revocation list if it's newer than the current one and removes revoked members.
+func (s *AuthStatus) ApplyRevocationList(raw string) error {
+	network := s.conf.GetNetwork()
+	if !network.Enabled() {
+		return ErrNotNetworkMember
+	}
+	list, err := protocol.DecodeRevocationList(raw, network.Name, network.AdminKeys)
+	if err != nil {
+		return err
+	}
+	if !s.conf.UpdateRevocationList(raw, list.IssuedAt, list.RevokedPeerIDs) {
+		return nil
+	}
+
+	if slices.Contains(list.RevokedPeerIDs, s.p2p.PeerID().String()) {
+		s.logger.Warnf("our membership in network %s has been revoked by %s, leaving network", network.Name, list.Issuer)
+		s.conf.SetNetwork(config.NetworkConfig{})
+		return nil
+	}
+	// only peers accepted as members are removed, peers added manually stay known
+	for _, peerID := range list.RevokedPeerIDs {
+		knownPeer, exists := s.conf.GetPeer(peerID)
+		if !exists || knownPeer.Certificate == "" {
+			continue
+		}
+		id, err := peer.Decode(peerID)
+		if err != nil {
+			continue
+		}
+		s.logger.Infof("removing peer %s (%s) because its membership has been revoked", knownPeer.DisplayName(), peerID)
+		s.conf.RemovePeer(peerID)
+		s.peerHistory.Remove(peerID)
+		s.p2p.UnprotectPeer(id)
+		s.BlockPeer(id, knownPeer.DisplayName())
+	}
+
+	return nil
+}
+
+func (s *AuthStatus) verifyMemberCertificate(raw, peerID string) (protocol.MembershipCertificate, error) {
+	network := s.conf.GetNetwork()
+	if !network.Enabled() {
+		return protocol.MembershipCertificate{}, ErrNotNetworkMember
+	}
+	cert, err := protocol.DecodeCertificate(raw)
+	if err != nil {
+		return protocol.MembershipCertificate{}, err
+	}
+	err = cert.Verify(network.Name, network.AdminKeys, peerID, network.RevokedPeerIDs, time.Now())
+	if err != nil {
+		return protocol.MembershipCertificate{}, err
+	}
+
+	return cert, nil
+}
+
+// addMemberGroups adds groups from certificate to peer groups, missing groups are created.
+func (s *AuthStatus) addMemberGroups(groups []string, cert protocol.MembershipCertificate) []string {
+	for _, name := range cert.Groups {
+		if !config.IsValidGroupName(name) || slices.Contains(groups, name) {
+			continue
+		}
+		if _, exists := s.conf.GetGroup(name); !exists {
+			s.conf.UpsertGroup(config.PeerGroup{
+				Name:        name,
+				Description: fmt.Sprintf("created by network %s", cert.Network),
+			})
+		}
+		groups = append(groups, name)
+	}
+	slices.Sort(groups)
+
+	return groups
+}
+
+// addNetworkInfo adds our certificate to status info, certificates of other members and revocation list are shared only with members.
+func (s *AuthStatus) addNetworkInfo(info *protocol.PeerStatusInfo, knownPeer config.KnownPeer) {
+	network := s.conf.GetNetwork()
+	if !network.Enabled() || !knownPeer.HasCapability(protocol.CapabilityNetworkCertificates) {
+		return
+	}
+	info.Certificate = network.Certificate
+	if knownPeer.Certificate == "" {
+		return
+	}
+	info.RevocationList = network.RevocationList
+	info.AdminList = network.AdminList
+	info.MemberCertificates = slices.DeleteFunc(s.conf.NetworkMemberCertificates(), func(cert string) bool {
+		return cert == knownPeer.Certificate
+	})
+}
+
+// processPeerCertificate stores valid certificate of known peer and adds peer to its groups.
+func (s *AuthStatus) processPeerCertificate(knownPeer *config.KnownPeer, raw string) {
+	if raw == "" || raw == knownPeer.Certificate {
+		return
+	}
+	cert, err := s.verifyMemberCertificate(raw, knownPeer.PeerID)
+	if err != nil {
+		if !errors.Is(err, ErrNotNetworkMember) {
+			s.logger.Warnf("peer %s (%s) presented invalid membership certificate: %v", knownPeer.DisplayName(), knownPeer.PeerID, err)
+		}
+		return
+	}
+	knownPeer.Certificate = raw
+	knownPeer.Groups = s.addMemberGroups(knownPeer.Groups, cert)
+}
+
+// processNetworkInfo applies revocation list from member and adds other members which we don't know yet.
+func (s *AuthStatus) processNetworkInfo(info protocol.PeerStatusInfo) {
+	// revocation list could be issued by new admin
+	if info.AdminList != "" {
+		err := s.ApplyAdminList(info.AdminList)
+		if err != nil {
+			s.logger.Warnf("invalid admin list: %v", err)
+		}
+	}
+	if info.RevocationList != "" {
+		err := s.ApplyRevocationList(info.RevocationList)
+		if err != nil {
+			s.logger.Warnf("invalid revocation list: %v", err)
+		}
+	}
+
+	myPeerID := s.p2p.PeerID().String()
+	for _, raw := range info.MemberCertificates {
+		cert, err := protocol.DecodeCertificate(raw)
+		if err != nil || cert.PeerID == myPeerID {
+			continue
+		}
+		if _, known := s.conf.GetPeer(cert.PeerID); known {
+			continue
+		}
+		if _, blocked := s.conf.GetBlockedPeer(cert.PeerID); blocked {
+			continue
+		}
+		_, err = s.verifyMemberCertificate(raw, cert.PeerID)
+		if err != nil {
+			continue
+		}
+		peerID, err := peer.Decode(cert.PeerID)
+		if err != nil {
+			continue
+		}
+		s.logger.Infof("adding member %s (%s) of network %s", cert.Name, cert.PeerID, cert.Network)
+		newPeer := s.newKnownPeer(peerID, cert.Name, s.conf.GenUniqPeerAlias(cert.Name, ""), false)
+		newPeer.Certificate = raw
+		newPeer.Groups = s.addMemberGroups(nil, cert)
+		s.addPeer(context.Background(), newPeer)
+	}
+}
+
+// removeExpiredMembers removes known peers accepted as members if their certificates have expired.
+// They are not blocked and are accepted again after admin renews their certificates.
+func (s *AuthStatus) removeExpiredMembers() {
+	if !s.conf.GetNetwork().Enabled() {
+		return
+	}
+	for _, peerID := range s.conf.NetworkMembers() {
+		knownPeer, exists := s.conf.GetPeer(peerID)
+		if !exists {
+			continue
+		}
+		_, err := s.verifyMemberCertificate(knownPeer.Certificate, peerID)
+		if !errors.Is(err, protocol.ErrCertificateExpired) {
+			continue
+		}
+		id, err := peer.Decode(peerID)
+		if err != nil {
+			continue
+		}
+		s.logger.Infof("removing peer %s (%s) because its membership certificate has expired", knownPeer.DisplayName(), peerID)
+		s.conf.RemovePeer(peerID)
+		s.peerHistory.Remove(peerID)
+		s.p2p.UnprotectPeer(id)
+	}
+}
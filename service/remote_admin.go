@@ -0,0 +1,162 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+
+	"github.com/anywherelan/awl/config"
+	"github.com/anywherelan/awl/protocol"
+)
+
+const (
+	remoteAdminTimeout = 30 * time.Second
+	// maxRemoteAdminMessageSize limits size of request and response
+	maxRemoteAdminMessageSize = 4 << 20
+)
+
+// RemoteAdmin lets peers with config.KnownPeer AllowRemoteAdmin call our api, and lets us call api of peers which allowed it to us.
+// Each stream carries single HTTP/1.1 request and response.
+type RemoteAdmin struct {
+	logger *log.ZapEventLogger
+	p2p    P2p
+	conf   *config.Config
+	audit  *config.RemoteAdminAudit
+
+	handlerLock sync.RWMutex
+	handler     http.Handler
+}
+
+func NewRemoteAdmin(p2pService P2p, conf *config.Config) *RemoteAdmin {
+	return &RemoteAdmin{
+		logger: log.Logger("awl/service/remote_admin"),
+		p2p:    p2pService,
+		conf:   conf,
+		audit:  config.LoadRemoteAdminAudit(conf.DataDir()),
+	}
+}
+
+// SetHandler sets handler which serves requests of remote peers, it should allow only safe subset of api.
+func (r *RemoteAdmin) SetHandler(handler http.Handler) {
+	r.handlerLock.Lock()
+	r.handler = handler
+	r.handlerLock.Unlock()
+}
+
+// Audit returns requests made by peers, the newest last.
+func (r *RemoteAdmin) Audit() []config.RemoteAdminAuditEntry {
+	return r.audit.Entries()
+}
+
+func (r *RemoteAdmin) StreamHandler(stream network.Stream) {
+	defer func() {
+		_ = stream.Close()
+	}()
+	_ = stream.SetDeadline(time.Now().Add(remoteAdminTimeout))
+
+	peerID := stream.Conn().RemotePeer().String()
+	knownPeer, known := r.conf.GetPeer(peerID)
+	if !known {
+		r.logger.Warnf("Unknown peer %s tried to use remote admin", peerID)
+		_ = stream.Reset()
+		return
+	}
+
+	req, err := http.ReadRequest(bufio.NewReader(io.LimitReader(stream, maxRemoteAdminMessageSize)))
+	if err != nil {
+		r.logger.Errorf("read remote admin request from %s: %v", peerID, err)
+		_ = stream.Reset()
+		return
+	}
+	entry := config.RemoteAdminAuditEntry{
+		Time:     time.Now(),
+		PeerID:   peerID,
+		PeerName: knownPeer.DisplayName(),
+		Method:   req.Method,
+		Path:     req.URL.Path,
+	}
+
+	r.handlerLock.RLock()
+	handler := r.handler
+	r.handlerLock.RUnlock()
+
+	recorder := httptest.NewRecorder()
+	switch {
+	case !knownPeer.Confirmed || !knownPeer.AllowRemoteAdmin || !r.conf.IsInboundAllowed(knownPeer):
+		entry.Error = "remote admin is not allowed for this peer"
+		writeRemoteAdminError(recorder, http.StatusForbidden, entry.Error)
+	case handler == nil:
+		entry.Error = "api is not ready"
+		writeRemoteAdminError(recorder, http.StatusServiceUnavailable, entry.Error)
+	default:
+		req = req.WithContext(context.Background())
+		req.RemoteAddr = peerID
+		handler.ServeHTTP(recorder, req)
+	}
+	resp := recorder.Result()
+	entry.Status = resp.StatusCode
+	r.audit.Add(entry)
+	r.logger.Infof("remote admin request %s %s from %s (%s): %d", entry.Method, entry.Path, entry.PeerName, peerID, entry.Status)
+
+	err = resp.Write(stream)
+	if err != nil {
+		r.logger.Errorf("write remote admin response to %s: %v", peerID, err)
+	}
+}
+
+// SendRequest sends request to api of peer and returns response with body read into memory.
+func (r *RemoteAdmin) SendRequest(ctx context.Context, peerID peer.ID, req *http.Request) (*http.Response, error) {
+	if _, known := r.conf.GetPeer(peerID.String()); !known {
+		return nil, config.ErrPeerNotFound
+	}
+	err := r.p2p.ConnectPeer(ctx, peerID)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := r.p2p.NewStream(ctx, peerID, protocol.RemoteAdminMethod)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = stream.Close()
+	}()
+	_ = stream.SetDeadline(time.Now().Add(remoteAdminTimeout))
+
+	err = req.Write(stream)
+	if err != nil {
+		_ = stream.Reset()
+		return nil, fmt.Errorf("write request: %v", err)
+	}
+	_ = stream.CloseWrite()
+
+	resp, err := http.ReadResponse(bufio.NewReader(io.LimitReader(stream, maxRemoteAdminMessageSize)), req)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response body: %v", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	return resp, nil
+}
+
+// writeRemoteAdminError writes error in the same format as api does.
+func writeRemoteAdminError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
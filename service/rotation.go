@@ -0,0 +1,102 @@
+package service
+
+import (
+	"crypto/rand"
+	"errors"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+
+	"github.com/anywherelan/awl/config"
+	"github.com/anywherelan/awl/protocol"
+)
+
+// RotateIdentityKey generates new identity key and signs handover with the current one.
+// New key is used after restart, known peers follow it when we present handover to them.
+func (s *AuthStatus) RotateIdentityKey(oldKey crypto.PrivKey, gracePeriod time.Duration) (protocol.KeyHandover, error) {
+	newKey, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		return protocol.KeyHandover{}, err
+	}
+	newPeerID, err := peer.IDFromPrivateKey(newKey)
+	if err != nil {
+		return protocol.KeyHandover{}, err
+	}
+
+	now := time.Now()
+	handover := protocol.KeyHandover{
+		OldPeerID:     s.p2p.PeerID().String(),
+		NewPeerID:     newPeerID.String(),
+		IssuedAt:      now,
+		BlockOldAfter: now.Add(gracePeriod),
+	}
+	raw, err := protocol.EncodeKeyHandover(handover, oldKey)
+	if err != nil {
+		return protocol.KeyHandover{}, err
+	}
+	err = s.conf.RotateIdentity(newKey, raw)
+	if err != nil {
+		return protocol.KeyHandover{}, err
+	}
+	s.logger.Infof("identity key rotated, new peer id %s is used after restart", newPeerID)
+
+	return handover, nil
+}
+
+// followKeyHandover moves known peer to its new id if remote peer presents valid handover signed by the old one.
+func (s *AuthStatus) followKeyHandover(remotePeer peer.ID, raw string) (config.KnownPeer, bool) {
+	handover, err := protocol.DecodeKeyHandover(raw)
+	if err != nil {
+		s.logger.Warnf("peer %s presented invalid key handover: %v", remotePeer, err)
+		return config.KnownPeer{}, false
+	}
+	if handover.NewPeerID != remotePeer.String() {
+		s.logger.Warnf("peer %s presented key handover for another peer %s", remotePeer, handover.NewPeerID)
+		return config.KnownPeer{}, false
+	}
+	oldPeerID, err := peer.Decode(handover.OldPeerID)
+	if err != nil {
+		return config.KnownPeer{}, false
+	}
+
+	knownPeer, err := s.conf.RotatePeerKey(handover.OldPeerID, handover.NewPeerID, handover.BlockOldAfter)
+	if err != nil {
+		if !errors.Is(err, config.ErrPeerNotFound) {
+			s.logger.Warnf("follow key handover of peer %s: %v", remotePeer, err)
+		}
+		return config.KnownPeer{}, false
+	}
+	s.logger.Infof("peer %s rotated identity key from %s to %s", knownPeer.DisplayName(), handover.OldPeerID, handover.NewPeerID)
+	s.p2p.UnprotectPeer(oldPeerID)
+	s.p2p.ProtectPeer(remotePeer)
+	s.peerHistory.Remove(handover.OldPeerID)
+	s.blockRotatedPeers()
+
+	return knownPeer, true
+}
+
+// clearExpiredKeyHandover stops sending our key handover after its grace period, friends block our old id by then.
+func (s *AuthStatus) clearExpiredKeyHandover() {
+	s.conf.RLock()
+	raw, rotationPending := s.conf.P2pNode.KeyHandover, s.conf.P2pNode.NextIdentity != ""
+	s.conf.RUnlock()
+	if raw == "" || rotationPending {
+		return
+	}
+	handover, err := protocol.DecodeKeyHandover(raw)
+	if err == nil && time.Now().Before(handover.BlockOldAfter) {
+		return
+	} else if err != nil {
+		s.logger.Warnf("clear stored key handover: %v", err)
+	}
+	s.conf.ClearKeyHandover()
+	s.logger.Infof("key handover grace period has ended, handover is not sent to peers anymore")
+}
+
+// blockRotatedPeers blocks old ids of peers which rotated identity key after grace period.
+func (s *AuthStatus) blockRotatedPeers() {
+	for _, peerID := range s.conf.BlockRotatedPeers(time.Now()) {
+		s.logger.Infof("blocked old peer id %s after key rotation grace period", peerID)
+	}
+}
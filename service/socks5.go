@@ -6,15 +6,19 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"net/netip"
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ipfs/go-log/v2"
 	pool "github.com/libp2p/go-buffer-pool"
 	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
 
+	"github.com/anywherelan/awl/awlevent"
 	"github.com/anywherelan/awl/config"
 	"github.com/anywherelan/awl/entity"
 	"github.com/anywherelan/awl/protocol"
@@ -26,16 +30,34 @@ type SOCKS5 struct {
 	p2p    P2p
 	conf   *config.Config
 
-	client *socks5.Client
-	server *socks5.Server
+	client  *socks5.Client
+	server  *socks5.Server
+	rule    *socks5.RulePeerDestinations
+	emitter awlevent.Emitter
+
+	// client sessions are proxied by us through exit node, server sessions are proxied by us for other peers
+	activeClientSessions atomic.Int64
+	totalClientSessions  atomic.Uint64
+	activeServerSessions atomic.Int64
+	totalServerSessions  atomic.Uint64
+}
+
+type SOCKS5Stats struct {
+	ActiveClientSessions int64
+	TotalClientSessions  uint64
+	ActiveServerSessions int64
+	TotalServerSessions  uint64
 }
 
-func NewSOCKS5(p2pService P2p, conf *config.Config) (*SOCKS5, error) {
+func NewSOCKS5(p2pService P2p, conf *config.Config, eventbus awlevent.Bus) (*SOCKS5, error) {
 	logger := log.Logger("awl/service/socks5")
+	emitter, err := eventbus.Emitter(new(awlevent.ProxyChanged))
+	if err != nil {
+		return nil, err
+	}
 
 	var client *socks5.Client
 	if conf.SOCKS5.ListenerEnabled {
-		var err error
 		client, err = socks5.NewClient(conf.SOCKS5.ListenAddress)
 		if err != nil {
 			return nil, fmt.Errorf("failed to start socks5 listener: %v", err)
@@ -46,12 +68,15 @@ func NewSOCKS5(p2pService P2p, conf *config.Config) (*SOCKS5, error) {
 
 	server := socks5.NewServer()
 	socks := &SOCKS5{
-		logger: logger,
-		p2p:    p2pService,
-		conf:   conf,
-		client: client,
-		server: server,
+		logger:  logger,
+		p2p:     p2pService,
+		conf:    conf,
+		client:  client,
+		server:  server,
+		emitter: emitter,
 	}
+	socks.rule = socks5.NewRulePeerDestinations(socks.peerDestinationRules)
+	server.SetRules(socks.rule)
 
 	return socks, nil
 }
@@ -62,6 +87,15 @@ func (s *SOCKS5) Close() {
 	}
 }
 
+func (s *SOCKS5) Stats() SOCKS5Stats {
+	return SOCKS5Stats{
+		ActiveClientSessions: s.activeClientSessions.Load(),
+		TotalClientSessions:  s.totalClientSessions.Load(),
+		ActiveServerSessions: s.activeServerSessions.Load(),
+		TotalServerSessions:  s.totalServerSessions.Load(),
+	}
+}
+
 func (s *SOCKS5) ListAvailableProxies() []entity.AvailableProxy {
 	s.conf.RLock()
 	proxies := []entity.AvailableProxy{}
@@ -91,9 +125,14 @@ func (s *SOCKS5) ListAvailableProxies() []entity.AvailableProxy {
 
 func (s *SOCKS5) SetProxyPeerID(peerID string) {
 	s.conf.Lock()
+	changed := s.conf.SOCKS5.UsingPeerID != peerID
 	s.conf.SOCKS5.UsingPeerID = peerID
 	s.conf.Unlock()
 	s.conf.Save()
+
+	if changed {
+		_ = s.emitter.Emit(awlevent.ProxyChanged{UsingPeerID: peerID})
+	}
 }
 
 func (s *SOCKS5) ProxyStreamHandler(stream network.Stream) {
@@ -108,7 +147,7 @@ func (s *SOCKS5) ProxyStreamHandler(stream network.Stream) {
 		s.logger.Infof("Unknown peer %s tried to socks5 proxy", peerID)
 		return
 	}
-	if !knownPeer.WeAllowUsingAsExitNode {
+	if !s.conf.IsExitNodeAllowed(knownPeer) {
 		s.logger.Infof("Peer %s without rights tried to socks5 proxy", peerID)
 		return
 	}
@@ -122,6 +161,10 @@ func (s *SOCKS5) ProxyStreamHandler(stream network.Stream) {
 		return
 	}
 
+	s.totalServerSessions.Add(1)
+	s.activeServerSessions.Add(1)
+	defer s.activeServerSessions.Add(-1)
+
 	// ignore error, we can do nothing about it
 	_ = s.server.ServeStreamConn(stream)
 
@@ -140,8 +183,11 @@ func (s *SOCKS5) ServeConns(ctx context.Context) {
 	proxyConns := s.client.ConnsChan()
 	for conn := range proxyConns {
 		go func() {
+			s.totalClientSessions.Add(1)
+			s.activeClientSessions.Add(1)
 			defer func() {
 				_ = conn.Close()
+				s.activeClientSessions.Add(-1)
 			}()
 
 			err := s.proxyConn(ctx, conn)
@@ -157,8 +203,99 @@ func (s *SOCKS5) SetProxyingLocalhostEnabled(enabled bool) {
 	if enabled {
 		s.server.SetRules(socks5.NewRulePermitAll())
 	} else {
-		s.server.SetRules(socks5.NewRuleDenyLocalhost())
+		s.server.SetRules(s.rule)
+	}
+}
+
+// SetExitNodeRules validates and saves destinations which peer can reach using us as exit node.
+func (s *SOCKS5) SetExitNodeRules(peerID string, rules config.ExitNodeRules) error {
+	_, err := ParseExitNodeRules(rules, "")
+	if err != nil {
+		return err
+	}
+	return s.conf.SetExitNodeRules(peerID, rules)
+}
+
+func (s *SOCKS5) peerDestinationRules(peerID peer.ID) (socks5.DestinationRules, bool) {
+	knownPeer, known := s.conf.GetPeer(peerID.String())
+	if !known || !s.conf.IsExitNodeAllowed(knownPeer) {
+		return socks5.DestinationRules{}, false
+	}
+	s.conf.RLock()
+	vpnNet := s.conf.VPNConfig.IPNet
+	s.conf.RUnlock()
+
+	rules, err := ParseExitNodeRules(knownPeer.ExitNodeRules, vpnNet)
+	if err != nil {
+		s.logger.Errorf("invalid exit node rules of peer %s: %v", knownPeer.DisplayName(), err)
+		return socks5.DestinationRules{}, false
+	}
+	return rules, true
+}
+
+// ParseExitNodeRules converts rules from config, private networks and vpnNet are denied by default.
+func ParseExitNodeRules(rules config.ExitNodeRules, vpnNet string) (socks5.DestinationRules, error) {
+	result := socks5.DestinationRules{
+		DeniedByDefault: slices.Clone(socks5.PrivateNetworks),
+	}
+	if vpnNet != "" {
+		prefix, err := netip.ParsePrefix(vpnNet)
+		if err != nil {
+			return socks5.DestinationRules{}, fmt.Errorf("invalid vpn subnet: %v", err)
+		}
+		result.DeniedByDefault = append(result.DeniedByDefault, prefix.Masked())
+	}
+
+	var err error
+	if result.AllowedCIDRs, err = parseList(rules.AllowedCIDRs, parseCIDR); err != nil {
+		return socks5.DestinationRules{}, err
+	}
+	if result.DeniedCIDRs, err = parseList(rules.DeniedCIDRs, parseCIDR); err != nil {
+		return socks5.DestinationRules{}, err
+	}
+	if result.AllowedDomains, err = parseList(rules.AllowedDomains, socks5.ParseDomainPattern); err != nil {
+		return socks5.DestinationRules{}, err
+	}
+	if result.DeniedDomains, err = parseList(rules.DeniedDomains, socks5.ParseDomainPattern); err != nil {
+		return socks5.DestinationRules{}, err
+	}
+	if result.AllowedPorts, err = parseList(rules.AllowedPorts, socks5.ParsePortRange); err != nil {
+		return socks5.DestinationRules{}, err
+	}
+	if result.DeniedPorts, err = parseList(rules.DeniedPorts, socks5.ParsePortRange); err != nil {
+		return socks5.DestinationRules{}, err
+	}
+
+	return result, nil
+}
+
+// parseCIDR accepts CIDR or single ip address.
+func parseCIDR(value string) (netip.Prefix, error) {
+	value = strings.TrimSpace(value)
+	if !strings.Contains(value, "/") {
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid CIDR %q", value)
+		}
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	prefix, err := netip.ParsePrefix(value)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid CIDR %q", value)
+	}
+	return prefix.Masked(), nil
+}
+
+func parseList[T any](values []string, parse func(string) (T, error)) ([]T, error) {
+	result := make([]T, 0, len(values))
+	for _, value := range values {
+		parsed, err := parse(value)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, parsed)
 	}
+	return result, nil
 }
 
 func (s *SOCKS5) proxyConn(ctx context.Context, conn net.Conn) error {
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/anywherelan/awl/config"
+	"github.com/anywherelan/awl/protocol"
+)
+
+// HealthCheck reports whether TUN interface and awl DNS are working.
+type HealthCheck func() (tunUp, dnsUp bool)
+
+// SetHealthCheck sets function used to share health with peers when it's enabled in config.StatusSharingConfig.
+func (s *AuthStatus) SetHealthCheck(healthCheck HealthCheck) {
+	s.healthCheckLock.Lock()
+	s.healthCheck = healthCheck
+	s.healthCheckLock.Unlock()
+}
+
+func (s *AuthStatus) addSharedStatus(info *protocol.PeerStatusInfo) {
+	sharing := s.conf.GetStatusSharing()
+	if sharing.Hostname {
+		hostname, err := os.Hostname()
+		if err != nil {
+			s.logger.Warnf("get hostname: %v", err)
+		}
+		info.Hostname = hostname
+	}
+	if sharing.OS {
+		info.OS = runtime.GOOS
+		info.Arch = runtime.GOARCH
+	}
+	if sharing.Version {
+		info.Version = config.Version
+	}
+	if sharing.Uptime {
+		info.UptimeSec = uint64(time.Since(s.startedAt).Seconds())
+	}
+	if sharing.Health {
+		s.healthCheckLock.RLock()
+		healthCheck := s.healthCheck
+		s.healthCheckLock.RUnlock()
+		if healthCheck != nil {
+			info.HealthShared = true
+			info.TunUp, info.DNSUp = healthCheck()
+		}
+	}
+	info.Note = sharing.Note
+}
+
+func makePeerSharedStatus(info protocol.PeerStatusInfo) config.PeerSharedStatus {
+	now := time.Now()
+	status := config.PeerSharedStatus{
+		Hostname:     info.Hostname,
+		OS:           info.OS,
+		Arch:         info.Arch,
+		Version:      info.Version,
+		HealthShared: info.HealthShared,
+		TunUp:        info.TunUp,
+		DNSUp:        info.DNSUp,
+		Note:         info.Note,
+		UpdatedAt:    now,
+	}
+	if info.UptimeSec != 0 {
+		status.StartedAt = now.Add(-time.Duration(info.UptimeSec) * time.Second)
+	}
+	return status
+}
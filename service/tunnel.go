@@ -1,12 +1,15 @@
 package service
 
 import (
+	"bufio"
 	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ipfs/go-log/v2"
@@ -47,24 +50,41 @@ func NewTunnel(p2pService P2p, device *vpn.Device, conf *config.Config) *Tunnel
 	return tunnel
 }
 
+// StreamHandler reads packets prefixed with uint64 length, it's used by peers without protocol.CapabilityTunnelVarintFraming.
 func (t *Tunnel) StreamHandler(stream network.Stream) {
+	t.handleStream(stream, stream, protocol.ReadUint64)
+}
+
+// VarintStreamHandler reads packets prefixed with uvarint length.
+func (t *Tunnel) VarintStreamHandler(stream network.Stream) {
+	reader := bufio.NewReader(stream)
+	t.handleStream(stream, reader, func(io.Reader) (uint64, error) {
+		return binary.ReadUvarint(reader)
+	})
+}
+
+func (t *Tunnel) handleStream(stream network.Stream, reader io.Reader, readPacketSize func(io.Reader) (uint64, error)) {
 	defer func() {
 		_ = stream.Close()
 	}()
 
 	peerID := stream.Conn().RemotePeer()
 	t.peersLock.RLock()
-	_, ok := t.peerIDToPeer[peerID]
+	vpnPeer, ok := t.peerIDToPeer[peerID]
 	t.peersLock.RUnlock()
 	if !ok {
 		t.logger.Infof("Unknown peer %s tried to tunnel packet", peerID)
 		return
 	}
+	if vpnPeer.inboundBlocked.Load() {
+		t.logger.Infof("Peer %s with blocked inbound access tried to tunnel packet", peerID)
+		return
+	}
 
 	wrappedStream := &io.LimitedReader{}
 	for {
 		packet := t.device.GetTempPacket()
-		packetSize, err := protocol.ReadUint64(stream)
+		packetSize, err := readPacketSize(reader)
 		if err != nil {
 			if !errors.Is(err, io.EOF) {
 				t.logger.Warnf("read packet size: %v", err)
@@ -72,7 +92,7 @@ func (t *Tunnel) StreamHandler(stream network.Stream) {
 			t.device.PutTempPacket(packet)
 			return
 		}
-		wrappedStream.R = stream
+		wrappedStream.R = reader
 		wrappedStream.N = int64(packetSize)
 		_, err = packet.ReadFrom(wrappedStream)
 		if err != nil {
@@ -83,17 +103,20 @@ func (t *Tunnel) StreamHandler(stream network.Stream) {
 
 		t.peersLock.RLock()
 		vpnPeer, ok := t.peerIDToPeer[peerID]
-		if !ok {
+		if !ok || vpnPeer.inboundBlocked.Load() {
 			t.device.PutTempPacket(packet)
 			t.peersLock.RUnlock()
 			return
 		}
 
+		vpnPeer.stats.packetsIn.Add(1)
+		vpnPeer.stats.bytesIn.Add(uint64(len(packet.Packet)))
 		select {
 		case vpnPeer.inboundCh <- packet:
 		default:
 			// REMOVE
 			t.logger.Warnf("inbound reader dropped packet, len %d", len(packet.Packet))
+			vpnPeer.stats.droppedIn.Add(1)
 			t.device.PutTempPacket(packet)
 		}
 		t.peersLock.RUnlock()
@@ -105,7 +128,6 @@ func (t *Tunnel) RefreshPeersList() {
 	defer t.peersLock.Unlock()
 
 	t.conf.RLock()
-	defer t.conf.RUnlock()
 	for _, knownPeer := range t.conf.KnownPeers {
 		peerID := knownPeer.PeerId()
 		if _, ok := t.peerIDToPeer[peerID]; ok {
@@ -114,6 +136,7 @@ func (t *Tunnel) RefreshPeersList() {
 		localIP := net.ParseIP(knownPeer.IPAddr).To4()
 		if localIP == nil {
 			t.logger.Errorf("Known peer %q has invalid IP %s in conf", knownPeer.DisplayName(), knownPeer.IPAddr)
+			t.conf.RUnlock()
 			return
 		}
 
@@ -137,6 +160,26 @@ func (t *Tunnel) RefreshPeersList() {
 		delete(t.peerIDToPeer, vpnPeer.peerID)
 		delete(t.netIPToPeer, string(vpnPeer.localIP))
 	}
+	t.conf.RUnlock()
+
+	// config methods take the lock themselves, so group policies are checked after unlocking
+	for peerID, vpnPeer := range t.peerIDToPeer {
+		knownPeer, _ := t.conf.GetPeer(peerID.String())
+		vpnPeer.inboundBlocked.Store(!t.conf.IsInboundAllowed(knownPeer))
+	}
+}
+
+// PeersStats returns tunnel counters for each known peer since start.
+func (t *Tunnel) PeersStats() map[peer.ID]TunnelPeerStats {
+	t.peersLock.RLock()
+	defer t.peersLock.RUnlock()
+
+	result := make(map[peer.ID]TunnelPeerStats, len(t.peerIDToPeer))
+	for peerID, vpnPeer := range t.peerIDToPeer {
+		result[peerID] = vpnPeer.stats.load()
+	}
+
+	return result
 }
 
 func (t *Tunnel) Close() {
@@ -164,6 +207,7 @@ func (t *Tunnel) backgroundReadPackets() {
 		select {
 		case vpnPeer.outboundCh <- packet:
 		default:
+			vpnPeer.stats.droppedOut.Add(1)
 			t.device.PutTempPacket(packet)
 		}
 		t.peersLock.RUnlock()
@@ -176,12 +220,18 @@ func (t *Tunnel) makeTunnelStream(ctx context.Context, peerID peer.ID) (network.
 		return nil, err
 	}
 
-	newStreamFunc := t.p2p.NewStream
-	if t.conf.P2pNode.UseDedicatedConnForEachStream {
-		newStreamFunc = t.p2p.NewStreamWithDedicatedConn
+	// framing is chosen from capabilities negotiated during the last status exchange
+	method := protocol.TunnelPacketMethod
+	if knownPeer, _ := t.conf.GetPeer(peerID.String()); knownPeer.HasCapability(protocol.CapabilityTunnelVarintFraming) {
+		method = protocol.TunnelVarintPacketMethod
 	}
 
-	stream, err := newStreamFunc(ctx, peerID, protocol.TunnelPacketMethod)
+	var stream network.Stream
+	if t.conf.P2pNode.UseDedicatedConnForEachStream {
+		stream, err = t.p2p.NewStreamWithDedicatedConn(ctx, peerID, method)
+	} else {
+		stream, err = t.p2p.NewStream(ctx, peerID, method)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -194,6 +244,37 @@ type VpnPeer struct {
 	localIP    net.IP
 	inboundCh  chan *vpn.Packet
 	outboundCh chan *vpn.Packet // from us to remote
+	stats      tunnelPeerStats
+	// inboundBlocked drops packets from peer, see config.PeerGroup BlockInboundAccess
+	inboundBlocked atomic.Bool
+}
+
+type TunnelPeerStats struct {
+	PacketsIn  uint64
+	PacketsOut uint64
+	BytesIn    uint64
+	BytesOut   uint64
+	// DroppedIn are packets from peer dropped because of full queue
+	DroppedIn uint64
+	// DroppedOut are packets to peer dropped because of full queue or send error
+	DroppedOut uint64
+}
+
+type tunnelPeerStats struct {
+	packetsIn, packetsOut atomic.Uint64
+	bytesIn, bytesOut     atomic.Uint64
+	droppedIn, droppedOut atomic.Uint64
+}
+
+func (s *tunnelPeerStats) load() TunnelPeerStats {
+	return TunnelPeerStats{
+		PacketsIn:  s.packetsIn.Load(),
+		PacketsOut: s.packetsOut.Load(),
+		BytesIn:    s.bytesIn.Load(),
+		BytesOut:   s.bytesOut.Load(),
+		DroppedIn:  s.droppedIn.Load(),
+		DroppedOut: s.droppedOut.Load(),
+	}
 }
 
 // TODO: remove Tunnel from VpnPeer dependencies
@@ -239,7 +320,12 @@ func (vp *VpnPeer) backgroundOutboundHandler(t *Tunnel) {
 		tmpPacket := t.device.GetTempPacket()
 		defer t.device.PutTempPacket(tmpPacket)
 
-		protocolPacket := protocol.WritePacketToBuf(tmpPacket.Buffer[:], packet.Packet)
+		var protocolPacket []byte
+		if stream.Protocol() == protocol.TunnelVarintPacketMethod {
+			protocolPacket = protocol.WriteVarintPacketToBuf(tmpPacket.Buffer[:], packet.Packet)
+		} else {
+			protocolPacket = protocol.WritePacketToBuf(tmpPacket.Buffer[:], packet.Packet)
+		}
 		_, err = stream.Write(protocolPacket)
 
 		return err
@@ -270,7 +356,11 @@ func (vp *VpnPeer) backgroundOutboundHandler(t *Tunnel) {
 			err := sendPacket(packet)
 			if err != nil {
 				t.logger.Warnf("send packet to peerID (%s) local ip (%s): %v", vp.peerID, vp.localIP, err)
+				vp.stats.droppedOut.Add(1)
 				closeStream()
+			} else {
+				vp.stats.packetsOut.Add(1)
+				vp.stats.bytesOut.Add(uint64(len(packet.Packet)))
 			}
 			t.device.PutTempPacket(packet)
 		case <-idleTicker.C:
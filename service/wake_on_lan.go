@@ -0,0 +1,189 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+
+	"github.com/anywherelan/awl/config"
+	"github.com/anywherelan/awl/protocol"
+)
+
+const wakeOnLANPort = 9
+
+var ErrNoWakeOnLANInterfaces = errors.New("no LAN interfaces with broadcast support")
+
+// WakeOnLAN sends magic packets to LAN on behalf of peers with config.KnownPeer AllowWakeOnLAN,
+// and asks peers to do it for us.
+type WakeOnLAN struct {
+	logger *log.ZapEventLogger
+	p2p    P2p
+	conf   *config.Config
+}
+
+func NewWakeOnLAN(p2pService P2p, conf *config.Config) *WakeOnLAN {
+	return &WakeOnLAN{
+		logger: log.Logger("awl/service/wake_on_lan"),
+		p2p:    p2pService,
+		conf:   conf,
+	}
+}
+
+func (w *WakeOnLAN) StreamHandler(stream network.Stream) {
+	defer func() {
+		_ = stream.Close()
+	}()
+
+	peerID := stream.Conn().RemotePeer().String()
+	knownPeer, known := w.conf.GetPeer(peerID)
+	if !known {
+		w.logger.Warnf("Unknown peer %s tried to use wake on lan", peerID)
+		_ = stream.Reset()
+		return
+	}
+
+	request, err := protocol.ReceiveWakeOnLAN(stream)
+	if err != nil {
+		w.logger.Errorf("receive wake on lan request from %s: %v", peerID, err)
+		_ = stream.Reset()
+		return
+	}
+
+	response := protocol.WakeOnLANResponse{}
+	mac, err := config.ParseWakeOnLANMAC(request.MAC)
+	switch {
+	case !knownPeer.Confirmed || !knownPeer.AllowWakeOnLAN || !w.conf.IsInboundAllowed(knownPeer):
+		response.Error = "wake on lan is not allowed for this peer"
+	case err != nil:
+		response.Error = err.Error()
+	default:
+		response.Interfaces, err = BroadcastMagicPacket(mac)
+		if err != nil {
+			response.Error = err.Error()
+		}
+	}
+	if response.Error != "" {
+		w.logger.Warnf("wake on lan %s requested by %s (%s): %s", request.MAC, knownPeer.DisplayName(), peerID, response.Error)
+	} else {
+		w.logger.Infof("wake on lan %s requested by %s (%s): sent on %v", mac, knownPeer.DisplayName(), peerID, response.Interfaces)
+	}
+
+	err = protocol.SendWakeOnLANResponse(stream, response)
+	if err != nil {
+		w.logger.Errorf("send wake on lan response to %s: %v", peerID, err)
+	}
+}
+
+// Wake asks peer to broadcast magic packet for mac in its LAN, it returns interfaces of peer where packet was sent.
+func (w *WakeOnLAN) Wake(ctx context.Context, peerID peer.ID, mac net.HardwareAddr) ([]string, error) {
+	if _, known := w.conf.GetPeer(peerID.String()); !known {
+		return nil, config.ErrPeerNotFound
+	}
+	ctx, cancel := context.WithTimeout(ctx, protocol.MessageTimeout)
+	defer cancel()
+	err := w.p2p.ConnectPeer(ctx, peerID)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := w.p2p.NewStream(ctx, peerID, protocol.WakeOnLANMethod)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = stream.Close()
+	}()
+
+	err = protocol.SendWakeOnLAN(stream, protocol.WakeOnLAN{MAC: mac.String()})
+	if err != nil {
+		_ = stream.Reset()
+		return nil, fmt.Errorf("send request: %v", err)
+	}
+	response, err := protocol.ReceiveWakeOnLANResponse(stream)
+	if err != nil {
+		return nil, fmt.Errorf("receive response: %v", err)
+	}
+	if response.Error != "" {
+		return nil, errors.New(response.Error)
+	}
+
+	return response.Interfaces, nil
+}
+
+// MagicPacket is 6 bytes of 0xFF followed by 16 repetitions of mac.
+func MagicPacket(mac net.HardwareAddr) []byte {
+	packet := bytes.Repeat([]byte{0xFF}, 6)
+	for i := 0; i < 16; i++ {
+		packet = append(packet, mac...)
+	}
+	return packet
+}
+
+// BroadcastMagicPacket sends magic packet to broadcast address of each IPv4 network on up interfaces,
+// it returns names of interfaces where packet was sent.
+func BroadcastMagicPacket(mac net.HardwareAddr) ([]string, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	packet := MagicPacket(mac)
+	sent := make([]string, 0)
+	var lastErr error
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagBroadcast == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		ok := false
+		for _, addr := range addrs {
+			ipNet, isIPNet := addr.(*net.IPNet)
+			if !isIPNet || ipNet.IP.To4() == nil {
+				continue
+			}
+			err = sendMagicPacket(packet, ipNet)
+			if err != nil {
+				lastErr = fmt.Errorf("%s: %v", iface.Name, err)
+				continue
+			}
+			ok = true
+		}
+		if ok {
+			sent = append(sent, iface.Name)
+		}
+	}
+
+	if len(sent) == 0 && lastErr != nil {
+		return nil, lastErr
+	} else if len(sent) == 0 {
+		return nil, ErrNoWakeOnLANInterfaces
+	}
+	return sent, nil
+}
+
+func sendMagicPacket(packet []byte, ipNet *net.IPNet) error {
+	ip := ipNet.IP.To4()
+	broadcast := make(net.IP, net.IPv4len)
+	for i := range ip {
+		broadcast[i] = ip[i] | ^ipNet.Mask[len(ipNet.Mask)-net.IPv4len+i]
+	}
+
+	conn, err := net.DialUDP("udp4", &net.UDPAddr{IP: ip}, &net.UDPAddr{IP: broadcast, Port: wakeOnLANPort})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_ = conn.SetWriteDeadline(time.Now().Add(time.Second))
+	_, err = conn.Write(packet)
+	return err
+}
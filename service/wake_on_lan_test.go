@@ -0,0 +1,22 @@
+package service
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMagicPacket(t *testing.T) {
+	a := require.New(t)
+	mac, err := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+	a.NoError(err)
+
+	packet := MagicPacket(mac)
+	a.Len(packet, 102)
+	a.Equal(bytes.Repeat([]byte{0xFF}, 6), packet[:6])
+	for i := 6; i < len(packet); i += 6 {
+		a.Equal([]byte(mac), packet[i:i+6])
+	}
+}
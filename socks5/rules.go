@@ -0,0 +1,210 @@
+package socks5
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/haxii/socks5"
+	"github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const authPayloadPeerID = "peer_id"
+
+// PrivateNetworks are denied for exit node users by default: RFC1918, link-local and IPv6 unique local addresses.
+var PrivateNetworks = []netip.Prefix{
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+}
+
+type peerIDContextKey struct{}
+
+// PeerIDFromContext returns id of peer which requested proxying, it is available in RuleSet.Allow.
+func PeerIDFromContext(ctx context.Context) (peer.ID, bool) {
+	peerID, ok := ctx.Value(peerIDContextKey{}).(peer.ID)
+	return peerID, ok
+}
+
+func withPeerID(ctx context.Context, req *socks5.Request) context.Context {
+	if req.AuthContext == nil {
+		return ctx
+	}
+	peerID, err := peer.Decode(req.AuthContext.Payload[authPayloadPeerID])
+	if err != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, peerIDContextKey{}, peerID)
+}
+
+// peerAuthenticator is "no authentication" method which remembers remote peer of libp2p stream.
+type peerAuthenticator struct {
+	socks5.NoAuthAuthenticator
+}
+
+func (a peerAuthenticator) Authenticate(reader io.Reader, writer io.Writer) (*socks5.AuthContext, error) {
+	authContext, err := a.NoAuthAuthenticator.Authenticate(reader, writer)
+	if err != nil {
+		return nil, err
+	}
+	if conn, ok := writer.(StreamConnWrapper); ok {
+		authContext.Payload = map[string]string{authPayloadPeerID: conn.Conn().RemotePeer().String()}
+	}
+	return authContext, nil
+}
+
+type PortRange struct {
+	From uint16
+	To   uint16
+}
+
+// ParsePortRange parses single port "443" or inclusive range "8000-9000".
+func ParsePortRange(value string) (PortRange, error) {
+	from, to, isRange := strings.Cut(strings.TrimSpace(value), "-")
+	if !isRange {
+		to = from
+	}
+	fromPort, err := strconv.ParseUint(strings.TrimSpace(from), 10, 16)
+	if err != nil || fromPort == 0 {
+		return PortRange{}, fmt.Errorf("invalid port %q", value)
+	}
+	toPort, err := strconv.ParseUint(strings.TrimSpace(to), 10, 16)
+	if err != nil || toPort < fromPort {
+		return PortRange{}, fmt.Errorf("invalid port range %q", value)
+	}
+	return PortRange{From: uint16(fromPort), To: uint16(toPort)}, nil
+}
+
+func (r PortRange) Contains(port int) bool {
+	return port >= int(r.From) && port <= int(r.To)
+}
+
+// ParseDomainPattern validates domain pattern: exact domain "example.com" or its subdomains "*.example.com".
+func ParseDomainPattern(value string) (string, error) {
+	pattern := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(value)), ".")
+	domain := strings.TrimPrefix(pattern, "*.")
+	if domain == "" || strings.ContainsAny(domain, "*/: ") {
+		return "", fmt.Errorf("invalid domain pattern %q", value)
+	}
+	return pattern, nil
+}
+
+// MatchDomain reports whether domain matches pattern parsed by ParseDomainPattern.
+func MatchDomain(pattern, domain string) bool {
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+		return strings.HasSuffix(domain, suffix)
+	}
+	return domain == pattern
+}
+
+// DestinationRules restrict destinations reachable through exit node.
+// Denied rules take precedence, non-empty allowed CIDRs and domains make an allowlist,
+// DeniedByDefault networks are reachable only when destination ip is in AllowedCIDRs. Localhost is always denied.
+type DestinationRules struct {
+	AllowedCIDRs    []netip.Prefix
+	DeniedCIDRs     []netip.Prefix
+	AllowedDomains  []string
+	DeniedDomains   []string
+	AllowedPorts    []PortRange
+	DeniedPorts     []PortRange
+	DeniedByDefault []netip.Prefix
+}
+
+func (r DestinationRules) Allow(dest *socks5.AddrSpec) bool {
+	if dest == nil {
+		return false
+	}
+	ip, ok := netip.AddrFromSlice(dest.IP)
+	if !ok {
+		return false
+	}
+	ip = ip.Unmap()
+	if ip.IsLoopback() || ip.IsUnspecified() {
+		return false
+	}
+
+	if containsPort(r.DeniedPorts, dest.Port) || containsIP(r.DeniedCIDRs, ip) ||
+		dest.FQDN != "" && matchesDomain(r.DeniedDomains, dest.FQDN) {
+		return false
+	}
+	if len(r.AllowedPorts) != 0 && !containsPort(r.AllowedPorts, dest.Port) {
+		return false
+	}
+
+	allowedIP := containsIP(r.AllowedCIDRs, ip)
+	if containsIP(r.DeniedByDefault, ip) && !allowedIP {
+		return false
+	}
+	if len(r.AllowedCIDRs) == 0 && len(r.AllowedDomains) == 0 {
+		return true
+	}
+
+	return allowedIP || dest.FQDN != "" && matchesDomain(r.AllowedDomains, dest.FQDN)
+}
+
+func containsIP(prefixes []netip.Prefix, ip netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsPort(ranges []PortRange, port int) bool {
+	for _, portRange := range ranges {
+		if portRange.Contains(port) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesDomain(patterns []string, domain string) bool {
+	for _, pattern := range patterns {
+		if MatchDomain(pattern, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// RulePeerDestinations allows requests to destinations permitted by rules of requesting peer.
+type RulePeerDestinations struct {
+	peerRules func(peerID peer.ID) (DestinationRules, bool)
+	logger    *log.ZapEventLogger
+}
+
+// NewRulePeerDestinations creates rule, peerRules returns false if peer is not allowed to proxy at all.
+func NewRulePeerDestinations(peerRules func(peerID peer.ID) (DestinationRules, bool)) *RulePeerDestinations {
+	return &RulePeerDestinations{
+		peerRules: peerRules,
+		logger:    log.Logger("socks5/rules"),
+	}
+}
+
+func (r *RulePeerDestinations) Allow(ctx context.Context, req *socks5.Request) (context.Context, bool) {
+	peerID, ok := PeerIDFromContext(ctx)
+	if !ok {
+		return ctx, false
+	}
+	rules, ok := r.peerRules(peerID)
+	if !ok {
+		r.logger.Infof("peer %s is not allowed to use exit node", peerID)
+		return ctx, false
+	}
+	if !rules.Allow(req.DestAddr) {
+		r.logger.Infof("peer %s is not allowed to connect to %s", peerID, req.DestAddr)
+		return ctx, false
+	}
+
+	return ctx, true
+}
@@ -0,0 +1,104 @@
+package socks5
+
+import (
+	"context"
+	"crypto/rand"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/haxii/socks5"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDestinationRules(t *testing.T) {
+	dest := func(fqdn, ip string, port int) *socks5.AddrSpec {
+		return &socks5.AddrSpec{FQDN: fqdn, IP: net.ParseIP(ip), Port: port}
+	}
+	defaultRules := DestinationRules{DeniedByDefault: PrivateNetworks}
+
+	a := require.New(t)
+	a.True(defaultRules.Allow(dest("", "1.1.1.1", 443)))
+	a.True(defaultRules.Allow(dest("", "2606:4700::1111", 443)))
+	a.False(defaultRules.Allow(dest("", "127.0.0.1", 80)))
+	a.False(defaultRules.Allow(dest("", "::1", 80)))
+	a.False(defaultRules.Allow(dest("", "192.168.1.1", 80)))
+	a.False(defaultRules.Allow(dest("", "10.1.2.3", 80)))
+	a.False(defaultRules.Allow(dest("", "169.254.169.254", 80)))
+	a.False(defaultRules.Allow(dest("", "fe80::1", 80)))
+	a.False(defaultRules.Allow(dest("", "::ffff:192.168.1.1", 80)))
+	// domain resolved to private address is denied too
+	a.False(defaultRules.Allow(dest("router.lan", "192.168.1.1", 80)))
+	a.False(defaultRules.Allow(nil))
+
+	rules := DestinationRules{
+		AllowedCIDRs:    []netip.Prefix{netip.MustParsePrefix("192.168.1.10/32")},
+		DeniedCIDRs:     []netip.Prefix{netip.MustParsePrefix("8.8.8.0/24")},
+		AllowedDomains:  []string{"*.example.com", "example.org"},
+		DeniedDomains:   []string{"admin.example.com"},
+		DeniedPorts:     []PortRange{{From: 25, To: 25}},
+		DeniedByDefault: PrivateNetworks,
+	}
+	a.True(rules.Allow(dest("", "192.168.1.10", 80)))
+	a.False(rules.Allow(dest("", "192.168.1.11", 80)))
+	a.True(rules.Allow(dest("www.example.com", "1.1.1.1", 443)))
+	a.True(rules.Allow(dest("EXAMPLE.ORG.", "1.1.1.1", 443)))
+	a.False(rules.Allow(dest("example.com", "1.1.1.1", 443)))
+	a.False(rules.Allow(dest("admin.example.com", "1.1.1.1", 443)))
+	a.False(rules.Allow(dest("www.example.com", "8.8.8.8", 443)))
+	a.False(rules.Allow(dest("www.example.com", "1.1.1.1", 25)))
+	// not in allowlist
+	a.False(rules.Allow(dest("", "1.1.1.1", 443)))
+
+	rules = DestinationRules{AllowedPorts: []PortRange{{From: 80, To: 80}, {From: 8000, To: 9000}}}
+	a.True(rules.Allow(dest("", "1.1.1.1", 8080)))
+	a.False(rules.Allow(dest("", "1.1.1.1", 443)))
+}
+
+func TestParseRules(t *testing.T) {
+	a := require.New(t)
+	portRange, err := ParsePortRange("443")
+	a.NoError(err)
+	a.Equal(PortRange{From: 443, To: 443}, portRange)
+	portRange, err = ParsePortRange("8000-9000")
+	a.NoError(err)
+	a.Equal(PortRange{From: 8000, To: 9000}, portRange)
+	for _, invalid := range []string{"", "0", "70000", "9000-8000", "a-b", "80-"} {
+		_, err = ParsePortRange(invalid)
+		a.Error(err, invalid)
+	}
+
+	pattern, err := ParseDomainPattern(" *.Example.com. ")
+	a.NoError(err)
+	a.Equal("*.example.com", pattern)
+	for _, invalid := range []string{"", "*.", "*", "a.*.com", "example.com:80"} {
+		_, err = ParseDomainPattern(invalid)
+		a.Error(err, invalid)
+	}
+}
+
+func TestRulePeerDestinations(t *testing.T) {
+	a := require.New(t)
+	privKey, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	a.NoError(err)
+	allowedPeer, err := peer.IDFromPrivateKey(privKey)
+	a.NoError(err)
+	rule := NewRulePeerDestinations(func(peerID peer.ID) (DestinationRules, bool) {
+		return DestinationRules{}, peerID == allowedPeer
+	})
+	req := &socks5.Request{DestAddr: &socks5.AddrSpec{IP: net.ParseIP("1.1.1.1"), Port: 443}}
+
+	_, allowed := rule.Allow(context.Background(), req)
+	a.False(allowed)
+	_, allowed = rule.Allow(context.WithValue(context.Background(), peerIDContextKey{}, peer.ID("other")), req)
+	a.False(allowed)
+	_, allowed = rule.Allow(context.WithValue(context.Background(), peerIDContextKey{}, allowedPeer), req)
+	a.True(allowed)
+
+	// peer id is taken from AuthContext filled by peerAuthenticator
+	req.AuthContext = &socks5.AuthContext{Payload: map[string]string{authPayloadPeerID: allowedPeer.String()}}
+	_, allowed = NewUpdatableRule(rule).Allow(context.Background(), req)
+	a.True(allowed)
+}
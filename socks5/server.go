@@ -28,9 +28,11 @@ func NewServer() *Server {
 		// fake addr, we don't bind address for server
 		BindIP:   net.IPv4(127, 0, 0, 1),
 		BindPort: 8000,
-		Rules:    rule,
-		Logger:   NewLogger(),
-		Resolver: nil,
+		// peer id is passed to Rules through AuthContext
+		AuthMethods: []socks5.Authenticator{peerAuthenticator{}},
+		Rules:       rule,
+		Logger:      NewLogger(),
+		Resolver:    nil,
 		// TODO: add optional password authentication method support
 	}
 	server, err := socks5.New(conf)
@@ -45,7 +47,7 @@ func NewServer() *Server {
 	}
 }
 
-// SetRules is created for tests and not intended for real usage.
+// SetRules replaces rules for all new requests, requesting peer id is available with PeerIDFromContext.
 func (s *Server) SetRules(rule socks5.RuleSet) {
 	s.rule.SetRule(rule)
 }
@@ -167,7 +169,7 @@ func NewUpdatableRule(rule socks5.RuleSet) *UpdatableRule {
 func (r *UpdatableRule) Allow(ctx context.Context, req *socks5.Request) (context.Context, bool) {
 	rule := *r.rule.Load()
 
-	return rule.Allow(ctx, req)
+	return rule.Allow(withPeerID(ctx, req), req)
 }
 
 func (r *UpdatableRule) SetRule(rule socks5.RuleSet) {
@@ -29,6 +29,7 @@ const (
 type Device struct {
 	tun        tun.Device
 	mtu        int64
+	up         atomic.Bool
 	localIP    net.IP
 	outboundCh chan *Packet
 
@@ -66,6 +67,7 @@ func NewDevice(existingTun tun.Device, interfaceName string, localIP net.IP, ipM
 		logger:  log.Logger("awl/vpn"),
 		closeCh: make(chan struct{}),
 	}
+	dev.up.Store(true)
 	go dev.tunEventsReader()
 	go dev.tunPacketsReader()
 
@@ -107,6 +109,11 @@ func (d *Device) OutboundChan() <-chan *Packet {
 	return d.outboundCh
 }
 
+// IsUp reports whether interface is up according to the latest TUN event.
+func (d *Device) IsUp() bool {
+	return d.up.Load()
+}
+
 func (d *Device) Close() error {
 	close(d.closeCh)
 	return d.tun.Close()
@@ -135,9 +142,12 @@ func (d *Device) tunEventsReader() {
 			}
 		}
 
-		// TODO: check for event&tun.EventUp
+		if event&tun.EventUp != 0 {
+			d.up.Store(true)
+		}
 		if event&tun.EventDown != 0 {
 			d.logger.Infof("Interface down requested")
+			d.up.Store(false)
 			// TODO
 		}
 	}